@@ -60,6 +60,7 @@ func createAlertRule(c *gin.Context) (uint64, error) {
 	r.FailTriggerTasks = arf.FailTriggerTasks
 	r.RecoverTriggerTasks = arf.RecoverTriggerTasks
 	r.NotificationGroupID = arf.NotificationGroupID
+	r.EscalationPolicyID = arf.EscalationPolicyID
 	enable := arf.Enable
 	r.TriggerMode = arf.TriggerMode
 	r.Enable = &enable
@@ -114,6 +115,7 @@ func updateAlertRule(c *gin.Context) (any, error) {
 	r.FailTriggerTasks = arf.FailTriggerTasks
 	r.RecoverTriggerTasks = arf.RecoverTriggerTasks
 	r.NotificationGroupID = arf.NotificationGroupID
+	r.EscalationPolicyID = arf.EscalationPolicyID
 	enable := arf.Enable
 	r.TriggerMode = arf.TriggerMode
 	r.Enable = &enable
@@ -177,6 +179,29 @@ func validateRule(c *gin.Context, r *model.AlertRule) error {
 			default:
 				return singleton.Localizer.ErrorT("permission denied")
 			}
+			if err := checkServerGroupListPermission(c, rule.ServerGroups); err != nil {
+				return err
+			}
+
+			if err := rule.ValidateTarget(); err != nil {
+				return singleton.Localizer.ErrorT("invalid rule target: %v", err)
+			}
+
+			if rule.IsExpressionRule() {
+				if err := rule.CompileExpression(); err != nil {
+					return singleton.Localizer.ErrorT("invalid expression: %v", err)
+				}
+			}
+
+			if rule.IsHistoryRule() {
+				if err := rule.ValidateHistory(); err != nil {
+					return singleton.Localizer.ErrorT("invalid aggregation rule: %v", err)
+				}
+				// 没有历史数据时规则视为通过，TSDB 未启用时规则永远不会触发
+				if !singleton.TSDBEnabled() {
+					return singleton.Localizer.ErrorT("aggregation rules require TSDB to be enabled")
+				}
+			}
 
 			if !rule.IsTransferDurationRule() {
 				if rule.Duration < 3 {
@@ -211,6 +236,9 @@ func validateRule(c *gin.Context, r *model.AlertRule) error {
 	if err := assertOwnsNotificationGroup(c, r.NotificationGroupID); err != nil {
 		return err
 	}
+	if err := assertOwnsEscalationPolicy(c, r.EscalationPolicyID); err != nil {
+		return err
+	}
 
 	return nil
 }
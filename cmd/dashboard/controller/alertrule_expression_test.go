@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nezhahq/nezha/model"
+)
+
+// Expression rules must be compiled at write time so a typo or an unknown
+// variable is reported to the caller instead of silently firing forever
+// inside the alert sentinel.
+func TestValidateRuleExpression(t *testing.T) {
+	setupAlertRuleFanoutFixture(t)
+
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+	newRule := func(expr string) *model.AlertRule {
+		return &model.AlertRule{
+			Common: model.Common{UserID: 1},
+			Name:   "expr",
+			Rules:  []*model.Rule{{Type: model.RuleTypeExpression, Expression: expr, Cover: model.RuleCoverAll, Duration: 10}},
+		}
+	}
+
+	c := newAlertRuleCtxWithPAT(t, admin, nil, nil)
+	require.NoError(t, validateRule(c, newRule("cpu > 90 && load5 > cores*2")))
+	require.NoError(t, validateRule(c, newRule("disk_free_bytes < 10GiB")))
+
+	for _, expr := range []string{"", "cpu", "cpu > 90 &&", "password > 1", "system(1) > 0"} {
+		require.Error(t, validateRule(c, newRule(expr)), "expression %q must be rejected", expr)
+	}
+
+	short := newRule("cpu > 90")
+	short.Rules[0].Duration = 1
+	require.Error(t, validateRule(c, short), "expression rules keep the minimum duration")
+}
+
+func TestValidateRuleHistory(t *testing.T) {
+	setupAlertRuleFanoutFixture(t)
+
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+	newRule := func(typ, agg string, window uint64) *model.AlertRule {
+		return &model.AlertRule{
+			Common: model.Common{UserID: 1},
+			Name:   "history",
+			Rules:  []*model.Rule{{Type: typ, Aggregation: agg, Window: window, Max: 80, Cover: model.RuleCoverAll, Duration: 3}},
+		}
+	}
+
+	c := newAlertRuleCtxWithPAT(t, admin, nil, nil)
+	// 未启用 TSDB 时没有历史数据，规则永远不会触发
+	require.Error(t, validateRule(c, newRule("cpu", "avg", 900)))
+	require.Error(t, validateRule(c, newRule("transfer_out", "increase", 86400)))
+
+	setupPrometheusTest(t)
+	require.NoError(t, validateRule(c, newRule("cpu", "avg", 900)))
+	require.NoError(t, validateRule(c, newRule("transfer_out", "increase", 86400)))
+	require.Error(t, validateRule(c, newRule("cpu", "median", 900)))
+	require.Error(t, validateRule(c, newRule("offline", "avg", 900)))
+	require.Error(t, validateRule(c, newRule("cpu", "avg", 0)))
+}
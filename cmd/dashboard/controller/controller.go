@@ -74,6 +74,9 @@ func routers(r *gin.Engine, frontendDist fs.FS) {
 	api := r.Group("api/v1")
 	api.POST("/login", authMiddleware.LoginHandler)
 	api.GET("/oauth2/:provider", commonHandler(oauth2redirect))
+	// 心跳地址由路径中的密钥鉴权，便于计划任务直接调用
+	api.GET("/heartbeat/:token", commonHandler(pushHeartbeat))
+	api.POST("/heartbeat/:token", commonHandler(pushHeartbeat))
 
 	fallbackAuthMw := fallbackAuthMiddleware(authMiddleware)
 	fallbackAuth := api.Group("", fallbackAuthMw)
@@ -144,24 +147,52 @@ func routers(r *gin.Engine, frontendDist fs.FS) {
 
 	// service monitor
 	auth.GET("/service/list", restScopeMiddleware(model.ScopeServiceRead), listHandler(listService))
+	auth.GET("/service/certificate", restScopeMiddleware(model.ScopeServiceRead), commonHandler(listServiceCertificates))
 	auth.POST("/service", restScopeMiddleware(model.ScopeServiceWrite), commonHandler(createService))
 	auth.PATCH("/service/:id", restScopeMiddleware(model.ScopeServiceWrite), commonHandler(updateService))
 	auth.POST("/batch-delete/service", restScopeMiddleware(model.ScopeServiceDelete), commonHandler(batchDeleteService))
 
+	// Prometheus 兼容查询接口，Grafana 默认使用 POST 发送查询
+	auth.GET("/prometheus/api/v1/query", restScopeMiddleware(model.ScopeMetricsRead), promHandler(promQuery))
+	auth.POST("/prometheus/api/v1/query", restScopeMiddleware(model.ScopeMetricsRead), promHandler(promQuery))
+	auth.GET("/prometheus/api/v1/query_range", restScopeMiddleware(model.ScopeMetricsRead), promHandler(promQueryRange))
+	auth.POST("/prometheus/api/v1/query_range", restScopeMiddleware(model.ScopeMetricsRead), promHandler(promQueryRange))
+	auth.GET("/prometheus/api/v1/series", restScopeMiddleware(model.ScopeMetricsRead), promHandler(promSeries))
+	auth.POST("/prometheus/api/v1/series", restScopeMiddleware(model.ScopeMetricsRead), promHandler(promSeries))
+	auth.GET("/prometheus/api/v1/labels", restScopeMiddleware(model.ScopeMetricsRead), promHandler(promLabels))
+	auth.POST("/prometheus/api/v1/labels", restScopeMiddleware(model.ScopeMetricsRead), promHandler(promLabels))
+	auth.GET("/prometheus/api/v1/label/:name/values", restScopeMiddleware(model.ScopeMetricsRead), promHandler(promLabelValues))
+	auth.GET("/metrics", restScopeMiddleware(model.ScopeMetricsRead), metricsExporter)
+
 	auth.GET("/notification-group", restScopeMiddleware(model.ScopeNotificationGroupRead), commonHandler(listNotificationGroup))
 	auth.POST("/notification-group", restScopeMiddleware(model.ScopeNotificationGroupWrite), commonHandler(createNotificationGroup))
 	auth.PATCH("/notification-group/:id", restScopeMiddleware(model.ScopeNotificationGroupWrite), commonHandler(updateNotificationGroup))
 	auth.POST("/batch-delete/notification-group", restScopeMiddleware(model.ScopeNotificationGroupDelete), commonHandler(batchDeleteNotificationGroup))
+	auth.GET("/escalation-policy", restScopeMiddleware(model.ScopeNotificationGroupRead), listHandler(listEscalationPolicy))
+	auth.POST("/escalation-policy", restScopeMiddleware(model.ScopeNotificationGroupWrite), commonHandler(createEscalationPolicy))
+	auth.PATCH("/escalation-policy/:id", restScopeMiddleware(model.ScopeNotificationGroupWrite), commonHandler(updateEscalationPolicy))
+	auth.POST("/batch-delete/escalation-policy", restScopeMiddleware(model.ScopeNotificationGroupDelete), commonHandler(batchDeleteEscalationPolicy))
 
 	auth.GET("/notification", restScopeMiddleware(model.ScopeNotificationRead), listHandler(listNotification))
 	auth.POST("/notification", restScopeMiddleware(model.ScopeNotificationWrite), commonHandler(createNotification))
+	auth.POST("/notification/preview", restScopeMiddleware(model.ScopeNotificationWrite), commonHandler(previewNotificationTemplate))
+	auth.POST("/notification/test", restScopeMiddleware(model.ScopeNotificationWrite), commonHandler(dryRunNotification))
+	auth.POST("/notification/:id/test", restScopeMiddleware(model.ScopeNotificationWrite), commonHandler(testNotification))
 	auth.PATCH("/notification/:id", restScopeMiddleware(model.ScopeNotificationWrite), commonHandler(updateNotification))
+	auth.GET("/notification/:id/deliveries", restScopeMiddleware(model.ScopeNotificationRead), pCommonHandler(listNotificationDelivery))
 	auth.POST("/batch-delete/notification", restScopeMiddleware(model.ScopeNotificationDelete), commonHandler(batchDeleteNotification))
 
 	auth.GET("/alert-rule", restScopeMiddleware(model.ScopeAlertRuleRead), listHandler(listAlertRule))
 	auth.POST("/alert-rule", restScopeMiddleware(model.ScopeAlertRuleWrite), commonHandler(createAlertRule))
 	auth.PATCH("/alert-rule/:id", restScopeMiddleware(model.ScopeAlertRuleWrite), commonHandler(updateAlertRule))
 	auth.POST("/batch-delete/alert-rule", restScopeMiddleware(model.ScopeAlertRuleDelete), commonHandler(batchDeleteAlertRule))
+	auth.GET("/incident", restScopeMiddleware(model.ScopeAlertRuleRead), pCommonHandler(listIncident))
+	auth.GET("/escalation-delivery", restScopeMiddleware(model.ScopeAlertRuleRead), pCommonHandler(listEscalationDelivery))
+	auth.POST("/incident/:id/acknowledge", restScopeMiddleware(model.ScopeSilenceWrite), commonHandler(acknowledgeIncident))
+
+	auth.GET("/silence", restScopeMiddleware(model.ScopeSilenceRead), listHandler(listSilence))
+	auth.POST("/silence", restScopeMiddleware(model.ScopeSilenceWrite), commonHandler(createSilence))
+	auth.POST("/batch-delete/silence", restScopeMiddleware(model.ScopeSilenceDelete), commonHandler(batchDeleteSilence))
 
 	auth.GET("/cron", restScopeMiddleware(model.ScopeCronRead), listHandler(listCron))
 	auth.POST("/cron", restScopeMiddleware(model.ScopeCronWrite), commonHandler(createCron))
@@ -190,6 +221,12 @@ func routers(r *gin.Engine, frontendDist fs.FS) {
 	auth.POST("/online-user/batch-block", restScopeMiddleware(model.ScopeAdminAll), adminHandler(batchBlockOnlineUser))
 	auth.PATCH("/setting", restScopeMiddleware(model.ScopeAdminAll), adminHandler(updateConfig))
 	auth.POST("/maintenance", restScopeMiddleware(model.ScopeAdminAll), adminHandler(runMaintenance))
+	auth.GET("/tsdb/snapshot", restScopeMiddleware(model.ScopeAdminAll), adminDownloadHandler(downloadTSDBSnapshot))
+	auth.GET("/tsdb/export", restScopeMiddleware(model.ScopeAdminAll), adminDownloadHandler(exportTSDB))
+	auth.GET("/maintenance-window", restScopeMiddleware(model.ScopeAdminAll), adminHandler(listMaintenanceWindow))
+	auth.POST("/maintenance-window", restScopeMiddleware(model.ScopeAdminAll), adminHandler(createMaintenanceWindow))
+	auth.PATCH("/maintenance-window/:id", restScopeMiddleware(model.ScopeAdminAll), adminHandler(updateMaintenanceWindow))
+	auth.POST("/batch-delete/maintenance-window", restScopeMiddleware(model.ScopeAdminAll), adminHandler(batchDeleteMaintenanceWindow))
 
 	r.NoRoute(fallbackToFrontend(frontendDist))
 }
@@ -54,11 +54,17 @@ func createCron(c *gin.Context) (uint64, error) {
 		return 0, singleton.Localizer.ErrorT("permission denied")
 	}
 
-	if err := checkCronServerListPermission(c, cf.Cover, cf.Servers, getUid(c)); err != nil {
+	if err := checkServerGroupListPermission(c, cf.ServerGroups); err != nil {
 		return 0, err
 	}
 
-	if err := rejectImplicitCoverForLimitedPAT(c, cf.Cover, cf.Servers); err != nil {
+	// 分组按当前成员展开后与 Servers 一并校验
+	servers := model.ExpandServerGroups(cf.Servers, cf.ServerGroups)
+	if err := checkCronServerListPermission(c, cf.Cover, servers, getUid(c)); err != nil {
+		return 0, err
+	}
+
+	if err := rejectImplicitCoverForLimitedPAT(c, cf.Cover, servers); err != nil {
 		return 0, err
 	}
 
@@ -72,6 +78,7 @@ func createCron(c *gin.Context) (uint64, error) {
 	cr.Scheduler = cf.Scheduler
 	cr.Command = cf.Command
 	cr.Servers = cf.Servers
+	cr.ServerGroups = cf.ServerGroups
 	cr.PushSuccessful = cf.PushSuccessful
 	cr.NotificationGroupID = cf.NotificationGroupID
 	cr.Cover = cf.Cover
@@ -132,11 +139,16 @@ func updateCron(c *gin.Context) (any, error) {
 		return nil, singleton.Localizer.ErrorT("permission denied")
 	}
 
-	if err := checkCronServerListPermission(c, cf.Cover, cf.Servers, cr.GetUserID()); err != nil {
+	if err := checkServerGroupListPermission(c, cf.ServerGroups); err != nil {
+		return nil, err
+	}
+
+	servers := model.ExpandServerGroups(cf.Servers, cf.ServerGroups)
+	if err := checkCronServerListPermission(c, cf.Cover, servers, cr.GetUserID()); err != nil {
 		return nil, err
 	}
 
-	if err := rejectImplicitCoverForLimitedPATWithOwner(c, cf.Cover, cf.Servers, cr.GetUserID()); err != nil {
+	if err := rejectImplicitCoverForLimitedPATWithOwner(c, cf.Cover, servers, cr.GetUserID()); err != nil {
 		return nil, err
 	}
 
@@ -149,6 +161,7 @@ func updateCron(c *gin.Context) (any, error) {
 	cr.Scheduler = cf.Scheduler
 	cr.Command = cf.Command
 	cr.Servers = cf.Servers
+	cr.ServerGroups = cf.ServerGroups
 	cr.PushSuccessful = cf.PushSuccessful
 	cr.NotificationGroupID = cf.NotificationGroupID
 	cr.Cover = cf.Cover
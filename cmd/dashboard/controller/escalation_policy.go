@@ -0,0 +1,234 @@
+package controller
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+// List escalation policies
+// @Summary List escalation policies
+// @Security BearerAuth
+// @Schemes
+// @Description List escalation policies
+// @Tags auth required
+// @Param id query uint false "Resource ID"
+// @Produce json
+// @Success 200 {object} model.CommonResponse[[]model.EscalationPolicy]
+// @Router /escalation-policy [get]
+func listEscalationPolicy(c *gin.Context) ([]*model.EscalationPolicy, error) {
+	return singleton.EscalationPolicyShared.GetSortedList(), nil
+}
+
+// Add escalation policy
+// @Summary Add escalation policy
+// @Security BearerAuth
+// @Schemes
+// @Description Add an escalation policy that notifies notification groups step by step
+// @Tags auth required
+// @Accept json
+// @param request body model.EscalationPolicyForm true "Escalation Policy Request"
+// @Produce json
+// @Success 200 {object} model.CommonResponse[uint64]
+// @Router /escalation-policy [post]
+func createEscalationPolicy(c *gin.Context) (uint64, error) {
+	var ef model.EscalationPolicyForm
+	if err := c.ShouldBindJSON(&ef); err != nil {
+		return 0, err
+	}
+
+	var p model.EscalationPolicy
+	p.UserID = getUid(c)
+	if err := applyEscalationPolicyForm(c, &p, &ef); err != nil {
+		return 0, err
+	}
+
+	if err := singleton.DB.Create(&p).Error; err != nil {
+		return 0, newGormError("%v", err)
+	}
+
+	singleton.EscalationPolicyShared.Update(&p)
+	return p.ID, nil
+}
+
+// Update escalation policy
+// @Summary Update escalation policy
+// @Security BearerAuth
+// @Schemes
+// @Description Update escalation policy, escalations in progress continue with the new steps
+// @Tags auth required
+// @Accept json
+// @param id path uint true "Escalation Policy ID"
+// @param request body model.EscalationPolicyForm true "Escalation Policy Request"
+// @Produce json
+// @Success 200 {object} model.CommonResponse[any]
+// @Router /escalation-policy/{id} [patch]
+func updateEscalationPolicy(c *gin.Context) (any, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	var ef model.EscalationPolicyForm
+	if err := c.ShouldBindJSON(&ef); err != nil {
+		return nil, err
+	}
+
+	var p model.EscalationPolicy
+	if err := singleton.DB.First(&p, id).Error; err != nil {
+		return nil, singleton.Localizer.ErrorT("escalation policy id %d does not exist", id)
+	}
+	if !p.HasPermission(c) {
+		return nil, singleton.Localizer.ErrorT("permission denied")
+	}
+	if err := applyEscalationPolicyForm(c, &p, &ef); err != nil {
+		return nil, err
+	}
+
+	if err := singleton.DB.Save(&p).Error; err != nil {
+		return nil, newGormError("%v", err)
+	}
+
+	singleton.EscalationPolicyShared.Update(&p)
+	return nil, nil
+}
+
+// Batch delete escalation policies
+// @Summary Batch delete escalation policies
+// @Security BearerAuth
+// @Schemes
+// @Description Batch delete escalation policies, alert rules and services using them fall back to their own notification group
+// @Tags auth required
+// @Accept json
+// @param request body []uint64 true "id list"
+// @Produce json
+// @Success 200 {object} model.CommonResponse[any]
+// @Router /batch-delete/escalation-policy [post]
+func batchDeleteEscalationPolicy(c *gin.Context) (any, error) {
+	var ids []uint64
+	if err := c.ShouldBindJSON(&ids); err != nil {
+		return nil, err
+	}
+
+	if !singleton.EscalationPolicyShared.CheckPermission(c, slices.Values(ids)) {
+		return nil, singleton.Localizer.ErrorT("permission denied")
+	}
+
+	if err := singleton.DB.Unscoped().Delete(&model.EscalationPolicy{}, "id in (?)", ids).Error; err != nil {
+		return nil, newGormError("%v", err)
+	}
+
+	singleton.EscalationPolicyShared.Delete(ids)
+	return nil, nil
+}
+
+// List escalation deliveries
+// @Summary List escalation deliveries
+// @Security BearerAuth
+// @Schemes
+// @Description List notifications sent by escalation policies, newest first
+// @Tags auth required
+// @Param limit query uint false "Page limit"
+// @Param offset query uint false "Page offset"
+// @Param escalation_policy_id query uint false "Escalation policy ID"
+// @Param alert_rule_id query uint false "Alert rule ID"
+// @Param service_id query uint false "Service ID"
+// @Param incident_id query uint false "Incident ID"
+// @Produce json
+// @Success 200 {object} model.PaginatedResponse[[]model.EscalationDelivery, model.EscalationDelivery]
+// @Router /escalation-delivery [get]
+func listEscalationDelivery(c *gin.Context) (*model.Value[[]*model.EscalationDelivery], error) {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit < 1 {
+		limit = 25
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	offset, err := strconv.Atoi(c.Query("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	query := singleton.DB.Model(&model.EscalationDelivery{})
+
+	user := c.MustGet(model.CtxKeyAuthorizedUser).(*model.User)
+	if !user.Role.IsAdmin() {
+		query = query.Where("user_id = ?", user.ID)
+	}
+	// 带 server_ids 白名单的 PAT 只能看到白名单内服务器的报警记录
+	if patHasServerWhitelist(c) {
+		v, _ := c.Get(model.CtxKeyAPIToken)
+		query = query.Where("server_id IN (?)", v.(model.APITokenWhitelistView).ServerIDs())
+	}
+
+	for _, column := range []string{"escalation_policy_id", "alert_rule_id", "service_id", "incident_id"} {
+		v := c.Query(column)
+		if v == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(column+" = ?", id)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, newGormError("%v", err)
+	}
+
+	var deliveries []*model.EscalationDelivery
+	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&deliveries).Error; err != nil {
+		return nil, newGormError("%v", err)
+	}
+
+	return &model.Value[[]*model.EscalationDelivery]{
+		Value: deliveries,
+		Pagination: model.Pagination{
+			Offset: offset,
+			Limit:  limit,
+			Total:  total,
+		},
+	}, nil
+}
+
+// applyEscalationPolicyForm 校验表单并写入升级策略：至少一个步骤，每个步骤的通知组需存在且归调用方所有，
+// 步骤按延迟升序保存
+func applyEscalationPolicyForm(c *gin.Context, p *model.EscalationPolicy, ef *model.EscalationPolicyForm) error {
+	name := strings.TrimSpace(ef.Name)
+	if name == "" {
+		return singleton.Localizer.ErrorT("name can't be empty")
+	}
+	if len(ef.Steps) == 0 {
+		return singleton.Localizer.ErrorT("need to configure at least one escalation step")
+	}
+	for _, step := range ef.Steps {
+		if step.Delay > model.EscalationPolicyMaxDelay {
+			return singleton.Localizer.ErrorT("escalation delay can't be longer than 7 days")
+		}
+		if step.NotificationGroupID == 0 {
+			return singleton.Localizer.ErrorT("notification group id %d does not exist", step.NotificationGroupID)
+		}
+		if err := assertOwnsNotificationGroup(c, step.NotificationGroupID); err != nil {
+			return err
+		}
+	}
+
+	steps := slices.Clone(ef.Steps)
+	slices.SortStableFunc(steps, func(a, b model.EscalationStep) int {
+		return cmp.Compare(a.Delay, b.Delay)
+	})
+
+	p.Name = name
+	p.Steps = steps
+	return nil
+}
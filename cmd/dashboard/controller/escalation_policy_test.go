@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+func TestEscalationPolicyCRUD(t *testing.T) {
+	setupAlertRuleFanoutFixture(t)
+	require.NoError(t, singleton.DB.AutoMigrate(&model.EscalationPolicy{}, &model.NotificationGroup{}))
+
+	originalEscalation := singleton.EscalationPolicyShared
+	singleton.EscalationPolicyShared = singleton.NewEscalationPolicyClass()
+	t.Cleanup(func() { singleton.EscalationPolicyShared = originalEscalation })
+
+	require.NoError(t, singleton.DB.Create(&model.NotificationGroup{Common: model.Common{ID: 1, UserID: 1}, Name: "ops"}).Error)
+	require.NoError(t, singleton.DB.Create(&model.NotificationGroup{Common: model.Common{ID: 2, UserID: 1}, Name: "leads"}).Error)
+
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+	member := &model.User{Common: model.Common{ID: 2}, Role: model.RoleMember}
+
+	id, err := createEscalationPolicy(newAlertRuleCtxWithPAT(t, admin, nil, model.EscalationPolicyForm{
+		Name: " oncall ",
+		Steps: []model.EscalationStep{
+			{Delay: 900, NotificationGroupID: 2},
+			{Delay: 0, NotificationGroupID: 1},
+		},
+	}))
+	require.NoError(t, err)
+	p, ok := singleton.EscalationPolicyShared.Get(id)
+	require.True(t, ok)
+	assert.Equal(t, "oncall", p.Name)
+	assert.Equal(t, []model.EscalationStep{{Delay: 0, NotificationGroupID: 1}, {Delay: 900, NotificationGroupID: 2}}, p.Steps)
+
+	for name, form := range map[string]model.EscalationPolicyForm{
+		"NoName":       {Steps: []model.EscalationStep{{NotificationGroupID: 1}}},
+		"NoSteps":      {Name: "p"},
+		"MissingGroup": {Name: "p", Steps: []model.EscalationStep{{NotificationGroupID: 42}}},
+		"ZeroGroup":    {Name: "p", Steps: []model.EscalationStep{{}}},
+		"TooLate":      {Name: "p", Steps: []model.EscalationStep{{Delay: model.EscalationPolicyMaxDelay + 1, NotificationGroupID: 1}}},
+	} {
+		_, err := createEscalationPolicy(newAlertRuleCtxWithPAT(t, admin, nil, form))
+		assert.Error(t, err, name)
+	}
+
+	// 成员不能在策略中引用他人的通知组，也不能修改他人的策略
+	_, err = createEscalationPolicy(newAlertRuleCtxWithPAT(t, member, nil, model.EscalationPolicyForm{
+		Name: "p", Steps: []model.EscalationStep{{NotificationGroupID: 1}},
+	}))
+	assert.Error(t, err)
+	c := newAlertRuleCtxWithPAT(t, member, nil, model.EscalationPolicyForm{Name: "p", Steps: []model.EscalationStep{}})
+	c.Params = gin.Params{{Key: "id", Value: itoa(id)}}
+	_, err = updateEscalationPolicy(c)
+	assert.Error(t, err)
+
+	// 报警规则只能引用存在且有权使用的策略
+	rule := &model.AlertRule{
+		Common: model.Common{UserID: 1},
+		Name:   "cpu",
+		Rules:  []*model.Rule{{Type: "cpu", Max: 80, Cover: model.RuleCoverAll, Duration: 10}},
+	}
+	rule.EscalationPolicyID = id
+	require.NoError(t, validateRule(newAlertRuleCtxWithPAT(t, admin, nil, nil), rule))
+	rule.EscalationPolicyID = 42
+	assert.Error(t, validateRule(newAlertRuleCtxWithPAT(t, admin, nil, nil), rule))
+
+	_, err = batchDeleteEscalationPolicy(newAlertRuleCtxWithPAT(t, admin, nil, []uint64{id}))
+	require.NoError(t, err)
+	_, ok = singleton.EscalationPolicyShared.Get(id)
+	assert.False(t, ok)
+}
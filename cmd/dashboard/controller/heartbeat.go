@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+const heartbeatMessageMaxLength = 1024
+
+// Push heartbeat
+// @Summary Push heartbeat
+// @Schemes
+// @Description Report a run of a heartbeat service monitor. The token in the path authenticates the request; GET requests pass the payload as query parameters.
+// @Tags common
+// @Accept json
+// @param token path string true "Heartbeat token"
+// @param request body model.HeartbeatForm false "Heartbeat payload"
+// @Produce json
+// @Success 200 {object} model.CommonResponse[any]
+// @Router /heartbeat/{token} [post]
+func pushHeartbeat(c *gin.Context) (any, error) {
+	var hf model.HeartbeatForm
+	if err := c.ShouldBind(&hf); err != nil {
+		return nil, err
+	}
+
+	successful := true
+	switch strings.ToLower(hf.Status) {
+	case "", "up", "ok", "success":
+	case "down", "fail", "error":
+		successful = false
+	default:
+		return nil, singleton.Localizer.ErrorT("invalid heartbeat status: %s", hf.Status)
+	}
+	if hf.Duration < 0 {
+		hf.Duration = 0
+	}
+	if msg := []rune(hf.Message); len(msg) > heartbeatMessageMaxLength {
+		hf.Message = string(msg[:heartbeatMessageMaxLength])
+	}
+
+	if !singleton.ServiceSentinelShared.Heartbeat(c.Param("token"), successful, hf.Duration, hf.Message) {
+		model.BlockIP(singleton.DB, c.GetString(model.CtxKeyRealIPStr), model.WAFBlockReasonTypeBruteForceToken, model.BlockIDToken)
+		return nil, singleton.Localizer.ErrorT("heartbeat service not found")
+	}
+	return nil, nil
+}
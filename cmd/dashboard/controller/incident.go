@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+// List incidents
+// @Summary List incidents
+// @Security BearerAuth
+// @Schemes
+// @Description List alert and service incidents, newest first
+// @Tags auth required
+// @Param limit query uint false "Page limit"
+// @Param offset query uint false "Page offset"
+// @Param alert_rule_id query uint false "Alert rule ID"
+// @Param server_id query uint false "Server ID"
+// @Param service_id query uint false "Service ID"
+// @Param status query string false "open or resolved"
+// @Param from query uint false "Opened at or after (unix seconds)"
+// @Param to query uint false "Opened before (unix seconds)"
+// @Produce json
+// @Success 200 {object} model.PaginatedResponse[[]model.Incident, model.Incident]
+// @Router /incident [get]
+func listIncident(c *gin.Context) (*model.Value[[]*model.Incident], error) {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit < 1 {
+		limit = 25
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	offset, err := strconv.Atoi(c.Query("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	query := singleton.DB.Model(&model.Incident{})
+
+	user := c.MustGet(model.CtxKeyAuthorizedUser).(*model.User)
+	if !user.Role.IsAdmin() {
+		query = query.Where("user_id = ?", user.ID)
+	}
+	// 带 server_ids 白名单的 PAT 只能看到白名单内服务器的事件
+	if patHasServerWhitelist(c) {
+		v, _ := c.Get(model.CtxKeyAPIToken)
+		query = query.Where("server_id IN (?)", v.(model.APITokenWhitelistView).ServerIDs())
+	}
+
+	if v := c.Query("alert_rule_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("alert_rule_id = ?", id)
+	}
+	if v := c.Query("server_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("server_id = ?", id)
+	}
+	if v := c.Query("service_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("service_id = ?", id)
+	}
+	switch c.Query("status") {
+	case "":
+	case "open":
+		query = query.Where("resolved_at IS NULL")
+	case "resolved":
+		query = query.Where("resolved_at IS NOT NULL")
+	default:
+		return nil, singleton.Localizer.ErrorT("invalid status")
+	}
+	if v := c.Query("from"); v != "" {
+		ts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("opened_at >= ?", time.Unix(ts, 0))
+	}
+	if v := c.Query("to"); v != "" {
+		ts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("opened_at < ?", time.Unix(ts, 0))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, newGormError("%v", err)
+	}
+
+	var incidents []*model.Incident
+	if err := query.Order("opened_at DESC, id DESC").Limit(limit).Offset(offset).Find(&incidents).Error; err != nil {
+		return nil, newGormError("%v", err)
+	}
+
+	return &model.Value[[]*model.Incident]{
+		Value: incidents,
+		Pagination: model.Pagination{
+			Offset: offset,
+			Limit:  limit,
+			Total:  total,
+		},
+	}, nil
+}
+
+// Acknowledge incident
+// @Summary Acknowledge incident
+// @Security BearerAuth
+// @Schemes
+// @Description Mark an incident as acknowledged and silence its alert on the server, or its service, for the given duration
+// @Tags auth required
+// @Accept json
+// @param id path uint true "Incident ID"
+// @param request body model.IncidentAcknowledgeForm true "Acknowledge Request"
+// @Produce json
+// @Success 200 {object} model.CommonResponse[uint64]
+// @Router /incident/{id}/acknowledge [post]
+func acknowledgeIncident(c *gin.Context) (uint64, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	var af model.IncidentAcknowledgeForm
+	if err := c.ShouldBindJSON(&af); err != nil {
+		return 0, err
+	}
+
+	var incident model.Incident
+	if err := singleton.DB.First(&incident, id).Error; err != nil {
+		return 0, singleton.Localizer.ErrorT("incident id %d does not exist", id)
+	}
+	if !incident.HasPermission(c) || !patAllowsServer(c, incident.ServerID) {
+		return 0, singleton.Localizer.ErrorT("permission denied")
+	}
+	if !incident.IsOpen() {
+		return 0, singleton.Localizer.ErrorT("incident has already been resolved")
+	}
+
+	uid := getUid(c)
+	s := model.Silence{
+		AlertRuleID: incident.AlertRuleID,
+		ServerID:    incident.ServerID,
+		ServiceID:   incident.ServiceID,
+		IncidentID:  incident.ID,
+	}
+	s.UserID = uid
+	if err := validateSilence(c, &s, af.Reason, af.Duration); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	if err := singleton.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&s).Error; err != nil {
+			return err
+		}
+		return tx.Model(&incident).Updates(map[string]any{
+			"acknowledged_by": uid,
+			"acknowledged_at": now,
+		}).Error
+	}); err != nil {
+		return 0, newGormError("%v", err)
+	}
+
+	singleton.SilenceShared.Update(&s)
+	return s.ID, nil
+}
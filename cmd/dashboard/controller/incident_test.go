@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+func newIncidentCtx(t *testing.T, viewer *model.User, tok *model.APIToken, query string) *gin.Context {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/incident?"+query, nil)
+	c.Set(model.CtxKeyAuthorizedUser, viewer)
+	if tok != nil {
+		c.Set(model.CtxKeyAPIToken, tok)
+	}
+	return c
+}
+
+func TestListIncidentFiltersAndVisibility(t *testing.T) {
+	setupAlertRuleFanoutFixture(t)
+	require.NoError(t, singleton.DB.AutoMigrate(&model.Incident{}))
+
+	now := time.Now()
+	resolved := now.Add(-time.Hour)
+	incidents := []*model.Incident{
+		{Common: model.Common{UserID: 1}, AlertRuleID: 1, ServerID: 1, OpenedAt: now.Add(-48 * time.Hour), ResolvedAt: &resolved},
+		{Common: model.Common{UserID: 1}, AlertRuleID: 1, ServerID: 2, OpenedAt: now.Add(-time.Hour)},
+		{Common: model.Common{UserID: 2}, AlertRuleID: 2, ServerID: 3, OpenedAt: now.Add(-time.Minute)},
+	}
+	for _, i := range incidents {
+		require.NoError(t, singleton.DB.Create(i).Error)
+	}
+
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+	member := &model.User{Common: model.Common{ID: 2}, Role: model.RoleMember}
+
+	list := func(viewer *model.User, tok *model.APIToken, query string) *model.Value[[]*model.Incident] {
+		v, err := listIncident(newIncidentCtx(t, viewer, tok, query))
+		require.NoError(t, err)
+		return v
+	}
+
+	all := list(admin, nil, "")
+	assert.Equal(t, int64(3), all.Pagination.Total)
+	assert.Equal(t, uint64(3), all.Value[0].ServerID, "newest first")
+
+	mine := list(member, nil, "")
+	require.Len(t, mine.Value, 1)
+	assert.Equal(t, uint64(2), mine.Value[0].UserID)
+
+	open := list(admin, nil, "status=open&alert_rule_id=1")
+	require.Len(t, open.Value, 1)
+	assert.Equal(t, uint64(2), open.Value[0].ServerID)
+
+	recent := list(admin, nil, "from="+strconv.FormatInt(now.Add(-2*time.Hour).Unix(), 10))
+	assert.Equal(t, int64(2), recent.Pagination.Total)
+
+	paged := list(admin, nil, "limit=1&offset=1")
+	require.Len(t, paged.Value, 1)
+	assert.Equal(t, int64(3), paged.Pagination.Total)
+
+	tok := &model.APIToken{ID: 5, UserID: 1}
+	tok.SetServerIDs([]uint64{1})
+	limited := list(admin, tok, "")
+	require.Len(t, limited.Value, 1)
+	assert.Equal(t, uint64(1), limited.Value[0].ServerID)
+
+	_, err := listIncident(newIncidentCtx(t, admin, nil, "status=bogus"))
+	require.Error(t, err)
+}
@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/copier"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+// List maintenance windows
+// @Summary List maintenance windows
+// @Security BearerAuth
+// @Schemes
+// @Description List maintenance windows
+// @Tags admin required
+// @Produce json
+// @Success 200 {object} model.CommonResponse[[]model.MaintenanceWindow]
+// @Router /maintenance-window [get]
+func listMaintenanceWindow(c *gin.Context) ([]*model.MaintenanceWindow, error) {
+	slist := singleton.MaintenanceWindowShared.GetSortedList()
+
+	var windows []*model.MaintenanceWindow
+	if err := copier.Copy(&windows, &slist); err != nil {
+		return nil, err
+	}
+	return windows, nil
+}
+
+// Create maintenance window
+// @Summary Create maintenance window
+// @Security BearerAuth
+// @Schemes
+// @Description Create a one-off or recurring maintenance window
+// @Tags admin required
+// @Accept json
+// @param request body model.MaintenanceWindowForm true "Maintenance Window Request"
+// @Produce json
+// @Success 200 {object} model.CommonResponse[uint64]
+// @Router /maintenance-window [post]
+func createMaintenanceWindow(c *gin.Context) (uint64, error) {
+	var mf model.MaintenanceWindowForm
+	if err := c.ShouldBindJSON(&mf); err != nil {
+		return 0, err
+	}
+
+	var w model.MaintenanceWindow
+	w.UserID = getUid(c)
+	if err := applyMaintenanceWindowForm(&w, &mf); err != nil {
+		return 0, err
+	}
+
+	if err := singleton.DB.Create(&w).Error; err != nil {
+		return 0, newGormError("%v", err)
+	}
+
+	singleton.MaintenanceWindowShared.Update(&w)
+	return w.ID, nil
+}
+
+// Update maintenance window
+// @Summary Update maintenance window
+// @Security BearerAuth
+// @Schemes
+// @Description Update maintenance window
+// @Tags admin required
+// @Accept json
+// @param id path uint true "Maintenance Window ID"
+// @param request body model.MaintenanceWindowForm true "Maintenance Window Request"
+// @Produce json
+// @Success 200 {object} model.CommonResponse[any]
+// @Router /maintenance-window/{id} [patch]
+func updateMaintenanceWindow(c *gin.Context) (any, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	var mf model.MaintenanceWindowForm
+	if err := c.ShouldBindJSON(&mf); err != nil {
+		return nil, err
+	}
+
+	var w model.MaintenanceWindow
+	if err := singleton.DB.First(&w, id).Error; err != nil {
+		return nil, singleton.Localizer.ErrorT("maintenance window id %d does not exist", id)
+	}
+	if err := applyMaintenanceWindowForm(&w, &mf); err != nil {
+		return nil, err
+	}
+
+	if err := singleton.DB.Save(&w).Error; err != nil {
+		return nil, newGormError("%v", err)
+	}
+
+	singleton.MaintenanceWindowShared.Update(&w)
+	return nil, nil
+}
+
+// Batch delete maintenance windows
+// @Summary Batch delete maintenance windows
+// @Security BearerAuth
+// @Schemes
+// @Description Batch delete maintenance windows
+// @Tags admin required
+// @Accept json
+// @param request body []uint64 true "id list"
+// @Produce json
+// @Success 200 {object} model.CommonResponse[any]
+// @Router /batch-delete/maintenance-window [post]
+func batchDeleteMaintenanceWindow(c *gin.Context) (any, error) {
+	var ids []uint64
+	if err := c.ShouldBindJSON(&ids); err != nil {
+		return nil, err
+	}
+
+	if err := singleton.DB.Unscoped().Delete(&model.MaintenanceWindow{}, "id in (?)", ids).Error; err != nil {
+		return nil, newGormError("%v", err)
+	}
+
+	singleton.MaintenanceWindowShared.Delete(ids)
+	return nil, nil
+}
+
+// applyMaintenanceWindowForm 校验表单并写入维护窗口：一次性窗口与周期窗口二选一，
+// 且至少指定一个存在的服务器、服务器分组或服务
+func applyMaintenanceWindowForm(w *model.MaintenanceWindow, mf *model.MaintenanceWindowForm) error {
+	name := strings.TrimSpace(mf.Name)
+	if name == "" {
+		return singleton.Localizer.ErrorT("name can't be empty")
+	}
+
+	oneOff := mf.StartAt != nil || mf.EndAt != nil
+	recurring := mf.Scheduler != ""
+	switch {
+	case oneOff && recurring, !oneOff && !recurring:
+		return singleton.Localizer.ErrorT("need to specify either a time range or a schedule")
+	case oneOff:
+		if mf.StartAt == nil || mf.EndAt == nil || !mf.EndAt.After(*mf.StartAt) {
+			return singleton.Localizer.ErrorT("end time must be after start time")
+		}
+		mf.Duration = 0
+	case recurring:
+		if mf.Duration == 0 || mf.Duration > model.MaintenanceWindowMaxDuration {
+			return singleton.Localizer.ErrorT("duration need to be between 1 second and 30 days")
+		}
+	}
+
+	if len(mf.Servers) == 0 && len(mf.ServerGroups) == 0 && len(mf.Services) == 0 {
+		return singleton.Localizer.ErrorT("need to specify at least one maintenance target")
+	}
+	for _, id := range mf.Servers {
+		if _, ok := singleton.ServerShared.Get(id); !ok {
+			return singleton.Localizer.ErrorT("server id %d does not exist", id)
+		}
+	}
+	for _, id := range mf.Services {
+		if _, ok := singleton.ServiceSentinelShared.Get(id); !ok {
+			return singleton.Localizer.ErrorT("service id %d does not exist", id)
+		}
+	}
+	for _, id := range mf.ServerGroups {
+		var sg model.ServerGroup
+		if err := singleton.DB.First(&sg, id).Error; err != nil {
+			return singleton.Localizer.ErrorT("group id %d does not exist", id)
+		}
+	}
+
+	w.Name = name
+	w.Enabled = mf.Enabled
+	w.StartAt = mf.StartAt
+	w.EndAt = mf.EndAt
+	w.Scheduler = mf.Scheduler
+	w.Duration = mf.Duration
+	w.Servers = mf.Servers
+	w.ServerGroups = mf.ServerGroups
+	w.Services = mf.Services
+	if err := w.ParseScheduler(); err != nil {
+		return singleton.Localizer.ErrorT("invalid schedule: %v", err)
+	}
+	return nil
+}
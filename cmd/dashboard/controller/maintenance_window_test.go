@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+func TestMaintenanceWindowCRUD(t *testing.T) {
+	setupAlertRuleFanoutFixture(t)
+	require.NoError(t, singleton.DB.AutoMigrate(&model.MaintenanceWindow{}, &model.ServerGroup{}, &model.ServerGroupServer{}))
+
+	originalMaintenance := singleton.MaintenanceWindowShared
+	singleton.MaintenanceWindowShared = singleton.NewMaintenanceWindowClass()
+	t.Cleanup(func() { singleton.MaintenanceWindowShared = originalMaintenance })
+
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+	start := time.Now().Add(-time.Minute)
+	end := start.Add(time.Hour)
+
+	id, err := createMaintenanceWindow(newAlertRuleCtxWithPAT(t, admin, nil, model.MaintenanceWindowForm{
+		Name: " upgrade ", Enabled: true, StartAt: &start, EndAt: &end, Servers: []uint64{1},
+	}))
+	require.NoError(t, err)
+	if status := singleton.MaintenanceWindowShared.ServerStatus(1); assert.NotNil(t, status) {
+		assert.Equal(t, "upgrade", status.Name)
+	}
+	assert.Nil(t, singleton.MaintenanceWindowShared.ServerStatus(2))
+
+	for name, form := range map[string]model.MaintenanceWindowForm{
+		"NoName":         {StartAt: &start, EndAt: &end, Servers: []uint64{1}},
+		"NoTimeRange":    {Name: "w", Servers: []uint64{1}},
+		"BothForms":      {Name: "w", StartAt: &start, EndAt: &end, Scheduler: "@daily", Duration: 60, Servers: []uint64{1}},
+		"EndBeforeStart": {Name: "w", StartAt: &end, EndAt: &start, Servers: []uint64{1}},
+		"NoDuration":     {Name: "w", Scheduler: "@daily", Servers: []uint64{1}},
+		"BadScheduler":   {Name: "w", Scheduler: "0 0 2 * *", Duration: 60, Servers: []uint64{1}},
+		"NoTarget":       {Name: "w", StartAt: &start, EndAt: &end},
+		"MissingServer":  {Name: "w", StartAt: &start, EndAt: &end, Servers: []uint64{42}},
+		"MissingGroup":   {Name: "w", StartAt: &start, EndAt: &end, ServerGroups: []uint64{42}},
+	} {
+		_, err := createMaintenanceWindow(newAlertRuleCtxWithPAT(t, admin, nil, form))
+		assert.Error(t, err, name)
+	}
+
+	// 改为每天 02:00 的周期窗口并更换目标后，原服务器立即解除维护
+	c := newAlertRuleCtxWithPAT(t, admin, nil, model.MaintenanceWindowForm{
+		Name: "nightly", Enabled: true, Scheduler: "0 0 2 * * *", Duration: 60, Servers: []uint64{2},
+	})
+	c.Params = gin.Params{{Key: "id", Value: itoa(id)}}
+	_, err = updateMaintenanceWindow(c)
+	require.NoError(t, err)
+	w, ok := singleton.MaintenanceWindowShared.Get(id)
+	require.True(t, ok)
+	assert.Equal(t, "nightly", w.Name)
+	assert.Nil(t, w.StartAt)
+	assert.Nil(t, singleton.MaintenanceWindowShared.ServerStatus(1))
+
+	var stored model.MaintenanceWindow
+	require.NoError(t, singleton.DB.First(&stored, id).Error)
+	assert.Equal(t, []uint64{2}, stored.Servers)
+
+	list, err := listMaintenanceWindow(c)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	_, err = batchDeleteMaintenanceWindow(newAlertRuleCtxWithPAT(t, admin, nil, []uint64{id}))
+	require.NoError(t, err)
+	_, ok = singleton.MaintenanceWindowShared.Get(id)
+	assert.False(t, ok)
+}
+
+func TestMarkServersUnderMaintenance(t *testing.T) {
+	servers := []model.StreamServer{{ID: 1}, {ID: 2}}
+	status := &model.MaintenanceStatus{WindowID: 3, Name: "upgrade", Until: time.Now().Add(time.Hour)}
+
+	markServersUnderMaintenance(servers, nil)
+	assert.Nil(t, servers[0].Maintenance)
+
+	markServersUnderMaintenance(servers, map[uint64]*model.MaintenanceStatus{2: status})
+	assert.Nil(t, servers[0].Maintenance)
+	assert.Equal(t, status, servers[1].Maintenance)
+}
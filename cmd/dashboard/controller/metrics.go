@@ -0,0 +1,289 @@
+package controller
+
+import (
+	"bytes"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/copier"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/service/rpc"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+// 服务器超过该时长未上报即视为离线，与前端的判断保持一致
+const metricsOnlineWindow = 30 * time.Second
+
+var metricsLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricFamily 同名指标的全部样本，文本格式要求同名样本连续输出
+type metricFamily struct {
+	name    string
+	help    string
+	typ     string
+	samples []string
+}
+
+// metricsWriter 按首次出现的顺序收集指标族，生成 Prometheus 文本格式
+type metricsWriter struct {
+	families []*metricFamily
+	index    map[string]*metricFamily
+}
+
+// add 追加一个样本，labels 为依次排列的标签名与标签值
+func (w *metricsWriter) add(name, typ, help string, value float64, labels ...string) {
+	if w.index == nil {
+		w.index = make(map[string]*metricFamily)
+	}
+	f, ok := w.index[name]
+	if !ok {
+		f = &metricFamily{name: name, help: help, typ: typ}
+		w.index[name] = f
+		w.families = append(w.families, f)
+	}
+
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(labels[i])
+			b.WriteString(`="`)
+			b.WriteString(metricsLabelEscaper.Replace(labels[i+1]))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	f.samples = append(f.samples, b.String())
+}
+
+func (w *metricsWriter) gauge(name, help string, value float64, labels ...string) {
+	w.add(name, "gauge", help, value, labels...)
+}
+
+func (w *metricsWriter) counter(name, help string, value float64, labels ...string) {
+	w.add(name, "counter", help, value, labels...)
+}
+
+func (w *metricsWriter) bytes() []byte {
+	var buf bytes.Buffer
+	for _, f := range w.families {
+		buf.WriteString("# HELP " + f.name + " " + f.help + "\n")
+		buf.WriteString("# TYPE " + f.name + " " + f.typ + "\n")
+		for _, s := range f.samples {
+			buf.WriteString(s)
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Prometheus exporter
+// @Summary Prometheus metrics of live server and service state
+// @Security BearerAuth
+// @Schemes
+// @Description Expose the current state of visible servers and services in the Prometheus text format. Dashboard internals are only exported to admins.
+// @Tags common
+// @Produce plain
+// @Success 200 {string} string
+// @Router /metrics [get]
+func metricsExporter(c *gin.Context) {
+	var w metricsWriter
+	if err := writeServerMetrics(c, &w); err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeServiceMetrics(c, &w)
+	writeCycleTransferMetrics(c, &w)
+	if callerIsAdmin(c) && !patHasServerWhitelist(c) {
+		writeDashboardMetrics(&w)
+	}
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", w.bytes())
+}
+
+// serverGroupLabels 返回 [server_id] -> 调用者可见的分组名，多个分组以逗号分隔
+func serverGroupLabels(c *gin.Context) (map[uint64]string, error) {
+	var groups []model.ServerGroup
+	if err := singleton.DB.Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	var members []model.ServerGroupServer
+	if err := singleton.DB.Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	names := make(map[uint64]string, len(groups))
+	for _, g := range groups {
+		if g.HasPermission(c) {
+			names[g.ID] = g.Name
+		}
+	}
+	serverGroups := make(map[uint64][]string)
+	for _, m := range members {
+		if name, ok := names[m.ServerGroupId]; ok {
+			serverGroups[m.ServerId] = append(serverGroups[m.ServerId], name)
+		}
+	}
+	labels := make(map[uint64]string, len(serverGroups))
+	for id, groupNames := range serverGroups {
+		slices.Sort(groupNames)
+		labels[id] = strings.Join(groupNames, ",")
+	}
+	return labels, nil
+}
+
+func writeServerMetrics(c *gin.Context, w *metricsWriter) error {
+	groups, err := serverGroupLabels(c)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, server := range singleton.ServerShared.GetSortedList() {
+		if !userCanViewServer(c, server) {
+			continue
+		}
+		var country string
+		if server.GeoIP != nil {
+			country = server.GeoIP.CountryCode
+		}
+		labels := []string{"id", strconv.FormatUint(server.ID, 10), "name", server.Name, "group", groups[server.ID], "country", country}
+
+		runtime := server.RuntimeSnapshot()
+		online := !runtime.LastActive.IsZero() && now.Sub(runtime.LastActive) < metricsOnlineWindow
+		w.gauge("nezha_server_online", "Whether the agent reported within the last 30 seconds.", boolToFloat(online), labels...)
+		if !runtime.LastActive.IsZero() {
+			w.gauge("nezha_server_last_active_timestamp_seconds", "Unix time of the last agent report.", float64(runtime.LastActive.Unix()), labels...)
+		}
+
+		state := runtime.State
+		if state == nil {
+			continue
+		}
+		w.gauge("nezha_server_cpu", "CPU usage in percent.", state.CPU, labels...)
+		w.gauge("nezha_server_memory", "Used memory in bytes.", float64(state.MemUsed), labels...)
+		w.gauge("nezha_server_swap", "Used swap in bytes.", float64(state.SwapUsed), labels...)
+		w.gauge("nezha_server_disk", "Used disk space in bytes.", float64(state.DiskUsed), labels...)
+		w.counter("nezha_server_net_in_transfer", "Total inbound traffic in bytes reported by the agent.", float64(state.NetInTransfer), labels...)
+		w.counter("nezha_server_net_out_transfer", "Total outbound traffic in bytes reported by the agent.", float64(state.NetOutTransfer), labels...)
+		w.gauge("nezha_server_net_in_speed", "Inbound speed in bytes per second.", float64(state.NetInSpeed), labels...)
+		w.gauge("nezha_server_net_out_speed", "Outbound speed in bytes per second.", float64(state.NetOutSpeed), labels...)
+		w.gauge("nezha_server_uptime", "System uptime in seconds.", float64(state.Uptime), labels...)
+		w.gauge("nezha_server_load1", "Load average over 1 minute.", state.Load1, labels...)
+		w.gauge("nezha_server_load5", "Load average over 5 minutes.", state.Load5, labels...)
+		w.gauge("nezha_server_load15", "Load average over 15 minutes.", state.Load15, labels...)
+		w.gauge("nezha_server_tcp_conn", "Number of TCP connections.", float64(state.TcpConnCount), labels...)
+		w.gauge("nezha_server_udp_conn", "Number of UDP connections.", float64(state.UdpConnCount), labels...)
+		w.gauge("nezha_server_process_count", "Number of processes.", float64(state.ProcessCount), labels...)
+		for _, t := range state.Temperatures {
+			w.gauge("nezha_server_temperature", "Sensor temperature in degrees Celsius.", t.Temperature, append(slices.Clone(labels), "sensor", t.Name)...)
+		}
+		for i, usage := range state.GPU {
+			w.gauge("nezha_server_gpu", "GPU usage in percent.", usage, append(slices.Clone(labels), "gpu", strconv.Itoa(i))...)
+		}
+		for _, d := range state.Disks {
+			diskLabels := append(slices.Clone(labels), "mountpoint", d.Mountpoint)
+			w.gauge("nezha_server_mount_used", "Used space of a mount point in bytes.", float64(d.Used), diskLabels...)
+			w.gauge("nezha_server_mount_total", "Total space of a mount point in bytes.", float64(d.Total), diskLabels...)
+		}
+		for _, n := range state.NetInterfaces {
+			nicLabels := append(slices.Clone(labels), "interface", n.Name)
+			w.counter("nezha_server_nic_in_transfer", "Total inbound traffic of a network interface in bytes.", float64(n.InTransfer), nicLabels...)
+			w.counter("nezha_server_nic_out_transfer", "Total outbound traffic of a network interface in bytes.", float64(n.OutTransfer), nicLabels...)
+			w.gauge("nezha_server_nic_in_speed", "Inbound speed of a network interface in bytes per second.", float64(n.InSpeed), nicLabels...)
+			w.gauge("nezha_server_nic_out_speed", "Outbound speed of a network interface in bytes per second.", float64(n.OutSpeed), nicLabels...)
+		}
+	}
+	return nil
+}
+
+func writeServiceMetrics(c *gin.Context, w *metricsWriter) {
+	if singleton.ServiceSentinelShared == nil {
+		return
+	}
+	stats := filterServiceStatsForViewer(c, singleton.ServiceSentinelShared.CopyStats())
+	servers := singleton.ServerShared.GetList()
+	for _, id := range slices.Sorted(maps.Keys(stats)) {
+		stat := stats[id]
+		labels := []string{"service_id", strconv.FormatUint(id, 10), "service_name", stat.ServiceName}
+		if stat.Status != "" && stat.Status != model.NotificationEventServiceNoData {
+			w.gauge("nezha_service_up", "Whether the service is not down across all monitoring locations.", boolToFloat(stat.Status != model.NotificationEventServiceDown), labels...)
+		}
+		if stat.TotalUp+stat.TotalDown > 0 {
+			w.gauge("nezha_service_availability", "Availability over the last 30 days in percent.", float64(stat.TotalUptime()), labels...)
+		}
+		if stat.CurrentUp+stat.CurrentDown > 0 {
+			w.gauge("nezha_service_availability_today", "Availability since midnight in percent.", float64(stat.CurrentUp)/float64(stat.CurrentUp+stat.CurrentDown)*100, labels...)
+		}
+		if stat.Delay != nil {
+			w.gauge("nezha_service_delay", "Average delay of today in milliseconds.", stat.Delay[len(stat.Delay)-1], labels...)
+		}
+
+		for _, reporterID := range slices.Sorted(maps.Keys(stat.Reporters)) {
+			reporter := stat.Reporters[reporterID]
+			reporterName := "Dashboard"
+			if server, ok := servers[reporterID]; ok {
+				reporterName = server.Name
+			}
+			reporterLabels := append(slices.Clone(labels), "reporter_id", strconv.FormatUint(reporterID, 10), "reporter_name", reporterName)
+			w.gauge("nezha_service_reporter_up", "Whether the latest check from the monitoring location succeeded.", boolToFloat(reporter.Status != model.NotificationEventServiceDown), reporterLabels...)
+			w.gauge("nezha_service_reporter_delay", "Latest delay from the monitoring location in milliseconds.", reporter.Delay, reporterLabels...)
+		}
+	}
+}
+
+func writeCycleTransferMetrics(c *gin.Context, w *metricsWriter) {
+	singleton.AlertsLock.RLock()
+	var cycleTransferStats map[uint64]model.CycleTransferStats
+	copier.Copy(&cycleTransferStats, singleton.AlertsCycleTransferStatsStore)
+	singleton.AlertsLock.RUnlock()
+
+	stats := filterCycleTransferStatsForViewer(c, cycleTransferStats)
+	for _, id := range slices.Sorted(maps.Keys(stats)) {
+		stat := stats[id]
+		labels := []string{"alert_id", strconv.FormatUint(id, 10), "alert_name", stat.Name}
+		w.gauge("nezha_cycle_transfer_start_timestamp_seconds", "Start of the current transfer cycle.", float64(stat.From.Unix()), labels...)
+		w.gauge("nezha_cycle_transfer_end_timestamp_seconds", "End of the current transfer cycle.", float64(stat.To.Unix()), labels...)
+		w.gauge("nezha_cycle_transfer_max", "Upper limit of the transfer cycle in bytes, 0 if unset.", float64(stat.Max), labels...)
+		w.gauge("nezha_cycle_transfer_min", "Lower limit of the transfer cycle in bytes, 0 if unset.", float64(stat.Min), labels...)
+		for _, serverID := range slices.Sorted(maps.Keys(stat.Transfer)) {
+			serverLabels := append(slices.Clone(labels), "id", strconv.FormatUint(serverID, 10), "name", stat.ServerName[serverID])
+			w.gauge("nezha_cycle_transfer", "Transfer in the current cycle in bytes.", float64(stat.Transfer[serverID]), serverLabels...)
+		}
+	}
+}
+
+func writeDashboardMetrics(w *metricsWriter) {
+	var agents int
+	for _, server := range singleton.ServerShared.GetSortedList() {
+		if server.GetTaskStream() != nil {
+			agents++
+		}
+	}
+	w.gauge("nezha_dashboard_connected_agents", "Number of agents holding a task stream.", float64(agents))
+	if rpc.NezhaHandlerSingleton != nil {
+		w.gauge("nezha_dashboard_io_streams", "Number of active terminal, file manager, NAT and transfer streams.", float64(rpc.NezhaHandlerSingleton.StreamCount()))
+	}
+	attempts, deliveries := singleton.NotificationFailureCounts()
+	w.counter("nezha_dashboard_notification_attempt_failures_total", "Failed notification send attempts since start, including ones retried later.", float64(attempts))
+	w.counter("nezha_dashboard_notification_delivery_failures_total", "Notifications given up after the maximum number of attempts since start.", float64(deliveries))
+	w.gauge("nezha_dashboard_info", "Dashboard version.", 1, "version", singleton.Version)
+}
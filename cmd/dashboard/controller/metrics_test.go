@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+func setupMetricsTest(t *testing.T) {
+	t.Helper()
+	originalDB := singleton.DB
+	originalServer := singleton.ServerShared
+	originalSentinel := singleton.ServiceSentinelShared
+	originalCycleStats := singleton.AlertsCycleTransferStatsStore
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = sqlDB.Close()
+		singleton.DB = originalDB
+		singleton.ServerShared = originalServer
+		singleton.ServiceSentinelShared = originalSentinel
+		singleton.AlertsLock.Lock()
+		singleton.AlertsCycleTransferStatsStore = originalCycleStats
+		singleton.AlertsLock.Unlock()
+	})
+	require.NoError(t, db.AutoMigrate(&model.ServerGroup{}, &model.ServerGroupServer{}))
+	singleton.DB = db
+	singleton.ServiceSentinelShared = nil
+
+	require.NoError(t, db.Create(&model.ServerGroup{Common: model.Common{ID: 1, UserID: 10}, Name: "edge"}).Error)
+	require.NoError(t, db.Create(&model.ServerGroup{Common: model.Common{ID: 2, UserID: 20}, Name: "private"}).Error)
+	require.NoError(t, db.Create(&model.ServerGroupServer{ServerGroupId: 1, ServerId: 1}).Error)
+	require.NoError(t, db.Create(&model.ServerGroupServer{ServerGroupId: 2, ServerId: 1}).Error)
+
+	singleton.ServerShared = singleton.NewEmptyServerClassForTest()
+	singleton.ServerShared.InsertForTest(&model.Server{
+		Common:     model.Common{ID: 1, UserID: 10},
+		Name:       `web "1"`,
+		GeoIP:      &model.GeoIP{CountryCode: "de"},
+		LastActive: time.Now(),
+		State: &model.HostState{
+			CPU:           12.5,
+			MemUsed:       1024,
+			Temperatures:  []model.SensorTemperature{{Name: "cpu", Temperature: 50}},
+			GPU:           []float64{30},
+			Disks:         []model.DiskState{{Mountpoint: "/data", Total: 2048, Used: 512}},
+			NetInterfaces: []model.NetInterfaceState{{Name: "eth0", InTransfer: 100, InSpeed: 10}},
+		},
+	})
+	singleton.ServerShared.InsertForTest(&model.Server{Common: model.Common{ID: 2, UserID: 20}, Name: "hidden", HideForGuest: true,
+		State: &model.HostState{CPU: 99}})
+
+	singleton.AlertsLock.Lock()
+	singleton.AlertsCycleTransferStatsStore = map[uint64]*model.CycleTransferStats{
+		5: {
+			Name:       "monthly",
+			Max:        1000,
+			ServerName: map[uint64]string{1: `web "1"`, 2: "hidden"},
+			Transfer:   map[uint64]uint64{1: 300, 2: 700},
+		},
+	}
+	singleton.AlertsLock.Unlock()
+}
+
+func scrapeMetrics(user *model.User) (int, string) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/metrics", nil)
+	c.Set(model.CtxKeyAuthorizedUser, user)
+	metricsExporter(c)
+	return w.Code, w.Body.String()
+}
+
+func TestMetricsExporterMember(t *testing.T) {
+	setupMetricsTest(t)
+
+	code, body := scrapeMetrics(&model.User{Common: model.Common{ID: 10}, Role: model.RoleMember})
+	require.Equal(t, http.StatusOK, code)
+
+	labels := `id="1",name="web \"1\"",group="edge",country="de"`
+	assert.Contains(t, body, "# TYPE nezha_server_cpu gauge\nnezha_server_cpu{"+labels+"} 12.5\n")
+	assert.Contains(t, body, "nezha_server_online{"+labels+"} 1\n")
+	assert.Contains(t, body, "nezha_server_memory{"+labels+"} 1024\n")
+	assert.Contains(t, body, `nezha_server_temperature{`+labels+`,sensor="cpu"} 50`)
+	assert.Contains(t, body, `nezha_server_gpu{`+labels+`,gpu="0"} 30`)
+	assert.Contains(t, body, `nezha_server_mount_used{`+labels+`,mountpoint="/data"} 512`)
+	assert.Contains(t, body, `nezha_server_nic_in_transfer{`+labels+`,interface="eth0"} 100`)
+	assert.Contains(t, body, `nezha_cycle_transfer{alert_id="5",alert_name="monthly",id="1",name="web \"1\""} 300`)
+
+	// 其他用户隐藏的服务器、分组与面板内部指标都不可见
+	assert.NotContains(t, body, "hidden")
+	assert.NotContains(t, body, "private")
+	assert.NotContains(t, body, "nezha_dashboard_")
+}
+
+func TestMetricsExporterAdmin(t *testing.T) {
+	setupMetricsTest(t)
+
+	code, body := scrapeMetrics(&model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin})
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `group="edge,private"`)
+	assert.Contains(t, body, `nezha_server_cpu{id="2",name="hidden",group="",country=""} 99`)
+	assert.Contains(t, body, `nezha_server_online{id="2",name="hidden",group="",country=""} 0`)
+	assert.Contains(t, body, "nezha_dashboard_connected_agents 0\n")
+	assert.Contains(t, body, "# TYPE nezha_dashboard_notification_attempt_failures_total counter\n")
+}
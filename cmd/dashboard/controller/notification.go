@@ -3,6 +3,7 @@ package controller
 import (
 	"slices"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/copier"
@@ -36,6 +37,12 @@ func listNotification(c *gin.Context) ([]*model.Notification, error) {
 		n.URL = ""
 		n.RequestHeader = ""
 		n.RequestBody = ""
+		// ProviderConfig 是指针，copier 复制后仍与原始数据共享，需先克隆再脱敏
+		if n.ProviderConfig != nil {
+			config := *n.ProviderConfig
+			config.Redact()
+			n.ProviderConfig = &config
+		}
 	}
 	return notifications, nil
 }
@@ -57,21 +64,13 @@ func createNotification(c *gin.Context) (uint64, error) {
 		return 0, err
 	}
 
-	var n model.Notification
-	n.UserID = getUid(c)
-	n.Name = nf.Name
-	n.RequestMethod = nf.RequestMethod
-	n.RequestType = nf.RequestType
-	n.RequestHeader = nf.RequestHeader
-	n.RequestBody = nf.RequestBody
-	n.URL = nf.URL
-	verifyTLS := nf.VerifyTLS
-	n.VerifyTLS = &verifyTLS
-	formatMetricUnits := nf.FormatMetricUnits
-	n.FormatMetricUnits = &formatMetricUnits
+	n, err := newNotificationFromForm(c, &nf)
+	if err != nil {
+		return 0, err
+	}
 
 	ns := model.NotificationServerBundle{
-		Notification: &n,
+		Notification: n,
 		Server:       nil,
 		Loc:          singleton.Loc,
 	}
@@ -82,11 +81,11 @@ func createNotification(c *gin.Context) (uint64, error) {
 		}
 	}
 
-	if err := singleton.DB.Create(&n).Error; err != nil {
+	if err := singleton.DB.Create(n).Error; err != nil {
 		return 0, newGormError("%v", err)
 	}
 
-	singleton.NotificationShared.Update(&n)
+	singleton.NotificationShared.Update(n)
 	return n.ID, nil
 }
 
@@ -125,8 +124,13 @@ func updateNotification(c *gin.Context) (any, error) {
 	n.Name = nf.Name
 	n.RequestMethod = nf.RequestMethod
 	n.RequestType = nf.RequestType
-	verifyTLS := nf.VerifyTLS
-	n.VerifyTLS = &verifyTLS
+	if nf.ProviderConfig != nil && nf.Type == n.Type {
+		nf.ProviderConfig.KeepSecrets(n.ProviderConfig)
+	}
+	n.Type = nf.Type
+	n.TemplateMode = nf.TemplateMode
+	n.ProviderConfig = nf.ProviderConfig
+	n.VerifyTLS = nf.VerifyTLS
 	formatMetricUnits := nf.FormatMetricUnits
 	n.FormatMetricUnits = &formatMetricUnits
 
@@ -140,6 +144,9 @@ func updateNotification(c *gin.Context) (any, error) {
 	if nf.RequestBody != "" {
 		n.RequestBody = nf.RequestBody
 	}
+	if err := n.Validate(); err != nil {
+		return nil, singleton.Localizer.ErrorT("invalid notification config: %v", err)
+	}
 
 	ns := model.NotificationServerBundle{
 		Notification: &n,
@@ -161,6 +168,205 @@ func updateNotification(c *gin.Context) (any, error) {
 	return nil, nil
 }
 
+// Preview notification template
+// @Summary Preview notification template
+// @Security BearerAuth
+// @Schemes
+// @Description Render a Go text/template against the current state of a server. Available data: .Message, .Time, .Duration, .Event (alert name, rule, incident link, service name...), .Server (.Name, .IP, .Host, .State); helper functions: bytes, percent, round, duration, seconds, formatTime, json, default, upper, lower, trim, join, contains, truncate
+// @Tags auth required
+// @Accept json
+// @param request body model.NotificationTemplatePreviewForm true "Template Preview Request"
+// @Produce json
+// @Success 200 {object} model.CommonResponse[string]
+// @Router /notification/preview [post]
+func previewNotificationTemplate(c *gin.Context) (string, error) {
+	var pf model.NotificationTemplatePreviewForm
+	if err := c.ShouldBindJSON(&pf); err != nil {
+		return "", err
+	}
+
+	ns := model.NotificationServerBundle{
+		Notification: &model.Notification{TemplateMode: model.NotificationTemplateGo},
+		Loc:          singleton.Loc,
+		Event:        pf.Event,
+	}
+	if pf.ServerID != 0 {
+		server, ok := singleton.ServerShared.Get(pf.ServerID)
+		if !ok || !server.HasPermission(c) {
+			return "", singleton.Localizer.ErrorT("server id %d does not exist", pf.ServerID)
+		}
+		ns.Server = server
+	}
+	if ns.Event == nil {
+		ns.Event = sampleNotificationEvent()
+	}
+	message := pf.Message
+	if message == "" {
+		message = singleton.Localizer.T("a test message")
+	}
+
+	result, err := ns.RenderTemplate(pf.Template, message)
+	if err != nil {
+		return "", singleton.Localizer.ErrorT("invalid notification config: %v", err)
+	}
+	return result, nil
+}
+
+// sampleNotificationEvent 预览模板时使用的示例报警事件
+func sampleNotificationEvent() *model.NotificationEvent {
+	startedAt := time.Now().Add(-5 * time.Minute)
+	value := 93.5
+	return &model.NotificationEvent{
+		Status:    model.NotificationEventIncident,
+		AlertName: "CPU usage",
+		Rule:      &model.NotificationRule{Type: "cpu", Max: 90, Duration: 60},
+		Value:     &value,
+		StartedAt: &startedAt,
+	}
+}
+
+// Test notification
+// @Summary Test notification
+// @Security BearerAuth
+// @Schemes
+// @Description Render a saved notification against a server (a sample server if server_id is 0) and send it, returning the request that was sent with stored credentials masked, the status code and the truncated response body. With dry_run the request is only rendered.
+// @Tags auth required
+// @Accept json
+// @param id path uint true "Notification ID"
+// @param request body model.NotificationTestForm true "Notification Test Request"
+// @Produce json
+// @Success 200 {object} model.CommonResponse[model.NotificationTestResult]
+// @Router /notification/{id}/test [post]
+func testNotification(c *gin.Context) (*model.NotificationTestResult, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	var tf model.NotificationTestForm
+	if err := c.ShouldBindJSON(&tf); err != nil {
+		return nil, err
+	}
+
+	n, ok := singleton.NotificationShared.Get(id)
+	if !ok {
+		return nil, singleton.Localizer.ErrorT("notification id %d does not exist", id)
+	}
+	if !n.HasPermission(c) {
+		return nil, singleton.Localizer.ErrorT("permission denied")
+	}
+
+	result, err := runNotificationTest(c, n, &tf)
+	if err != nil {
+		return nil, err
+	}
+	if result.Request != nil {
+		result.Request.Redact(n)
+	}
+	return result, nil
+}
+
+// Test unsaved notification
+// @Summary Test unsaved notification
+// @Security BearerAuth
+// @Schemes
+// @Description Same as /notification/{id}/test but for a notification form that has not been saved yet
+// @Tags auth required
+// @Accept json
+// @param request body model.NotificationDryRunForm true "Notification Dry Run Request"
+// @Produce json
+// @Success 200 {object} model.CommonResponse[model.NotificationTestResult]
+// @Router /notification/test [post]
+func dryRunNotification(c *gin.Context) (*model.NotificationTestResult, error) {
+	var df model.NotificationDryRunForm
+	if err := c.ShouldBindJSON(&df); err != nil {
+		return nil, err
+	}
+
+	n, err := newNotificationFromForm(c, &df.Notification)
+	if err != nil {
+		return nil, err
+	}
+	return runNotificationTest(c, n, &df.NotificationTestForm)
+}
+
+// runNotificationTest 以指定服务器或示例服务器、示例报警事件渲染并发送通知
+func runNotificationTest(c *gin.Context, n *model.Notification, tf *model.NotificationTestForm) (*model.NotificationTestResult, error) {
+	ns := model.NotificationServerBundle{
+		Notification: n,
+		Loc:          singleton.Loc,
+		Event:        sampleNotificationEvent(),
+	}
+	if tf.ServerID != 0 {
+		server, ok := singleton.ServerShared.Get(tf.ServerID)
+		if !ok || !server.HasPermission(c) {
+			return nil, singleton.Localizer.ErrorT("server id %d does not exist", tf.ServerID)
+		}
+		ns.Server = server
+	} else {
+		ns.Server = sampleNotificationServer()
+	}
+
+	message := tf.Message
+	if message == "" {
+		message = singleton.Localizer.T("a test message")
+	}
+	return ns.Test(message, tf.DryRun), nil
+}
+
+// newNotificationFromForm 以表单创建通知方式并校验配置
+func newNotificationFromForm(c *gin.Context, nf *model.NotificationForm) (*model.Notification, error) {
+	var n model.Notification
+	n.UserID = getUid(c)
+	n.Name = nf.Name
+	n.Type = nf.Type
+	n.TemplateMode = nf.TemplateMode
+	n.ProviderConfig = nf.ProviderConfig
+	n.RequestMethod = nf.RequestMethod
+	n.RequestType = nf.RequestType
+	n.RequestHeader = nf.RequestHeader
+	n.RequestBody = nf.RequestBody
+	n.URL = nf.URL
+	n.VerifyTLS = nf.VerifyTLS
+	formatMetricUnits := nf.FormatMetricUnits
+	n.FormatMetricUnits = &formatMetricUnits
+	if err := n.Validate(); err != nil {
+		return nil, singleton.Localizer.ErrorT("invalid notification config: %v", err)
+	}
+	return &n, nil
+}
+
+// sampleNotificationServer 测试发送时使用的示例服务器
+func sampleNotificationServer() *model.Server {
+	return &model.Server{
+		Common: model.Common{ID: 1},
+		Name:   "Sample Server",
+		Host: &model.Host{
+			Platform:  "debian",
+			CPU:       []string{"Sample CPU 4 Physical Core"},
+			MemTotal:  8 << 30,
+			DiskTotal: 100 << 30,
+			SwapTotal: 2 << 30,
+			Arch:      "x86_64",
+		},
+		State: &model.HostState{
+			CPU:          93.5,
+			MemUsed:      6 << 30,
+			SwapUsed:     512 << 20,
+			DiskUsed:     72 << 30,
+			NetInSpeed:   12 << 20,
+			NetOutSpeed:  3 << 20,
+			Load1:        3.2,
+			Load5:        2.8,
+			Load15:       2.1,
+			TcpConnCount: 128,
+			Temperatures: []model.SensorTemperature{{Name: "cpu", Temperature: 68}},
+		},
+		GeoIP:      &model.GeoIP{IP: model.IP{IPv4Addr: "203.0.113.10"}},
+		LastActive: time.Now(),
+	}
+}
+
 // Batch delete notifications
 // @Summary Batch delete notifications
 // @Security BearerAuth
@@ -189,6 +395,9 @@ func batchDeleteNotification(c *gin.Context) (any, error) {
 		if err := tx.Unscoped().Delete(&model.NotificationGroupNotification{}, "notification_id in (?)", n).Error; err != nil {
 			return err
 		}
+		if err := tx.Unscoped().Delete(&model.NotificationDelivery{}, "notification_id in (?)", n).Error; err != nil {
+			return err
+		}
 		return nil
 	})
 
@@ -199,3 +408,77 @@ func batchDeleteNotification(c *gin.Context) (any, error) {
 	singleton.NotificationShared.Delete(n)
 	return nil, nil
 }
+
+// List notification deliveries
+// @Summary List notification deliveries
+// @Security BearerAuth
+// @Schemes
+// @Description List delivery attempts of a notification, newest first
+// @Tags auth required
+// @Param id path uint true "Notification ID"
+// @Param limit query uint false "Page limit"
+// @Param offset query uint false "Page offset"
+// @Param status query uint false "1: pending, 2: succeeded, 3: failed"
+// @Produce json
+// @Success 200 {object} model.PaginatedResponse[[]model.NotificationDelivery, model.NotificationDelivery]
+// @Router /notification/{id}/deliveries [get]
+func listNotificationDelivery(c *gin.Context) (*model.Value[[]*model.NotificationDelivery], error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	n, ok := singleton.NotificationShared.Get(id)
+	if !ok {
+		return nil, singleton.Localizer.ErrorT("notification id %d does not exist", id)
+	}
+	if !n.HasPermission(c) {
+		return nil, singleton.Localizer.ErrorT("permission denied")
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit < 1 {
+		limit = 25
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	offset, err := strconv.Atoi(c.Query("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	query := singleton.DB.Model(&model.NotificationDelivery{}).Where("notification_id = ?", id)
+	// 带 server_ids 白名单的 PAT 只能看到白名单内服务器的通知内容
+	if patHasServerWhitelist(c) {
+		v, _ := c.Get(model.CtxKeyAPIToken)
+		query = query.Where("server_id IN (?)", v.(model.APITokenWhitelistView).ServerIDs())
+	}
+	if v := c.Query("status"); v != "" {
+		status, err := strconv.ParseUint(v, 10, 8)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, newGormError("%v", err)
+	}
+
+	var deliveries []*model.NotificationDelivery
+	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&deliveries).Error; err != nil {
+		return nil, newGormError("%v", err)
+	}
+
+	return &model.Value[[]*model.NotificationDelivery]{
+		Value: deliveries,
+		Pagination: model.Pagination{
+			Offset: offset,
+			Limit:  limit,
+			Total:  total,
+		},
+	}, nil
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+func TestListNotificationDelivery(t *testing.T) {
+	setupAlertRuleFanoutFixture(t)
+	require.NoError(t, singleton.DB.AutoMigrate(&model.NotificationDelivery{}))
+
+	originalNotification := singleton.NotificationShared
+	singleton.NotificationShared = singleton.NewEmptyNotificationClassForTest()
+	t.Cleanup(func() { singleton.NotificationShared = originalNotification })
+	singleton.NotificationShared.InsertForTest(&model.Notification{Common: model.Common{ID: 1, UserID: 1}, Name: "hook"})
+
+	for _, d := range []model.NotificationDelivery{
+		{NotificationID: 1, ServerID: 1, Status: model.NotificationDeliverySucceeded, Attempts: 1, StatusCode: 200},
+		{NotificationID: 1, ServerID: 2, Status: model.NotificationDeliveryFailed, Attempts: 5, Error: "timeout"},
+		{NotificationID: 2, Status: model.NotificationDeliverySucceeded, Attempts: 1},
+	} {
+		require.NoError(t, singleton.DB.Create(&d).Error)
+	}
+
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+	member := &model.User{Common: model.Common{ID: 2}, Role: model.RoleMember}
+	list := func(viewer *model.User, tok *model.APIToken, id, query string) (*model.Value[[]*model.NotificationDelivery], error) {
+		c := newAlertRuleCtxWithPAT(t, viewer, tok, nil)
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		c.Request.URL.RawQuery = query
+		return listNotificationDelivery(c)
+	}
+
+	res, err := list(admin, nil, "1", "")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, res.Pagination.Total)
+	assert.Equal(t, "timeout", res.Value[0].Error, "newest first")
+
+	res, err = list(admin, nil, "1", "status=2")
+	require.NoError(t, err)
+	require.Len(t, res.Value, 1)
+	assert.Equal(t, 200, res.Value[0].StatusCode)
+
+	tok := &model.APIToken{ID: 5, UserID: 1}
+	tok.SetServerIDs([]uint64{1})
+	res, err = list(admin, tok, "1", "")
+	require.NoError(t, err)
+	require.Len(t, res.Value, 1)
+	assert.Equal(t, uint64(1), res.Value[0].ServerID)
+
+	_, err = list(member, nil, "1", "")
+	assert.Error(t, err)
+	_, err = list(admin, nil, "9", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+// 内置通知类型的凭据在列表接口脱敏，编辑时留空表示沿用旧值
+func TestNotificationProviderSecrets(t *testing.T) {
+	setupAlertRuleFanoutFixture(t)
+	require.NoError(t, singleton.DB.AutoMigrate(&model.Notification{}))
+
+	originalNotification := singleton.NotificationShared
+	singleton.NotificationShared = singleton.NewEmptyNotificationClassForTest()
+	t.Cleanup(func() { singleton.NotificationShared = originalNotification })
+
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+	form := map[string]any{
+		"name":       "phone",
+		"type":       model.NotificationTypeBark,
+		"skip_check": true,
+		"provider_config": map[string]any{
+			"device_key": "secret-key",
+			"sound":      "alarm",
+		},
+	}
+	id, err := createNotification(newAlertRuleCtxWithPAT(t, admin, nil, form))
+	require.NoError(t, err)
+
+	list, err := listNotification(newAlertRuleCtxWithPAT(t, admin, nil, nil))
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.NotNil(t, list[0].ProviderConfig)
+	assert.Empty(t, list[0].ProviderConfig.DeviceKey)
+	assert.Equal(t, "alarm", list[0].ProviderConfig.Sound)
+
+	stored, ok := singleton.NotificationShared.Get(id)
+	require.True(t, ok)
+	assert.Equal(t, "secret-key", stored.ProviderConfig.DeviceKey, "redaction must not touch the cached notification")
+
+	form["provider_config"] = map[string]any{"sound": "bell"}
+	c := newAlertRuleCtxWithPAT(t, admin, nil, form)
+	c.Params = gin.Params{{Key: "id", Value: itoa(id)}}
+	_, err = updateNotification(c)
+	require.NoError(t, err)
+
+	var n model.Notification
+	require.NoError(t, singleton.DB.First(&n, id).Error)
+	require.NotNil(t, n.ProviderConfig)
+	assert.Equal(t, "secret-key", n.ProviderConfig.DeviceKey)
+	assert.Equal(t, "bell", n.ProviderConfig.Sound)
+
+	form["provider_config"] = map[string]any{}
+	form["type"] = model.NotificationTypeGotify
+	c = newAlertRuleCtxWithPAT(t, admin, nil, form)
+	c.Params = gin.Params{{Key: "id", Value: itoa(id)}}
+	_, err = updateNotification(c)
+	assert.Error(t, err, "gotify requires a server url and token")
+}
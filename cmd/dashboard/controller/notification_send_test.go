@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+func TestTestNotification(t *testing.T) {
+	setupAlertRuleFanoutFixture(t)
+
+	originalNotification := singleton.NotificationShared
+	singleton.NotificationShared = singleton.NewEmptyNotificationClassForTest()
+	t.Cleanup(func() { singleton.NotificationShared = originalNotification })
+	singleton.NotificationShared.InsertForTest(&model.Notification{
+		Common:        model.Common{ID: 1, UserID: 1},
+		Name:          "hook",
+		URL:           "https://hooks.example.com/#SERVER.NAME#",
+		RequestMethod: model.NotificationRequestMethodPOST,
+		RequestType:   model.NotificationRequestTypeJSON,
+		RequestHeader: `{"X-Token":"secret"}`,
+		RequestBody:   `{"text":"#NEZHA#"}`,
+	})
+
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+	member := &model.User{Common: model.Common{ID: 2}, Role: model.RoleMember}
+	test := func(viewer *model.User, id string, form map[string]any) (*model.NotificationTestResult, error) {
+		c := newAlertRuleCtxWithPAT(t, viewer, nil, form)
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		return testNotification(c)
+	}
+
+	res, err := test(admin, "1", map[string]any{"server_id": 2, "message": "hi", "dry_run": true})
+	require.NoError(t, err)
+	require.NotNil(t, res.Request)
+	assert.False(t, res.Sent)
+	assert.Equal(t, "https://hooks.example.com/s2", res.Request.URL)
+	assert.Equal(t, `{"text":"hi"}`, res.Request.Body)
+	assert.Equal(t, "******", res.Request.Header.Get("X-Token"), "stored credentials are masked")
+
+	res, err = test(admin, "1", map[string]any{"dry_run": true})
+	require.NoError(t, err)
+	assert.Equal(t, "https://hooks.example.com/Sample+Server", res.Request.URL, "sample server when none is chosen")
+
+	_, err = test(admin, "9", map[string]any{})
+	assert.Error(t, err)
+	_, err = test(member, "1", map[string]any{})
+	assert.Error(t, err)
+	_, err = test(admin, "1", map[string]any{"server_id": 3})
+	assert.Error(t, err)
+
+	// 未保存的表单：回环地址被出站限制拒绝，错误随结果返回
+	c := newAlertRuleCtxWithPAT(t, admin, nil, map[string]any{
+		"message": "hi",
+		"notification": map[string]any{
+			"name":           "local",
+			"url":            "http://127.0.0.1/hook",
+			"request_method": model.NotificationRequestMethodGET,
+		},
+	})
+	res, err = dryRunNotification(c)
+	require.NoError(t, err)
+	assert.True(t, res.Sent)
+	assert.Zero(t, res.StatusCode)
+	assert.NotEmpty(t, res.Error)
+	assert.Equal(t, "http://127.0.0.1/hook", res.Request.URL)
+
+	c = newAlertRuleCtxWithPAT(t, admin, nil, map[string]any{
+		"notification": map[string]any{"name": "bad", "type": model.NotificationTypeGotify, "provider_config": map[string]any{}},
+	})
+	_, err = dryRunNotification(c)
+	assert.Error(t, err, "invalid form is rejected before rendering")
+}
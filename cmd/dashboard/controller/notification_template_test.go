@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nezhahq/nezha/model"
+)
+
+func TestPreviewNotificationTemplate(t *testing.T) {
+	setupAlertRuleFanoutFixture(t)
+
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+	member := &model.User{Common: model.Common{ID: 2}, Role: model.RoleMember}
+	preview := func(viewer *model.User, tok *model.APIToken, form map[string]any) (string, error) {
+		return previewNotificationTemplate(newAlertRuleCtxWithPAT(t, viewer, tok, form))
+	}
+
+	out, err := preview(admin, nil, map[string]any{
+		"template":  `{{.Message}} {{.Server.Name}} {{.Event.AlertName}}`,
+		"message":   "hello",
+		"server_id": 2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "hello s2 CPU usage", out, "sample event is used when none is given")
+
+	out, err = preview(admin, nil, map[string]any{
+		"template": `{{with .Server}}{{.Name}}{{else}}none{{end}} {{.Event.ServiceName}}`,
+		"event":    map[string]any{"service_name": "api"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "none api", out)
+
+	_, err = preview(admin, nil, map[string]any{"template": `{{.Nope}}`})
+	assert.Error(t, err)
+
+	_, err = preview(member, nil, map[string]any{"template": `x`, "server_id": 1})
+	assert.Error(t, err, "members can't render other users' servers")
+
+	tok := &model.APIToken{ID: 5, UserID: 1}
+	tok.SetServerIDs([]uint64{1})
+	_, err = preview(admin, tok, map[string]any{"template": `x`, "server_id": 2})
+	assert.Error(t, err, "server outside the PAT whitelist")
+}
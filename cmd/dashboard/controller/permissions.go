@@ -274,6 +274,24 @@ func assertPATCoverFanoutWithinWhitelist(c *gin.Context, ownerUID uint64, mode c
 // fail-closed，保证「未知 cover 必须显式 wire，否则拒绝」的不变量。
 const coverModeUnknown coverMode = 255
 
+// checkServerGroupListPermission verifies every server group referenced by
+// an alert rule, service monitor or cron exists and belongs to the caller.
+// Groups expand to their current members at check / dispatch time, so the
+// member-level checks (ownership, PAT whitelist) are done by each resource
+// against the expanded server list.
+func checkServerGroupListPermission(c *gin.Context, groupIDs []uint64) error {
+	for _, id := range groupIDs {
+		var sg model.ServerGroup
+		if err := singleton.DB.First(&sg, id).Error; err != nil {
+			return singleton.Localizer.ErrorT("group id %d does not exist", id)
+		}
+		if !sg.HasPermission(c) {
+			return singleton.Localizer.ErrorT("permission denied")
+		}
+	}
+	return nil
+}
+
 // patGroupMembershipAccessAllowed returns false when the caller's PAT
 // carries a server_ids whitelist that does not cover every current member
 // of groupID. JWT requests and unscoped PATs always pass. Used by
@@ -424,26 +442,26 @@ func skipServersToDenyList(skip map[uint64]bool) []uint64 {
 }
 
 // enforcePATCronDispatchScope 是 cron 运行时入口（manualTriggerCron /
-// batchDeleteCron）的 PAT 收口。把 cr.Cover / cr.Servers 翻译成 coverMode
-// 后交给共享底座；语义与写侧 rejectImplicitCoverForLimitedPAT* 严格对齐，
+// batchDeleteCron）的 PAT 收口。把 cr.Cover / cr.Servers（连同 ServerGroups
+// 当前成员）翻译成 coverMode 后交给共享底座；语义与写侧 rejectImplicitCoverForLimitedPAT* 严格对齐，
 // 闭合「写时拦下 / 运行时回放同一条规则」的不变量，避免历史脏数据 + 受
 // 限 PAT 形成越权 fan-out。
 func enforcePATCronDispatchScope(c *gin.Context, cr *model.Cron) error {
 	if cr == nil {
 		return nil
 	}
-	return assertPATCoverFanoutWithinWhitelist(c, cr.GetUserID(), cronCoverMode(cr.Cover), cr.Servers)
+	return assertPATCoverFanoutWithinWhitelist(c, cr.GetUserID(), cronCoverMode(cr.Cover), cr.ServerIDs())
 }
 
 // enforcePATServiceDispatchScope 是 service monitor 运行时入口
 // （batchDeleteService 等）的 PAT 收口。SkipServers 是 map[uint64]bool，
-// 这里展开成 deny-list 切片喂给共享底座；语义与
+// 这里连同 SkipServerGroups 当前成员展开成 deny-list 切片喂给共享底座；语义与
 // rejectImplicitServiceCoverForLimitedPAT 严格对齐。
 func enforcePATServiceDispatchScope(c *gin.Context, svc *model.Service) error {
 	if svc == nil {
 		return nil
 	}
-	return assertPATCoverFanoutWithinWhitelist(c, svc.GetUserID(), serviceCoverMode(svc.Cover), skipServersToDenyList(svc.SkipServers))
+	return assertPATCoverFanoutWithinWhitelist(c, svc.GetUserID(), serviceCoverMode(svc.Cover), svc.SkipServerIDs())
 }
 
 // enforcePATTriggerTaskScope 阻止 service:write / alertrule:write 的 PAT 通过绑定
@@ -515,3 +533,19 @@ func assertOwnsNotificationGroup(c *gin.Context, groupID uint64) error {
 	}
 	return nil
 }
+
+// assertOwnsEscalationPolicy 报警规则 / 服务监控引用的升级策略需存在且调用方有权使用，0 表示不使用升级策略
+func assertOwnsEscalationPolicy(c *gin.Context, policyID uint64) error {
+	if policyID == 0 {
+		return nil
+	}
+
+	p, ok := singleton.EscalationPolicyShared.Get(policyID)
+	if !ok {
+		return singleton.Localizer.ErrorT("escalation policy id %d does not exist", policyID)
+	}
+	if !p.HasPermission(c) {
+		return singleton.Localizer.ErrorT("permission denied")
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nezhahq/nezha/pkg/tsdb"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+// Prometheus 兼容的只读查询接口，Grafana 中以 <面板地址>/api/v1/prometheus 作为 Prometheus 数据源地址，
+// 使用带 nezha:metrics:read 的 API Token 鉴权。响应沿用 Prometheus HTTP API 的格式而不是 CommonResponse。
+
+// promResponse Prometheus HTTP API 响应
+type promResponse struct {
+	Status    string `json:"status"`
+	Data      any    `json:"data,omitempty"`
+	ErrorType string `json:"errorType,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type promQueryData struct {
+	ResultType string `json:"resultType"`
+	Result     any    `json:"result"`
+}
+
+type promVectorSample struct {
+	Metric map[string]string `json:"metric"`
+	Value  [2]any            `json:"value"`
+}
+
+type promMatrixSeries struct {
+	Metric map[string]string `json:"metric"`
+	Values [][2]any          `json:"values"`
+}
+
+func promHandler(handler func(c *gin.Context) (any, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !singleton.TSDBEnabled() {
+			c.JSON(http.StatusServiceUnavailable, promResponse{
+				Status:    "error",
+				ErrorType: "unavailable",
+				Error:     singleton.Localizer.T("TSDB is not enabled"),
+			})
+			return
+		}
+		// GET 参数与 POST 表单都可以携带查询参数
+		if err := c.Request.ParseForm(); err != nil {
+			c.JSON(http.StatusBadRequest, promResponse{Status: "error", ErrorType: "bad_data", Error: err.Error()})
+			return
+		}
+		data, err := handler(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, promResponse{Status: "error", ErrorType: "bad_data", Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, promResponse{Status: "success", Data: data})
+	}
+}
+
+// promQueryScope 不受 PAT 白名单限制的管理员可以查询全部序列（包括已删除服务器、服务的历史数据），
+// 其他用户只能查询自己的、PAT 白名单允许的服务器，服务指标另外要求能查看该服务，与服务历史接口一致
+func promQueryScope(c *gin.Context) tsdb.QueryScope {
+	if callerIsAdmin(c) && !patHasServerWhitelist(c) {
+		return tsdb.QueryScope{}
+	}
+	scope := tsdb.QueryScope{Restricted: true}
+	for _, server := range singleton.ServerShared.GetSortedList() {
+		if patAllowsServer(c, server.ID) && server.HasPermission(c) {
+			scope.ServerIDs = append(scope.ServerIDs, server.ID)
+		}
+	}
+	for _, service := range singleton.ServiceSentinelShared.GetSortedList() {
+		if userCanViewService(c, service) {
+			scope.ServiceIDs = append(scope.ServiceIDs, service.ID)
+		}
+	}
+	return scope
+}
+
+func promTimeParam(c *gin.Context, name string, fallback time.Time) (time.Time, error) {
+	s := c.Request.Form.Get(name)
+	if s == "" {
+		return fallback, nil
+	}
+	t, err := tsdb.ParsePromTime(s)
+	if err != nil {
+		return time.Time{}, singleton.Localizer.ErrorT("invalid parameter %s: %v", name, err)
+	}
+	return t, nil
+}
+
+// promSeriesRange series 与 labels 接口的时间范围，默认为整个保留期
+func promSeriesRange(c *gin.Context) (time.Time, time.Time, error) {
+	now := time.Now()
+	end, err := promTimeParam(c, "end", now)
+	if err != nil {
+		return end, end, err
+	}
+	retention := time.Duration(singleton.TSDBShared.Config().RetentionDays) * 24 * time.Hour
+	start, err := promTimeParam(c, "start", end.Add(-retention))
+	return start, end, err
+}
+
+func promSampleValue(ts int64, v float64) [2]any {
+	return [2]any{float64(ts) / 1000, strconv.FormatFloat(v, 'f', -1, 64)}
+}
+
+// Prometheus instant query
+// @Summary Prometheus compatible instant query
+// @Security BearerAuth
+// @Schemes
+// @Description Evaluate a PromQL expression at a single point in time. Non-admin users only see series of their own servers and of services they can view.
+// @Tags common
+// @param query query string true "PromQL expression"
+// @param time query string false "Evaluation timestamp, RFC3339 or unix timestamp (default: now)"
+// @Produce json
+// @Success 200 {object} promResponse
+// @Router /prometheus/api/v1/query [get]
+func promQuery(c *gin.Context) (any, error) {
+	ts, err := promTimeParam(c, "time", time.Now())
+	if err != nil {
+		return nil, err
+	}
+	result, err := singleton.TSDBShared.PromQuery(c.Request.Form.Get("query"), ts, promQueryScope(c))
+	if err != nil {
+		return nil, err
+	}
+	if result.Scalar {
+		s := result.Series[0]
+		return promQueryData{ResultType: "scalar", Result: promSampleValue(s.Timestamps[0], s.Values[0])}, nil
+	}
+	vector := make([]promVectorSample, 0, len(result.Series))
+	for _, s := range result.Series {
+		vector = append(vector, promVectorSample{Metric: s.Labels, Value: promSampleValue(s.Timestamps[0], s.Values[0])})
+	}
+	return promQueryData{ResultType: "vector", Result: vector}, nil
+}
+
+// Prometheus range query
+// @Summary Prometheus compatible range query
+// @Security BearerAuth
+// @Schemes
+// @Description Evaluate a PromQL expression over a range of time. Non-admin users only see series of their own servers and of services they can view.
+// @Tags common
+// @param query query string true "PromQL expression"
+// @param start query string true "Start timestamp, RFC3339 or unix timestamp"
+// @param end query string true "End timestamp, RFC3339 or unix timestamp"
+// @param step query string true "Resolution, duration or seconds"
+// @Produce json
+// @Success 200 {object} promResponse
+// @Router /prometheus/api/v1/query_range [get]
+func promQueryRange(c *gin.Context) (any, error) {
+	start, err := promTimeParam(c, "start", time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	end, err := promTimeParam(c, "end", time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	if start.IsZero() || end.IsZero() {
+		return nil, singleton.Localizer.ErrorT("start and end are required")
+	}
+	step, err := tsdb.ParsePromDuration(c.Request.Form.Get("step"))
+	if err != nil {
+		return nil, singleton.Localizer.ErrorT("invalid parameter %s: %v", "step", err)
+	}
+	result, err := singleton.TSDBShared.PromQueryRange(c.Request.Form.Get("query"), start, end, step, promQueryScope(c))
+	if err != nil {
+		return nil, err
+	}
+	matrix := make([]promMatrixSeries, 0, len(result.Series))
+	for _, s := range result.Series {
+		labels := s.Labels
+		if result.Scalar {
+			labels = map[string]string{}
+		}
+		series := promMatrixSeries{Metric: labels, Values: make([][2]any, len(s.Values))}
+		for i, v := range s.Values {
+			series.Values[i] = promSampleValue(s.Timestamps[i], v)
+		}
+		matrix = append(matrix, series)
+	}
+	return promQueryData{ResultType: "matrix", Result: matrix}, nil
+}
+
+// Prometheus series
+// @Summary Prometheus compatible series lookup
+// @Security BearerAuth
+// @Schemes
+// @Description Find series matching the given selectors. Non-admin users only see series of their own servers and of services they can view.
+// @Tags common
+// @param match[] query []string true "Series selectors"
+// @param start query string false "Start timestamp (default: beginning of retention)"
+// @param end query string false "End timestamp (default: now)"
+// @Produce json
+// @Success 200 {object} promResponse
+// @Router /prometheus/api/v1/series [get]
+func promSeries(c *gin.Context) (any, error) {
+	start, end, err := promSeriesRange(c)
+	if err != nil {
+		return nil, err
+	}
+	return singleton.TSDBShared.PromSeriesLabels(c.Request.Form["match[]"], start, end, promQueryScope(c))
+}
+
+// Prometheus label names
+// @Summary Prometheus compatible label names
+// @Security BearerAuth
+// @Schemes
+// @Description List label names, optionally restricted to series matching the given selectors
+// @Tags common
+// @param match[] query []string false "Series selectors"
+// @param start query string false "Start timestamp (default: beginning of retention)"
+// @param end query string false "End timestamp (default: now)"
+// @Produce json
+// @Success 200 {object} promResponse
+// @Router /prometheus/api/v1/labels [get]
+func promLabels(c *gin.Context) (any, error) {
+	start, end, err := promSeriesRange(c)
+	if err != nil {
+		return nil, err
+	}
+	return singleton.TSDBShared.PromLabelNames(c.Request.Form["match[]"], start, end, promQueryScope(c))
+}
+
+// Prometheus label values
+// @Summary Prometheus compatible label values
+// @Security BearerAuth
+// @Schemes
+// @Description List values of a label, optionally restricted to series matching the given selectors
+// @Tags common
+// @param name path string true "Label name"
+// @param match[] query []string false "Series selectors"
+// @param start query string false "Start timestamp (default: beginning of retention)"
+// @param end query string false "End timestamp (default: now)"
+// @Produce json
+// @Success 200 {object} promResponse
+// @Router /prometheus/api/v1/label/{name}/values [get]
+func promLabelValues(c *gin.Context) (any, error) {
+	start, end, err := promSeriesRange(c)
+	if err != nil {
+		return nil, err
+	}
+	return singleton.TSDBShared.PromLabelValues(c.Param("name"), c.Request.Form["match[]"], start, end, promQueryScope(c))
+}
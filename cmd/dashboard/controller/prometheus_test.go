@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/pkg/i18n"
+	"github.com/nezhahq/nezha/pkg/tsdb"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+func setupPrometheusTest(t *testing.T) time.Time {
+	t.Helper()
+	originalServer := singleton.ServerShared
+	originalSentinel := singleton.ServiceSentinelShared
+	originalTSDB := singleton.TSDBShared
+	originalLocalizer := singleton.Localizer
+	t.Cleanup(func() {
+		singleton.ServerShared = originalServer
+		singleton.ServiceSentinelShared = originalSentinel
+		singleton.TSDBShared = originalTSDB
+		singleton.Localizer = originalLocalizer
+	})
+	singleton.Localizer = i18n.NewLocalizer("en_US", "nezha", "translations", i18n.Translations)
+
+	singleton.ServerShared = singleton.NewEmptyServerClassForTest()
+	singleton.ServerShared.InsertForTest(&model.Server{Common: model.Common{ID: 1, UserID: 10}, Name: "mine"})
+	singleton.ServerShared.InsertForTest(&model.Server{Common: model.Common{ID: 2, UserID: 20}, Name: "other"})
+	// 两个服务都由 server 1 上报：5 为管理员对访客隐藏的服务，6 属于 server 1 的所有者
+	singleton.ServiceSentinelShared = singleton.NewEmptyServiceSentinelForTest()
+	singleton.ServiceSentinelShared.InsertForTest(&model.Service{Common: model.Common{ID: 5, UserID: 1}, Name: "admin", Cover: model.ServiceCoverAll, HideForGuest: true})
+	singleton.ServiceSentinelShared.InsertForTest(&model.Service{Common: model.Common{ID: 6, UserID: 10}, Name: "mine", HideForGuest: true})
+
+	tempDir, err := os.MkdirTemp("", "tsdb_prometheus_test")
+	require.NoError(t, err)
+	db, err := tsdb.Open(&tsdb.Config{
+		DataPath:           filepath.Join(tempDir, "tsdb"),
+		RetentionDays:      1,
+		MinFreeDiskSpaceGB: 1,
+		DedupInterval:      time.Second,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close()
+		os.RemoveAll(tempDir)
+	})
+
+	now := time.Now().Truncate(time.Second)
+	for _, id := range []uint64{1, 2} {
+		require.NoError(t, db.WriteServerMetrics(&tsdb.ServerMetrics{ServerID: id, Timestamp: now, CPU: float64(id * 10)}))
+	}
+	for _, id := range []uint64{5, 6} {
+		require.NoError(t, db.WriteServiceMetrics(&tsdb.ServiceMetrics{ServiceID: id, ServerID: 1, Timestamp: now, Delay: 10, Successful: true}))
+	}
+	db.Flush()
+	singleton.TSDBShared = db
+	return now
+}
+
+func servePrometheus(t *testing.T, user *model.User, handler func(*gin.Context) (any, error), method string, form url.Values) (int, map[string]any) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	if method == http.MethodPost {
+		c.Request = httptest.NewRequest(method, "/", strings.NewReader(form.Encode()))
+		c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		c.Request = httptest.NewRequest(method, "/?"+form.Encode(), nil)
+	}
+	if user != nil {
+		c.Set(model.CtxKeyAuthorizedUser, user)
+	}
+	promHandler(handler)(c)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body
+}
+
+func TestPrometheusQueryFiltersMemberSeries(t *testing.T) {
+	now := setupPrometheusTest(t)
+	form := url.Values{"query": {"nezha_server_cpu"}, "time": {now.Format(time.RFC3339)}}
+
+	member := &model.User{Common: model.Common{ID: 10}, Role: model.RoleMember}
+	code, body := servePrometheus(t, member, promQuery, http.MethodPost, form)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "success", body["status"])
+	data := body["data"].(map[string]any)
+	assert.Equal(t, "vector", data["resultType"])
+	result := data["result"].([]any)
+	require.Len(t, result, 1)
+	sample := result[0].(map[string]any)
+	assert.Equal(t, "1", sample["metric"].(map[string]any)["server_id"])
+	assert.Equal(t, "10", sample["value"].([]any)[1])
+
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+	_, body = servePrometheus(t, admin, promQuery, http.MethodGet, form)
+	assert.Len(t, body["data"].(map[string]any)["result"], 2)
+
+	_, body = servePrometheus(t, member, promLabelValues, http.MethodGet, url.Values{})
+	assert.Equal(t, "success", body["status"])
+}
+
+func TestPrometheusQueryFiltersHiddenServices(t *testing.T) {
+	now := setupPrometheusTest(t)
+	form := url.Values{"query": {"nezha_service_delay"}, "time": {now.Format(time.RFC3339)}}
+	serviceIDs := func(result any) []string {
+		var ids []string
+		for _, s := range result.([]any) {
+			ids = append(ids, s.(map[string]any)["metric"].(map[string]any)["service_id"].(string))
+		}
+		return ids
+	}
+
+	// 管理员的服务由成员的服务器上报，成员仍然看不到
+	member := &model.User{Common: model.Common{ID: 10}, Role: model.RoleMember}
+	_, body := servePrometheus(t, member, promQuery, http.MethodGet, form)
+	assert.Equal(t, []string{"6"}, serviceIDs(body["data"].(map[string]any)["result"]))
+
+	_, body = servePrometheus(t, member, promSeries, http.MethodGet, url.Values{"match[]": {"nezha_service_delay"}})
+	series := body["data"].([]any)
+	require.Len(t, series, 1)
+	assert.Equal(t, "6", series[0].(map[string]any)["service_id"])
+
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+	_, body = servePrometheus(t, admin, promQuery, http.MethodGet, form)
+	assert.ElementsMatch(t, []string{"5", "6"}, serviceIDs(body["data"].(map[string]any)["result"]))
+}
+
+func TestPrometheusQueryScopeHonoursPATWhitelist(t *testing.T) {
+	setupPrometheusTest(t)
+
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+	c := newServiceVisibilityCtx(admin)
+	assert.Equal(t, tsdb.QueryScope{}, promQueryScope(c))
+
+	tok := &model.APIToken{ID: 7, UserID: 1}
+	tok.SetServerIDs([]uint64{2})
+	c.Set(model.CtxKeyAPIToken, tok)
+	assert.Equal(t, tsdb.QueryScope{Restricted: true, ServerIDs: []uint64{2}}, promQueryScope(c))
+
+	member := &model.User{Common: model.Common{ID: 30}, Role: model.RoleMember}
+	assert.Equal(t, tsdb.QueryScope{Restricted: true}, promQueryScope(newServiceVisibilityCtx(member)))
+}
+
+func TestPrometheusQueryRangeErrors(t *testing.T) {
+	now := setupPrometheusTest(t)
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+
+	code, body := servePrometheus(t, admin, promQueryRange, http.MethodGet, url.Values{"query": {"nezha_server_cpu"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "error", body["status"])
+	assert.Equal(t, "bad_data", body["errorType"])
+
+	code, body = servePrometheus(t, admin, promQueryRange, http.MethodGet, url.Values{
+		"query": {"sum(nezha_server_cpu)"},
+		"start": {now.Add(-time.Minute).Format(time.RFC3339)},
+		"end":   {now.Format(time.RFC3339)},
+		"step":  {"30s"},
+	})
+	require.Equal(t, http.StatusOK, code)
+	data := body["data"].(map[string]any)
+	assert.Equal(t, "matrix", data["resultType"])
+	require.Len(t, data["result"], 1)
+
+	singleton.TSDBShared.Close()
+	code, body = servePrometheus(t, admin, promQuery, http.MethodGet, url.Values{"query": {"1"}})
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", body["errorType"])
+}
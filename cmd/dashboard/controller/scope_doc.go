@@ -11,8 +11,8 @@
 // # Scope naming
 //
 //	nezha:{resource}:{verb}
-//	  resource: inventory | server | service | alertrule | cron | ddns | nat |
-//	            notification | notification-group | transfer | admin
+//	  resource: inventory | server | service | alertrule | silence | cron | ddns |
+//	            nat | notification | notification-group | transfer | metrics | admin
 //	  verb:     read | write | delete | exec
 //
 //	inventory vs server：inventory 管“能看到/能删哪些机器”（列出 server /
@@ -20,7 +20,7 @@
 //	已知机器的运行态操作（exec / 文件读写 / 编辑配置 / metrics / server.get）。
 //
 //	nezha:*               Admin-only superuser
-//	nezha:admin:*         Admin-only user/waf/setting/online-user management
+//	nezha:admin:*         Admin-only user/waf/setting/online-user/maintenance-window/tsdb management
 //	nezha:<res>:*         All actions on a resource
 //
 // # MCP tools (POST /mcp tools/call)
@@ -66,14 +66,33 @@
 //	GET    /api/v1/ws/transfer                       nezha:transfer:read
 //
 //	GET    /api/v1/service/list                      nezha:service:read
+//	GET    /api/v1/service/certificate               nezha:service:read
 //	POST   /api/v1/service                           nezha:service:write
 //	PATCH  /api/v1/service/{id}                      nezha:service:write
 //	POST   /api/v1/batch-delete/service              nezha:service:delete
 //
+//	GET    /api/v1/prometheus/api/v1/query           nezha:metrics:read
+//	POST   /api/v1/prometheus/api/v1/query           nezha:metrics:read
+//	GET    /api/v1/prometheus/api/v1/query_range     nezha:metrics:read
+//	POST   /api/v1/prometheus/api/v1/query_range     nezha:metrics:read
+//	GET    /api/v1/prometheus/api/v1/series          nezha:metrics:read
+//	POST   /api/v1/prometheus/api/v1/series          nezha:metrics:read
+//	GET    /api/v1/prometheus/api/v1/labels          nezha:metrics:read
+//	POST   /api/v1/prometheus/api/v1/labels          nezha:metrics:read
+//	GET    /api/v1/prometheus/api/v1/label/{name}/values nezha:metrics:read
+//	GET    /api/v1/metrics                           nezha:metrics:read
+//
 //	GET    /api/v1/alert-rule                        nezha:alertrule:read
 //	POST   /api/v1/alert-rule                        nezha:alertrule:write
 //	PATCH  /api/v1/alert-rule/{id}                   nezha:alertrule:write
 //	POST   /api/v1/batch-delete/alert-rule           nezha:alertrule:delete
+//	GET    /api/v1/incident                          nezha:alertrule:read
+//	GET    /api/v1/escalation-delivery               nezha:alertrule:read
+//	POST   /api/v1/incident/{id}/acknowledge         nezha:silence:write
+//
+//	GET    /api/v1/silence                           nezha:silence:read
+//	POST   /api/v1/silence                           nezha:silence:write
+//	POST   /api/v1/batch-delete/silence              nezha:silence:delete
 //
 //	GET    /api/v1/cron                              nezha:cron:read
 //	POST   /api/v1/cron                              nezha:cron:write
@@ -94,13 +113,21 @@
 //
 //	GET    /api/v1/notification                      nezha:notification:read
 //	POST   /api/v1/notification                      nezha:notification:write
+//	POST   /api/v1/notification/preview              nezha:notification:write
+//	POST   /api/v1/notification/test                 nezha:notification:write
+//	POST   /api/v1/notification/{id}/test            nezha:notification:write
 //	PATCH  /api/v1/notification/{id}                 nezha:notification:write
+//	GET    /api/v1/notification/{id}/deliveries      nezha:notification:read
 //	POST   /api/v1/batch-delete/notification         nezha:notification:delete
 //
 //	GET    /api/v1/notification-group                nezha:notification-group:read
 //	POST   /api/v1/notification-group                nezha:notification-group:write
 //	PATCH  /api/v1/notification-group/{id}           nezha:notification-group:write
 //	POST   /api/v1/batch-delete/notification-group   nezha:notification-group:delete
+//	GET    /api/v1/escalation-policy                 nezha:notification-group:read
+//	POST   /api/v1/escalation-policy                 nezha:notification-group:write
+//	PATCH  /api/v1/escalation-policy/{id}            nezha:notification-group:write
+//	POST   /api/v1/batch-delete/escalation-policy    nezha:notification-group:delete
 //
 //	GET    /api/v1/user                              nezha:admin:*
 //	POST   /api/v1/user                              nezha:admin:*
@@ -111,6 +138,12 @@
 //	POST   /api/v1/online-user/batch-block           nezha:admin:*
 //	PATCH  /api/v1/setting                           nezha:admin:*
 //	POST   /api/v1/maintenance                       nezha:admin:*
+//	GET    /api/v1/tsdb/snapshot                     nezha:admin:*
+//	GET    /api/v1/tsdb/export                       nezha:admin:*
+//	GET    /api/v1/maintenance-window                nezha:admin:*
+//	POST   /api/v1/maintenance-window                nezha:admin:*
+//	PATCH  /api/v1/maintenance-window/{id}           nezha:admin:*
+//	POST   /api/v1/batch-delete/maintenance-window   nezha:admin:*
 //
 // # Endpoints permanently forbidden to PAT
 //
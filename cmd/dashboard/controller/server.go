@@ -472,23 +472,31 @@ func batchMoveServer(c *gin.Context) ([]model.BatchMoveServerResult, error) {
 }
 
 var serverMetricMap = map[string]tsdb.MetricType{
-	"cpu":              tsdb.MetricServerCPU,
-	"memory":           tsdb.MetricServerMemory,
-	"swap":             tsdb.MetricServerSwap,
-	"disk":             tsdb.MetricServerDisk,
-	"net_in_speed":     tsdb.MetricServerNetInSpeed,
-	"net_out_speed":    tsdb.MetricServerNetOutSpeed,
-	"net_in_transfer":  tsdb.MetricServerNetInTransfer,
-	"net_out_transfer": tsdb.MetricServerNetOutTransfer,
-	"load1":            tsdb.MetricServerLoad1,
-	"load5":            tsdb.MetricServerLoad5,
-	"load15":           tsdb.MetricServerLoad15,
-	"tcp_conn":         tsdb.MetricServerTCPConn,
-	"udp_conn":         tsdb.MetricServerUDPConn,
-	"process_count":    tsdb.MetricServerProcessCount,
-	"temperature":      tsdb.MetricServerTemperature,
-	"uptime":           tsdb.MetricServerUptime,
-	"gpu":              tsdb.MetricServerGPU,
+	"cpu":                tsdb.MetricServerCPU,
+	"memory":             tsdb.MetricServerMemory,
+	"swap":               tsdb.MetricServerSwap,
+	"disk":               tsdb.MetricServerDisk,
+	"net_in_speed":       tsdb.MetricServerNetInSpeed,
+	"net_out_speed":      tsdb.MetricServerNetOutSpeed,
+	"net_in_transfer":    tsdb.MetricServerNetInTransfer,
+	"net_out_transfer":   tsdb.MetricServerNetOutTransfer,
+	"load1":              tsdb.MetricServerLoad1,
+	"load5":              tsdb.MetricServerLoad5,
+	"load15":             tsdb.MetricServerLoad15,
+	"tcp_conn":           tsdb.MetricServerTCPConn,
+	"udp_conn":           tsdb.MetricServerUDPConn,
+	"process_count":      tsdb.MetricServerProcessCount,
+	"temperature":        tsdb.MetricServerTemperature,
+	"uptime":             tsdb.MetricServerUptime,
+	"gpu":                tsdb.MetricServerGPU,
+	"mount_used":         tsdb.MetricServerMountUsed,
+	"mount_total":        tsdb.MetricServerMountTotal,
+	"nic_in_speed":       tsdb.MetricServerNICInSpeed,
+	"nic_out_speed":      tsdb.MetricServerNICOutSpeed,
+	"nic_in_transfer":    tsdb.MetricServerNICInTransfer,
+	"nic_out_transfer":   tsdb.MetricServerNICOutTransfer,
+	"sensor_temperature": tsdb.MetricServerSensorTemperature,
+	"gpu_usage":          tsdb.MetricServerGPUUsage,
 }
 
 // Get server metrics history
@@ -498,8 +506,13 @@ var serverMetricMap = map[string]tsdb.MetricType{
 // @Description Get server metrics history for a specific server
 // @Tags common
 // @param id path uint true "Server ID"
-// @param metric query string true "Metric name: cpu, memory, swap, disk, net_in_speed, net_out_speed, net_in_transfer, net_out_transfer, load1, load5, load15, tcp_conn, udp_conn, process_count, temperature, uptime, gpu"
+// @param metric query string true "Metric name: cpu, memory, swap, disk, net_in_speed, net_out_speed, net_in_transfer, net_out_transfer, load1, load5, load15, tcp_conn, udp_conn, process_count, temperature, uptime, gpu, mount_used, mount_total, nic_in_speed, nic_out_speed, nic_in_transfer, nic_out_transfer, sensor_temperature, gpu_usage"
+// @param device query string false "Mount point, interface name, sensor name or GPU index, required by mount_*, nic_*, sensor_temperature and gpu_usage"
 // @param period query string false "Time period: 1d, 7d, 30d (default: 1d)"
+// @param from query string false "Range start, RFC3339 or unix timestamp (default: to minus period)"
+// @param to query string false "Range end, RFC3339 or unix timestamp (default: now)"
+// @param step query string false "Resolution, e.g. 30s, 5m, 1h or seconds"
+// @param aggregation query string false "Aggregation per step: avg, min, max, last, p50, p90, p95, p99, increase, rate"
 // @Produce json
 // @Success 200 {object} model.CommonResponse[model.ServerMetricsResponse]
 // @Router /server/{id}/metrics [get]
@@ -518,28 +531,29 @@ func getServerMetrics(c *gin.Context) (*model.ServerMetricsResponse, error) {
 	if !userCanViewServer(c, server) {
 		return nil, singleton.Localizer.ErrorT("unauthorized")
 	}
-	_, isMember := c.Get(model.CtxKeyAuthorizedUser)
-
 	metricName := c.Query("metric")
 	metricType, ok := serverMetricMap[metricName]
 	if !ok {
 		return nil, singleton.Localizer.ErrorT("invalid metric name")
 	}
 
-	periodStr := c.DefaultQuery("period", "1d")
-	period, err := tsdb.ParseQueryPeriod(periodStr)
-	if err != nil {
-		return nil, err
+	var device string
+	if metricType.DeviceLabel() != "" {
+		if device = c.Query("device"); device == "" {
+			return nil, singleton.Localizer.ErrorT("device is required for metric %s", metricName)
+		}
 	}
 
-	if !isMember && period != tsdb.Period1Day {
-		return nil, singleton.Localizer.ErrorT("unauthorized: only 1d data available for guests")
+	queryRange, err := parseHistoryRange(c)
+	if err != nil {
+		return nil, err
 	}
 
 	response := &model.ServerMetricsResponse{
 		ServerID:   serverID,
 		ServerName: server.Name,
 		Metric:     metricName,
+		Device:     device,
 		DataPoints: make([]model.ServerMetricsDataPoint, 0),
 	}
 
@@ -547,7 +561,7 @@ func getServerMetrics(c *gin.Context) (*model.ServerMetricsResponse, error) {
 		return response, nil
 	}
 
-	points, err := singleton.TSDBShared.QueryServerMetrics(serverID, metricType, period)
+	points, err := singleton.TSDBShared.QueryServerDeviceMetricsRange(serverID, metricType, device, queryRange)
 	if err != nil {
 		return nil, err
 	}
@@ -556,3 +570,19 @@ func getServerMetrics(c *gin.Context) (*model.ServerMetricsResponse, error) {
 
 	return response, nil
 }
+
+// parseHistoryRange 解析历史查询的 period、from、to、step 与 aggregation 参数，
+// 未登录用户只能查看最近 1 天的数据
+func parseHistoryRange(c *gin.Context) (tsdb.QueryRange, error) {
+	now := time.Now()
+	queryRange, err := tsdb.ParseQueryRange(c.DefaultQuery("period", "1d"), c.Query("from"), c.Query("to"),
+		c.Query("step"), c.Query("aggregation"), now)
+	if err != nil {
+		return queryRange, err
+	}
+	if _, isMember := c.Get(model.CtxKeyAuthorizedUser); !isMember &&
+		queryRange.From.Before(now.Add(-tsdb.Period1Day.Duration())) {
+		return queryRange, singleton.Localizer.ErrorT("unauthorized: only 1d data available for guests")
+	}
+	return queryRange, nil
+}
@@ -135,6 +135,7 @@ func createServerGroup(c *gin.Context) (uint64, error) {
 		return 0, newGormError("%v", err)
 	}
 
+	singleton.InvalidateServerGroupMembers()
 	return sg.ID, nil
 }
 
@@ -218,6 +219,7 @@ func updateServerGroup(c *gin.Context) (any, error) {
 		return nil, newGormError("%v", err)
 	}
 
+	singleton.InvalidateServerGroupMembers()
 	return nil, nil
 }
 
@@ -275,5 +277,6 @@ func batchDeleteServerGroup(c *gin.Context) (any, error) {
 		return nil, newGormError("%v", err)
 	}
 
+	singleton.InvalidateServerGroupMembers()
 	return nil, nil
 }
@@ -13,6 +13,7 @@ import (
 
 	"github.com/nezhahq/nezha/model"
 	"github.com/nezhahq/nezha/pkg/tsdb"
+	"github.com/nezhahq/nezha/pkg/utils"
 	"github.com/nezhahq/nezha/service/singleton"
 )
 
@@ -51,17 +52,34 @@ func filterServiceStatsForViewer(c *gin.Context, stats map[uint64]model.ServiceR
 		return stats
 	}
 	services := singleton.ServiceSentinelShared.GetList()
+	servers := singleton.ServerShared.GetList()
 	filteredStats := make(map[uint64]model.ServiceResponseItem, len(stats))
 	for serviceID, stat := range stats {
 		service, ok := services[serviceID]
 		if !ok || !userCanViewService(c, service) {
 			continue
 		}
+		stat.Reporters = filterReporterStatusForViewer(c, stat.Reporters, servers)
 		filteredStats[serviceID] = stat
 	}
 	return filteredStats
 }
 
+// filterReporterStatusForViewer 只保留可见服务器的监测点状态，面板自身（ID 0）始终保留
+func filterReporterStatusForViewer(c *gin.Context, reporters map[uint64]model.ServiceReporterStatus, servers map[uint64]*model.Server) map[uint64]model.ServiceReporterStatus {
+	if len(reporters) == 0 {
+		return reporters
+	}
+	filtered := make(map[uint64]model.ServiceReporterStatus, len(reporters))
+	for id, status := range reporters {
+		if id != model.DashboardReporterID && !userCanViewServer(c, servers[id]) {
+			continue
+		}
+		filtered[id] = status
+	}
+	return filtered
+}
+
 func serviceResponseCacheKey(c *gin.Context) string {
 	auth, ok := c.Get(model.CtxKeyAuthorizedUser)
 	if !ok {
@@ -138,6 +156,23 @@ func listService(c *gin.Context) ([]*model.Service, error) {
 	return ss, nil
 }
 
+// List service certificates
+// @Summary List TLS certificates of HTTP services
+// @Security BearerAuth
+// @Schemes
+// @Description List the latest TLS certificate of each HTTP service, sorted by expiration time
+// @Tags auth required
+// @Produce json
+// @Success 200 {object} model.CommonResponse[[]model.ServiceCertificate]
+// @Router /service/certificate [get]
+func listServiceCertificates(c *gin.Context) ([]model.ServiceCertificate, error) {
+	services := singleton.ServiceSentinelShared.GetList()
+	return slices.DeleteFunc(singleton.ServiceSentinelShared.ListCertificates(), func(cert model.ServiceCertificate) bool {
+		service, ok := services[cert.ServiceID]
+		return !ok || !service.HasPermission(c)
+	}), nil
+}
+
 // Get service history
 // @Summary Get service history by service ID
 // @Security BearerAuth
@@ -146,6 +181,10 @@ func listService(c *gin.Context) ([]*model.Service, error) {
 // @Tags common
 // @param id path uint true "Service ID"
 // @param period query string false "Time period: 1d, 7d, 30d (default: 1d)"
+// @param from query string false "Range start, RFC3339 or unix timestamp (default: to minus period)"
+// @param to query string false "Range end, RFC3339 or unix timestamp (default: now)"
+// @param step query string false "Resolution, e.g. 30s, 5m, 1h or seconds"
+// @param aggregation query string false "Delay aggregation per step: avg, min, max, last, p50, p90, p95, p99"
 // @Produce json
 // @Success 200 {object} model.CommonResponse[model.ServiceHistoryResponse]
 // @Router /service/{id}/history [get]
@@ -161,19 +200,11 @@ func getServiceHistory(c *gin.Context) (*model.ServiceHistoryResponse, error) {
 		return nil, singleton.Localizer.ErrorT("service not found")
 	}
 
-	// 解析时间范围
-	periodStr := c.DefaultQuery("period", "1d")
-	period, err := tsdb.ParseQueryPeriod(periodStr)
+	queryRange, err := parseHistoryRange(c)
 	if err != nil {
 		return nil, err
 	}
 
-	// 权限检查：未登录用户只能查看 1d 数据
-	_, isMember := c.Get(model.CtxKeyAuthorizedUser)
-	if !isMember && period != tsdb.Period1Day {
-		return nil, singleton.Localizer.ErrorT("unauthorized: only 1d data available for guests")
-	}
-
 	response := &model.ServiceHistoryResponse{
 		ServiceID:   serviceID,
 		ServiceName: service.Name,
@@ -181,10 +212,13 @@ func getServiceHistory(c *gin.Context) (*model.ServiceHistoryResponse, error) {
 	}
 
 	if !singleton.TSDBEnabled() {
-		return queryServiceHistoryFromDB(c, serviceID, period, response)
+		if _, err := queryServiceHistoryFromDB(c, serviceID, queryRange, response); err != nil {
+			return nil, err
+		}
+		return withReporterStatus(response), nil
 	}
 
-	result, err := singleton.TSDBShared.QueryServiceHistory(serviceID, period)
+	result, err := singleton.TSDBShared.QueryServiceHistoryRange(serviceID, queryRange)
 	if err != nil {
 		return nil, err
 	}
@@ -202,14 +236,25 @@ func getServiceHistory(c *gin.Context) (*model.ServiceHistoryResponse, error) {
 	}
 	response.Servers = filtered
 
-	return response, nil
+	return withReporterStatus(response), nil
 }
 
-func queryServiceHistoryFromDB(c *gin.Context, serviceID uint64, period tsdb.QueryPeriod, response *model.ServiceHistoryResponse) (*model.ServiceHistoryResponse, error) {
-	since := time.Now().Add(-period.Duration())
+// withReporterStatus 为历史记录中的每个监测点附上当前状态
+func withReporterStatus(response *model.ServiceHistoryResponse) *model.ServiceHistoryResponse {
+	current := singleton.ServiceSentinelShared.ReporterStatus(response.ServiceID)
+	for i := range response.Servers {
+		if status, ok := current[response.Servers[i].ServerID]; ok {
+			response.Servers[i].Current = &status
+		}
+	}
+	return response
+}
 
+// queryServiceHistoryFromDB 未启用 TSDB 时直接返回范围内的原始记录，不支持步长与聚合
+func queryServiceHistoryFromDB(c *gin.Context, serviceID uint64, queryRange tsdb.QueryRange, response *model.ServiceHistoryResponse) (*model.ServiceHistoryResponse, error) {
 	var histories []model.ServiceHistory
-	if err := singleton.DB.Where("service_id = ? AND server_id != 0 AND created_at >= ?", serviceID, since).
+	if err := singleton.DB.Where("service_id = ? AND server_id != 0 AND created_at >= ? AND created_at <= ?",
+		serviceID, queryRange.From, queryRange.To).
 		Order("server_id, created_at").Find(&histories).Error; err != nil {
 		return nil, err
 	}
@@ -331,11 +376,11 @@ func listServerServices(c *gin.Context) ([]*model.ServiceInfos, error) {
 
 	for _, service := range services {
 		if service.Cover == model.ServiceCoverAll {
-			if service.SkipServers[serverID] {
+			if service.Skips(serverID) {
 				continue
 			}
 		} else {
-			if !service.SkipServers[serverID] {
+			if !service.Skips(serverID) {
 				continue
 			}
 		}
@@ -385,11 +430,11 @@ func queryServerServicesFromDB(serverID uint64, serverName string, period tsdb.Q
 	var result []*model.ServiceInfos
 	for _, service := range services {
 		if service.Cover == model.ServiceCoverAll {
-			if service.SkipServers[serverID] {
+			if service.Skips(serverID) {
 				continue
 			}
 		} else {
-			if !service.SkipServers[serverID] {
+			if !service.Skips(serverID) {
 				continue
 			}
 		}
@@ -439,17 +484,16 @@ func listServerWithServices(c *gin.Context) ([]uint64, error) {
 	for _, service := range services {
 		if service.Cover == model.ServiceCoverAll {
 			// 除了跳过的服务器，其他都包含
+			skip := service.SkipServerIDs()
 			for serverID := range serverMap {
-				if !service.SkipServers[serverID] {
+				if !slices.Contains(skip, serverID) {
 					serverIDSet[serverID] = true
 				}
 			}
 		} else {
 			// 只包含指定的服务器
-			for serverID, enabled := range service.SkipServers {
-				if enabled {
-					serverIDSet[serverID] = true
-				}
+			for _, serverID := range service.SkipServerIDs() {
+				serverIDSet[serverID] = true
 			}
 		}
 	}
@@ -487,6 +531,9 @@ func createService(c *gin.Context) (uint64, error) {
 	if err := model.ValidateServiceMonitorType(uint64(mf.Type)); err != nil {
 		return 0, err
 	}
+	if err := validateServiceProbe(&mf); err != nil {
+		return 0, err
+	}
 
 	if !isValidServiceCover(mf.Cover) {
 		return 0, singleton.Localizer.ErrorT("permission denied")
@@ -500,10 +547,12 @@ func createService(c *gin.Context) (uint64, error) {
 	m.Target = strings.TrimSpace(mf.Target)
 	m.Type = mf.Type
 	m.SkipServers = mf.SkipServers
+	m.SkipServerGroups = mf.SkipServerGroups
 	m.Cover = mf.Cover
 	m.DisplayIndex = mf.DisplayIndex
 	m.Notify = mf.Notify
 	m.NotificationGroupID = mf.NotificationGroupID
+	m.EscalationPolicyID = mf.EscalationPolicyID
 	m.Duration = mf.Duration
 	m.LatencyNotify = mf.LatencyNotify
 	m.MinLatency = mf.MinLatency
@@ -512,6 +561,18 @@ func createService(c *gin.Context) (uint64, error) {
 	m.EnableTriggerTask = mf.EnableTriggerTask
 	m.RecoverTriggerTasks = mf.RecoverTriggerTasks
 	m.FailTriggerTasks = mf.FailTriggerTasks
+	m.HTTP = mf.HTTP
+	m.DNS = mf.DNS
+	m.HeartbeatGrace = mf.HeartbeatGrace
+	m.DashboardProbe = mf.DashboardProbe
+	m.GoodThreshold = mf.GoodThreshold
+	m.LowThreshold = mf.LowThreshold
+	m.FailureConfirmations = mf.FailureConfirmations
+	m.DownQuorum = mf.DownQuorum
+	m.TLSWarnDays = mf.TLSWarnDays
+	if err := updateHeartbeatToken(&m, mf.ResetHeartbeatToken); err != nil {
+		return 0, err
+	}
 
 	if err := validateServers(c, &m); err != nil {
 		return 0, err
@@ -554,6 +615,9 @@ func updateService(c *gin.Context) (any, error) {
 	if err := model.ValidateServiceMonitorType(uint64(mf.Type)); err != nil {
 		return nil, err
 	}
+	if err := validateServiceProbe(&mf); err != nil {
+		return nil, err
+	}
 
 	if !isValidServiceCover(mf.Cover) {
 		return nil, singleton.Localizer.ErrorT("permission denied")
@@ -572,10 +636,12 @@ func updateService(c *gin.Context) (any, error) {
 	m.Target = strings.TrimSpace(mf.Target)
 	m.Type = mf.Type
 	m.SkipServers = mf.SkipServers
+	m.SkipServerGroups = mf.SkipServerGroups
 	m.Cover = mf.Cover
 	m.DisplayIndex = mf.DisplayIndex
 	m.Notify = mf.Notify
 	m.NotificationGroupID = mf.NotificationGroupID
+	m.EscalationPolicyID = mf.EscalationPolicyID
 	m.Duration = mf.Duration
 	m.LatencyNotify = mf.LatencyNotify
 	m.MinLatency = mf.MinLatency
@@ -584,6 +650,18 @@ func updateService(c *gin.Context) (any, error) {
 	m.EnableTriggerTask = mf.EnableTriggerTask
 	m.RecoverTriggerTasks = mf.RecoverTriggerTasks
 	m.FailTriggerTasks = mf.FailTriggerTasks
+	m.HTTP = mf.HTTP
+	m.DNS = mf.DNS
+	m.HeartbeatGrace = mf.HeartbeatGrace
+	m.DashboardProbe = mf.DashboardProbe
+	m.GoodThreshold = mf.GoodThreshold
+	m.LowThreshold = mf.LowThreshold
+	m.FailureConfirmations = mf.FailureConfirmations
+	m.DownQuorum = mf.DownQuorum
+	m.TLSWarnDays = mf.TLSWarnDays
+	if err := updateHeartbeatToken(&m, mf.ResetHeartbeatToken); err != nil {
+		return nil, err
+	}
 
 	if err := validateServers(c, &m); err != nil {
 		return 0, err
@@ -646,12 +724,80 @@ func batchDeleteService(c *gin.Context) (any, error) {
 	return nil, nil
 }
 
+// validateServiceProbe 校验 HTTP 与 DNS 监控的配置，其他类型不保留这些配置
+func validateServiceProbe(mf *model.ServiceForm) error {
+	if mf.Type != model.TaskTypeHTTPGet || mf.HTTP.IsZero() {
+		mf.HTTP = nil
+	}
+	if mf.Type != model.TaskTypeDNS {
+		mf.DNS = nil
+	}
+	if err := mf.HTTP.Validate(); err != nil {
+		return singleton.Localizer.ErrorT("invalid http probe config: %v", err)
+	}
+	if mf.Type != model.TaskTypeHTTPGet {
+		mf.TLSWarnDays = nil
+	}
+	if err := model.ValidateTLSWarnDays(mf.TLSWarnDays); err != nil {
+		return singleton.Localizer.ErrorT("invalid http probe config: %v", err)
+	}
+	rules := model.Service{GoodThreshold: mf.GoodThreshold, LowThreshold: mf.LowThreshold}
+	if err := rules.ValidateStateRules(); err != nil {
+		return singleton.Localizer.ErrorT("invalid service state rules: %v", err)
+	}
+	if mf.DashboardProbe && !model.SupportsDashboardProbe(mf.Type) {
+		return singleton.Localizer.ErrorT("this service type cannot be probed from the dashboard")
+	}
+	if mf.Type == model.TaskTypeHeartbeat {
+		// 心跳监控没有探测目标
+		mf.Target = ""
+	} else {
+		mf.HeartbeatGrace = 0
+	}
+	if mf.Type == model.TaskTypeDNS {
+		if err := model.ValidateDNSTarget(strings.TrimSpace(mf.Target)); err != nil {
+			return singleton.Localizer.ErrorT("invalid dns probe config: %v", err)
+		}
+		if err := mf.DNS.Validate(); err != nil {
+			return singleton.Localizer.ErrorT("invalid dns probe config: %v", err)
+		}
+	}
+	return nil
+}
+
+// updateHeartbeatToken 心跳监控首次保存或要求重置时生成新的心跳地址，其他类型清除密钥
+func updateHeartbeatToken(m *model.Service, reset bool) error {
+	if m.Type != model.TaskTypeHeartbeat {
+		m.HeartbeatToken = ""
+		return nil
+	}
+	if m.HeartbeatToken != "" && !reset {
+		return nil
+	}
+	token, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return err
+	}
+	m.HeartbeatToken = token
+	return nil
+}
+
 func validateServers(c *gin.Context, ss *model.Service) error {
-	if err := checkServiceSkipServerPermission(c, ss.Cover, ss.SkipServers, ss.GetUserID()); err != nil {
+	if err := checkServerGroupListPermission(c, ss.SkipServerGroups); err != nil {
+		return err
+	}
+
+	// 分组按当前成员展开后与 SkipServers 一并校验
+	skip := make(map[uint64]bool)
+	for _, id := range ss.SkipServerIDs() {
+		skip[id] = true
+	}
+
+	if err := checkServiceSkipServerPermission(c, ss.Cover, skip, ss.GetUserID()); err != nil {
 		return err
 	}
 
-	if err := rejectImplicitServiceCoverForLimitedPAT(c, ss.Cover, ss.SkipServers, ss.GetUserID()); err != nil {
+	if err := rejectImplicitServiceCoverForLimitedPAT(c, ss.Cover, skip, ss.GetUserID()); err != nil {
 		return err
 	}
 
@@ -668,6 +814,9 @@ func validateServers(c *gin.Context, ss *model.Service) error {
 	if err := assertOwnsNotificationGroup(c, ss.NotificationGroupID); err != nil {
 		return err
 	}
+	if err := assertOwnsEscalationPolicy(c, ss.EscalationPolicyID); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -85,3 +85,26 @@ func TestUserCanViewServiceLimitedPATAllowsAdminInsideWhitelist(t *testing.T) {
 	assert.True(t, userCanViewService(ctx, visible),
 		"admin caller using a server_ids=[1] PAT must still see a CoverIgnoreAll service bound to whitelisted server 1")
 }
+
+func TestFilterReporterStatusForViewerHidesInvisibleServers(t *testing.T) {
+	servers := map[uint64]*model.Server{
+		1: {Common: model.Common{ID: 1, UserID: 1}, Name: "public server"},
+		2: {Common: model.Common{ID: 2, UserID: 1}, Name: "hidden server", HideForGuest: true},
+	}
+	reporters := map[uint64]model.ServiceReporterStatus{
+		model.DashboardReporterID: {Status: model.NotificationEventServiceGood},
+		1:                         {Status: model.NotificationEventServiceDown},
+		2:                         {Status: model.NotificationEventServiceGood},
+		3:                         {Status: model.NotificationEventServiceGood},
+	}
+
+	// 面板自身始终可见，隐藏与已删除的服务器不返回
+	got := filterReporterStatusForViewer(newServiceVisibilityCtx(nil), reporters, servers)
+	assert.Equal(t, map[uint64]model.ServiceReporterStatus{
+		model.DashboardReporterID: {Status: model.NotificationEventServiceGood},
+		1:                         {Status: model.NotificationEventServiceDown},
+	}, got)
+
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+	assert.Len(t, filterReporterStatusForViewer(newServiceVisibilityCtx(admin), reporters, servers), 3)
+}
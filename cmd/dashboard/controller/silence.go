@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+// List silences
+// @Summary List silences
+// @Security BearerAuth
+// @Schemes
+// @Description List active silences
+// @Tags auth required
+// @Param id query uint false "Resource ID"
+// @Produce json
+// @Success 200 {object} model.CommonResponse[[]model.Silence]
+// @Router /silence [get]
+func listSilence(c *gin.Context) ([]*model.Silence, error) {
+	now := time.Now()
+	return slices.DeleteFunc(singleton.SilenceShared.GetSortedList(), func(s *model.Silence) bool {
+		return !s.IsActive(now)
+	}), nil
+}
+
+// Add silence
+// @Summary Add silence
+// @Security BearerAuth
+// @Schemes
+// @Description Suppress matching notifications until the silence expires
+// @Tags auth required
+// @Accept json
+// @param request body model.SilenceForm true "Silence Request"
+// @Produce json
+// @Success 200 {object} model.CommonResponse[uint64]
+// @Router /silence [post]
+func createSilence(c *gin.Context) (uint64, error) {
+	var sf model.SilenceForm
+	if err := c.ShouldBindJSON(&sf); err != nil {
+		return 0, err
+	}
+
+	s := model.Silence{
+		AlertRuleID:   sf.AlertRuleID,
+		ServerID:      sf.ServerID,
+		ServiceID:     sf.ServiceID,
+		ServerGroupID: sf.ServerGroupID,
+	}
+	s.UserID = getUid(c)
+	if err := validateSilence(c, &s, sf.Reason, sf.Duration); err != nil {
+		return 0, err
+	}
+
+	if err := singleton.DB.Create(&s).Error; err != nil {
+		return 0, newGormError("%v", err)
+	}
+
+	singleton.SilenceShared.Update(&s)
+	return s.ID, nil
+}
+
+// Batch delete silences
+// @Summary Batch delete silences
+// @Security BearerAuth
+// @Schemes
+// @Description Batch delete silences
+// @Tags auth required
+// @Accept json
+// @param request body []uint64 true "id list"
+// @Produce json
+// @Success 200 {object} model.CommonResponse[any]
+// @Router /batch-delete/silence [post]
+func batchDeleteSilence(c *gin.Context) (any, error) {
+	var ids []uint64
+	if err := c.ShouldBindJSON(&ids); err != nil {
+		return nil, err
+	}
+
+	if !singleton.SilenceShared.CheckPermission(c, slices.Values(ids)) {
+		return nil, singleton.Localizer.ErrorT("permission denied")
+	}
+
+	if err := singleton.DB.Unscoped().Delete(&model.Silence{}, "id in (?)", ids).Error; err != nil {
+		return nil, newGormError("%v", err)
+	}
+
+	singleton.SilenceShared.Delete(ids)
+	return nil, nil
+}
+
+// validateSilence 校验静默的时长、匹配条件以及调用方对每个匹配对象的权限，
+// 通过后填充 Reason 与 ExpiresAt
+func validateSilence(c *gin.Context, s *model.Silence, reason string, duration uint64) error {
+	if duration == 0 || duration > model.SilenceMaxDuration {
+		return singleton.Localizer.ErrorT("duration need to be between 1 second and 30 days")
+	}
+	if s.AlertRuleID == 0 && s.ServerID == 0 && s.ServiceID == 0 && s.ServerGroupID == 0 {
+		return singleton.Localizer.ErrorT("need to specify at least one silence target")
+	}
+
+	if s.AlertRuleID != 0 {
+		var ar model.AlertRule
+		if err := singleton.DB.First(&ar, s.AlertRuleID).Error; err != nil {
+			return singleton.Localizer.ErrorT("alert id %d does not exist", s.AlertRuleID)
+		}
+		if !ar.Common.HasPermission(c) {
+			return singleton.Localizer.ErrorT("permission denied")
+		}
+	}
+	if s.ServerID != 0 {
+		server, ok := singleton.ServerShared.Get(s.ServerID)
+		if !ok {
+			return singleton.Localizer.ErrorT("server id %d does not exist", s.ServerID)
+		}
+		if !server.HasPermission(c) {
+			return singleton.Localizer.ErrorT("permission denied")
+		}
+	}
+	if s.ServiceID != 0 {
+		service, ok := singleton.ServiceSentinelShared.Get(s.ServiceID)
+		if !ok {
+			return singleton.Localizer.ErrorT("service id %d does not exist", s.ServiceID)
+		}
+		if !service.Common.HasPermission(c) {
+			return singleton.Localizer.ErrorT("permission denied")
+		}
+	}
+	if s.ServerGroupID != 0 {
+		var sg model.ServerGroup
+		if err := singleton.DB.First(&sg, s.ServerGroupID).Error; err != nil {
+			return singleton.Localizer.ErrorT("group id %d does not exist", s.ServerGroupID)
+		}
+		if !sg.HasPermission(c) {
+			return singleton.Localizer.ErrorT("permission denied")
+		}
+	}
+	// 受限 PAT 只能创建限定在白名单内某台服务器上的静默
+	if !s.HasPermission(c) {
+		return singleton.Localizer.ErrorT("permission denied")
+	}
+
+	s.Reason = strings.TrimSpace(reason)
+	s.ExpiresAt = time.Now().Add(time.Duration(duration) * time.Second)
+	return nil
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+func setupSilenceFixture(t *testing.T) {
+	t.Helper()
+	setupAlertRuleFanoutFixture(t)
+	require.NoError(t, singleton.DB.AutoMigrate(&model.Incident{}, &model.Silence{}, &model.ServerGroup{}, &model.ServerGroupServer{}))
+
+	originalSilence := singleton.SilenceShared
+	singleton.SilenceShared = singleton.NewSilenceClass()
+	t.Cleanup(func() { singleton.SilenceShared = originalSilence })
+
+	enable := true
+	require.NoError(t, singleton.DB.Create(&model.AlertRule{Common: model.Common{ID: 1, UserID: 1}, Name: "cpu", Enable: &enable}).Error)
+}
+
+func TestCreateSilence(t *testing.T) {
+	setupSilenceFixture(t)
+
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+	member := &model.User{Common: model.Common{ID: 2}, Role: model.RoleMember}
+
+	c := newAlertRuleCtxWithPAT(t, admin, nil, model.SilenceForm{AlertRuleID: 1, ServerID: 1, Duration: 7200, Reason: " on it "})
+	id, err := createSilence(c)
+	require.NoError(t, err)
+
+	s, ok := singleton.SilenceShared.Get(id)
+	require.True(t, ok)
+	assert.Equal(t, "on it", s.Reason)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), s.ExpiresAt, time.Minute)
+	assert.NotNil(t, singleton.SilenceShared.Match(model.NotificationSubject{AlertRuleID: 1, ServerID: 1}))
+
+	for name, form := range map[string]model.SilenceForm{
+		"NoTarget":    {Duration: 60},
+		"NoDuration":  {ServerID: 1},
+		"TooLong":     {ServerID: 1, Duration: model.SilenceMaxDuration + 1},
+		"MissingRule": {AlertRuleID: 42, Duration: 60},
+	} {
+		_, err := createSilence(newAlertRuleCtxWithPAT(t, admin, nil, form))
+		assert.Error(t, err, name)
+	}
+
+	// 成员不能静默别人的服务器
+	_, err = createSilence(newAlertRuleCtxWithPAT(t, member, nil, model.SilenceForm{ServerID: 1, Duration: 60}))
+	assert.Error(t, err)
+
+	// 受限 PAT 必须把静默限定在白名单内的服务器上
+	tok := &model.APIToken{ID: 5, UserID: 1}
+	tok.SetServerIDs([]uint64{1})
+	_, err = createSilence(newAlertRuleCtxWithPAT(t, admin, tok, model.SilenceForm{AlertRuleID: 1, Duration: 60}))
+	assert.Error(t, err)
+	_, err = createSilence(newAlertRuleCtxWithPAT(t, admin, tok, model.SilenceForm{ServerID: 2, Duration: 60}))
+	assert.Error(t, err)
+	_, err = createSilence(newAlertRuleCtxWithPAT(t, admin, tok, model.SilenceForm{AlertRuleID: 1, ServerID: 1, Duration: 60}))
+	assert.NoError(t, err)
+
+	// 成员看不到也删不掉管理员的静默
+	list, err := listSilence(c)
+	require.NoError(t, err)
+	assert.Len(t, filter(newAlertRuleCtxWithPAT(t, member, nil, nil), list), 0)
+	_, err = batchDeleteSilence(newAlertRuleCtxWithPAT(t, member, nil, []uint64{id}))
+	assert.Error(t, err)
+	_, err = batchDeleteSilence(newAlertRuleCtxWithPAT(t, admin, nil, []uint64{id}))
+	require.NoError(t, err)
+	_, ok = singleton.SilenceShared.Get(id)
+	assert.False(t, ok)
+}
+
+func TestAcknowledgeIncident(t *testing.T) {
+	setupSilenceFixture(t)
+
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+	incident := model.Incident{Common: model.Common{UserID: 1}, AlertRuleID: 1, ServerID: 2, OpenedAt: time.Now()}
+	require.NoError(t, singleton.DB.Create(&incident).Error)
+
+	c := newAlertRuleCtxWithPAT(t, admin, nil, model.IncidentAcknowledgeForm{Duration: 3600, Reason: "investigating"})
+	c.Params = gin.Params{{Key: "id", Value: itoa(incident.ID)}}
+	silenceID, err := acknowledgeIncident(c)
+	require.NoError(t, err)
+
+	require.NoError(t, singleton.DB.First(&incident, incident.ID).Error)
+	assert.Equal(t, uint64(1), incident.AcknowledgedBy)
+	require.NotNil(t, incident.AcknowledgedAt)
+
+	s, ok := singleton.SilenceShared.Get(silenceID)
+	require.True(t, ok)
+	assert.Equal(t, incident.ID, s.IncidentID)
+	assert.NotNil(t, singleton.SilenceShared.Match(model.NotificationSubject{AlertRuleID: 1, ServerID: 2}))
+	assert.Nil(t, singleton.SilenceShared.Match(model.NotificationSubject{AlertRuleID: 1, ServerID: 1}))
+
+	// 已恢复的事件不能再确认
+	resolved := time.Now()
+	require.NoError(t, singleton.DB.Model(&incident).Update("resolved_at", resolved).Error)
+	c = newAlertRuleCtxWithPAT(t, admin, nil, model.IncidentAcknowledgeForm{Duration: 3600})
+	c.Params = gin.Params{{Key: "id", Value: itoa(incident.ID)}}
+	_, err = acknowledgeIncident(c)
+	assert.Error(t, err)
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nezhahq/nezha/pkg/tsdb"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+// adminDownloadHandler 与 adminHandler 相同，但处理函数直接向 c.Writer 写入文件。
+// 开始写入之前的错误仍以 JSON 返回；开始写入之后只能中断连接并记录日志
+func adminDownloadHandler(handler func(c *gin.Context) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !callerIsAdmin(c) {
+			c.JSON(http.StatusOK, newErrorResponse(singleton.Localizer.ErrorT("permission denied")))
+			return
+		}
+		if !singleton.TSDBEnabled() {
+			c.JSON(http.StatusOK, newErrorResponse(singleton.Localizer.ErrorT("TSDB is not enabled")))
+			return
+		}
+
+		err := handler(c)
+		if err == nil {
+			return
+		}
+		if c.Writer.Written() {
+			log.Printf("NEZHA>> TSDB download %s aborted: %v", c.Request.URL.Path, err)
+			c.Abort()
+			return
+		}
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		c.JSON(http.StatusOK, newErrorResponse(err))
+	}
+}
+
+func setAttachment(c *gin.Context, contentType, filename string) {
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+}
+
+// Download TSDB snapshot
+// @Summary Download TSDB snapshot
+// @Security BearerAuth
+// @Schemes
+// @Description Stream all raw and downsampled TSDB data as gzip-compressed JSON lines in the VictoriaMetrics export format. Restore it with `tsdb restore`. Raw data contains the samples up to the request time; downsampled data contains the buckets aggregated by then.
+// @Tags admin required
+// @Produce application/gzip
+// @Success 200 {file} file
+// @Router /tsdb/snapshot [get]
+func downloadTSDBSnapshot(c *gin.Context) error {
+	setAttachment(c, "application/gzip", fmt.Sprintf("nezha-tsdb-%s.jsonl.gz", time.Now().Format("20060102-150405")))
+	stats, err := singleton.TSDBShared.Snapshot(c.Writer)
+	if err != nil {
+		return err
+	}
+	log.Printf("NEZHA>> TSDB snapshot downloaded: %d series, %d samples", stats.Series, stats.Samples)
+	return nil
+}
+
+// Export server TSDB data
+// @Summary Export server TSDB data
+// @Security BearerAuth
+// @Schemes
+// @Description Export raw server metrics and the service metrics measured by the server in a time range as CSV or JSON lines
+// @Tags admin required
+// @param server_id query uint true "Server ID"
+// @param format query string false "Export format: csv, jsonl (default: csv)"
+// @param period query string false "Time period: 1d, 7d, 30d (default: 1d)"
+// @param from query string false "Range start, RFC3339 or unix timestamp (default: to minus period)"
+// @param to query string false "Range end, RFC3339 or unix timestamp (default: now)"
+// @Produce plain
+// @Success 200 {file} file
+// @Router /tsdb/export [get]
+func exportTSDB(c *gin.Context) error {
+	serverID, err := strconv.ParseUint(c.Query("server_id"), 10, 64)
+	if err != nil {
+		return err
+	}
+	// 已删除的服务器仍可导出保留期内的数据，因此不检查服务器是否存在
+	r, err := tsdb.ParseQueryRange(c.DefaultQuery("period", "1d"), c.Query("from"), c.Query("to"), "", "", time.Now())
+	if err != nil {
+		return err
+	}
+
+	format := c.DefaultQuery("format", tsdb.ExportFormatCSV)
+	contentType := "text/csv; charset=utf-8"
+	switch format {
+	case tsdb.ExportFormatCSV:
+	case tsdb.ExportFormatJSONLines:
+		contentType = "application/x-ndjson"
+	default:
+		return singleton.Localizer.ErrorT("unsupported export format: %s", format)
+	}
+
+	setAttachment(c, contentType, fmt.Sprintf("nezha-server-%d-%s.%s", serverID, r.From.Format("20060102"), format))
+	_, err = singleton.TSDBShared.Export(c.Writer, tsdb.ExportOptions{
+		ServerID: serverID,
+		From:     r.From,
+		To:       r.To,
+		Format:   format,
+	})
+	return err
+}
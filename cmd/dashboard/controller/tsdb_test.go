@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nezhahq/nezha/model"
+)
+
+func serveTSDBDownload(user *model.User, handler func(*gin.Context) error, query string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+	c.Set(model.CtxKeyAuthorizedUser, user)
+	adminDownloadHandler(handler)(c)
+	return w
+}
+
+func TestTSDBDownloadRequiresAdmin(t *testing.T) {
+	setupPrometheusTest(t)
+
+	member := &model.User{Common: model.Common{ID: 10}, Role: model.RoleMember}
+	w := serveTSDBDownload(member, downloadTSDBSnapshot, "")
+	assert.JSONEq(t, `{"error":"permission denied"}`, w.Body.String())
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+}
+
+func TestTSDBSnapshotDownload(t *testing.T) {
+	setupPrometheusTest(t)
+
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+	w := serveTSDBDownload(admin, downloadTSDBSnapshot, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/gzip", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), `attachment; filename="nezha-tsdb-`)
+
+	gr, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	data, err := io.ReadAll(gr)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"__name__":"nezha_server_cpu"`)
+}
+
+func TestTSDBExport(t *testing.T) {
+	now := setupPrometheusTest(t)
+	admin := &model.User{Common: model.Common{ID: 1}, Role: model.RoleAdmin}
+
+	w := serveTSDBDownload(admin, exportTSDB, "server_id=2&format=csv")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), now.UTC().Format(time.RFC3339Nano)+",nezha_server_cpu,2,,,20\n")
+	assert.NotContains(t, w.Body.String(), ",nezha_server_cpu,1,")
+
+	// 开始写入之前的错误以 JSON 返回，不带附件响应头
+	w = serveTSDBDownload(admin, exportTSDB, "server_id=2&format=xml")
+	assert.JSONEq(t, `{"error":"unsupported export format: xml"}`, w.Body.String())
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "application/json"))
+}
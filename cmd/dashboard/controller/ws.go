@@ -184,6 +184,7 @@ func getServerStat(withPublicNote bool, viewerUserID uint64, viewerIsAdmin bool,
 			singleton.ServerShared.GetSortedList(),
 			viewerUserID, viewerIsAdmin, withPublicNote, pat,
 		)
+		markServersUnderMaintenance(servers, singleton.MaintenanceWindowShared.ServersUnderMaintenance())
 		return json.Marshal(model.StreamServerData{
 			Now:     time.Now().Unix() * 1000,
 			Online:  singleton.GetOnlineUserCount(),
@@ -254,3 +255,14 @@ func filterServersForViewer(servers []*model.Server, viewerUserID uint64, viewer
 	}
 	return out
 }
+
+// markServersUnderMaintenance fills in the maintenance status so public status
+// pages can render "under maintenance" without access to the admin API.
+func markServersUnderMaintenance(servers []model.StreamServer, maintenance map[uint64]*model.MaintenanceStatus) {
+	if len(maintenance) == 0 {
+		return
+	}
+	for i := range servers {
+		servers[i].Maintenance = maintenance[servers[i].ID]
+	}
+}
@@ -127,6 +127,13 @@ func main() {
 		os.Exit(0)
 	}
 
+	if flag.Arg(0) == "tsdb" {
+		if err := runTSDBCommand(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	}
+
 	serviceSentinelDispatchBus := make(chan *model.Service)
 	if err := utils.FirstError(singleton.InitFrontendTemplates,
 		func() error { return singleton.InitConfigFromPath(dashboardCliParam.ConfigFile) },
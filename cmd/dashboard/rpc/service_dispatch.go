@@ -3,6 +3,7 @@ package rpc
 import (
 	"errors"
 	"log"
+	"slices"
 
 	"github.com/nezhahq/nezha/model"
 	"github.com/nezhahq/nezha/proto"
@@ -20,19 +21,15 @@ func DispatchTask(serviceSentinelDispatchBus <-chan *model.Service) {
 			log.Printf("NEZHA>> DispatchTask rejected service %d: %v", task.ID, err)
 			continue
 		}
-		probe := task.PB()
-		if probe == nil {
+		// 实际下发的任务按服务器的 agent 版本生成，这里只校验能否生成任务
+		if task.PB() == nil {
 			log.Printf("NEZHA>> DispatchTask rejected service %d: invalid probe", task.ID)
 			continue
 		}
 
 		switch task.Cover {
 		case model.ServiceCoverIgnoreAll:
-			for id, enabled := range task.SkipServers {
-				if !enabled {
-					continue
-				}
-
+			for _, id := range task.SkipServerIDs() {
 				server, _ := singleton.ServerShared.Get(id)
 				if server == nil {
 					continue
@@ -40,19 +37,20 @@ func DispatchTask(serviceSentinelDispatchBus <-chan *model.Service) {
 				if !canSendTaskToServer(task, server) {
 					continue
 				}
-				if err := server.SendTask(probe); err != nil && !errors.Is(err, model.ErrTaskStreamOffline) {
+				if err := server.SendTask(task.PBForServer(server)); err != nil && !errors.Is(err, model.ErrTaskStreamOffline) {
 					log.Printf("NEZHA>> DispatchTask send error (server=%d): %v", id, err)
 				}
 			}
 		case model.ServiceCoverAll:
+			skip := task.SkipServerIDs()
 			for id, server := range singleton.ServerShared.GetList() {
-				if server == nil || task.SkipServers[id] {
+				if server == nil || slices.Contains(skip, id) {
 					continue
 				}
 				if !canSendTaskToServer(task, server) {
 					continue
 				}
-				if err := server.SendTask(probe); err != nil && !errors.Is(err, model.ErrTaskStreamOffline) {
+				if err := server.SendTask(task.PBForServer(server)); err != nil && !errors.Is(err, model.ErrTaskStreamOffline) {
 					log.Printf("NEZHA>> DispatchTask send error (server=%d): %v", id, err)
 				}
 			}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/nezhahq/nezha/pkg/tsdb"
+	"github.com/nezhahq/nezha/pkg/utils"
+	"github.com/nezhahq/nezha/service/singleton"
+)
+
+const tsdbCommandUsage = `usage: dashboard [-c config.yaml] tsdb <command> [flags]
+
+commands:
+  snapshot  write all raw and downsampled data to a gzip-compressed JSON lines file
+  restore   load a snapshot into a fresh local data path
+  export    export one server's data in a time range as CSV or JSON lines
+
+The embedded storage can only be opened by one process, stop the dashboard before
+running these commands or use the /api/v1/tsdb endpoints instead.`
+
+// runTSDBCommand 处理 tsdb 子命令，TSDB 配置读取自 -c 指定的配置文件
+func runTSDBCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(tsdbCommandUsage)
+	}
+	if err := utils.FirstError(singleton.InitFrontendTemplates,
+		func() error { return singleton.InitConfigFromPath(dashboardCliParam.ConfigFile) }); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "snapshot":
+		return runTSDBSnapshot(args[1:])
+	case "restore":
+		return runTSDBRestore(args[1:])
+	case "export":
+		return runTSDBExport(args[1:])
+	default:
+		return errors.New(tsdbCommandUsage)
+	}
+}
+
+func openTSDBForCommand(config *tsdb.Config) (*tsdb.TSDB, error) {
+	if !config.Enabled() {
+		return nil, errors.New("TSDB is not enabled (tsdb.data_path or tsdb.remote.write_url not configured)")
+	}
+	return tsdb.Open(config)
+}
+
+func runTSDBSnapshot(args []string) error {
+	fs := flag.NewFlagSet("tsdb snapshot", flag.ExitOnError)
+	output := fs.String("o", fmt.Sprintf("nezha-tsdb-%s.jsonl.gz", time.Now().Format("20060102-150405")), "快照文件路径")
+	fs.Parse(args)
+
+	db, err := openTSDBForCommand(singleton.TSDBConfig())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	stats, err := writeCommandOutput(*output, func(w io.Writer) (tsdb.BackupStats, error) {
+		return db.Snapshot(w)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("NEZHA>> TSDB snapshot written to %s: %d series, %d samples", *output, stats.Series, stats.Samples)
+	return nil
+}
+
+func runTSDBRestore(args []string) error {
+	fs := flag.NewFlagSet("tsdb restore", flag.ExitOnError)
+	input := fs.String("i", "", "快照文件路径")
+	dataPath := fs.String("data-path", "", "恢复到的 TSDB 数据目录，必须不存在或为空")
+	fs.Parse(args)
+	if *input == "" || *dataPath == "" {
+		fs.Usage()
+		return errors.New("-i and -data-path are required")
+	}
+
+	if entries, err := os.ReadDir(*dataPath); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s is not empty, restore only into a fresh data path", *dataPath)
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	f, err := os.Open(*input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// 保留期与降采样层级沿用配置文件，存储后端固定为嵌入式存储
+	config := singleton.TSDBConfig()
+	config.Backend = tsdb.BackendLocal
+	config.DataPath = *dataPath
+	db, err := openTSDBForCommand(config)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	stats, err := db.Restore(f)
+	if err != nil {
+		return err
+	}
+	log.Printf("NEZHA>> TSDB snapshot restored to %s: %d series, %d samples, %d skipped", *dataPath, stats.Series, stats.Samples, stats.Skipped)
+	log.Printf("NEZHA>> Set tsdb.data_path to %s to use the restored data", *dataPath)
+	return nil
+}
+
+func runTSDBExport(args []string) error {
+	fs := flag.NewFlagSet("tsdb export", flag.ExitOnError)
+	serverID := fs.Uint64("server", 0, "服务器 ID")
+	format := fs.String("format", tsdb.ExportFormatCSV, "导出格式：csv、jsonl")
+	period := fs.String("period", "1d", "时间范围：1d、7d、30d，与 -from 同时指定时忽略")
+	from := fs.String("from", "", "开始时间，RFC3339 或 Unix 时间戳")
+	to := fs.String("to", "", "结束时间，RFC3339 或 Unix 时间戳，默认为当前时间")
+	output := fs.String("o", "-", "导出文件路径，- 为标准输出")
+	fs.Parse(args)
+	if *serverID == 0 {
+		fs.Usage()
+		return errors.New("-server is required")
+	}
+
+	r, err := tsdb.ParseQueryRange(*period, *from, *to, "", "", time.Now())
+	if err != nil {
+		return err
+	}
+
+	db, err := openTSDBForCommand(singleton.TSDBConfig())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	stats, err := writeCommandOutput(*output, func(w io.Writer) (tsdb.BackupStats, error) {
+		return db.Export(w, tsdb.ExportOptions{ServerID: *serverID, From: r.From, To: r.To, Format: *format})
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("NEZHA>> TSDB export finished: %d series, %d samples", stats.Series, stats.Samples)
+	return nil
+}
+
+// writeCommandOutput 把 write 的输出写入 path，失败时删除不完整的文件
+func writeCommandOutput(path string, write func(w io.Writer) (tsdb.BackupStats, error)) (tsdb.BackupStats, error) {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return tsdb.BackupStats{}, err
+	}
+	stats, err := write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return stats, err
+}
@@ -18,8 +18,9 @@ type AlertRule struct {
 	Name                   string   `json:"name"`
 	RulesRaw               string   `json:"-"`
 	Enable                 *bool    `json:"enable,omitempty"`
-	TriggerMode            uint8    `gorm:"default:0" json:"trigger_mode"` // 触发模式: 0-始终触发(默认) 1-单次触发
-	NotificationGroupID    uint64   `json:"notification_group_id"`         // 该报警规则所在的通知组
+	TriggerMode            uint8    `gorm:"default:0" json:"trigger_mode"`  // 触发模式: 0-始终触发(默认) 1-单次触发
+	NotificationGroupID    uint64   `json:"notification_group_id"`          // 该报警规则所在的通知组
+	EscalationPolicyID     uint64   `json:"escalation_policy_id,omitempty"` // 绑定的升级策略，非 0 时报警与恢复通知按策略发送
 	FailTriggerTasksRaw    string   `gorm:"default:'[]'" json:"-"`
 	RecoverTriggerTasksRaw string   `gorm:"default:'[]'" json:"-"`
 	Rules                  []*Rule  `gorm:"-" json:"rules"`
@@ -67,7 +68,8 @@ func (r *AlertRule) Enabled() bool {
 // HasPermission extends the default owner/admin check with PAT
 // server_ids whitelist enforcement. AlertRule.Snapshot fans out across
 // every owner-visible server filtered only by Rule.Ignore semantics
-// (RuleCoverAll: deny-list; RuleCoverIgnoreAll: allow-list). A
+// (RuleCoverAll: deny-list; RuleCoverIgnoreAll: allow-list), with
+// Rule.ServerGroups expanded to their current members. A
 // server-limited PAT must therefore satisfy the same cover-fanout rule
 // the cron / service paths use — otherwise it can create or update a
 // rule that monitors servers outside its whitelist (admin owner: any
@@ -98,18 +100,12 @@ func (r *AlertRule) HasPermission(ctx *gin.Context) bool {
 		}
 		switch rule.Cover {
 		case RuleCoverAll:
-			denyIDs := make([]uint64, 0, len(rule.Ignore))
-			for id, ignored := range rule.Ignore {
-				if ignored {
-					denyIDs = append(denyIDs, id)
-				}
-			}
-			if !DenyListSafeForLimitedPAT(tok, r.GetUserID(), denyIDs) {
+			if !DenyListSafeForLimitedPAT(tok, r.GetUserID(), rule.ListedServerIDs()) {
 				return false
 			}
 		case RuleCoverIgnoreAll:
-			for id, monitored := range rule.Ignore {
-				if monitored && !tok.CanAccessServer(id) {
+			for _, id := range rule.ListedServerIDs() {
+				if !tok.CanAccessServer(id) {
 					return false
 				}
 			}
@@ -130,6 +126,29 @@ func (r *AlertRule) Snapshot(cycleTransferStats *CycleTransferStats, server *Ser
 	return point
 }
 
+// FailedValue 返回 point 中第一条未通过且有数值的规则在 serverID 上的最近指标值
+func (r *AlertRule) FailedValue(serverID uint64, point []bool) (float64, bool) {
+	for i, rule := range r.Rules {
+		if i >= len(point) || point[i] {
+			continue
+		}
+		if v, ok := rule.LastValue(serverID); ok {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// FailedRule 返回 point 中第一条未通过的规则，全部通过时返回 nil
+func (r *AlertRule) FailedRule(point []bool) *Rule {
+	for i, rule := range r.Rules {
+		if i < len(point) && !point[i] && rule != nil {
+			return rule
+		}
+	}
+	return nil
+}
+
 // Check 传入包含当前报警规则下所有type检查结果 返回报警持续时间与是否通过报警检查(通过则返回true)
 func (r *AlertRule) Check(points [][]bool) (int, bool) {
 	var hasPassedRule bool
@@ -12,8 +12,8 @@ import (
 
 // Scope 命名规范（唯一一套）：nezha:{resource}:{verb}
 //
-//   - resource: inventory / server / service / alertrule / cron / ddns / nat /
-//     notification / notification-group / transfer / admin
+//   - resource: inventory / server / service / alertrule / silence / cron / ddns /
+//     nat / notification / notification-group / transfer / admin
 //   - verb: read / write / delete / exec
 //
 // `*` 通配在 resource 或 verb 位均可：
@@ -56,6 +56,12 @@ const (
 	ScopeAlertRuleWrite  = "nezha:alertrule:write"
 	ScopeAlertRuleDelete = "nezha:alertrule:delete"
 
+	// silence 资源域：人工静默与报警事件确认。与 alertrule 分开，值班用的 PAT
+	// 可以静默告警而无权修改报警规则本身。
+	ScopeSilenceRead   = "nezha:silence:read"
+	ScopeSilenceWrite  = "nezha:silence:write"
+	ScopeSilenceDelete = "nezha:silence:delete"
+
 	ScopeCronRead   = "nezha:cron:read"
 	ScopeCronWrite  = "nezha:cron:write"
 	ScopeCronDelete = "nezha:cron:delete"
@@ -81,6 +87,10 @@ const (
 	ScopeTransferWrite  = "nezha:transfer:write"
 	ScopeTransferDelete = "nezha:transfer:delete"
 
+	// metrics 资源域：Prometheus 兼容查询接口，只读。单独成域便于给 Grafana
+	// 等外部看板签发不能访问其他资源的 PAT。
+	ScopeMetricsRead = "nezha:metrics:read"
+
 	ScopeAdminAll = "nezha:admin:*"
 )
 
@@ -89,23 +99,27 @@ var AllScopes = []string{
 	ScopeServerRead, ScopeServerWrite, ScopeServerDelete, ScopeServerExec,
 	ScopeServiceRead, ScopeServiceWrite, ScopeServiceDelete,
 	ScopeAlertRuleRead, ScopeAlertRuleWrite, ScopeAlertRuleDelete,
+	ScopeSilenceRead, ScopeSilenceWrite, ScopeSilenceDelete,
 	ScopeCronRead, ScopeCronWrite, ScopeCronDelete, ScopeCronExec,
 	ScopeDDNSRead, ScopeDDNSWrite, ScopeDDNSDelete,
 	ScopeNATRead, ScopeNATWrite, ScopeNATDelete,
 	ScopeNotificationRead, ScopeNotificationWrite, ScopeNotificationDelete,
 	ScopeNotificationGroupRead, ScopeNotificationGroupWrite, ScopeNotificationGroupDelete,
 	ScopeTransferRead, ScopeTransferWrite, ScopeTransferDelete,
+	ScopeMetricsRead,
 
 	"nezha:inventory:*",
 	"nezha:server:*",
 	"nezha:service:*",
 	"nezha:alertrule:*",
+	"nezha:silence:*",
 	"nezha:cron:*",
 	"nezha:ddns:*",
 	"nezha:nat:*",
 	"nezha:notification:*",
 	"nezha:notification-group:*",
 	"nezha:transfer:*",
+	"nezha:metrics:*",
 }
 
 var AdminOnlyScopes = []string{ScopeNezhaAll, ScopeAdminAll}
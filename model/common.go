@@ -25,7 +25,9 @@ type APITokenAccessor interface {
 }
 
 const (
-	CacheKeyOauth2State = "cko2s::"
+	CacheKeyOauth2State        = "cko2s::"
+	CacheKeyMetricHistory      = "ckmh::"
+	CacheKeyServerGroupMembers = "ckgm"
 )
 
 type CtxKeyRealIP struct{}
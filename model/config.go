@@ -134,6 +134,30 @@ type TSDBConf struct {
 	MaxMemoryMB              int64   `koanf:"max_memory_mb" json:"max_memory_mb,omitempty"`
 	WriteBufferSize          int     `koanf:"write_buffer_size" json:"write_buffer_size,omitempty"`
 	WriteBufferFlushInterval int     `koanf:"write_buffer_flush_interval" json:"write_buffer_flush_interval,omitempty"`
+	// Backend 存储后端：local（默认）为嵌入式存储，remote 写入外部 Prometheus remote write 接口
+	Backend string         `koanf:"backend" json:"backend,omitempty"`
+	Remote  TSDBRemoteConf `koanf:"remote" json:"remote,omitempty"`
+	// Rollups 长期保留的降采样层级，为空时默认 5 分钟保留 1 年、1 小时保留 5 年
+	Rollups        []TSDBRollupConf `koanf:"rollups" json:"rollups,omitempty"`
+	DisableRollups bool             `koanf:"disable_rollups" json:"disable_rollups,omitempty"`
+}
+
+// TSDBRollupConf TSDB 降采样层级配置
+type TSDBRollupConf struct {
+	Interval      int `koanf:"interval" json:"interval,omitempty"` // 秒
+	RetentionDays int `koanf:"retention_days" json:"retention_days,omitempty"`
+}
+
+// TSDBRemoteConf 远端 TSDB 配置
+type TSDBRemoteConf struct {
+	WriteURL    string `koanf:"write_url" json:"write_url,omitempty"`
+	QueryURL    string `koanf:"query_url" json:"query_url,omitempty"` // Prometheus HTTP API 地址，为空时由 write_url 推导
+	BearerToken string `koanf:"bearer_token" json:"bearer_token,omitempty"`
+	Username    string `koanf:"username" json:"username,omitempty"`
+	Password    string `koanf:"password" json:"password,omitempty"`
+	Timeout     int    `koanf:"timeout" json:"timeout,omitempty"` // 秒
+	SpoolPath   string `koanf:"spool_path" json:"spool_path,omitempty"`
+	MaxSpoolMB  int64  `koanf:"max_spool_mb" json:"max_spool_mb,omitempty"`
 }
 
 // MemoryConf 内存配置
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"slices"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -24,14 +25,16 @@ type Cron struct {
 	Scheduler           string    `json:"scheduler"`                  // 分钟 小时 天 月 星期
 	Command             string    `json:"command,omitempty"`
 	Servers             []uint64  `gorm:"-" json:"servers"`
+	ServerGroups        []uint64  `gorm:"-" json:"server_groups"`     // 与 Servers 语义相同的服务器分组，派发时按当前成员展开
 	PushSuccessful      bool      `json:"push_successful,omitempty"`  // 推送成功的通知
 	NotificationGroupID uint64    `json:"notification_group_id"`      // 指定通知方式的分组
 	LastExecutedAt      time.Time `json:"last_executed_at,omitempty"` // 最后一次执行时间
 	LastResult          bool      `json:"last_result,omitempty"`      // 最后一次执行结果
 	Cover               uint8     `json:"cover"`                      // 计划任务覆盖范围 (0:仅覆盖特定服务器 1:仅忽略特定服务器 2:由触发该计划任务的服务器执行)
 
-	CronJobID  cron.EntryID `gorm:"-" json:"cron_job_id,omitempty"`
-	ServersRaw string       `json:"-"`
+	CronJobID       cron.EntryID `gorm:"-" json:"cron_job_id,omitempty"`
+	ServersRaw      string       `json:"-"`
+	ServerGroupsRaw string       `gorm:"default:'[]'" json:"-"`
 }
 
 func (c *Cron) BeforeSave(tx *gorm.DB) error {
@@ -40,11 +43,29 @@ func (c *Cron) BeforeSave(tx *gorm.DB) error {
 	} else {
 		c.ServersRaw = string(data)
 	}
+	if data, err := json.Marshal(c.ServerGroups); err != nil {
+		return err
+	} else {
+		c.ServerGroupsRaw = string(data)
+	}
 	return nil
 }
 
 func (c *Cron) AfterFind(tx *gorm.DB) error {
-	return json.Unmarshal([]byte(c.ServersRaw), &c.Servers)
+	if err := json.Unmarshal([]byte(c.ServersRaw), &c.Servers); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(c.ServerGroupsRaw), &c.ServerGroups)
+}
+
+// ServerIDs 返回 Servers 与 ServerGroups 当前成员的并集
+func (c *Cron) ServerIDs() []uint64 {
+	return ExpandServerGroups(c.Servers, c.ServerGroups)
+}
+
+// Lists 服务器是否被 Servers 或 ServerGroups 列出
+func (c *Cron) Lists(serverID uint64) bool {
+	return slices.Contains(c.Servers, serverID) || serverInGroups(serverID, c.ServerGroups)
 }
 
 // HasPermission 扩展默认的 owner/admin 检查，使得 PAT 的 server_ids 白名单
@@ -63,6 +84,8 @@ func (c *Cron) AfterFind(tx *gorm.DB) error {
 //     rejectImplicitCoverForLimitedPAT* / 运行时 guard
 //     enforcePATCronDispatchScope 共用 DenyListSafeForLimitedPAT，避免列表
 //     视图把越界历史/旁路写入行漏给受限 PAT。
+//
+// ServerGroups 按当前成员展开后并入 Servers 参与上述判定。
 func (c *Cron) HasPermission(ctx *gin.Context) bool {
 	if !c.Common.HasPermission(ctx) {
 		return false
@@ -77,9 +100,9 @@ func (c *Cron) HasPermission(ctx *gin.Context) bool {
 	}
 	switch c.Cover {
 	case CronCoverAll:
-		return DenyListSafeForLimitedPAT(tok, c.GetUserID(), c.Servers)
+		return DenyListSafeForLimitedPAT(tok, c.GetUserID(), c.ServerIDs())
 	default:
-		for _, id := range c.Servers {
+		for _, id := range c.ServerIDs() {
 			if !tok.CanAccessServer(id) {
 				return false
 			}
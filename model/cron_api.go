@@ -6,6 +6,7 @@ type CronForm struct {
 	Scheduler           string   `json:"scheduler,omitempty"`
 	Command             string   `json:"command,omitempty" validate:"optional"`
 	Servers             []uint64 `json:"servers,omitempty"`
+	ServerGroups        []uint64 `json:"server_groups,omitempty" validate:"optional"`
 	Cover               uint8    `json:"cover,omitempty" default:"0"`
 	PushSuccessful      bool     `json:"push_successful,omitempty" validate:"optional"`
 	NotificationGroupID uint64   `json:"notification_group_id,omitempty"`
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"time"
+
+	"github.com/goccy/go-json"
+	"gorm.io/gorm"
+)
+
+// EscalationStep 升级策略中的一级：报警持续 Delay 秒仍未恢复且未被确认时通知 NotificationGroupID
+type EscalationStep struct {
+	Delay               uint64 `json:"delay"` // 距报警开始的秒数，0 表示立即通知
+	NotificationGroupID uint64 `json:"notification_group_id"`
+}
+
+// EscalationPolicy 报警升级策略：报警规则或服务监控绑定后，由策略按步骤依次通知多个通知组，
+// 取代其自身的 NotificationGroupID。Steps 按 Delay 升序保存。
+type EscalationPolicy struct {
+	Common
+	Name     string           `json:"name"`
+	Steps    []EscalationStep `gorm:"-" json:"steps"`
+	StepsRaw string           `gorm:"default:'[]'" json:"-"`
+}
+
+func (p *EscalationPolicy) BeforeSave(tx *gorm.DB) error {
+	if data, err := json.Marshal(p.Steps); err != nil {
+		return err
+	} else {
+		p.StepsRaw = string(data)
+	}
+	return nil
+}
+
+func (p *EscalationPolicy) AfterFind(tx *gorm.DB) error {
+	return json.Unmarshal([]byte(p.StepsRaw), &p.Steps)
+}
+
+// DueSteps 返回从第 reached 级起、报警已持续 elapsed 时应当通知的步骤下标
+func (p *EscalationPolicy) DueSteps(reached int, elapsed time.Duration) []int {
+	var due []int
+	for i := reached; i < len(p.Steps); i++ {
+		if time.Duration(p.Steps[i].Delay)*time.Second > elapsed {
+			break
+		}
+		due = append(due, i)
+	}
+	return due
+}
+
+// EscalationDelivery 升级策略每一级实际发出的通知记录，用于追溯报警通知了哪些通知组。
+// UserID 为报警规则或服务监控的所有者，CreatedAt 即发送时间。
+type EscalationDelivery struct {
+	Common
+	EscalationPolicyID    uint64 `gorm:"index" json:"escalation_policy_id"`
+	Step                  int    `json:"step"` // 策略中的步骤下标，从 0 开始
+	NotificationGroupID   uint64 `json:"notification_group_id"`
+	NotificationGroupName string `json:"notification_group_name"`
+	AlertRuleID           uint64 `gorm:"index" json:"alert_rule_id,omitempty"`
+	ServerID              uint64 `gorm:"index" json:"server_id,omitempty"`
+	ServiceID             uint64 `gorm:"index" json:"service_id,omitempty"`
+	IncidentID            uint64 `gorm:"index" json:"incident_id,omitempty"`
+	Message               string `json:"message"`
+}
@@ -0,0 +1,8 @@
+package model
+
+const EscalationPolicyMaxDelay = 7 * 24 * 60 * 60 // 升级步骤延迟上限 (秒)
+
+type EscalationPolicyForm struct {
+	Name  string           `json:"name" minLength:"1"`
+	Steps []EscalationStep `json:"steps"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestEscalationPolicyDueSteps(t *testing.T) {
+	p := EscalationPolicy{Steps: []EscalationStep{
+		{Delay: 0, NotificationGroupID: 1},
+		{Delay: 900, NotificationGroupID: 2},
+		{Delay: 3600, NotificationGroupID: 3},
+	}}
+	cases := []struct {
+		msg     string
+		reached int
+		elapsed time.Duration
+		exp     []int
+	}{
+		{"Immediate", 0, 0, []int{0}},
+		{"NotYetDue", 1, 14 * time.Minute, nil},
+		{"SecondStep", 1, 15 * time.Minute, []int{1}},
+		{"CatchUp", 1, 2 * time.Hour, []int{1, 2}},
+		{"AllReached", 3, 2 * time.Hour, nil},
+	}
+	for _, c := range cases {
+		assertEq(t, c.msg, true, slices.Equal(c.exp, p.DueSteps(c.reached, c.elapsed)))
+	}
+}
@@ -17,6 +17,23 @@ type SensorTemperature struct {
 	Temperature float64
 }
 
+// DiskState 单个挂载点的磁盘用量
+type DiskState struct {
+	Mountpoint string `json:"mountpoint"`
+	Fstype     string `json:"fstype,omitempty"`
+	Total      uint64 `json:"total"`
+	Used       uint64 `json:"used"`
+}
+
+// NetInterfaceState 单个网卡的流量与速率
+type NetInterfaceState struct {
+	Name        string `json:"name"`
+	InTransfer  uint64 `json:"in_transfer"`
+	OutTransfer uint64 `json:"out_transfer"`
+	InSpeed     uint64 `json:"in_speed"`
+	OutSpeed    uint64 `json:"out_speed"`
+}
+
 type HostState struct {
 	CPU            float64             `json:"cpu,omitempty"`
 	MemUsed        uint64              `json:"mem_used,omitempty"`
@@ -35,6 +52,28 @@ type HostState struct {
 	ProcessCount   uint64              `json:"process_count,omitempty"`
 	Temperatures   []SensorTemperature `json:"temperatures,omitempty"`
 	GPU            []float64           `json:"gpu,omitempty"`
+	Disks          []DiskState         `json:"disks,omitempty"`
+	NetInterfaces  []NetInterfaceState `json:"net_interfaces,omitempty"`
+}
+
+// Disk 按挂载点查找磁盘
+func (s *HostState) Disk(mountpoint string) (DiskState, bool) {
+	for _, d := range s.Disks {
+		if d.Mountpoint == mountpoint {
+			return d, true
+		}
+	}
+	return DiskState{}, false
+}
+
+// NetInterface 按名称查找网卡
+func (s *HostState) NetInterface(name string) (NetInterfaceState, bool) {
+	for _, n := range s.NetInterfaces {
+		if n.Name == name {
+			return n, true
+		}
+	}
+	return NetInterfaceState{}, false
 }
 
 func (s *HostState) PB() *pb.State {
@@ -45,6 +84,25 @@ func (s *HostState) PB() *pb.State {
 			Temperature: t.Temperature,
 		})
 	}
+	var disks []*pb.State_Disk
+	for _, d := range s.Disks {
+		disks = append(disks, &pb.State_Disk{
+			Mountpoint: d.Mountpoint,
+			Fstype:     d.Fstype,
+			Total:      d.Total,
+			Used:       d.Used,
+		})
+	}
+	var nics []*pb.State_NetInterface
+	for _, n := range s.NetInterfaces {
+		nics = append(nics, &pb.State_NetInterface{
+			Name:        n.Name,
+			InTransfer:  n.InTransfer,
+			OutTransfer: n.OutTransfer,
+			InSpeed:     n.InSpeed,
+			OutSpeed:    n.OutSpeed,
+		})
+	}
 
 	return &pb.State{
 		Cpu:            s.CPU,
@@ -64,6 +122,8 @@ func (s *HostState) PB() *pb.State {
 		ProcessCount:   s.ProcessCount,
 		Temperatures:   ts,
 		Gpu:            s.GPU,
+		Disks:          disks,
+		NetInterfaces:  nics,
 	}
 }
 
@@ -75,6 +135,25 @@ func PB2State(s *pb.State) HostState {
 			Temperature: t.GetTemperature(),
 		})
 	}
+	var disks []DiskState
+	for _, d := range s.GetDisks() {
+		disks = append(disks, DiskState{
+			Mountpoint: d.GetMountpoint(),
+			Fstype:     d.GetFstype(),
+			Total:      d.GetTotal(),
+			Used:       d.GetUsed(),
+		})
+	}
+	var nics []NetInterfaceState
+	for _, n := range s.GetNetInterfaces() {
+		nics = append(nics, NetInterfaceState{
+			Name:        n.GetName(),
+			InTransfer:  n.GetInTransfer(),
+			OutTransfer: n.GetOutTransfer(),
+			InSpeed:     n.GetInSpeed(),
+			OutSpeed:    n.GetOutSpeed(),
+		})
+	}
 
 	return HostState{
 		CPU:            s.GetCpu(),
@@ -94,6 +173,8 @@ func PB2State(s *pb.State) HostState {
 		ProcessCount:   s.GetProcessCount(),
 		Temperatures:   ts,
 		GPU:            s.GetGpu(),
+		Disks:          disks,
+		NetInterfaces:  nics,
 	}
 }
 
@@ -109,6 +190,7 @@ type Host struct {
 	BootTime        uint64   `json:"boot_time,omitempty"`
 	Version         string   `json:"version,omitempty"`
 	GPU             []string `json:"gpu,omitempty"`
+	Features        []string `json:"features,omitempty"` // agent 支持的可选能力，见 AgentFeature 常量
 }
 
 func (h *Host) PB() *pb.Host {
@@ -124,6 +206,7 @@ func (h *Host) PB() *pb.Host {
 		BootTime:        h.BootTime,
 		Version:         h.Version,
 		Gpu:             h.GPU,
+		Features:        h.Features,
 	}
 }
 
@@ -155,6 +238,7 @@ func PB2Host(h *pb.Host) Host {
 		BootTime:        h.GetBootTime(),
 		Version:         h.GetVersion(),
 		GPU:             h.GetGpu(),
+		Features:        h.GetFeatures(),
 	}
 }
 
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Incident 报警事件：一条报警规则在一台服务器上，或绑定了升级策略的服务监控从故障到恢复的完整记录。
+// UserID 为报警规则或服务监控的所有者，名称字段在触发时快照，规则、服务器或服务被删除后仍可追溯。
+type Incident struct {
+	Common
+	AlertRuleID    uint64     `gorm:"index" json:"alert_rule_id"`
+	ServerID       uint64     `gorm:"index" json:"server_id"`
+	ServiceID      uint64     `gorm:"index" json:"service_id,omitempty"`
+	AlertName      string     `json:"alert_name"`
+	ServerName     string     `json:"server_name"`
+	ServiceName    string     `json:"service_name,omitempty"`
+	OpenedAt       time.Time  `gorm:"index" json:"opened_at"`
+	ResolvedAt     *time.Time `gorm:"index" json:"resolved_at,omitempty"`
+	LastValue      *float64   `json:"last_value,omitempty"`      // 最近一次未通过检查时的指标值，表达式 / 离线规则没有数值
+	AcknowledgedBy uint64     `json:"acknowledged_by,omitempty"` // 确认该事件的用户 ID
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+}
+
+// IsOpen 事件尚未恢复
+func (i *Incident) IsOpen() bool {
+	return i.ResolvedAt == nil
+}
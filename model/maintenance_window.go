@@ -0,0 +1,142 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// maintenanceScheduleParser 与计划任务使用同一套带秒的 cron 语法
+var maintenanceScheduleParser = cron.NewParser(
+	cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// MaintenanceWindow 维护窗口：生效期间命中的服务器、服务器分组内的服务器与服务
+// 不发送报警通知、不执行触发任务，状态页展示为维护中。
+// 一次性窗口使用 StartAt / EndAt；周期窗口由 Scheduler 决定每次开始时间，持续 Duration 秒。
+type MaintenanceWindow struct {
+	Common
+	Name         string     `json:"name"`
+	Enabled      bool       `json:"enabled"`
+	StartAt      *time.Time `json:"start_at,omitempty"`
+	EndAt        *time.Time `json:"end_at,omitempty"`
+	Scheduler    string     `json:"scheduler,omitempty"` // 秒 分钟 小时 天 月 星期
+	Duration     uint64     `json:"duration,omitempty"`  // 周期窗口每次持续时长 (秒)
+	Servers      []uint64   `gorm:"-" json:"servers"`
+	ServerGroups []uint64   `gorm:"-" json:"server_groups"`
+	Services     []uint64   `gorm:"-" json:"services"`
+
+	ServersRaw      string `json:"-"`
+	ServerGroupsRaw string `json:"-"`
+	ServicesRaw     string `json:"-"`
+
+	schedule cron.Schedule
+}
+
+func (w *MaintenanceWindow) BeforeSave(tx *gorm.DB) error {
+	if data, err := json.Marshal(w.Servers); err != nil {
+		return err
+	} else {
+		w.ServersRaw = string(data)
+	}
+	if data, err := json.Marshal(w.ServerGroups); err != nil {
+		return err
+	} else {
+		w.ServerGroupsRaw = string(data)
+	}
+	if data, err := json.Marshal(w.Services); err != nil {
+		return err
+	} else {
+		w.ServicesRaw = string(data)
+	}
+	return nil
+}
+
+func (w *MaintenanceWindow) AfterFind(tx *gorm.DB) error {
+	if err := json.Unmarshal([]byte(w.ServersRaw), &w.Servers); err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(w.ServerGroupsRaw), &w.ServerGroups); err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(w.ServicesRaw), &w.Services); err != nil {
+		return err
+	}
+	// 保存前已校验过表达式，解析失败的周期窗口视为永不生效
+	w.ParseScheduler()
+	return nil
+}
+
+// IsRecurring 是否为周期窗口
+func (w *MaintenanceWindow) IsRecurring() bool {
+	return w.Scheduler != ""
+}
+
+// ParseScheduler 解析周期窗口的 cron 表达式并缓存，需在窗口对其他协程可见前调用
+func (w *MaintenanceWindow) ParseScheduler() error {
+	w.schedule = nil
+	if !w.IsRecurring() {
+		return nil
+	}
+	schedule, err := maintenanceScheduleParser.Parse(w.Scheduler)
+	if err != nil {
+		return err
+	}
+	w.schedule = schedule
+	return nil
+}
+
+// ActiveAt 返回窗口在 now 时刻是否生效以及本次维护的结束时间。
+// 周期表达式按 now 所在时区计算，调用方应传入面板时区的时间。
+func (w *MaintenanceWindow) ActiveAt(now time.Time) (time.Time, bool) {
+	if !w.Enabled {
+		return time.Time{}, false
+	}
+	if w.IsRecurring() {
+		if w.schedule == nil || w.Duration == 0 {
+			return time.Time{}, false
+		}
+		duration := time.Duration(w.Duration) * time.Second
+		// 最近一次开始时间落在 (now-duration, now] 内即处于维护中
+		start := w.schedule.Next(now.Add(-duration))
+		if start.IsZero() || start.After(now) {
+			return time.Time{}, false
+		}
+		return start.Add(duration), true
+	}
+	if w.StartAt == nil || w.EndAt == nil {
+		return time.Time{}, false
+	}
+	if now.Before(*w.StartAt) || !now.Before(*w.EndAt) {
+		return time.Time{}, false
+	}
+	return *w.EndAt, true
+}
+
+// CoversServer 服务器本身或其所在分组是否在窗口范围内
+func (w *MaintenanceWindow) CoversServer(serverID uint64, serverGroupIDs []uint64) bool {
+	if slices.Contains(w.Servers, serverID) {
+		return true
+	}
+	for _, gid := range serverGroupIDs {
+		if slices.Contains(w.ServerGroups, gid) {
+			return true
+		}
+	}
+	return false
+}
+
+// CoversService 服务是否在窗口范围内
+func (w *MaintenanceWindow) CoversService(serviceID uint64) bool {
+	return slices.Contains(w.Services, serviceID)
+}
+
+// MaintenanceStatus 对外展示的维护状态
+type MaintenanceStatus struct {
+	WindowID uint64    `json:"window_id"`
+	Name     string    `json:"name"`
+	Until    time.Time `json:"until"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+const MaintenanceWindowMaxDuration = 30 * 24 * 60 * 60 // 周期维护窗口单次时长上限 (秒)
+
+type MaintenanceWindowForm struct {
+	Name         string     `json:"name,omitempty" minLength:"1"`
+	Enabled      bool       `json:"enabled,omitempty" validate:"optional"`
+	StartAt      *time.Time `json:"start_at,omitempty" validate:"optional"`  // 一次性窗口开始时间
+	EndAt        *time.Time `json:"end_at,omitempty" validate:"optional"`    // 一次性窗口结束时间
+	Scheduler    string     `json:"scheduler,omitempty" validate:"optional"` // 周期窗口开始时间的 cron 表达式
+	Duration     uint64     `json:"duration,omitempty" validate:"optional"`  // 周期窗口每次持续时长 (秒)
+	Servers      []uint64   `json:"servers,omitempty" validate:"optional"`
+	ServerGroups []uint64   `json:"server_groups,omitempty" validate:"optional"`
+	Services     []uint64   `json:"services,omitempty" validate:"optional"`
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindowActiveAt(t *testing.T) {
+	start := time.Date(2026, 1, 1, 2, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	oneOff := MaintenanceWindow{Enabled: true, StartAt: &start, EndAt: &end}
+	cases := []struct {
+		msg string
+		now time.Time
+		exp bool
+	}{
+		{"BeforeStart", start.Add(-time.Second), false},
+		{"AtStart", start, true},
+		{"Inside", start.Add(30 * time.Minute), true},
+		{"AtEnd", end, false},
+	}
+	for _, c := range cases {
+		until, ok := oneOff.ActiveAt(c.now)
+		assertEq(t, "OneOff"+c.msg, c.exp, ok)
+		if ok {
+			assertEq(t, "OneOff"+c.msg+"Until", end, until)
+		}
+	}
+
+	// 每天 02:00 开始，持续一小时
+	recurring := MaintenanceWindow{Enabled: true, Scheduler: "0 0 2 * * *", Duration: 3600}
+	if err := recurring.ParseScheduler(); err != nil {
+		t.Fatal(err)
+	}
+	day := 24 * time.Hour
+	cases = []struct {
+		msg string
+		now time.Time
+		exp bool
+	}{
+		{"BeforeStart", start.Add(day - time.Second), false},
+		{"AtStart", start.Add(day), true},
+		{"Inside", start.Add(day + 59*time.Minute), true},
+		{"AtEnd", end.Add(day), false},
+	}
+	for _, c := range cases {
+		until, ok := recurring.ActiveAt(c.now)
+		assertEq(t, "Recurring"+c.msg, c.exp, ok)
+		if ok {
+			assertEq(t, "Recurring"+c.msg+"Until", end.Add(day), until)
+		}
+	}
+
+	oneOff.Enabled = false
+	_, ok := oneOff.ActiveAt(start)
+	assertEq(t, "Disabled", false, ok)
+
+	invalid := MaintenanceWindow{Enabled: true, Scheduler: "not a cron", Duration: 60}
+	if err := invalid.ParseScheduler(); err == nil {
+		t.Fatal("expected invalid scheduler to fail parsing")
+	}
+	_, ok = invalid.ActiveAt(start)
+	assertEq(t, "InvalidScheduler", false, ok)
+}
+
+func TestMaintenanceWindowCovers(t *testing.T) {
+	w := MaintenanceWindow{Servers: []uint64{1}, ServerGroups: []uint64{5}, Services: []uint64{9}}
+	assertEq(t, "Server", true, w.CoversServer(1, nil))
+	assertEq(t, "Group", true, w.CoversServer(2, []uint64{4, 5}))
+	assertEq(t, "OtherServer", false, w.CoversServer(2, []uint64{4}))
+	assertEq(t, "Service", true, w.CoversService(9))
+	assertEq(t, "OtherService", false, w.CoversService(1))
+}
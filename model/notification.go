@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/goccy/go-json"
+	"gorm.io/gorm"
+
 	"github.com/nezhahq/nezha/pkg/utils"
 )
 
@@ -29,11 +31,14 @@ type NotificationServerBundle struct {
 	Notification *Notification
 	Server       *Server
 	Loc          *time.Location
+	Event        *NotificationEvent // 触发通知的事件，只用于 Go 模板
 }
 
 type Notification struct {
 	Common
 	Name              string `json:"name"`
+	Type              uint8  `gorm:"default:0" json:"type"`          // 通知类型，默认为自定义 Webhook
+	TemplateMode      uint8  `gorm:"default:0" json:"template_mode"` // URL 与请求体的模板语法，默认为占位符替换
 	URL               string `json:"url"`
 	RequestMethod     uint8  `json:"request_method"`
 	RequestType       uint8  `json:"request_type"`
@@ -41,11 +46,35 @@ type Notification struct {
 	RequestBody       string `json:"request_body" gorm:"type:longtext"`
 	VerifyTLS         *bool  `json:"verify_tls,omitempty"`
 	FormatMetricUnits *bool  `json:"format_metric_units,omitempty"`
+
+	ProviderConfig    *NotificationProviderConfig `gorm:"-" json:"provider_config,omitempty"` // 非 Webhook 类型的配置
+	ProviderConfigRaw string                      `gorm:"type:longtext" json:"-"`
+}
+
+func (n *Notification) BeforeSave(tx *gorm.DB) error {
+	if n.ProviderConfig == nil {
+		n.ProviderConfigRaw = ""
+		return nil
+	}
+	if data, err := json.Marshal(n.ProviderConfig); err != nil {
+		return err
+	} else {
+		n.ProviderConfigRaw = string(data)
+	}
+	return nil
+}
+
+func (n *Notification) AfterFind(tx *gorm.DB) error {
+	if n.ProviderConfigRaw == "" {
+		return nil
+	}
+	n.ProviderConfig = new(NotificationProviderConfig)
+	return json.Unmarshal([]byte(n.ProviderConfigRaw), n.ProviderConfig)
 }
 
-func (ns *NotificationServerBundle) reqURL(message string) string {
+func (ns *NotificationServerBundle) reqURL(message string) (string, error) {
 	n := ns.Notification
-	return ns.replaceParamsInString(n.URL, message, func(msg string) string {
+	return ns.render(n.URL, message, func(msg string) string {
 		return url.QueryEscape(msg)
 	})
 }
@@ -67,10 +96,10 @@ func (ns *NotificationServerBundle) reqBody(message string) (string, error) {
 	}
 	switch n.RequestType {
 	case NotificationRequestTypeJSON:
-		return ns.replaceParamsInString(n.RequestBody, message, func(msg string) string {
+		return ns.render(n.RequestBody, message, func(msg string) string {
 			msgBytes, _ := json.Marshal(msg)
 			return string(msgBytes)[1 : len(msgBytes)-1]
-		}), nil
+		})
 	case NotificationRequestTypeForm:
 		data, err := utils.GjsonIter(n.RequestBody)
 		if err != nil {
@@ -78,7 +107,11 @@ func (ns *NotificationServerBundle) reqBody(message string) (string, error) {
 		}
 		params := url.Values{}
 		for k, v := range data {
-			params.Add(k, ns.replaceParamsInString(v, message, nil))
+			value, err := ns.render(v, message, nil)
+			if err != nil {
+				return "", err
+			}
+			params.Add(k, value)
 		}
 		return params.Encode(), nil
 	}
@@ -111,59 +144,177 @@ func (n *Notification) setRequestHeader(req *http.Request) error {
 }
 
 func (ns *NotificationServerBundle) Send(message string) error {
+	_, err := ns.Deliver(message)
+	return err
+}
+
+// NotificationRequest 渲染完成、即将发出的通知请求。邮件的 Method 为 SMTP，Body 为完整的邮件内容
+type NotificationRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// notificationResponseMaxLength 保留的响应内容长度上限
+const notificationResponseMaxLength = 4096
+
+// Deliver 发送通知并返回响应的 HTTP 状态码，请求未能发出或不是 HTTP 通知时状态码为 0
+func (ns *NotificationServerBundle) Deliver(message string) (int, error) {
+	req, err := ns.BuildRequest(message)
+	if err != nil {
+		return 0, err
+	}
+	code, _, err := ns.Do(req)
+	return code, err
+}
+
+// BuildRequest 渲染通知内容并构造请求，不会发出请求
+func (ns *NotificationServerBundle) BuildRequest(message string) (*NotificationRequest, error) {
 	n := ns.Notification
-	verifyTLS := n.VerifyTLS != nil && *n.VerifyTLS
+	if n.Type != NotificationTypeWebhook {
+		return ns.providerRequest(message)
+	}
 
 	reqBody, err := ns.reqBody(message)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	reqMethod, err := n.reqMethod()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	reqURL := ns.reqURL(message)
-	client, err := newNotificationHTTPClient(reqURL, verifyTLS)
+	reqURL, err := ns.reqURL(message)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	req, err := http.NewRequest(reqMethod, reqURL, strings.NewReader(reqBody))
+	req, err := http.NewRequest(reqMethod, reqURL, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	n.setContentType(req)
 
 	if err := n.setRequestHeader(req); err != nil {
-		return err
+		return nil, err
+	}
+
+	return &NotificationRequest{
+		Method: reqMethod,
+		URL:    reqURL,
+		Header: req.Header,
+		Body:   reqBody,
+	}, nil
+}
+
+// Do 发出 BuildRequest 构造的请求，返回状态码与截断后的响应内容，非 2xx 响应视为失败
+func (ns *NotificationServerBundle) Do(r *NotificationRequest) (int, string, error) {
+	n := ns.Notification
+	if n.Type == NotificationTypeSMTP {
+		return 0, "", ns.sendMail([]byte(r.Body))
+	}
+	verifyTLS := n.VerifyTLS != nil && *n.VerifyTLS
+
+	client, err := newNotificationHTTPClient(r.URL, verifyTLS)
+	if err != nil {
+		return 0, "", err
+	}
+
+	req, err := http.NewRequest(r.Method, r.URL, strings.NewReader(r.Body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header = r.Header.Clone()
+	if req.Header == nil {
+		req.Header = make(http.Header)
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return 0, "", err
 	}
 	defer func() {
 		_ = resp.Body.Close()
 	}()
 
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, notificationResponseMaxLength))
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return notificationResponseError(resp)
-	} else {
-		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, string(respBody), notificationResponseError(resp)
 	}
+	_, _ = io.Copy(io.Discard, resp.Body)
 
-	return nil
+	return resp.StatusCode, string(respBody), nil
+}
+
+// Test 渲染并发送一次通知，返回实际发出的请求与响应；dryRun 时只渲染不发送
+func (ns *NotificationServerBundle) Test(message string, dryRun bool) *NotificationTestResult {
+	req, err := ns.BuildRequest(message)
+	result := &NotificationTestResult{Request: req}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if dryRun {
+		return result
+	}
+
+	start := time.Now()
+	code, body, err := ns.Do(req)
+	result.Sent = true
+	result.Latency = uint64(time.Since(start).Milliseconds())
+	result.StatusCode = code
+	result.Response = body
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Redact 隐藏请求中通知方式保存的凭据：配置的请求头取值、URL 中的密码以及内置类型的凭据
+func (r *NotificationRequest) Redact(n *Notification) {
+	const mask = "******"
+	if n.RequestHeader != "" {
+		if m, err := utils.GjsonIter(n.RequestHeader); err == nil {
+			for k := range m {
+				if r.Header.Get(k) != "" {
+					r.Header.Set(k, mask)
+				}
+			}
+		}
+	}
+	if u, err := url.Parse(r.URL); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), mask)
+			r.URL = u.String()
+		}
+	}
+	if c := n.ProviderConfig; c != nil {
+		for _, secret := range []string{c.Password, c.BotToken, c.Token, c.DeviceKey} {
+			if secret == "" {
+				continue
+			}
+			r.URL = strings.ReplaceAll(r.URL, secret, mask)
+			r.Body = strings.ReplaceAll(r.Body, secret, mask)
+			for _, values := range r.Header {
+				for i := range values {
+					values[i] = strings.ReplaceAll(values[i], secret, mask)
+				}
+			}
+		}
+	}
 }
 
+// notificationResponseError 错误信息不包含响应内容，避免发送记录等处回显上游服务的数据
 func notificationResponseError(resp *http.Response) error {
-	_, _ = io.CopyN(io.Discard, resp.Body, 4096)
+	_, _ = io.CopyN(io.Discard, resp.Body, notificationResponseMaxLength)
 	return fmt.Errorf("%d@%s", resp.StatusCode, resp.Status)
 }
 
-func newNotificationHTTPClient(rawURL string, verifyTLS bool) (*http.Client, error) {
+// newNotificationHTTPClient 出站通知只允许访问公网地址，测试中可替换为访问本地替身服务
+var newNotificationHTTPClient = func(rawURL string, verifyTLS bool) (*http.Client, error) {
 	return utils.NewRestrictedHTTPClient(rawURL, !verifyTLS)
 }
 
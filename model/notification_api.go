@@ -2,12 +2,47 @@ package model
 
 type NotificationForm struct {
 	Name              string `json:"name,omitempty" minLength:"1"`
+	Type              uint8  `json:"type,omitempty"`
+	TemplateMode      uint8  `json:"template_mode,omitempty" validate:"optional"` // 0: #NEZHA# 占位符 1: Go text/template
 	URL               string `json:"url,omitempty"`
 	RequestMethod     uint8  `json:"request_method,omitempty"`
 	RequestType       uint8  `json:"request_type,omitempty"`
 	RequestHeader     string `json:"request_header,omitempty"`
 	RequestBody       string `json:"request_body,omitempty"`
-	VerifyTLS         bool   `json:"verify_tls,omitempty" validate:"optional"`
+	VerifyTLS         *bool  `json:"verify_tls,omitempty" validate:"optional"` // 未指定时 Webhook 不校验证书，邮件校验证书
 	SkipCheck         bool   `json:"skip_check,omitempty" validate:"optional"`
 	FormatMetricUnits bool   `json:"format_metric_units,omitempty" validate:"optional"`
+
+	ProviderConfig *NotificationProviderConfig `json:"provider_config,omitempty" validate:"optional"`
+}
+
+// NotificationTemplatePreviewForm 以指定服务器的当前状态渲染 Go 模板
+type NotificationTemplatePreviewForm struct {
+	Template string             `json:"template"`
+	Message  string             `json:"message,omitempty" validate:"optional"`   // 为空时使用测试消息
+	ServerID uint64             `json:"server_id,omitempty" validate:"optional"` // 为 0 时 .Server 为 nil
+	Event    *NotificationEvent `json:"event,omitempty" validate:"optional"`     // 为空时使用示例报警事件
+}
+
+// NotificationTestForm 测试发送的选项
+type NotificationTestForm struct {
+	ServerID uint64 `json:"server_id,omitempty" validate:"optional"` // 为 0 时使用示例服务器
+	Message  string `json:"message,omitempty" validate:"optional"`   // 为空时使用测试消息
+	DryRun   bool   `json:"dry_run,omitempty" validate:"optional"`   // 只渲染请求，不发送
+}
+
+// NotificationDryRunForm 以尚未保存的通知方式表单测试发送
+type NotificationDryRunForm struct {
+	NotificationTestForm
+	Notification NotificationForm `json:"notification"`
+}
+
+// NotificationTestResult 测试发送的结果，渲染或发送失败时 Error 不为空
+type NotificationTestResult struct {
+	Request    *NotificationRequest `json:"request,omitempty"`
+	Sent       bool                 `json:"sent"`
+	StatusCode int                  `json:"status_code,omitempty"`
+	Response   string               `json:"response,omitempty"` // 响应内容，最多保留 4 KiB
+	Latency    uint64               `json:"latency,omitempty"`  // 发送耗时 (毫秒)
+	Error      string               `json:"error,omitempty"`
 }
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+
+	"github.com/goccy/go-json"
+	"gorm.io/gorm"
+)
+
+const (
+	_                             = iota
+	NotificationDeliveryPending   // 等待发送或重试
+	NotificationDeliverySucceeded // 已送达
+	NotificationDeliveryFailed    // 重试次数用尽仍未送达
+)
+
+// NotificationDelivery 一条通知发往某个通知方式的发送记录，同时作为持久化的重试队列：
+// 发送失败的记录保持 Pending 并在 NextAttemptAt 到期后重试，面板重启后继续。
+// UserID 为通知方式的所有者；ServerID 为通知附带的服务器，重试时按其当前信息重新渲染消息。
+type NotificationDelivery struct {
+	Common
+	NotificationID uint64     `gorm:"index" json:"notification_id"`
+	ServerID       uint64     `json:"server_id,omitempty"`
+	Message        string     `gorm:"type:longtext" json:"message"`
+	Status         uint8      `gorm:"index" json:"status"`
+	StatusCode     int        `json:"status_code,omitempty"` // 最近一次尝试的 HTTP 状态码，请求未发出时为 0
+	Error          string     `json:"error,omitempty"`       // 最近一次尝试的错误
+	Attempts       uint       `json:"attempts"`
+	Latency        uint64     `json:"latency"` // 最近一次尝试的耗时 (毫秒)
+	NextAttemptAt  *time.Time `gorm:"index" json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+
+	Event    *NotificationEvent `gorm:"-" json:"event,omitempty"` // 触发通知的事件，重试时用于重新渲染 Go 模板
+	EventRaw string             `gorm:"type:longtext" json:"-"`
+}
+
+func (d *NotificationDelivery) BeforeSave(tx *gorm.DB) error {
+	if d.Event == nil {
+		d.EventRaw = ""
+		return nil
+	}
+	data, err := json.Marshal(d.Event)
+	if err != nil {
+		return err
+	}
+	d.EventRaw = string(data)
+	return nil
+}
+
+func (d *NotificationDelivery) AfterFind(tx *gorm.DB) error {
+	if d.EventRaw == "" {
+		return nil
+	}
+	d.Event = new(NotificationEvent)
+	return json.Unmarshal([]byte(d.EventRaw), d.Event)
+}
@@ -0,0 +1,421 @@
+package model
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/goccy/go-json"
+
+	"github.com/nezhahq/nezha/pkg/utils"
+)
+
+const (
+	NotificationTypeWebhook  = iota // 自定义 Webhook 模板
+	NotificationTypeSMTP            // 邮件
+	NotificationTypeTelegram        // Telegram Bot API
+	NotificationTypeSlack           // Slack Incoming Webhook
+	NotificationTypeDiscord         // Discord Webhook
+	NotificationTypeNtfy            // ntfy
+	NotificationTypeGotify          // Gotify
+	NotificationTypeBark            // Bark
+)
+
+const (
+	SMTPSecuritySTARTTLS = iota // 明文连接后升级为 TLS（默认）
+	SMTPSecurityTLS             // 隐式 TLS，通常为 465 端口
+	SMTPSecurityNone            // 不加密，仅允许不需要认证的服务器
+)
+
+const (
+	defaultTelegramAPI = "https://api.telegram.org"
+	defaultNtfyServer  = "https://ntfy.sh"
+	defaultBarkServer  = "https://api.day.app"
+
+	notificationTitleMaxLength = 100
+	discordContentMaxLength    = 2000
+	smtpDialTimeout            = 30 * time.Second
+)
+
+var (
+	telegramBotTokenRegex = regexp.MustCompile(`^\d+:[A-Za-z0-9_-]+$`)
+	ntfyTopicRegex        = regexp.MustCompile(`^[-_A-Za-z0-9]{1,64}$`)
+)
+
+// NotificationProviderConfig 内置通知类型的配置，各类型只使用其中的一部分字段。
+// Slack / Discord 的 Webhook 地址以及 Telegram / ntfy / Gotify / Bark 的服务地址使用 Notification.URL。
+// Password、BotToken、Token、DeviceKey 为凭据，列表接口不回显。
+type NotificationProviderConfig struct {
+	// 邮件
+	Host     string   `json:"host,omitempty"`
+	Port     uint16   `json:"port,omitempty"`
+	Security uint8    `json:"security,omitempty"` // 0: STARTTLS 1: TLS 2: 不加密
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+
+	// Telegram
+	BotToken string `json:"bot_token,omitempty"`
+	ChatID   string `json:"chat_id,omitempty"`
+
+	// ntfy / Gotify / Bark
+	Topic     string `json:"topic,omitempty"`      // ntfy 主题
+	Token     string `json:"token,omitempty"`      // ntfy 访问令牌 / Gotify 应用令牌
+	DeviceKey string `json:"device_key,omitempty"` // Bark 设备 Key
+	Priority  int    `json:"priority,omitempty"`   // ntfy 1-5，Gotify 0-10
+	Group     string `json:"group,omitempty"`      // Bark 分组
+	Sound     string `json:"sound,omitempty"`      // Bark 铃声
+}
+
+// Redact 清除凭据，用于列表接口
+func (c *NotificationProviderConfig) Redact() {
+	c.Password = ""
+	c.BotToken = ""
+	c.Token = ""
+	c.DeviceKey = ""
+}
+
+// KeepSecrets 编辑时凭据留空表示不修改，沿用 old 中的值
+func (c *NotificationProviderConfig) KeepSecrets(old *NotificationProviderConfig) {
+	if old == nil {
+		return
+	}
+	if c.Password == "" {
+		c.Password = old.Password
+	}
+	if c.BotToken == "" {
+		c.BotToken = old.BotToken
+	}
+	if c.Token == "" {
+		c.Token = old.Token
+	}
+	if c.DeviceKey == "" {
+		c.DeviceKey = old.DeviceKey
+	}
+}
+
+// Validate 校验通知类型、配置及模板
+func (n *Notification) Validate() error {
+	if err := n.validateTemplates(); err != nil {
+		return err
+	}
+	if n.Type == NotificationTypeWebhook {
+		return nil
+	}
+	if n.Type > NotificationTypeBark {
+		return fmt.Errorf("unknown notification type %d", n.Type)
+	}
+	c := n.ProviderConfig
+	if c == nil {
+		return errors.New("provider config is required")
+	}
+
+	switch n.Type {
+	case NotificationTypeSMTP:
+		if c.Host == "" || c.Port == 0 {
+			return errors.New("smtp host and port are required")
+		}
+		if c.Security > SMTPSecurityNone {
+			return fmt.Errorf("unknown smtp security mode %d", c.Security)
+		}
+		if c.Security == SMTPSecurityNone && c.Username != "" {
+			return errors.New("smtp authentication requires TLS or STARTTLS")
+		}
+		if !n.smtpVerifyTLS() && c.Username != "" {
+			return errors.New("smtp authentication requires TLS certificate verification")
+		}
+		if _, err := mail.ParseAddress(c.From); err != nil {
+			return fmt.Errorf("invalid sender address: %w", err)
+		}
+		if len(c.To) == 0 {
+			return errors.New("at least one recipient is required")
+		}
+		for _, to := range c.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				return fmt.Errorf("invalid recipient address %q: %w", to, err)
+			}
+		}
+	case NotificationTypeTelegram:
+		if !telegramBotTokenRegex.MatchString(c.BotToken) {
+			return errors.New("invalid telegram bot token")
+		}
+		if c.ChatID == "" {
+			return errors.New("telegram chat id is required")
+		}
+		return validateProviderURL(n.URL, true)
+	case NotificationTypeSlack, NotificationTypeDiscord:
+		return validateProviderURL(n.URL, false)
+	case NotificationTypeNtfy:
+		if !ntfyTopicRegex.MatchString(c.Topic) {
+			return errors.New("invalid ntfy topic")
+		}
+		if c.Priority < 0 || c.Priority > 5 {
+			return errors.New("ntfy priority must be between 1 and 5")
+		}
+		return validateProviderURL(n.URL, true)
+	case NotificationTypeGotify:
+		if c.Token == "" {
+			return errors.New("gotify application token is required")
+		}
+		if c.Priority < 0 || c.Priority > 10 {
+			return errors.New("gotify priority must be between 0 and 10")
+		}
+		return validateProviderURL(n.URL, false)
+	case NotificationTypeBark:
+		if c.DeviceKey == "" {
+			return errors.New("bark device key is required")
+		}
+		return validateProviderURL(n.URL, true)
+	}
+	return nil
+}
+
+func validateProviderURL(rawURL string, optional bool) error {
+	if rawURL == "" {
+		if optional {
+			return nil
+		}
+		return errors.New("url is required")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https url")
+	}
+	return nil
+}
+
+// providerRequest 按通知类型格式化消息并构造请求
+func (ns *NotificationServerBundle) providerRequest(message string) (*NotificationRequest, error) {
+	n := ns.Notification
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+	c := n.ProviderConfig
+	// Go 模板模式下以请求体模板渲染出的文本作为通知内容
+	if n.TemplateMode == NotificationTemplateGo && n.RequestBody != "" {
+		rendered, err := ns.RenderTemplate(n.RequestBody, message)
+		if err != nil {
+			return nil, err
+		}
+		message = rendered
+	}
+	title := notificationTitle(message)
+
+	switch n.Type {
+	case NotificationTypeSMTP:
+		from, _ := mail.ParseAddress(c.From)
+		to := make([]string, 0, len(c.To))
+		for _, addr := range c.To {
+			rcpt, _ := mail.ParseAddress(addr)
+			to = append(to, rcpt.String())
+		}
+		return &NotificationRequest{
+			Method: "SMTP",
+			URL:    "smtp://" + net.JoinHostPort(c.Host, strconv.Itoa(int(c.Port))),
+			Body:   string(buildMail(from.String(), to, title, message)),
+		}, nil
+	case NotificationTypeTelegram:
+		endpoint := providerURL(n.URL, defaultTelegramAPI, "bot"+c.BotToken, "sendMessage")
+		return jsonRequest(endpoint, map[string]any{
+			"chat_id":                  c.ChatID,
+			"text":                     message,
+			"disable_web_page_preview": true,
+		}, nil)
+	case NotificationTypeSlack:
+		return jsonRequest(n.URL, map[string]any{"text": message}, nil)
+	case NotificationTypeDiscord:
+		return jsonRequest(n.URL, map[string]any{"content": truncateRunes(message, discordContentMaxLength)}, nil)
+	case NotificationTypeNtfy:
+		payload := map[string]any{
+			"topic":   c.Topic,
+			"title":   title,
+			"message": message,
+		}
+		if c.Priority > 0 {
+			payload["priority"] = c.Priority
+		}
+		var header map[string]string
+		if c.Token != "" {
+			header = map[string]string{"Authorization": "Bearer " + c.Token}
+		}
+		return jsonRequest(providerURL(n.URL, defaultNtfyServer), payload, header)
+	case NotificationTypeGotify:
+		return jsonRequest(providerURL(n.URL, "", "message"), map[string]any{
+			"title":    title,
+			"message":  message,
+			"priority": c.Priority,
+		}, map[string]string{"X-Gotify-Key": c.Token})
+	case NotificationTypeBark:
+		payload := map[string]any{
+			"device_key": c.DeviceKey,
+			"title":      title,
+			"body":       message,
+		}
+		if c.Group != "" {
+			payload["group"] = c.Group
+		}
+		if c.Sound != "" {
+			payload["sound"] = c.Sound
+		}
+		return jsonRequest(providerURL(n.URL, defaultBarkServer, "push"), payload, nil)
+	}
+	return nil, fmt.Errorf("unknown notification type %d", n.Type)
+}
+
+// jsonRequest 构造以 JSON 为请求体的 POST 请求
+func jsonRequest(endpoint string, payload any, header map[string]string) (*NotificationRequest, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	h := make(http.Header)
+	h.Set("Content-Type", "application/json")
+	for k, v := range header {
+		h.Set(k, v)
+	}
+	return &NotificationRequest{
+		Method: http.MethodPost,
+		URL:    endpoint,
+		Header: h,
+		Body:   string(body),
+	}, nil
+}
+
+// dialNotificationSMTP 连接邮件服务器，与 Webhook 一样只允许公网地址，测试中可替换为访问本地替身服务
+var dialNotificationSMTP = func(host string, port uint16) (net.Conn, error) {
+	ip, err := utils.ResolveAllowedHost(host)
+	if err != nil {
+		return nil, err
+	}
+	return net.DialTimeout("tcp", net.JoinHostPort(ip.String(), strconv.Itoa(int(port))), smtpDialTimeout)
+}
+
+// smtpVerifyTLS 邮件默认校验服务器证书，VerifyTLS 显式为 false 时才跳过
+func (n *Notification) smtpVerifyTLS() bool {
+	return n.VerifyTLS == nil || *n.VerifyTLS
+}
+
+func (n *Notification) smtpTLSConfig() *tls.Config {
+	return &tls.Config{
+		ServerName:         n.ProviderConfig.Host,
+		InsecureSkipVerify: !n.smtpVerifyTLS(),
+	}
+}
+
+// sendMail 通过 SMTP 发送 providerRequest 生成的邮件
+func (ns *NotificationServerBundle) sendMail(data []byte) error {
+	n := ns.Notification
+	c := n.ProviderConfig
+	// 不校验证书时无法确认对端身份，拒绝发送账号密码
+	if c.Username != "" && !n.smtpVerifyTLS() {
+		return errors.New("smtp authentication requires TLS certificate verification")
+	}
+	tlsConfig := n.smtpTLSConfig()
+
+	conn, err := dialNotificationSMTP(c.Host, c.Port)
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(smtpDialTimeout * 2))
+	if c.Security == SMTPSecurityTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, c.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if c.Security == SMTPSecuritySTARTTLS {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if c.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.Username, c.Password, c.Host)); err != nil {
+			return err
+		}
+	}
+
+	from, _ := mail.ParseAddress(c.From)
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, addr := range c.To {
+		rcpt, _ := mail.ParseAddress(addr)
+		if err := client.Rcpt(rcpt.Address); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func buildMail(from string, to []string, subject, message string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: " + from + "\r\n")
+	buf.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&buf)
+	_, _ = qp.Write([]byte(message))
+	_ = qp.Close()
+	return buf.Bytes()
+}
+
+// notificationTitle 取消息首行作为标题
+func notificationTitle(message string) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return truncateRunes(strings.TrimSpace(title), notificationTitleMaxLength)
+}
+
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max-1]) + "…"
+}
+
+// providerURL 拼接服务地址与路径，未配置服务地址时使用 fallback
+func providerURL(base, fallback string, paths ...string) string {
+	if base == "" {
+		base = fallback
+	}
+	base = strings.TrimRight(base, "/")
+	for _, p := range paths {
+		base += "/" + url.PathEscape(p)
+	}
+	return base
+}
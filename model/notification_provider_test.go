@@ -0,0 +1,399 @@
+package model
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+type providerRequest struct {
+	path   string
+	header http.Header
+	body   map[string]any
+}
+
+// withProviderServer 启动本地 HTTP 替身服务，并允许通知客户端访问回环地址
+func withProviderServer(t *testing.T, status int) (*httptest.Server, chan providerRequest) {
+	t.Helper()
+	reqs := make(chan providerRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		_ = json.Unmarshal(data, &body)
+		reqs <- providerRequest{path: r.URL.Path, header: r.Header, body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	old := newNotificationHTTPClient
+	newNotificationHTTPClient = func(string, bool) (*http.Client, error) {
+		return srv.Client(), nil
+	}
+	t.Cleanup(func() { newNotificationHTTPClient = old })
+	return srv, reqs
+}
+
+func TestNotificationProviderDeliver(t *testing.T) {
+	const message = "[Incident] server-1\nCPU usage is above 90%"
+
+	cases := []struct {
+		name     string
+		typ      uint8
+		config   NotificationProviderConfig
+		path     func(n *Notification) string
+		expected map[string]any
+		header   [2]string
+	}{
+		{
+			name:     "telegram",
+			typ:      NotificationTypeTelegram,
+			config:   NotificationProviderConfig{BotToken: "123:abc", ChatID: "-100"},
+			path:     func(*Notification) string { return "/bot123:abc/sendMessage" },
+			expected: map[string]any{"chat_id": "-100", "text": message},
+		},
+		{
+			name:     "slack",
+			typ:      NotificationTypeSlack,
+			config:   NotificationProviderConfig{},
+			path:     func(*Notification) string { return "/services/hook" },
+			expected: map[string]any{"text": message},
+		},
+		{
+			name:     "discord",
+			typ:      NotificationTypeDiscord,
+			config:   NotificationProviderConfig{},
+			path:     func(*Notification) string { return "/services/hook" },
+			expected: map[string]any{"content": message},
+		},
+		{
+			name:     "ntfy",
+			typ:      NotificationTypeNtfy,
+			config:   NotificationProviderConfig{Topic: "alerts", Token: "tk", Priority: 4},
+			path:     func(*Notification) string { return "/" },
+			expected: map[string]any{"topic": "alerts", "title": "[Incident] server-1", "message": message, "priority": float64(4)},
+			header:   [2]string{"Authorization", "Bearer tk"},
+		},
+		{
+			name:     "gotify",
+			typ:      NotificationTypeGotify,
+			config:   NotificationProviderConfig{Token: "app", Priority: 8},
+			path:     func(*Notification) string { return "/message" },
+			expected: map[string]any{"title": "[Incident] server-1", "message": message, "priority": float64(8)},
+			header:   [2]string{"X-Gotify-Key", "app"},
+		},
+		{
+			name:     "bark",
+			typ:      NotificationTypeBark,
+			config:   NotificationProviderConfig{DeviceKey: "dev", Group: "nezha"},
+			path:     func(*Notification) string { return "/push" },
+			expected: map[string]any{"device_key": "dev", "title": "[Incident] server-1", "body": message, "group": "nezha"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			srv, reqs := withProviderServer(t, http.StatusOK)
+			config := c.config
+			n := &Notification{Type: c.typ, URL: srv.URL, ProviderConfig: &config}
+			if c.typ == NotificationTypeSlack || c.typ == NotificationTypeDiscord {
+				n.URL = srv.URL + "/services/hook"
+			}
+
+			ns := NotificationServerBundle{Notification: n}
+			status, err := ns.Deliver(message)
+			if err != nil {
+				t.Fatalf("Deliver: %v", err)
+			}
+			if status != http.StatusOK {
+				t.Fatalf("status = %d, want 200", status)
+			}
+
+			req := <-reqs
+			if req.path != c.path(n) {
+				t.Errorf("path = %q, want %q", req.path, c.path(n))
+			}
+			for k, v := range c.expected {
+				if req.body[k] != v {
+					t.Errorf("body[%s] = %v, want %v", k, req.body[k], v)
+				}
+			}
+			if c.header[0] != "" && req.header.Get(c.header[0]) != c.header[1] {
+				t.Errorf("header %s = %q, want %q", c.header[0], req.header.Get(c.header[0]), c.header[1])
+			}
+		})
+	}
+}
+
+func TestNotificationProviderDeliverErrorStatus(t *testing.T) {
+	srv, reqs := withProviderServer(t, http.StatusUnauthorized)
+	n := &Notification{Type: NotificationTypeSlack, URL: srv.URL, ProviderConfig: &NotificationProviderConfig{}}
+	ns := NotificationServerBundle{Notification: n}
+
+	status, err := ns.Deliver("msg")
+	<-reqs
+	if err == nil {
+		t.Fatal("expected error for non-2xx response")
+	}
+	if status != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", status)
+	}
+}
+
+func TestNotificationProviderDiscordTruncate(t *testing.T) {
+	srv, reqs := withProviderServer(t, http.StatusNoContent)
+	n := &Notification{Type: NotificationTypeDiscord, URL: srv.URL, ProviderConfig: &NotificationProviderConfig{}}
+	ns := NotificationServerBundle{Notification: n}
+
+	if _, err := ns.Deliver(strings.Repeat("告", 3000)); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	content := (<-reqs).body["content"].(string)
+	if n := len([]rune(content)); n != discordContentMaxLength {
+		t.Fatalf("content length = %d, want %d", n, discordContentMaxLength)
+	}
+}
+
+// fakeSMTPServer 只实现发送一封邮件所需命令的本地 SMTP 替身
+func fakeSMTPServer(t *testing.T) (net.Listener, chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	mails := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = io.WriteString(conn, s+"\r\n") }
+
+		reply("220 localhost ESMTP")
+		var envelope []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250-localhost")
+				reply("250 8BITMIME")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				envelope = append(envelope, strings.TrimSpace(line))
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				mails <- strings.Join(envelope, "\n") + "\n" + data.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return ln, mails
+}
+
+func TestNotificationProviderSMTP(t *testing.T) {
+	ln, mails := fakeSMTPServer(t)
+	old := dialNotificationSMTP
+	dialNotificationSMTP = func(string, uint16) (net.Conn, error) {
+		return net.Dial("tcp", ln.Addr().String())
+	}
+	t.Cleanup(func() { dialNotificationSMTP = old })
+
+	n := &Notification{
+		Type: NotificationTypeSMTP,
+		ProviderConfig: &NotificationProviderConfig{
+			Host:     "mail.example.com",
+			Port:     25,
+			Security: SMTPSecurityNone,
+			From:     "Nezha <nezha@example.com>",
+			To:       []string{"ops@example.com", "Admin <admin@example.com>"},
+		},
+	}
+	ns := NotificationServerBundle{Notification: n}
+	if _, err := ns.Deliver("服务器离线\nserver-1 is offline"); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	mail := <-mails
+	for _, want := range []string{
+		"MAIL FROM:<nezha@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"RCPT TO:<admin@example.com>",
+		"Subject: =?utf-8?q?",
+		"Content-Type: text/plain; charset=UTF-8",
+		"server-1 is offline",
+	} {
+		if !strings.Contains(mail, want) {
+			t.Errorf("mail does not contain %q:\n%s", want, mail)
+		}
+	}
+}
+
+// 邮件默认校验证书，显式关闭校验时拒绝发送账号密码
+func TestNotificationSMTPVerifiesTLSByDefault(t *testing.T) {
+	n := &Notification{Type: NotificationTypeSMTP, ProviderConfig: &NotificationProviderConfig{
+		Host: "mail.example.com", Port: 587, Username: "u", Password: "p",
+		From: "nezha@example.com", To: []string{"ops@example.com"},
+	}}
+	if cfg := n.smtpTLSConfig(); cfg.InsecureSkipVerify || cfg.ServerName != "mail.example.com" {
+		t.Fatalf("unexpected default tls config: skip=%v server=%q", cfg.InsecureSkipVerify, cfg.ServerName)
+	}
+
+	skipVerify := false
+	n.VerifyTLS = &skipVerify
+	if !n.smtpTLSConfig().InsecureSkipVerify {
+		t.Fatal("verify_tls=false should skip certificate verification")
+	}
+
+	old := dialNotificationSMTP
+	dialNotificationSMTP = func(string, uint16) (net.Conn, error) {
+		t.Fatal("credentials must be refused before connecting")
+		return nil, nil
+	}
+	t.Cleanup(func() { dialNotificationSMTP = old })
+	ns := NotificationServerBundle{Notification: n}
+	if _, err := ns.Deliver("test"); err == nil {
+		t.Fatal("expected smtp auth without certificate verification to fail")
+	}
+}
+
+func TestNotificationValidate(t *testing.T) {
+	skipVerify := false
+	cases := []struct {
+		name string
+		n    Notification
+		ok   bool
+	}{
+		{"webhook", Notification{}, true},
+		{"unknown type", Notification{Type: 200, ProviderConfig: &NotificationProviderConfig{}}, false},
+		{"missing config", Notification{Type: NotificationTypeSlack, URL: "https://hooks.slack.com/x"}, false},
+		{"slack", Notification{Type: NotificationTypeSlack, URL: "https://hooks.slack.com/x", ProviderConfig: &NotificationProviderConfig{}}, true},
+		{"slack without url", Notification{Type: NotificationTypeSlack, ProviderConfig: &NotificationProviderConfig{}}, false},
+		{"discord bad scheme", Notification{Type: NotificationTypeDiscord, URL: "ftp://discord.com/x", ProviderConfig: &NotificationProviderConfig{}}, false},
+		{"telegram", Notification{Type: NotificationTypeTelegram, ProviderConfig: &NotificationProviderConfig{BotToken: "123:abc-DEF", ChatID: "1"}}, true},
+		{"telegram bad token", Notification{Type: NotificationTypeTelegram, ProviderConfig: &NotificationProviderConfig{BotToken: "abc", ChatID: "1"}}, false},
+		{"telegram no chat", Notification{Type: NotificationTypeTelegram, ProviderConfig: &NotificationProviderConfig{BotToken: "123:abc"}}, false},
+		{"ntfy", Notification{Type: NotificationTypeNtfy, ProviderConfig: &NotificationProviderConfig{Topic: "nezha_alerts"}}, true},
+		{"ntfy bad topic", Notification{Type: NotificationTypeNtfy, ProviderConfig: &NotificationProviderConfig{Topic: "a/b"}}, false},
+		{"ntfy bad priority", Notification{Type: NotificationTypeNtfy, ProviderConfig: &NotificationProviderConfig{Topic: "a", Priority: 6}}, false},
+		{"gotify", Notification{Type: NotificationTypeGotify, URL: "https://gotify.example.com", ProviderConfig: &NotificationProviderConfig{Token: "t"}}, true},
+		{"gotify without server", Notification{Type: NotificationTypeGotify, ProviderConfig: &NotificationProviderConfig{Token: "t"}}, false},
+		{"bark", Notification{Type: NotificationTypeBark, ProviderConfig: &NotificationProviderConfig{DeviceKey: "k"}}, true},
+		{"bark without key", Notification{Type: NotificationTypeBark, ProviderConfig: &NotificationProviderConfig{}}, false},
+		{"smtp", Notification{Type: NotificationTypeSMTP, ProviderConfig: &NotificationProviderConfig{Host: "smtp.example.com", Port: 587, Username: "u", From: "a@example.com", To: []string{"b@example.com"}}}, true},
+		{"smtp bad recipient", Notification{Type: NotificationTypeSMTP, ProviderConfig: &NotificationProviderConfig{Host: "smtp.example.com", Port: 587, From: "a@example.com", To: []string{"nobody"}}}, false},
+		{"smtp no recipient", Notification{Type: NotificationTypeSMTP, ProviderConfig: &NotificationProviderConfig{Host: "smtp.example.com", Port: 587, From: "a@example.com"}}, false},
+		{"smtp auth without tls", Notification{Type: NotificationTypeSMTP, ProviderConfig: &NotificationProviderConfig{Host: "smtp.example.com", Port: 25, Security: SMTPSecurityNone, Username: "u", From: "a@example.com", To: []string{"b@example.com"}}}, false},
+		{"smtp auth without verification", Notification{Type: NotificationTypeSMTP, VerifyTLS: &skipVerify, ProviderConfig: &NotificationProviderConfig{Host: "smtp.example.com", Port: 587, Username: "u", From: "a@example.com", To: []string{"b@example.com"}}}, false},
+		{"smtp without verification", Notification{Type: NotificationTypeSMTP, VerifyTLS: &skipVerify, ProviderConfig: &NotificationProviderConfig{Host: "smtp.example.com", Port: 587, From: "a@example.com", To: []string{"b@example.com"}}}, true},
+	}
+
+	for _, c := range cases {
+		err := c.n.Validate()
+		if (err == nil) != c.ok {
+			t.Errorf("%s: Validate() = %v, want ok=%v", c.name, err, c.ok)
+		}
+	}
+}
+
+func TestNotificationProviderConfigPersist(t *testing.T) {
+	n := &Notification{Type: NotificationTypeBark, ProviderConfig: &NotificationProviderConfig{DeviceKey: "k", Sound: "alarm"}}
+	if err := n.BeforeSave(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded Notification
+	loaded.ProviderConfigRaw = n.ProviderConfigRaw
+	if err := loaded.AfterFind(nil); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.ProviderConfig == nil || loaded.ProviderConfig.DeviceKey != "k" || loaded.ProviderConfig.Sound != "alarm" {
+		t.Fatalf("unexpected config after round trip: %+v", loaded.ProviderConfig)
+	}
+
+	loaded.ProviderConfig.Redact()
+	if loaded.ProviderConfig.DeviceKey != "" || loaded.ProviderConfig.Sound != "alarm" {
+		t.Fatalf("Redact should only clear credentials: %+v", loaded.ProviderConfig)
+	}
+	loaded.ProviderConfig.KeepSecrets(n.ProviderConfig)
+	if loaded.ProviderConfig.DeviceKey != "k" {
+		t.Fatalf("KeepSecrets should restore empty credentials: %+v", loaded.ProviderConfig)
+	}
+}
+
+func TestNotificationTest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, strings.Repeat("x", notificationResponseMaxLength+100))
+	}))
+	defer srv.Close()
+	old := newNotificationHTTPClient
+	newNotificationHTTPClient = func(string, bool) (*http.Client, error) { return srv.Client(), nil }
+	defer func() { newNotificationHTTPClient = old }()
+
+	n := &Notification{
+		URL:           srv.URL + "/hook?m=#NEZHA#",
+		RequestMethod: NotificationRequestMethodPOST,
+		RequestType:   NotificationRequestTypeJSON,
+		RequestHeader: `{"Authorization":"Bearer secret"}`,
+		RequestBody:   `{"text":"#NEZHA#"}`,
+	}
+	ns := NotificationServerBundle{Notification: n, Loc: time.UTC}
+
+	dry := ns.Test("hi", true)
+	if dry.Sent || dry.Error != "" || dry.Request == nil {
+		t.Fatalf("unexpected dry run result: %+v", dry)
+	}
+	if dry.Request.URL != srv.URL+"/hook?m=hi" || dry.Request.Body != `{"text":"hi"}` || dry.Request.Header.Get("Authorization") != "Bearer secret" {
+		t.Fatalf("unexpected rendered request: %+v", dry.Request)
+	}
+
+	res := ns.Test("hi", false)
+	if !res.Sent || res.StatusCode != http.StatusBadRequest || res.Error == "" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if len(res.Response) != notificationResponseMaxLength {
+		t.Fatalf("response should be truncated to %d bytes, got %d", notificationResponseMaxLength, len(res.Response))
+	}
+
+	res.Request.Redact(n)
+	if res.Request.Header.Get("Authorization") != "******" || res.Request.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected redacted header: %v", res.Request.Header)
+	}
+
+	bot := &Notification{Type: NotificationTypeTelegram, ProviderConfig: &NotificationProviderConfig{BotToken: "123:abc", ChatID: "1"}}
+	req, err := (&NotificationServerBundle{Notification: bot}).BuildRequest("hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Redact(bot)
+	if strings.Contains(req.URL, "123:abc") {
+		t.Fatalf("bot token should be masked: %s", req.URL)
+	}
+}
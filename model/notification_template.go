@@ -0,0 +1,374 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/nezhahq/nezha/pkg/utils"
+)
+
+const (
+	NotificationTemplatePlaceholder = iota // #SERVER.CPU# 风格的占位符替换（默认）
+	NotificationTemplateGo                 // Go text/template
+)
+
+// NotificationTemplateMaxLength 单个模板的长度上限
+const NotificationTemplateMaxLength = 16 * 1024
+
+// notificationTemplateOutputMaxLength 渲染结果的长度上限，超出时中止渲染
+const notificationTemplateOutputMaxLength = 64 * 1024
+
+// NotificationEvent.Status 的取值，模板中可用于条件判断
+const (
+	NotificationEventIncident      = "incident"         // 报警规则触发
+	NotificationEventResolved      = "resolved"         // 报警规则恢复
+	NotificationEventServiceDown   = "down"             // 服务监控故障
+	NotificationEventServiceLow    = "low_availability" // 服务监控可用率低
+	NotificationEventServiceGood   = "good"             // 服务监控恢复正常
+	NotificationEventServiceNoData = "no_data"          // 服务监控没有数据
+)
+
+// NotificationEvent 触发通知的事件，模板中通过 .Event 访问，与通知内容一同保存以便重试时重新渲染
+type NotificationEvent struct {
+	Status      string            `json:"status,omitempty"`        // 事件状态，见 NotificationEventIncident 等常量
+	AlertRuleID uint64            `json:"alert_rule_id,omitempty"` // 报警规则事件
+	AlertName   string            `json:"alert_name,omitempty"`
+	Rule        *NotificationRule `json:"rule,omitempty"`       // 未通过检查的第一条规则，恢复通知中为空
+	Value       *float64          `json:"value,omitempty"`      // 未通过规则最近一次的指标值，表达式与离线规则没有数值
+	StartedAt   *time.Time        `json:"started_at,omitempty"` // 报警事件开始时间
+	ServiceID   uint64            `json:"service_id,omitempty"` // 服务监控事件
+	ServiceName string            `json:"service_name,omitempty"`
+	Reporter    string            `json:"reporter,omitempty"` // 上报服务监控结果的服务器
+	Detail      string            `json:"detail,omitempty"`   // 服务监控的错误信息
+	IncidentID  uint64            `json:"incident_id,omitempty"`
+	IncidentURL string            `json:"incident_url,omitempty"` // 面板中报警事件的地址，未配置面板地址时为空
+}
+
+// NotificationRule 报警规则的只读摘要
+type NotificationRule struct {
+	Type        string  `json:"type"`
+	Min         float64 `json:"min,omitempty"`
+	Max         float64 `json:"max,omitempty"`
+	Duration    uint64  `json:"duration,omitempty"`
+	Expression  string  `json:"expression,omitempty"`
+	Aggregation string  `json:"aggregation,omitempty"`
+	Window      uint64  `json:"window,omitempty"`
+}
+
+func NewNotificationRule(r *Rule) *NotificationRule {
+	return &NotificationRule{
+		Type:        r.Type,
+		Min:         r.Min,
+		Max:         r.Max,
+		Duration:    r.Duration,
+		Expression:  r.Expression,
+		Aggregation: r.Aggregation,
+		Window:      r.Window,
+	}
+}
+
+// NotificationTemplateData 模板的数据模型：
+//
+//	.Message   通知内容，与 #NEZHA# 相同
+//	.Time      发送时间（面板时区）
+//	.Duration  报警持续时间，事件没有开始时间时为 0
+//	.Event     触发通知的事件，测试消息等没有事件时为 nil
+//	.Server    相关服务器，没有服务器时为 nil；.Server.Host 与 .Server.State 在服务器未上报时为 nil
+type NotificationTemplateData struct {
+	Message  string
+	Time     time.Time
+	Duration time.Duration
+	Event    *NotificationEvent
+	Server   *NotificationTemplateServer
+}
+
+type NotificationTemplateServer struct {
+	ID         uint64
+	Name       string
+	IP         string // IPv4 优先
+	IPv4       string
+	IPv6       string
+	LastActive time.Time
+	Host       *Host
+	State      *HostState
+}
+
+// notificationTemplateFuncs 模板可用的函数，另有 text/template 内置的 printf、urlquery、len、index 等
+var notificationTemplateFuncs = template.FuncMap{
+	// bytes 1536 => "1.5 kB"
+	"bytes": func(v any) string { return utils.Bytes(uint64(toFloat(v))) },
+	// percent used total => 0-100 的百分比，total 为 0 时返回 0
+	"percent": func(used, total any) float64 {
+		t := toFloat(total)
+		if t == 0 {
+			return 0
+		}
+		return toFloat(used) / t * 100
+	},
+	// round v 2 => 保留 2 位小数
+	"round": func(v any, places int) float64 {
+		p := math.Pow10(places)
+		return math.Round(toFloat(v)*p) / p
+	},
+	"duration": func(d time.Duration) string { return d.Round(time.Second).String() },
+	// seconds 90 => "1m30s"
+	"seconds": func(v any) string { return (time.Duration(toFloat(v)) * time.Second).String() },
+	// formatTime .Time "2006-01-02 15:04:05"
+	"formatTime": func(t time.Time, layout string) string { return t.Format(layout) },
+	// json 将值编码为 JSON，用于拼接 JSON 请求体，字符串会带上引号
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"trim":     strings.TrimSpace,
+	"join":     strings.Join,
+	"contains": strings.Contains,
+	"truncate": func(n int, s string) string {
+		if n < 1 || len([]rune(s)) <= n {
+			return s
+		}
+		return string([]rune(s)[:n])
+	},
+	// default "N/A" .Event.AlertName => 值为空时使用默认值
+	"default": func(def, v any) any {
+		if v == nil || reflect.ValueOf(v).IsZero() {
+			return def
+		}
+		return v
+	},
+}
+
+func toFloat(v any) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case uint:
+		return float64(n)
+	case uint8:
+		return float64(n)
+	case uint32:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	case *float64:
+		if n != nil {
+			return *n
+		}
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	}
+	return 0
+}
+
+// notificationTemplateMaxSteps 单次渲染中 range 遍历的元素数与带参数的 {{template}} 调用次数之和的上限。
+// 嵌套的 range 与递归的 {{template}} 即使没有输出也会指数级增长，输出长度上限无法限制
+const notificationTemplateMaxSteps = 10000
+
+// 解析后插入到管道末尾的函数名，以下划线开头避免与模板函数冲突
+const (
+	templateRangeGuard = "_rangeable" // {{range X}} 改写为 {{range X | _rangeable}}
+	templateCallGuard  = "_step"      // {{template "t" X}} 改写为 {{template "t" X | _step}}
+)
+
+// templateBudget 记录一次渲染已经执行的步数
+type templateBudget struct {
+	steps int
+}
+
+func (b *templateBudget) spend(n int) error {
+	b.steps += n
+	if b.steps > notificationTemplateMaxSteps {
+		return fmt.Errorf("template exceeds %d iterations", notificationTemplateMaxSteps)
+	}
+	return nil
+}
+
+// rangeable 只允许 range 遍历切片、数组与 map 并计入元素数，range 整数或迭代函数可能产生几乎无法结束的循环
+func (b *templateBudget) rangeable(v any) (any, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return v, b.spend(1)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v, b.spend(1 + rv.Len())
+	}
+	return nil, fmt.Errorf("range over %T is not allowed", v)
+}
+
+func (b *templateBudget) step(v any) (any, error) {
+	return v, b.spend(1)
+}
+
+// appendGuard 在管道末尾追加对 name 的调用
+func appendGuard(tree *parse.Tree, pipe *parse.PipeNode, name string) {
+	guard := parse.NewIdentifier(name).SetTree(tree).SetPos(pipe.Pos)
+	pipe.Cmds = append(pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: pipe.Pos, Args: []parse.Node{guard}})
+}
+
+// guardTemplate 为 node 下每个 range 与带参数的 {{template}} 追加计数函数。
+// 不带参数的 {{template}} 每层的数据相同，递归时无法终止，会触发 text/template 的深度上限
+func guardTemplate(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			guardTemplate(tree, child)
+		}
+	case *parse.IfNode:
+		guardTemplate(tree, n.List)
+		guardTemplate(tree, n.ElseList)
+	case *parse.WithNode:
+		guardTemplate(tree, n.List)
+		guardTemplate(tree, n.ElseList)
+	case *parse.RangeNode:
+		appendGuard(tree, n.Pipe, templateRangeGuard)
+		guardTemplate(tree, n.List)
+		guardTemplate(tree, n.ElseList)
+	case *parse.TemplateNode:
+		if n.Pipe != nil {
+			appendGuard(tree, n.Pipe, templateCallGuard)
+		}
+	}
+}
+
+// ParseNotificationTemplate 解析模板，用于保存前校验。每次解析的模板有独立的步数预算
+func ParseNotificationTemplate(text string) (*template.Template, error) {
+	if len(text) > NotificationTemplateMaxLength {
+		return nil, fmt.Errorf("template is longer than %d bytes", NotificationTemplateMaxLength)
+	}
+	budget := &templateBudget{}
+	tmpl, err := template.New("notification").
+		Funcs(notificationTemplateFuncs).
+		Funcs(template.FuncMap{templateRangeGuard: budget.rangeable, templateCallGuard: budget.step}).
+		Option("missingkey=error").
+		Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			guardTemplate(t.Tree, t.Tree.Root)
+		}
+	}
+	return tmpl, nil
+}
+
+// limitedWriter 写入超过 limit 字节时返回错误，模板执行随之中止
+type limitedWriter struct {
+	strings.Builder
+	limit int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.Len()+len(p) > w.limit {
+		return 0, fmt.Errorf("rendered template is longer than %d bytes", w.limit)
+	}
+	return w.Builder.Write(p)
+}
+
+// RenderTemplate 以 Go 模板渲染 text，结果超过 64 KiB 时返回错误
+func (ns *NotificationServerBundle) RenderTemplate(text, message string) (string, error) {
+	tmpl, err := ParseNotificationTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	buf := limitedWriter{limit: notificationTemplateOutputMaxLength}
+	if err := tmpl.Execute(&buf, ns.templateData(message)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (ns *NotificationServerBundle) templateData(message string) *NotificationTemplateData {
+	now := time.Now()
+	if ns.Loc != nil {
+		now = now.In(ns.Loc)
+	}
+	data := &NotificationTemplateData{
+		Message: message,
+		Time:    now,
+		Event:   ns.Event,
+	}
+	if ns.Event != nil && ns.Event.StartedAt != nil {
+		data.Duration = now.Sub(*ns.Event.StartedAt)
+	}
+	if ns.Server != nil {
+		runtime := ns.Server.RuntimeSnapshot()
+		ip := ns.Server.GeoIP.IP
+		s := &NotificationTemplateServer{
+			ID:         ns.Server.ID,
+			Name:       ns.Server.Name,
+			IPv4:       ip.IPv4Addr,
+			IPv6:       ip.IPv6Addr,
+			IP:         ip.IPv4Addr,
+			LastActive: runtime.LastActive,
+			Host:       runtime.Host,
+			State:      runtime.State,
+		}
+		if s.IP == "" {
+			s.IP = ip.IPv6Addr
+		}
+		data.Server = s
+	}
+	return data
+}
+
+// render 按通知的模板模式渲染 URL、请求体等字段，mod 只用于占位符模式下转义替换值
+func (ns *NotificationServerBundle) render(str, message string, mod func(string) string) (string, error) {
+	if ns.Notification.TemplateMode == NotificationTemplateGo {
+		return ns.RenderTemplate(str, message)
+	}
+	return ns.replaceParamsInString(str, message, mod), nil
+}
+
+// validateTemplates 校验 Go 模板模式下所有会被渲染的字段
+func (n *Notification) validateTemplates() error {
+	switch n.TemplateMode {
+	case NotificationTemplatePlaceholder:
+		return nil
+	case NotificationTemplateGo:
+	default:
+		return fmt.Errorf("unknown template mode %d", n.TemplateMode)
+	}
+	fields := []string{n.RequestBody}
+	if n.Type == NotificationTypeWebhook {
+		fields = append(fields, n.URL)
+		if n.RequestType == NotificationRequestTypeForm && n.RequestBody != "" {
+			data, err := utils.GjsonIter(n.RequestBody)
+			if err != nil {
+				return err
+			}
+			fields = fields[1:]
+			for _, v := range data {
+				fields = append(fields, v)
+			}
+		}
+	}
+	for _, f := range fields {
+		if _, err := ParseNotificationTemplate(f); err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+	}
+	return nil
+}
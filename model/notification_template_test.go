@@ -0,0 +1,225 @@
+package model
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+func templateTestServer() *Server {
+	return &Server{
+		Common: Common{ID: 7},
+		Name:   "web-1",
+		Host: &Host{
+			MemTotal: 8 * 1024 * 1024 * 1024,
+			GPU:      []string{"RTX 4090", "RTX 4080"},
+		},
+		State: &HostState{
+			CPU:     87.456,
+			MemUsed: 2 * 1024 * 1024 * 1024,
+			Temperatures: []SensorTemperature{
+				{Name: "cpu", Temperature: 71.2},
+				{Name: "nvme", Temperature: 45},
+			},
+			GPU: []float64{12.5, 99},
+		},
+		GeoIP: &GeoIP{IP: IP{IPv4Addr: "1.1.1.1"}},
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	startedAt := time.Now().Add(-90 * time.Second)
+	value := 93.5
+	ns := NotificationServerBundle{
+		Notification: &Notification{TemplateMode: NotificationTemplateGo},
+		Server:       templateTestServer(),
+		Loc:          time.UTC,
+		Event: &NotificationEvent{
+			Status:      NotificationEventIncident,
+			AlertName:   "CPU usage",
+			Rule:        &NotificationRule{Type: "cpu", Max: 80},
+			Value:       &value,
+			StartedAt:   &startedAt,
+			IncidentURL: "https://nezha.example.com/dashboard/incident/3",
+		},
+	}
+
+	cases := []struct {
+		tmpl   string
+		expect string
+	}{
+		{`{{.Message}}`, "msg"},
+		{`{{.Server.Name}}({{.Server.IP}}) #{{.Server.ID}}`, "web-1(1.1.1.1) #7"},
+		{`{{if eq .Event.Status "incident"}}FIRING{{else}}OK{{end}}`, "FIRING"},
+		{`{{.Event.AlertName}} {{.Event.Rule.Type}} > {{.Event.Rule.Max}}: {{round .Event.Value 0}}`, "CPU usage cpu > 80: 94"},
+		{`{{round .Server.State.CPU 1}}%`, "87.5%"},
+		{`{{printf "%.1f" (percent .Server.State.MemUsed .Server.Host.MemTotal)}}%`, "25.0%"},
+		{`{{bytes .Server.Host.MemTotal}}`, "8.0 GB"},
+		{`{{range .Server.State.Temperatures}}{{.Name}}={{.Temperature}};{{end}}`, "cpu=71.2;nvme=45;"},
+		{`{{range $i, $u := .Server.State.GPU}}{{index $.Server.Host.GPU $i}}:{{$u}} {{end}}`, "RTX 4090:12.5 RTX 4080:99 "},
+		{`{{duration .Duration}}`, "1m30s"},
+		{`{{seconds 90}}`, "1m30s"},
+		{`{{default "-" .Event.ServiceName}}`, "-"},
+		{`{{json .Message}}`, `"msg"`},
+		{`{{.Event.IncidentURL}}`, "https://nezha.example.com/dashboard/incident/3"},
+		{`{{upper .Server.Name | truncate 3}}`, "WEB"},
+	}
+	for _, c := range cases {
+		got, err := ns.RenderTemplate(c.tmpl, msg)
+		if err != nil {
+			t.Errorf("%s: %v", c.tmpl, err)
+			continue
+		}
+		if got != c.expect {
+			t.Errorf("%s: expected %q, got %q", c.tmpl, c.expect, got)
+		}
+	}
+
+	// 没有服务器时访问 .Server 的字段会报错，应使用 with 判断
+	ns.Server = nil
+	if _, err := ns.RenderTemplate(`{{.Server.Name}}`, msg); err == nil {
+		t.Fatal("expected error when accessing a nil server")
+	}
+	got, err := ns.RenderTemplate(`{{with .Server}}{{.Name}}{{else}}no server{{end}}`, msg)
+	if err != nil || got != "no server" {
+		t.Fatalf("expected %q, got %q (%v)", "no server", got, err)
+	}
+}
+
+func TestRenderTemplateLimits(t *testing.T) {
+	ns := NotificationServerBundle{
+		Notification: &Notification{TemplateMode: NotificationTemplateGo},
+		Server:       templateTestServer(),
+	}
+
+	// 输出超过上限时中止渲染
+	if _, err := ns.RenderTemplate(`{{range .Server.Host.GPU}}{{printf "%70000s" .}}{{end}}`, msg); err == nil || !strings.Contains(err.Error(), "longer than") {
+		t.Fatalf("expected output limit error, got %v", err)
+	}
+	got, err := ns.RenderTemplate(`{{printf "%65536s" "x"}}`, msg)
+	if err != nil || len(got) != notificationTemplateOutputMaxLength {
+		t.Fatalf("expected output at the limit to render, got %d bytes (%v)", len(got), err)
+	}
+
+	// range 整数即使没有输出也会空转，直接拒绝
+	for _, tmpl := range []string{
+		`{{range 1000000000000}}{{end}}`,
+		`{{range $i := .Server.Host.MemTotal}}{{end}}`,
+		`{{define "t"}}{{range .}}{{end}}{{end}}{{template "t" 1000000000000}}`,
+		`{{if true}}{{with .Server}}{{range .ID}}{{end}}{{end}}{{end}}`,
+	} {
+		if _, err := ns.RenderTemplate(tmpl, msg); err == nil || !strings.Contains(err.Error(), "not allowed") {
+			t.Errorf("%s: expected range over integer to be rejected, got %v", tmpl, err)
+		}
+	}
+	// 嵌套 range 与递归模板没有输出时同样受步数限制
+	nested := strings.Repeat(`{{range $.Server.Host.GPU}}`, 40) + strings.Repeat(`{{end}}`, 40)
+	recursive := `{{define "t"}}{{if .}}{{template "t" (slice . 1)}}{{template "t" (slice . 1)}}{{end}}{{end}}{{template "t" .Message}}`
+	for _, tmpl := range []string{nested, recursive} {
+		start := time.Now()
+		_, err := ns.RenderTemplate(tmpl, strings.Repeat("x", 64))
+		if err == nil || !strings.Contains(err.Error(), "iterations") {
+			t.Errorf("expected iteration limit error, got %v", err)
+		}
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("render took %v", d)
+		}
+	}
+	if _, err := ns.RenderTemplate(`{{define "t"}}{{.}}{{end}}{{range .Server.Host.GPU}}{{template "t" .}}{{end}}`, msg); err != nil {
+		t.Fatalf("expected template calls within the budget to render: %v", err)
+	}
+
+	got, err = ns.RenderTemplate(`{{range $i, $g := .Server.Host.GPU}}{{$i}}{{end}}{{range .Server.State.Temperatures}}{{else}}none{{end}}`, msg)
+	if err != nil || got != "01" {
+		t.Fatalf("expected slice ranges to render, got %q (%v)", got, err)
+	}
+}
+
+func TestTemplateModeWebhook(t *testing.T) {
+	var body, query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		query = r.URL.RawQuery
+	}))
+	defer srv.Close()
+	old := newNotificationHTTPClient
+	newNotificationHTTPClient = func(string, bool) (*http.Client, error) { return srv.Client(), nil }
+	defer func() { newNotificationHTTPClient = old }()
+
+	n := &Notification{
+		TemplateMode:  NotificationTemplateGo,
+		URL:           srv.URL + "/hook?server={{urlquery .Server.Name}}",
+		RequestMethod: NotificationRequestMethodPOST,
+		RequestType:   NotificationRequestTypeJSON,
+		RequestBody:   `{"text": {{json .Message}}, "temps": [{{range $i, $t := .Server.State.Temperatures}}{{if $i}},{{end}}{{$t.Temperature}}{{end}}]}`,
+	}
+	if err := n.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	ns := NotificationServerBundle{Notification: n, Server: templateTestServer(), Loc: time.UTC}
+	if _, err := ns.Deliver("line1\n\"quoted\""); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	if query != "server=web-1" {
+		t.Errorf("unexpected query %q", query)
+	}
+	var decoded struct {
+		Text  string    `json:"text"`
+		Temps []float64 `json:"temps"`
+	}
+	if err := json.Unmarshal([]byte(body), &decoded); err != nil {
+		t.Fatalf("body is not valid JSON: %v\n%s", err, body)
+	}
+	if decoded.Text != "line1\n\"quoted\"" || len(decoded.Temps) != 2 {
+		t.Errorf("unexpected body %s", body)
+	}
+}
+
+func TestTemplateModeProvider(t *testing.T) {
+	_, reqs := withProviderServer(t, http.StatusOK)
+	n := &Notification{
+		Type:           NotificationTypeSlack,
+		TemplateMode:   NotificationTemplateGo,
+		URL:            "http://hooks.example.com/x",
+		ProviderConfig: &NotificationProviderConfig{},
+		RequestBody:    `*{{.Event.AlertName}}* on {{.Server.Name}}`,
+	}
+	ns := NotificationServerBundle{
+		Notification: n,
+		Server:       templateTestServer(),
+		Event:        &NotificationEvent{AlertName: "Disk"},
+	}
+	if _, err := ns.Deliver(msg); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if text := (<-reqs).body["text"]; text != "*Disk* on web-1" {
+		t.Fatalf("unexpected text %v", text)
+	}
+}
+
+func TestTemplateValidate(t *testing.T) {
+	n := &Notification{TemplateMode: NotificationTemplateGo, URL: "https://example.com/{{.Message", RequestType: NotificationRequestTypeJSON}
+	if err := n.Validate(); err == nil {
+		t.Fatal("expected parse error for URL template")
+	}
+	n = &Notification{TemplateMode: NotificationTemplateGo, RequestType: NotificationRequestTypeForm, RequestBody: `{"a": "{{nosuchfunc}}"}`}
+	if err := n.Validate(); err == nil || !strings.Contains(err.Error(), "nosuchfunc") {
+		t.Fatalf("expected undefined function error, got %v", err)
+	}
+	n = &Notification{TemplateMode: 9}
+	if err := n.Validate(); err == nil {
+		t.Fatal("expected error for unknown template mode")
+	}
+	// 占位符模式下不解析 Go 模板语法
+	n = &Notification{URL: "https://example.com/{{"}
+	if err := n.Validate(); err != nil {
+		t.Fatalf("placeholder mode should not parse templates: %v", err)
+	}
+}
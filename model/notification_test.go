@@ -88,8 +88,12 @@ func execCase(t *testing.T, item testSt) {
 		Server:       &server,
 		Loc:          time.Local,
 	}
-	if item.expectURL != ns.reqURL(msg) {
-		t.Fatalf("Expected %s, but got %s", item.expectURL, ns.reqURL(msg))
+	reqURL, err := ns.reqURL(msg)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if item.expectURL != reqURL {
+		t.Fatalf("Expected %s, but got %s", item.expectURL, reqURL)
 	}
 	reqBody, err := ns.reqBody(msg)
 	if err != nil {
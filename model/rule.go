@@ -1,12 +1,14 @@
 package model
 
 import (
+	"errors"
 	"slices"
 	"strings"
 	"time"
 
 	"gorm.io/gorm"
 
+	"github.com/nezhahq/nezha/pkg/ruleexpr"
 	"github.com/nezhahq/nezha/pkg/utils"
 )
 
@@ -15,6 +17,9 @@ const (
 	RuleCoverIgnoreAll
 )
 
+// RuleDeviceTypes 针对单个挂载点或网卡的规则类型，需要设置 Target
+var RuleDeviceTypes = []string{"disk_mount", "nic_in_speed", "nic_out_speed", "nic_all_speed"}
+
 type NResult struct {
 	N uint64
 }
@@ -23,6 +28,8 @@ type Rule struct {
 	// 指标类型，cpu、memory、swap、disk、net_in_speed、net_out_speed
 	// net_all_speed、transfer_in、transfer_out、transfer_all、offline
 	// transfer_in_cycle、transfer_out_cycle、transfer_all_cycle
+	// disk_mount、nic_in_speed、nic_out_speed、nic_all_speed（针对 Target 指定的挂载点或网卡）
+	// expression（使用 Expression 描述报警条件）
 	Type          string          `json:"type"`
 	Min           float64         `json:"min,omitempty" validate:"optional"`                                                        // 最小阈值 (百分比、字节 kb ÷ 1024)
 	Max           float64         `json:"max,omitempty" validate:"optional"`                                                        // 最大阈值 (百分比、字节 kb ÷ 1024)
@@ -32,10 +39,18 @@ type Rule struct {
 	Duration      uint64          `json:"duration,omitempty" validate:"optional"`                                                   // 持续时间 (秒)
 	Cover         uint64          `json:"cover"`                                                                                    // 覆盖范围 RuleCoverAll/IgnoreAll
 	Ignore        map[uint64]bool `json:"ignore,omitempty" validate:"optional"`                                                     // 覆盖范围的排除
+	ServerGroups  []uint64        `json:"server_groups,omitempty" validate:"optional"`                                              // 与 Ignore 语义相同的服务器分组，检查时按当前成员展开
+	Expression    string          `json:"expression,omitempty" validate:"optional"`                                                 // 报警条件表达式，仅 expression 类型使用，为真时视为未通过
+	Aggregation   string          `json:"aggregation,omitempty" validate:"optional"`                                                // 历史窗口聚合方式 avg、min、max、last、p50、p90、p95、p99、increase、rate，非空时按 TSDB 历史聚合值与阈值比较，窗口内没有数据时视为通过
+	Window        uint64          `json:"window,omitempty" validate:"optional"`                                                     // 历史聚合窗口 (秒)，仅 Aggregation 非空时使用
+	Target        string          `json:"target,omitempty" validate:"optional"`                                                     // 磁盘挂载点或网卡名，仅 disk_mount、nic_* 类型使用
 
 	// 只作为缓存使用，记录下次该检测的时间
 	NextTransferAt  map[uint64]time.Time `json:"-"`
 	LastCycleStatus map[uint64]bool      `json:"-"`
+
+	program   *ruleexpr.Program  // Expression 的编译缓存
+	lastValue map[uint64]float64 // 各服务器最近一次检查得到的指标值，仅由报警检查协程读写
 }
 
 func percentage(used, total uint64) float64 {
@@ -45,14 +60,30 @@ func percentage(used, total uint64) float64 {
 	return float64(used) * 100 / float64(total)
 }
 
+// Listed 服务器是否被 Ignore 或 ServerGroups 列出
+func (u *Rule) Listed(serverID uint64) bool {
+	return u.Ignore[serverID] || serverInGroups(serverID, u.ServerGroups)
+}
+
+// ListedServerIDs 返回 Ignore 中标记的服务器与 ServerGroups 当前成员的并集
+func (u *Rule) ListedServerIDs() []uint64 {
+	ids := make([]uint64, 0, len(u.Ignore))
+	for id, listed := range u.Ignore {
+		if listed {
+			ids = append(ids, id)
+		}
+	}
+	return ExpandServerGroups(ids, u.ServerGroups)
+}
+
 // Snapshot 未通过规则返回 false, 通过返回 true
 func (u *Rule) Snapshot(cycleTransferStats *CycleTransferStats, server *Server, db *gorm.DB) bool {
 	// 监控全部但是排除了此服务器
-	if u.Cover == RuleCoverAll && u.Ignore[server.ID] {
+	if u.Cover == RuleCoverAll && u.Listed(server.ID) {
 		return true
 	}
 	// 忽略全部但是指定监控了此服务器
-	if u.Cover == RuleCoverIgnoreAll && !u.Ignore[server.ID] {
+	if u.Cover == RuleCoverIgnoreAll && !u.Listed(server.ID) {
 		return true
 	}
 
@@ -68,6 +99,21 @@ func (u *Rule) Snapshot(cycleTransferStats *CycleTransferStats, server *Server,
 	}
 	state := runtime.State
 
+	if u.IsExpressionRule() {
+		return u.snapshotExpression(runtime)
+	}
+
+	// 历史窗口聚合 · TSDB 不可用、查询失败或窗口内没有数据时视为通过，
+	// 不能回退到即时采样，否则单次尖峰就会触发本应平滑的规则
+	if u.IsHistoryRule() {
+		src, ok := u.historyValue(server.ID, runtime)
+		if !ok {
+			return true
+		}
+		u.setLastValue(server.ID, src)
+		return !((u.Max > 0 && src > u.Max) || (u.Min > 0 && src < u.Min))
+	}
+
 	switch u.Type {
 	case "cpu":
 		src = float64(state.CPU)
@@ -139,6 +185,27 @@ func (u *Rule) Snapshot(cycleTransferStats *CycleTransferStats, server *Server,
 		src = float64(state.UdpConnCount)
 	case "process_count":
 		src = float64(state.ProcessCount)
+	case "disk_mount":
+		// 旧版 agent 不上报设备列表，挂载点也可能被卸载或改名，
+		// 找不到目标时无法判断阈值，视为通过以免对所有服务器误报
+		disk, ok := state.Disk(u.Target)
+		if !ok {
+			return true
+		}
+		src = percentage(disk.Used, disk.Total)
+	case "nic_in_speed", "nic_out_speed", "nic_all_speed":
+		nic, ok := state.NetInterface(u.Target)
+		if !ok {
+			return true
+		}
+		switch u.Type {
+		case "nic_in_speed":
+			src = float64(nic.InSpeed)
+		case "nic_out_speed":
+			src = float64(nic.OutSpeed)
+		default:
+			src = float64(nic.InSpeed + nic.OutSpeed)
+		}
 	case "temperature_max":
 		var temp []float64
 		if state.Temperatures != nil {
@@ -176,6 +243,10 @@ func (u *Rule) Snapshot(cycleTransferStats *CycleTransferStats, server *Server,
 		cycleTransferStats.To = u.GetTransferDurationEnd()
 	}
 
+	if !u.IsOfflineRule() {
+		u.setLastValue(server.ID, src)
+	}
+
 	if u.Type == "offline" && float64(time.Now().Unix())-src > 6 {
 		return false
 	} else if (u.Max > 0 && src > u.Max) || (u.Min > 0 && src < u.Min) {
@@ -185,11 +256,43 @@ func (u *Rule) Snapshot(cycleTransferStats *CycleTransferStats, server *Server,
 	return true
 }
 
+func (u *Rule) setLastValue(serverID uint64, value float64) {
+	if u.lastValue == nil {
+		u.lastValue = make(map[uint64]float64)
+	}
+	u.lastValue[serverID] = value
+}
+
+// LastValue 返回该规则最近一次对 serverID 检查时得到的指标值
+func (u *Rule) LastValue(serverID uint64) (float64, bool) {
+	v, ok := u.lastValue[serverID]
+	return v, ok
+}
+
 // IsTransferDurationRule 判断该规则是否属于周期流量规则 属于则返回true
 func (u *Rule) IsTransferDurationRule() bool {
 	return strings.HasSuffix(u.Type, "_cycle")
 }
 
+// IsDeviceRule 判断该规则是否针对 Target 指定的单个挂载点或网卡
+func (u *Rule) IsDeviceRule() bool {
+	return slices.Contains(RuleDeviceTypes, u.Type)
+}
+
+// ValidateTarget 设备规则必须指定挂载点或网卡，其他规则不能设置 Target
+func (u *Rule) ValidateTarget() error {
+	if u.IsDeviceRule() {
+		if strings.TrimSpace(u.Target) == "" {
+			return errors.New("target is required")
+		}
+		return nil
+	}
+	if u.Target != "" {
+		return errors.New("target only applies to disk_mount and nic_* rules")
+	}
+	return nil
+}
+
 func (u *Rule) IsOfflineRule() bool {
 	return u.Type == "offline"
 }
@@ -0,0 +1,77 @@
+package model
+
+import "testing"
+
+func TestDeviceRuleSnapshot(t *testing.T) {
+	server := &Server{
+		Common: Common{ID: 1},
+		Host:   &Host{DiskTotal: 1000},
+		State: &HostState{
+			DiskUsed: 100,
+			Disks: []DiskState{
+				{Mountpoint: "/", Total: 1000, Used: 100},
+				{Mountpoint: "/data", Total: 1000, Used: 950},
+			},
+			NetInterfaces: []NetInterfaceState{
+				{Name: "eth0", InSpeed: 100, OutSpeed: 50},
+				{Name: "eth1", InSpeed: 2000, OutSpeed: 3000},
+			},
+		},
+	}
+
+	cases := []struct {
+		rule *Rule
+		pass bool
+	}{
+		{&Rule{Type: "disk_mount", Target: "/", Max: 90, Duration: 3}, true},
+		{&Rule{Type: "disk_mount", Target: "/data", Max: 90, Duration: 3}, false},
+		{&Rule{Type: "nic_in_speed", Target: "eth0", Max: 1000, Duration: 3}, true},
+		{&Rule{Type: "nic_out_speed", Target: "eth1", Max: 1000, Duration: 3}, false},
+		{&Rule{Type: "nic_all_speed", Target: "eth1", Min: 6000, Duration: 3}, false},
+		// 上报中没有对应的挂载点或网卡时无法判断，视为通过
+		{&Rule{Type: "disk_mount", Target: "/backup", Max: 90, Duration: 3}, true},
+		{&Rule{Type: "nic_in_speed", Target: "wlan0", Max: 1000, Duration: 3}, true},
+	}
+	for _, c := range cases {
+		assertEq(t, c.rule.Type+" "+c.rule.Target, c.pass, c.rule.Snapshot(nil, server, nil))
+	}
+
+	// 旧版 agent 不上报设备列表
+	legacy := &Server{Common: Common{ID: 2}, Host: &Host{}, State: &HostState{CPU: 10}}
+	for _, rule := range []*Rule{
+		{Type: "disk_mount", Target: "/", Max: 1, Duration: 3},
+		{Type: "nic_all_speed", Target: "eth0", Min: 1, Duration: 3},
+	} {
+		assertEq(t, "legacy "+rule.Type, true, rule.Snapshot(nil, legacy, nil))
+		_, ok := rule.LastValue(legacy.ID)
+		assertEq(t, "legacy LastValueOk "+rule.Type, false, ok)
+	}
+
+	v, ok := cases[1].rule.LastValue(server.ID)
+	assertEq(t, "LastValueOk", true, ok)
+	assertEq(t, "LastValue", float64(95), v)
+}
+
+func TestDeviceRuleValidateTarget(t *testing.T) {
+	valid := []*Rule{
+		{Type: "disk_mount", Target: "/data"},
+		{Type: "nic_all_speed", Target: "eth0"},
+		{Type: "cpu"},
+	}
+	for _, r := range valid {
+		if err := r.ValidateTarget(); err != nil {
+			t.Errorf("%s: %v", r.Type, err)
+		}
+	}
+
+	invalid := []*Rule{
+		{Type: "disk_mount"},
+		{Type: "nic_in_speed", Target: " "},
+		{Type: "cpu", Target: "eth0"},
+	}
+	for _, r := range invalid {
+		if err := r.ValidateTarget(); err == nil {
+			t.Errorf("%s/%q: expected error", r.Type, r.Target)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nezhahq/nezha/pkg/ruleexpr"
+)
+
+const RuleTypeExpression = "expression"
+
+// RuleExpressionVariables 表达式规则可引用的变量。百分比类变量与同名的固定
+// 规则类型含义一致（0~100），*_bytes / *_used / *_total 为原始字节数。
+var RuleExpressionVariables = []string{
+	"cpu", "gpu_max", "memory", "swap", "disk",
+	"mem_used", "mem_total", "mem_free_bytes",
+	"swap_used", "swap_total", "swap_free_bytes",
+	"disk_used", "disk_total", "disk_free_bytes",
+	"net_in_speed", "net_out_speed", "net_all_speed",
+	"transfer_in", "transfer_out", "transfer_all",
+	"load1", "load5", "load15", "cores",
+	"tcp_conn_count", "udp_conn_count", "process_count",
+	"temperature_max", "uptime",
+}
+
+// IsExpressionRule 判断该规则是否为表达式规则
+func (u *Rule) IsExpressionRule() bool {
+	return u.Type == RuleTypeExpression
+}
+
+// CompileExpression 编译并校验表达式，成功后缓存编译结果供 Snapshot 复用。
+func (u *Rule) CompileExpression() error {
+	prog, err := ruleexpr.Compile(u.Expression)
+	if err != nil {
+		return err
+	}
+	for _, v := range prog.Variables() {
+		if !slices.Contains(RuleExpressionVariables, v) {
+			return fmt.Errorf("ruleexpr: unknown variable %q", v)
+		}
+	}
+	u.program = prog
+	return nil
+}
+
+// snapshotExpression 表达式描述的是报警条件：表达式为真即视为未通过规则。
+// 表达式无法编译或求值（如缺少 Host 信息、除零）时与其它规则缺数据的处理一致，
+// 返回 false。
+func (u *Rule) snapshotExpression(runtime RuntimeSnapshot) bool {
+	if u.program == nil || u.program.Source() != strings.TrimSpace(u.Expression) {
+		if err := u.CompileExpression(); err != nil {
+			return false
+		}
+	}
+	firing, err := u.program.Eval(ruleExpressionEnv(runtime))
+	if err != nil {
+		return false
+	}
+	return !firing
+}
+
+// ruleExpressionEnv 由运行时快照构造表达式变量表；依赖 Host 的变量在 Host
+// 缺失时不提供，引用它们的表达式求值失败。
+func ruleExpressionEnv(runtime RuntimeSnapshot) ruleexpr.Env {
+	state := runtime.State
+	env := ruleexpr.Env{
+		"cpu":             state.CPU,
+		"mem_used":        float64(state.MemUsed),
+		"swap_used":       float64(state.SwapUsed),
+		"disk_used":       float64(state.DiskUsed),
+		"net_in_speed":    float64(state.NetInSpeed),
+		"net_out_speed":   float64(state.NetOutSpeed),
+		"net_all_speed":   float64(state.NetInSpeed + state.NetOutSpeed),
+		"transfer_in":     float64(state.NetInTransfer),
+		"transfer_out":    float64(state.NetOutTransfer),
+		"transfer_all":    float64(state.NetInTransfer + state.NetOutTransfer),
+		"load1":           state.Load1,
+		"load5":           state.Load5,
+		"load15":          state.Load15,
+		"tcp_conn_count":  float64(state.TcpConnCount),
+		"udp_conn_count":  float64(state.UdpConnCount),
+		"process_count":   float64(state.ProcessCount),
+		"uptime":          float64(state.Uptime),
+		"gpu_max":         0,
+		"temperature_max": 0,
+	}
+	if len(state.GPU) > 0 {
+		env["gpu_max"] = slices.Max(state.GPU)
+	}
+	for _, t := range state.Temperatures {
+		env["temperature_max"] = max(env["temperature_max"], t.Temperature)
+	}
+
+	if host := runtime.Host; host != nil {
+		env["mem_total"] = float64(host.MemTotal)
+		env["swap_total"] = float64(host.SwapTotal)
+		env["disk_total"] = float64(host.DiskTotal)
+		env["mem_free_bytes"] = float64(host.MemTotal) - float64(state.MemUsed)
+		env["swap_free_bytes"] = float64(host.SwapTotal) - float64(state.SwapUsed)
+		env["disk_free_bytes"] = float64(host.DiskTotal) - float64(state.DiskUsed)
+		env["memory"] = percentage(state.MemUsed, host.MemTotal)
+		env["swap"] = percentage(state.SwapUsed, host.SwapTotal)
+		env["disk"] = percentage(state.DiskUsed, host.DiskTotal)
+		env["cores"] = float64(hostCores(host))
+	}
+	return env
+}
+
+// hostCores 从 Agent 上报的 CPU 描述（形如 "<model> 8 Virtual Core"）中累加核心数，
+// 无法解析的条目按 1 个核心计。
+func hostCores(host *Host) int {
+	var cores int
+	for _, desc := range host.CPU {
+		fields := strings.Fields(desc)
+		if len(fields) >= 3 && fields[len(fields)-1] == "Core" {
+			if n, err := strconv.Atoi(fields[len(fields)-3]); err == nil && n > 0 {
+				cores += n
+				continue
+			}
+		}
+		cores++
+	}
+	return cores
+}
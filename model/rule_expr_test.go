@@ -0,0 +1,67 @@
+package model
+
+import "testing"
+
+func TestExpressionRuleSnapshot(t *testing.T) {
+	server := &Server{
+		Common: Common{ID: 1},
+		Host: &Host{
+			CPU:       []string{"AMD EPYC 7763 64-Core Processor 4 Virtual Core"},
+			MemTotal:  8 << 30,
+			DiskTotal: 100 << 30,
+		},
+		State: &HostState{
+			CPU:      95,
+			Load5:    9,
+			MemUsed:  2 << 30,
+			DiskUsed: 95 << 30,
+		},
+	}
+
+	cases := []struct {
+		expr   string
+		passed bool
+	}{
+		{"cpu > 90 && load5 > cores*2", false},
+		{"cpu > 90 && load5 > cores*3", true},
+		{"disk_free_bytes < 10GiB", false},
+		{"memory > 50", true},
+		{"temperature_max > 80 || gpu_max > 80", true},
+		{"cpu / uptime > 1", false}, // 求值失败按未通过处理
+	}
+	for _, c := range cases {
+		rule := &Rule{Type: RuleTypeExpression, Expression: c.expr, Duration: 3}
+		assertEq(t, c.expr, c.passed, rule.Snapshot(nil, server, nil))
+	}
+
+	// Host 缺失时依赖 Host 的变量不可用
+	noHost := &Server{Common: Common{ID: 2}, State: &HostState{CPU: 10}}
+	rule := &Rule{Type: RuleTypeExpression, Expression: "disk < 90", Duration: 3}
+	assertEq(t, "MissingHost", false, rule.Snapshot(nil, noHost, nil))
+	rule = &Rule{Type: RuleTypeExpression, Expression: "cpu > 90", Duration: 3}
+	assertEq(t, "StateOnly", true, rule.Snapshot(nil, noHost, nil))
+}
+
+func TestExpressionRuleCompile(t *testing.T) {
+	rule := &Rule{Type: RuleTypeExpression, Expression: "cpu > 90 && load5 > cores * 2"}
+	if err := rule.CompileExpression(); err != nil {
+		t.Fatalf("CompileExpression: %v", err)
+	}
+	for _, expr := range []string{"", "cpu", "secret > 1", "cpu > 90 &&"} {
+		rule := &Rule{Type: RuleTypeExpression, Expression: expr}
+		if err := rule.CompileExpression(); err == nil {
+			t.Errorf("CompileExpression(%q) should fail", expr)
+		}
+	}
+}
+
+func TestExpressionRuleCheck(t *testing.T) {
+	// 表达式规则沿用常规规则的 Duration + 70% 判定
+	rule := &AlertRule{Rules: []*Rule{{Type: RuleTypeExpression, Expression: "cpu > 90", Duration: 10}}}
+	d, passed := rule.Check(append(repeat([]bool{true}, 3), repeat([]bool{false}, 7)...))
+	assertEq(t, "SeventyPercent", 10, d)
+	assertEq(t, "SeventyPercent", true, passed)
+	_, passed = rule.Check(append(repeat([]bool{true}, 2), repeat([]bool{false}, 8)...))
+	assertEq(t, "EightyPercent", false, passed)
+	assertEq(t, "RetentionWindow", 10, rule.RetentionWindow())
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+const (
+	RuleHistoryMinWindow = 60                // 历史窗口下限 (秒)
+	RuleHistoryMaxWindow = 30 * 24 * 60 * 60 // 历史窗口上限 (秒)，与 TSDB 最长查询区间一致
+)
+
+// RuleHistoryAggregations 历史规则支持的聚合方式，与 tsdb.Aggregation 一一对应
+var RuleHistoryAggregations = []string{
+	"avg", "min", "max", "last", "p50", "p90", "p95", "p99", "increase", "rate",
+}
+
+// RuleHistoryTypes 可按 TSDB 历史聚合的规则类型
+var RuleHistoryTypes = []string{
+	"cpu", "gpu_max", "memory", "swap", "disk",
+	"net_in_speed", "net_out_speed", "net_all_speed",
+	"transfer_in", "transfer_out", "transfer_all",
+	"load1", "load5", "load15",
+	"tcp_conn_count", "udp_conn_count", "process_count", "temperature_max",
+	"disk_mount", "nic_in_speed", "nic_out_speed", "nic_all_speed",
+}
+
+// ServerMetricHistoryLookup 由 singleton 在启动时注入，返回 serverID 在最近
+// window 内某规则类型指标的聚合值（memory / swap / disk / disk_mount 为字节数），
+// target 为 disk_mount、nic_* 规则的挂载点或网卡名。
+// TSDB 未启用或窗口内数据不足时返回 ok=false。model 不能直接依赖 pkg/tsdb
+// （tsdb 引用了 model），测试 / 无头环境下保持 nil。
+var ServerMetricHistoryLookup func(serverID uint64, ruleType, target, aggregation string, window time.Duration) (value float64, ok bool)
+
+// IsHistoryRule 判断该规则是否基于 TSDB 历史窗口聚合
+func (u *Rule) IsHistoryRule() bool {
+	return u.Aggregation != ""
+}
+
+// IsCounterAggregation increase / rate 只对累积型指标有意义
+func (u *Rule) IsCounterAggregation() bool {
+	return u.Aggregation == "increase" || u.Aggregation == "rate"
+}
+
+// ValidateHistory 校验历史规则的聚合方式、窗口与规则类型
+func (u *Rule) ValidateHistory() error {
+	if !slices.Contains(RuleHistoryAggregations, u.Aggregation) {
+		return errors.New("unsupported aggregation")
+	}
+	if !slices.Contains(RuleHistoryTypes, u.Type) {
+		return errors.New("rule type does not support aggregation")
+	}
+	if u.IsCounterAggregation() && !slices.Contains([]string{"transfer_in", "transfer_out", "transfer_all"}, u.Type) {
+		return errors.New("increase and rate only apply to transfer rules")
+	}
+	if u.Window < RuleHistoryMinWindow || u.Window > RuleHistoryMaxWindow {
+		return errors.New("window out of range")
+	}
+	return nil
+}
+
+// historyValue 查询历史聚合值并换算成与即时规则相同的单位
+func (u *Rule) historyValue(serverID uint64, runtime RuntimeSnapshot) (float64, bool) {
+	if ServerMetricHistoryLookup == nil {
+		return 0, false
+	}
+	value, ok := ServerMetricHistoryLookup(serverID, u.Type, u.Target, u.Aggregation, time.Duration(u.Window)*time.Second)
+	if !ok {
+		return 0, false
+	}
+
+	if !slices.Contains([]string{"memory", "swap", "disk", "disk_mount"}, u.Type) {
+		return value, true
+	}
+	var total uint64
+	if u.Type == "disk_mount" {
+		// 挂载点容量取最近一次上报的值
+		disk, ok := runtime.State.Disk(u.Target)
+		if !ok {
+			return 0, false
+		}
+		total = disk.Total
+	} else if runtime.Host == nil {
+		return 0, false
+	}
+	switch u.Type {
+	case "memory":
+		total = runtime.Host.MemTotal
+	case "swap":
+		total = runtime.Host.SwapTotal
+	case "disk":
+		total = runtime.Host.DiskTotal
+	}
+	if total == 0 {
+		return 0, true
+	}
+	return value * 100 / float64(total), true
+}
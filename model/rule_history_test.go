@@ -0,0 +1,93 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistoryRuleSnapshot(t *testing.T) {
+	server := &Server{
+		Common: Common{ID: 1},
+		Host:   &Host{MemTotal: 1000},
+		State:  &HostState{CPU: 10, MemUsed: 100, Load1: 2, NetInTransfer: 100},
+	}
+
+	history := map[string]float64{"cpu": 85, "memory": 950, "transfer_out": 5 << 30}
+	var gotWindow time.Duration
+	ServerMetricHistoryLookup = func(serverID uint64, ruleType, target, aggregation string, window time.Duration) (float64, bool) {
+		gotWindow = window
+		v, ok := history[ruleType]
+		return v, ok
+	}
+	defer func() { ServerMetricHistoryLookup = nil }()
+
+	rule := &Rule{Type: "cpu", Max: 80, Aggregation: "avg", Window: 900, Duration: 3}
+	assertEq(t, "AvgCPU", false, rule.Snapshot(nil, server, nil))
+	assertEq(t, "Window", 15*time.Minute, gotWindow)
+
+	rule = &Rule{Type: "memory", Max: 90, Aggregation: "p95", Window: 3600, Duration: 3}
+	assertEq(t, "MemoryPercent", false, rule.Snapshot(nil, server, nil))
+
+	rule = &Rule{Type: "transfer_out", Max: 10 << 30, Aggregation: "increase", Window: 86400, Duration: 3}
+	assertEq(t, "TransferIncrease", true, rule.Snapshot(nil, server, nil))
+
+	// 无历史数据时视为通过，即使即时采样超过阈值也不触发
+	rule = &Rule{Type: "load1", Max: 1, Aggregation: "avg", Window: 900, Duration: 3}
+	assertEq(t, "NoHistory", true, rule.Snapshot(nil, server, nil))
+	_, ok := rule.LastValue(server.ID)
+	assertEq(t, "NoHistoryLastValue", false, ok)
+	rule = &Rule{Type: "transfer_in", Max: 1, Aggregation: "rate", Window: 900, Duration: 3}
+	assertEq(t, "NoHistoryCounter", true, rule.Snapshot(nil, server, nil))
+
+	ServerMetricHistoryLookup = nil
+	rule = &Rule{Type: "cpu", Max: 5, Aggregation: "avg", Window: 900, Duration: 3}
+	assertEq(t, "TSDBDisabled", true, rule.Snapshot(nil, server, nil))
+}
+
+func TestHistoryRuleDevice(t *testing.T) {
+	server := &Server{
+		Common: Common{ID: 1},
+		State:  &HostState{Disks: []DiskState{{Mountpoint: "/data", Total: 1000, Used: 100}}},
+	}
+	var gotTarget string
+	ServerMetricHistoryLookup = func(serverID uint64, ruleType, target, aggregation string, window time.Duration) (float64, bool) {
+		gotTarget = target
+		return 950, true
+	}
+	defer func() { ServerMetricHistoryLookup = nil }()
+
+	rule := &Rule{Type: "disk_mount", Target: "/data", Max: 90, Aggregation: "avg", Window: 900, Duration: 3}
+	assertEq(t, "MountPercent", false, rule.Snapshot(nil, server, nil))
+	assertEq(t, "Target", "/data", gotTarget)
+
+	// 挂载点不在最近一次上报中时无法换算百分比，视为通过
+	rule = &Rule{Type: "disk_mount", Target: "/missing", Max: 90, Aggregation: "avg", Window: 900, Duration: 3}
+	assertEq(t, "MissingMount", true, rule.Snapshot(nil, server, nil))
+}
+
+func TestHistoryRuleValidate(t *testing.T) {
+	valid := []*Rule{
+		{Type: "cpu", Aggregation: "avg", Window: 900},
+		{Type: "net_out_speed", Aggregation: "p95", Window: 3600},
+		{Type: "transfer_all", Aggregation: "increase", Window: 86400},
+	}
+	for _, r := range valid {
+		if err := r.ValidateHistory(); err != nil {
+			t.Errorf("%s/%s: %v", r.Type, r.Aggregation, err)
+		}
+	}
+
+	invalid := []*Rule{
+		{Type: "cpu", Aggregation: "median", Window: 900},
+		{Type: "offline", Aggregation: "avg", Window: 900},
+		{Type: "transfer_in_cycle", Aggregation: "avg", Window: 900},
+		{Type: "cpu", Aggregation: "rate", Window: 900},
+		{Type: "cpu", Aggregation: "avg", Window: 10},
+		{Type: "cpu", Aggregation: "avg", Window: RuleHistoryMaxWindow + 1},
+	}
+	for _, r := range invalid {
+		if err := r.ValidateHistory(); err == nil {
+			t.Errorf("%s/%s/%d should be rejected", r.Type, r.Aggregation, r.Window)
+		}
+	}
+}
@@ -425,6 +425,8 @@ func cloneHostState(state *HostState) *HostState {
 	clone := *state
 	clone.GPU = slices.Clone(state.GPU)
 	clone.Temperatures = slices.Clone(state.Temperatures)
+	clone.Disks = slices.Clone(state.Disks)
+	clone.NetInterfaces = slices.Clone(state.NetInterfaces)
 	return &clone
 }
 
@@ -12,6 +12,8 @@ type StreamServer struct {
 	State       *HostState `json:"state,omitempty"`
 	CountryCode string     `json:"country_code,omitempty"`
 	LastActive  time.Time  `json:"last_active,omitempty"`
+
+	Maintenance *MaintenanceStatus `json:"maintenance,omitempty"` // 处于维护窗口中时非空
 }
 
 type StreamServerData struct {
@@ -1,7 +1,32 @@
 package model
 
+import "slices"
+
 type ServerGroup struct {
 	Common
 
 	Name string `json:"name"`
 }
+
+// ServerGroupMembersLookup 返回若干服务器分组当前成员的并集，由 singleton 在启动时注入
+// （model 不能 import singleton）。报警规则、服务监控与计划任务引用的分组在检查 /
+// 派发时才展开，分组成员变化无需逐一修改这些配置。未注入时分组视为没有成员。
+var ServerGroupMembersLookup func(groupIDs []uint64) []uint64
+
+// ExpandServerGroups 返回 servers 与 groups 全部成员的并集（升序、去重）
+func ExpandServerGroups(servers []uint64, groups []uint64) []uint64 {
+	out := slices.Clone(servers)
+	if len(groups) > 0 && ServerGroupMembersLookup != nil {
+		out = append(out, ServerGroupMembersLookup(groups)...)
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// serverInGroups 判断服务器是否属于 groups 中任一分组
+func serverInGroups(serverID uint64, groups []uint64) bool {
+	if len(groups) == 0 || ServerGroupMembersLookup == nil {
+		return false
+	}
+	return slices.Contains(ServerGroupMembersLookup(groups), serverID)
+}
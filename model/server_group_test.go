@@ -0,0 +1,84 @@
+package model
+
+import (
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubServerGroups 安装一份固定的分组成员表：分组 5 = {2, 3}
+func stubServerGroups(t *testing.T) {
+	t.Helper()
+	saved := ServerGroupMembersLookup
+	t.Cleanup(func() { ServerGroupMembersLookup = saved })
+	ServerGroupMembersLookup = func(groupIDs []uint64) []uint64 {
+		if slices.Contains(groupIDs, 5) {
+			return []uint64{2, 3}
+		}
+		return nil
+	}
+}
+
+func TestExpandServerGroups(t *testing.T) {
+	saved := ServerGroupMembersLookup
+	ServerGroupMembersLookup = nil
+	assertEq(t, "NoLookup", true, slices.Equal([]uint64{1}, ExpandServerGroups([]uint64{1}, []uint64{5})))
+	ServerGroupMembersLookup = saved
+
+	stubServerGroups(t)
+	assertEq(t, "Union", true, slices.Equal([]uint64{1, 2, 3}, ExpandServerGroups([]uint64{3, 1}, []uint64{5})))
+	assertEq(t, "UnknownGroup", true, slices.Equal([]uint64{1}, ExpandServerGroups([]uint64{1}, []uint64{6})))
+
+	rule := Rule{Ignore: map[uint64]bool{1: true, 4: false}, ServerGroups: []uint64{5}}
+	assertEq(t, "RuleIgnore", true, rule.Listed(1))
+	assertEq(t, "RuleGroup", true, rule.Listed(3))
+	assertEq(t, "RuleIgnoreFalse", false, rule.Listed(4))
+	assertEq(t, "RuleIDs", true, slices.Equal([]uint64{1, 2, 3}, rule.ListedServerIDs()))
+
+	service := Service{SkipServers: map[uint64]bool{1: true}, SkipServerGroups: []uint64{5}}
+	assertEq(t, "ServiceGroup", true, service.Skips(2))
+	assertEq(t, "ServiceOther", false, service.Skips(4))
+	assertEq(t, "ServiceIDs", true, slices.Equal([]uint64{1, 2, 3}, service.SkipServerIDs()))
+
+	cron := Cron{Servers: []uint64{1}, ServerGroups: []uint64{5}}
+	assertEq(t, "CronGroup", true, cron.Lists(3))
+	assertEq(t, "CronOther", false, cron.Lists(4))
+	assertEq(t, "CronIDs", true, slices.Equal([]uint64{1, 2, 3}, cron.ServerIDs()))
+}
+
+// 分组在权限判定时按当前成员展开：分组内有白名单外的服务器时，allow-list 语义下
+// 受限 PAT 被拒绝；deny-list 语义下分组成员可以把白名单外的服务器排除掉
+func TestServerGroupTargetsHasPermission(t *testing.T) {
+	stubServerGroups(t)
+	saved := OwnerServerIDsLookup
+	savedAdmin := OwnerIsAdminLookup
+	t.Cleanup(func() {
+		OwnerServerIDsLookup = saved
+		OwnerIsAdminLookup = savedAdmin
+	})
+	OwnerServerIDsLookup = func(uid uint64) []uint64 { return []uint64{1, 2, 3} }
+	OwnerIsAdminLookup = func(uid uint64) bool { return false }
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Set(CtxKeyAuthorizedUser, &User{Common: Common{ID: 100}, Role: RoleMember})
+	ctx.Set(CtxKeyAPIToken, &stubPATAccessor{ids: []uint64{1}})
+
+	owner := Common{ID: 1, UserID: 100}
+	cases := []struct {
+		msg string
+		res interface{ HasPermission(*gin.Context) bool }
+		exp bool
+	}{
+		{"RuleAllowGroup", &AlertRule{Common: owner, Rules: []*Rule{{Cover: RuleCoverIgnoreAll, ServerGroups: []uint64{5}}}}, false},
+		{"RuleDenyGroup", &AlertRule{Common: owner, Rules: []*Rule{{Cover: RuleCoverAll, ServerGroups: []uint64{5}}}}, true},
+		{"ServiceAllowGroup", &Service{Common: owner, Cover: ServiceCoverIgnoreAll, SkipServerGroups: []uint64{5}}, false},
+		{"ServiceDenyGroup", &Service{Common: owner, Cover: ServiceCoverAll, SkipServerGroups: []uint64{5}}, true},
+		{"CronAllowGroup", &Cron{Common: owner, Cover: CronCoverIgnoreAll, ServerGroups: []uint64{5}}, false},
+		{"CronDenyGroup", &Cron{Common: owner, Cover: CronCoverAll, ServerGroups: []uint64{5}}, true},
+	}
+	for _, c := range cases {
+		assertEq(t, c.msg, c.exp, c.res.HasPermission(ctx))
+	}
+}
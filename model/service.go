@@ -3,6 +3,7 @@ package model
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/goccy/go-json"
@@ -37,6 +38,9 @@ const (
 	TaskTypeFsWrite
 	TaskTypeFsDelete
 	TaskTypeFsTransfer
+	TaskTypeDNS
+	// TaskTypeHeartbeat 心跳监控由被监控方主动请求面板，不会下发给 agent
+	TaskTypeHeartbeat
 )
 
 // IsServiceMonitorType reports whether t is a passive service probe. Service
@@ -45,7 +49,7 @@ const (
 // must use this allowlist instead of accepting an arbitrary task integer.
 func IsServiceMonitorType(t uint64) bool {
 	switch t {
-	case TaskTypeHTTPGet, TaskTypeICMPPing, TaskTypeTCPPing:
+	case TaskTypeHTTPGet, TaskTypeICMPPing, TaskTypeTCPPing, TaskTypeDNS, TaskTypeHeartbeat:
 		return true
 	default:
 		return false
@@ -58,7 +62,7 @@ func IsServiceMonitorType(t uint64) bool {
 // execution by copying Service.Type into pb.Task.Type.
 func ValidateServiceMonitorType(t uint64) error {
 	if !IsServiceMonitorType(t) {
-		return fmt.Errorf("invalid service monitor type %d: allowed types are 1 (HTTP GET), 2 (ICMP ping), and 3 (TCP ping)", t)
+		return fmt.Errorf("invalid service monitor type %d: allowed types are 1 (HTTP GET), 2 (ICMP ping), 3 (TCP ping), %d (DNS), and %d (heartbeat)", t, TaskTypeDNS, TaskTypeHeartbeat)
 	}
 	return nil
 }
@@ -222,6 +226,9 @@ type TaskFM struct {
 	StreamID string
 }
 
+// DashboardReporterID 面板自身产生的监控结果（心跳与面板探测）使用的监测点 ID
+const DashboardReporterID = 0
+
 const (
 	ServiceCoverAll = iota
 	ServiceCoverIgnoreAll
@@ -236,7 +243,8 @@ type Service struct {
 	Duration            uint64 `json:"duration"`
 	DisplayIndex        int    `json:"display_index"` // 展示排序，越大越靠前
 	Notify              bool   `json:"notify,omitempty"`
-	NotificationGroupID uint64 `json:"notification_group_id"` // 当前服务监控所属的通知组 ID
+	NotificationGroupID uint64 `json:"notification_group_id"`          // 当前服务监控所属的通知组 ID
+	EscalationPolicyID  uint64 `json:"escalation_policy_id,omitempty"` // 绑定的升级策略，非 0 时故障与恢复通知按策略发送
 	Cover               uint8  `json:"cover"`
 
 	EnableTriggerTask      bool   `gorm:"default: false" json:"enable_trigger_task,omitempty"`
@@ -251,18 +259,45 @@ type Service struct {
 	MaxLatency    float32 `json:"max_latency"`
 	LatencyNotify bool    `json:"latency_notify,omitempty"`
 
-	SkipServers map[uint64]bool `gorm:"-" json:"skip_servers"`
-	CronJobID   cron.EntryID    `gorm:"-" json:"-"`
+	// 状态判定，均未设置时沿用默认规则
+	GoodThreshold        float32 `json:"good_threshold,omitempty"`        // 可用率高于该值为正常，默认 95
+	LowThreshold         float32 `json:"low_threshold,omitempty"`         // 可用率高于该值为低可用，默认 80
+	FailureConfirmations uint32  `json:"failure_confirmations,omitempty"` // 监测点连续失败该次数后才确认故障
+	DownQuorum           uint32  `json:"down_quorum,omitempty"`           // 确认故障的监测点达到该数量时服务才视为故障
+
+	HTTP    *HTTPProbe `gorm:"-" json:"http,omitempty"` // HTTP 监控的请求与断言配置
+	HTTPRaw string     `json:"-"`
+	DNS     *DNSProbe  `gorm:"-" json:"dns,omitempty"` // DNS 监控的查询配置
+	DNSRaw  string     `json:"-"`
+
+	TLSWarnDays    []uint32 `gorm:"-" json:"tls_warn_days,omitempty"` // 证书剩余天数低于这些值时提醒，为空时使用 DefaultTLSWarnDays
+	TLSWarnDaysRaw string   `json:"-"`
+
+	DashboardProbe bool   `json:"dashboard_probe,omitempty"`              // 面板自身也作为监测点执行探测
+	HeartbeatToken string `gorm:"index" json:"heartbeat_token,omitempty"` // 心跳地址中的密钥
+	HeartbeatGrace uint64 `json:"heartbeat_grace,omitempty"`              // 超过 Duration 后再等待的秒数
+
+	SkipServers         map[uint64]bool `gorm:"-" json:"skip_servers"`
+	SkipServerGroups    []uint64        `gorm:"-" json:"skip_server_groups"` // 与 SkipServers 语义相同的服务器分组，派发时按当前成员展开
+	SkipServerGroupsRaw string          `gorm:"default:'[]'" json:"-"`
+	CronJobID           cron.EntryID    `gorm:"-" json:"-"`
 }
 
 func (m *Service) PB() *pb.Task {
-	if m == nil || !IsServiceMonitorType(uint64(m.Type)) {
+	if m == nil || !IsServiceMonitorType(uint64(m.Type)) || m.Type == TaskTypeHeartbeat {
 		return nil
 	}
+	data := m.Target
+	switch m.Type {
+	case TaskTypeHTTPGet:
+		data = m.httpTaskData()
+	case TaskTypeDNS:
+		data = m.dnsTaskData()
+	}
 	return &pb.Task{
 		Id:   m.ID,
 		Type: uint64(m.Type),
-		Data: m.Target,
+		Data: data,
 	}
 }
 
@@ -276,6 +311,7 @@ func (m *Service) PB() *pb.Task {
 //     共用 denyListSafeForLimitedPAT。
 //   - ServiceCoverIgnoreAll：SkipServers 是 allow-set，要求每个被覆盖的
 //     server 都在 PAT 白名单内。
+//   - SkipServerGroups 按当前成员展开后并入 SkipServers 参与上述判定。
 //   - 其它情况保留旧的“PAT 按 owner 关系判定”行为。
 func (m *Service) HasPermission(ctx *gin.Context) bool {
 	if !m.Common.HasPermission(ctx) {
@@ -291,9 +327,9 @@ func (m *Service) HasPermission(ctx *gin.Context) bool {
 	}
 	switch m.Cover {
 	case ServiceCoverAll:
-		return DenyListSafeForLimitedPAT(tok, m.GetUserID(), skipServersTrueIDs(m.SkipServers))
+		return DenyListSafeForLimitedPAT(tok, m.GetUserID(), m.SkipServerIDs())
 	case ServiceCoverIgnoreAll:
-		for _, id := range skipServersTrueIDs(m.SkipServers) {
+		for _, id := range m.SkipServerIDs() {
 			if !tok.CanAccessServer(id) {
 				return false
 			}
@@ -317,12 +353,26 @@ func skipServersTrueIDs(skip map[uint64]bool) []uint64 {
 	return out
 }
 
+// SkipServerIDs 返回 SkipServers 中标记的服务器与 SkipServerGroups 当前成员的并集
+func (m *Service) SkipServerIDs() []uint64 {
+	return ExpandServerGroups(skipServersTrueIDs(m.SkipServers), m.SkipServerGroups)
+}
+
+// Skips 服务器是否被 SkipServers 或 SkipServerGroups 列出
+func (m *Service) Skips(serverID uint64) bool {
+	return m.SkipServers[serverID] || serverInGroups(serverID, m.SkipServerGroups)
+}
+
 // CronSpec 返回服务监控请求间隔对应的 cron 表达式
 func (m *Service) CronSpec() string {
 	if m.Duration == 0 {
 		// 默认间隔 30 秒
 		m.Duration = 30
 	}
+	// 心跳监控的定时任务只检查是否超时，间隔较长时也按分钟检查
+	if m.Type == TaskTypeHeartbeat && m.Duration > 60 {
+		return "@every 60s"
+	}
 	return fmt.Sprintf("@every %ds", m.Duration)
 }
 
@@ -335,6 +385,11 @@ func (m *Service) BeforeSave(tx *gorm.DB) error {
 	} else {
 		m.SkipServersRaw = string(data)
 	}
+	if data, err := json.Marshal(m.SkipServerGroups); err != nil {
+		return err
+	} else {
+		m.SkipServerGroupsRaw = string(data)
+	}
 	if data, err := json.Marshal(m.FailTriggerTasks); err != nil {
 		return err
 	} else {
@@ -345,6 +400,50 @@ func (m *Service) BeforeSave(tx *gorm.DB) error {
 	} else {
 		m.RecoverTriggerTasksRaw = string(data)
 	}
+	m.HTTPRaw = ""
+	if !m.HTTP.IsZero() {
+		if err := m.HTTP.Validate(); err != nil {
+			return err
+		}
+		if data, err := json.Marshal(m.HTTP); err != nil {
+			return err
+		} else {
+			m.HTTPRaw = string(data)
+		}
+	}
+	if err := m.ValidateStateRules(); err != nil {
+		return err
+	}
+	if err := ValidateTLSWarnDays(m.TLSWarnDays); err != nil {
+		return err
+	}
+	m.TLSWarnDaysRaw = ""
+	if len(m.TLSWarnDays) > 0 {
+		if data, err := json.Marshal(m.TLSWarnDays); err != nil {
+			return err
+		} else {
+			m.TLSWarnDaysRaw = string(data)
+		}
+	}
+	if m.DashboardProbe && !SupportsDashboardProbe(m.Type) {
+		return fmt.Errorf("service type %d cannot be probed from the dashboard", m.Type)
+	}
+	m.DNSRaw = ""
+	if m.Type == TaskTypeDNS {
+		if err := ValidateDNSTarget(m.Target); err != nil {
+			return err
+		}
+		if err := m.DNS.Validate(); err != nil {
+			return err
+		}
+	}
+	if m.DNS != nil {
+		if data, err := json.Marshal(m.DNS); err != nil {
+			return err
+		} else {
+			m.DNSRaw = string(data)
+		}
+	}
 	return nil
 }
 
@@ -355,6 +454,10 @@ func (m *Service) AfterFind(tx *gorm.DB) error {
 		return nil
 	}
 
+	if err := json.Unmarshal([]byte(m.SkipServerGroupsRaw), &m.SkipServerGroups); err != nil {
+		return err
+	}
+
 	// 加载触发任务列表
 	if err := json.Unmarshal([]byte(m.FailTriggerTasksRaw), &m.FailTriggerTasks); err != nil {
 		return err
@@ -362,13 +465,97 @@ func (m *Service) AfterFind(tx *gorm.DB) error {
 	if err := json.Unmarshal([]byte(m.RecoverTriggerTasksRaw), &m.RecoverTriggerTasks); err != nil {
 		return err
 	}
+	if m.HTTPRaw != "" {
+		m.HTTP = &HTTPProbe{}
+		if err := json.Unmarshal([]byte(m.HTTPRaw), m.HTTP); err != nil {
+			return err
+		}
+	}
+	if m.DNSRaw != "" {
+		m.DNS = &DNSProbe{}
+		if err := json.Unmarshal([]byte(m.DNSRaw), m.DNS); err != nil {
+			return err
+		}
+	}
+	if m.TLSWarnDaysRaw != "" {
+		if err := json.Unmarshal([]byte(m.TLSWarnDaysRaw), &m.TLSWarnDays); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
-// IsServiceSentinelNeeded accepts results only for the three probe types. An
+// CheckResult 按监控类型与配置判定 agent 回传的结果，返回是否成功与写入监控记录的内容
+func (m *Service) CheckResult(successful bool, data string) (bool, string) {
+	switch m.Type {
+	case TaskTypeHTTPGet:
+		return m.CheckHTTPResult(successful, data)
+	case TaskTypeDNS:
+		return m.CheckDNSResult(data)
+	default:
+		return successful, data
+	}
+}
+
+// IsServiceSentinelNeeded accepts results only for the service probe types. An
 // unknown or privileged task type must never enter ServiceSentinel merely
-// because it was not listed in a denylist.
+// because it was not listed in a denylist. Heartbeat results are produced by
+// the dashboard itself, so an Agent can never report one.
 func IsServiceSentinelNeeded(t uint64) bool {
-	return IsServiceMonitorType(t)
+	return IsServiceMonitorType(t) && t != TaskTypeHeartbeat
+}
+
+const (
+	DefaultServiceGoodThreshold = 95
+	DefaultServiceLowThreshold  = 80
+)
+
+// StatusThresholds 返回正常与低可用的可用率阈值
+func (m *Service) StatusThresholds() (good, low float32) {
+	good, low = m.GoodThreshold, m.LowThreshold
+	if good == 0 {
+		good = DefaultServiceGoodThreshold
+	}
+	if low == 0 {
+		low = DefaultServiceLowThreshold
+	}
+	return good, low
+}
+
+// HasCustomState 是否自定义了状态判定规则
+func (m *Service) HasCustomState() bool {
+	return m.GoodThreshold != 0 || m.LowThreshold != 0 || m.RequiresDownConfirmation()
+}
+
+// RequiresDownConfirmation 是否需要连续失败或多个监测点确认后才视为故障
+func (m *Service) RequiresDownConfirmation() bool {
+	return m.FailureConfirmations > 0 || m.DownQuorum > 0
+}
+
+// ValidateStateRules 校验状态判定规则
+func (m *Service) ValidateStateRules() error {
+	good, low := m.StatusThresholds()
+	if good < 0 || good > 100 || low < 0 || low > 100 {
+		return fmt.Errorf("availability thresholds must be between 0 and 100")
+	}
+	if low >= good {
+		return fmt.Errorf("low availability threshold %g must be below good threshold %g", low, good)
+	}
+	return nil
+}
+
+// SupportsDashboardProbe 面板可以自行执行的监控类型
+func SupportsDashboardProbe(t uint8) bool {
+	switch t {
+	case TaskTypeHTTPGet, TaskTypeTCPPing, TaskTypeICMPPing:
+		return true
+	default:
+		return false
+	}
+}
+
+// HeartbeatDeadline 最近一次心跳后超过该时长未收到心跳即视为失败
+func (m *Service) HeartbeatDeadline() time.Duration {
+	return time.Duration(m.Duration+m.HeartbeatGrace) * time.Second
 }
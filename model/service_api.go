@@ -3,22 +3,41 @@ package model
 import "time"
 
 type ServiceForm struct {
-	Name                string          `json:"name,omitempty" minLength:"1"`
-	Target              string          `json:"target,omitempty"`
-	Type                uint8           `json:"type,omitempty"`
-	Cover               uint8           `json:"cover,omitempty"`
-	DisplayIndex        int             `json:"display_index,omitempty" default:"0"` // 展示排序，越大越靠前
-	Notify              bool            `json:"notify,omitempty" validate:"optional"`
-	Duration            uint64          `json:"duration,omitempty"`
-	MinLatency          float32         `json:"min_latency,omitempty" default:"0.0"`
-	MaxLatency          float32         `json:"max_latency,omitempty" default:"0.0"`
-	LatencyNotify       bool            `json:"latency_notify,omitempty" validate:"optional"`
-	EnableTriggerTask   bool            `json:"enable_trigger_task,omitempty" validate:"optional"`
-	HideForGuest        bool            `json:"hide_for_guest,omitempty" validate:"optional"`
-	FailTriggerTasks    []uint64        `json:"fail_trigger_tasks,omitempty"`
-	RecoverTriggerTasks []uint64        `json:"recover_trigger_tasks,omitempty"`
-	SkipServers         map[uint64]bool `json:"skip_servers,omitempty"`
-	NotificationGroupID uint64          `json:"notification_group_id,omitempty"`
+	Name                 string          `json:"name,omitempty" minLength:"1"`
+	Target               string          `json:"target,omitempty"`
+	Type                 uint8           `json:"type,omitempty"`
+	Cover                uint8           `json:"cover,omitempty"`
+	DisplayIndex         int             `json:"display_index,omitempty" default:"0"` // 展示排序，越大越靠前
+	Notify               bool            `json:"notify,omitempty" validate:"optional"`
+	Duration             uint64          `json:"duration,omitempty"`
+	MinLatency           float32         `json:"min_latency,omitempty" default:"0.0"`
+	MaxLatency           float32         `json:"max_latency,omitempty" default:"0.0"`
+	LatencyNotify        bool            `json:"latency_notify,omitempty" validate:"optional"`
+	EnableTriggerTask    bool            `json:"enable_trigger_task,omitempty" validate:"optional"`
+	HideForGuest         bool            `json:"hide_for_guest,omitempty" validate:"optional"`
+	FailTriggerTasks     []uint64        `json:"fail_trigger_tasks,omitempty"`
+	RecoverTriggerTasks  []uint64        `json:"recover_trigger_tasks,omitempty"`
+	SkipServers          map[uint64]bool `json:"skip_servers,omitempty"`
+	SkipServerGroups     []uint64        `json:"skip_server_groups,omitempty" validate:"optional"`
+	NotificationGroupID  uint64          `json:"notification_group_id,omitempty"`
+	EscalationPolicyID   uint64          `json:"escalation_policy_id,omitempty" validate:"optional"`
+	GoodThreshold        float32         `json:"good_threshold,omitempty" validate:"optional"` // 可用率高于该值为正常，默认 95
+	LowThreshold         float32         `json:"low_threshold,omitempty" validate:"optional"`  // 可用率高于该值为低可用，默认 80
+	FailureConfirmations uint32          `json:"failure_confirmations,omitempty" validate:"optional"`
+	DownQuorum           uint32          `json:"down_quorum,omitempty" validate:"optional"`
+	HTTP                 *HTTPProbe      `json:"http,omitempty" validate:"optional"`            // 仅 HTTP 监控有效，需要 agent 上报 AgentFeatureHTTPProbeTask 能力
+	DNS                  *DNSProbe       `json:"dns,omitempty" validate:"optional"`             // 仅 DNS 监控有效
+	DashboardProbe       bool            `json:"dashboard_probe,omitempty" validate:"optional"` // 面板自身也作为监测点，仅支持 HTTP、TCP 与 ICMP
+	HeartbeatGrace       uint64          `json:"heartbeat_grace,omitempty" validate:"optional"`
+	TLSWarnDays          []uint32        `json:"tls_warn_days,omitempty" validate:"optional"`         // 证书到期提醒天数，默认 30、14、7、1 天
+	ResetHeartbeatToken  bool            `json:"reset_heartbeat_token,omitempty" validate:"optional"` // 重新生成心跳地址
+}
+
+// HeartbeatForm 心跳请求可选携带的内容，GET 请求使用查询参数
+type HeartbeatForm struct {
+	Status   string  `json:"status,omitempty" form:"status"`     // up 或 down，默认 up
+	Duration float32 `json:"duration,omitempty" form:"duration"` // 任务耗时，单位毫秒，记为延迟
+	Message  string  `json:"msg,omitempty" form:"msg"`
 }
 
 type ServiceResponseItem struct {
@@ -30,6 +49,19 @@ type ServiceResponseItem struct {
 	Delay       *[30]float64 `json:"delay,omitempty"`
 	Up          *[30]uint64  `json:"up,omitempty"`
 	Down        *[30]uint64  `json:"down,omitempty"`
+
+	Status    string                           `json:"status,omitempty"`    // 汇总各监测点后的当前状态：good、low_availability、down 或 no_data
+	Reporters map[uint64]ServiceReporterStatus `json:"reporters,omitempty"` // 各监测点的当前状态，键为服务器 ID，0 为面板自身
+}
+
+// ServiceReporterStatus 单个监测点最近的监控结果
+type ServiceReporterStatus struct {
+	Status              string    `json:"status"` // 只有连续失败达到确认次数的监测点为 down
+	Up                  uint64    `json:"up"`
+	Down                uint64    `json:"down"`
+	Delay               float64   `json:"delay"`
+	ConsecutiveFailures uint32    `json:"consecutive_failures,omitempty"`
+	LastCheck           time.Time `json:"last_check"`
 }
 
 func (r ServiceResponseItem) TotalUptime() float32 {
@@ -0,0 +1,167 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+// DNSRecordTypes DNS 监控支持的记录类型
+var DNSRecordTypes = []string{"A", "AAAA", "CNAME", "MX", "NS", "TXT", "SRV", "CAA", "PTR", "SOA"}
+
+// DNS 监控失败原因
+const (
+	DNSProbeFailureRequest = "request"
+	DNSProbeFailureRcode   = "rcode"
+	DNSProbeFailureAnswer  = "answer"
+)
+
+// DNSProbe DNS 监控配置，查询的域名使用 Service.Target
+type DNSProbe struct {
+	RecordType string `json:"record_type,omitempty"` // 默认 A
+	// Resolver 使用的递归或权威服务器，形如 "1.1.1.1" 或 "ns1.example.com:53"，为空时使用 agent 系统配置
+	Resolver string `json:"resolver,omitempty"`
+	// Expected 期望出现在应答中的记录，为空时只要求应答非空
+	Expected []string `json:"expected,omitempty"`
+	// ExactMatch 应答中不允许出现 Expected 之外的记录
+	ExactMatch bool `json:"exact_match,omitempty"`
+}
+
+// DNSProbeTask DNS 监控通过 pb.Task.Data 下发的载荷（JSON）
+type DNSProbeTask struct {
+	Name       string `json:"name"`
+	RecordType string `json:"record_type"`
+	Resolver   string `json:"resolver,omitempty"`
+}
+
+// DNSProbeResult agent 通过 TaskResult.Data 回传的查询结果（JSON），延迟使用 TaskResult.Delay
+type DNSProbeResult struct {
+	Rcode    string   `json:"rcode,omitempty"` // 如 NOERROR、NXDOMAIN、SERVFAIL
+	Answers  []string `json:"answers,omitempty"`
+	Resolver string   `json:"resolver,omitempty"` // 实际使用的服务器
+	Error    string   `json:"error,omitempty"`
+}
+
+func (p *DNSProbe) recordType() string {
+	if p == nil || p.RecordType == "" {
+		return "A"
+	}
+	return p.RecordType
+}
+
+// ValidateDNSTarget 校验查询的域名
+func ValidateDNSTarget(name string) error {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || len(name) > 253 {
+		return errors.New("invalid dns query name")
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 {
+			return fmt.Errorf("invalid dns query name: %s", name)
+		}
+		for _, c := range label {
+			if !(c == '-' || c == '_' || c == '*' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')) {
+				return fmt.Errorf("invalid dns query name: %s", name)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *DNSProbe) Validate() error {
+	if p == nil {
+		return nil
+	}
+	p.RecordType = strings.ToUpper(strings.TrimSpace(p.RecordType))
+	if p.RecordType != "" && !slices.Contains(DNSRecordTypes, p.RecordType) {
+		return fmt.Errorf("unsupported dns record type: %s", p.RecordType)
+	}
+	if p.Resolver != "" {
+		host, port := p.Resolver, "53"
+		if h, pt, err := net.SplitHostPort(p.Resolver); err == nil {
+			host, port = h, pt
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid resolver port: %s", port)
+		}
+		if net.ParseIP(host) == nil && ValidateDNSTarget(host) != nil {
+			return fmt.Errorf("invalid resolver: %s", p.Resolver)
+		}
+	}
+	if p.ExactMatch && len(p.Expected) == 0 {
+		return errors.New("exact_match requires expected answers")
+	}
+	return nil
+}
+
+// normalizeDNSAnswer 忽略大小写与域名末尾的点
+func normalizeDNSAnswer(s string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), ".")
+}
+
+// Check 判定一次查询结果，成功时 reason 为空
+func (p *DNSProbe) Check(r *DNSProbeResult) (reason, detail string) {
+	if r.Error != "" {
+		return DNSProbeFailureRequest, r.Error
+	}
+	if r.Rcode != "" && !strings.EqualFold(r.Rcode, "NOERROR") {
+		return DNSProbeFailureRcode, strings.ToUpper(r.Rcode)
+	}
+	if len(r.Answers) == 0 {
+		return DNSProbeFailureAnswer, fmt.Sprintf("no %s record", p.recordType())
+	}
+	if p == nil || len(p.Expected) == 0 {
+		return "", ""
+	}
+	answers := make(map[string]bool, len(r.Answers))
+	for _, a := range r.Answers {
+		answers[normalizeDNSAnswer(a)] = true
+	}
+	expected := make(map[string]bool, len(p.Expected))
+	for _, e := range p.Expected {
+		e = normalizeDNSAnswer(e)
+		expected[e] = true
+		if !answers[e] {
+			return DNSProbeFailureAnswer, fmt.Sprintf("%s not in answers %s", e, strings.Join(r.Answers, ", "))
+		}
+	}
+	if p.ExactMatch {
+		for _, a := range r.Answers {
+			if !expected[normalizeDNSAnswer(a)] {
+				return DNSProbeFailureAnswer, fmt.Sprintf("unexpected answer %s", a)
+			}
+		}
+	}
+	return "", ""
+}
+
+func (m *Service) dnsTaskData() string {
+	task := DNSProbeTask{Name: m.Target, RecordType: m.DNS.recordType()}
+	if m.DNS != nil {
+		task.Resolver = m.DNS.Resolver
+	}
+	data, _ := json.Marshal(task)
+	return string(data)
+}
+
+// CheckDNSResult 按监控配置判定 agent 回传的 DNS 查询结果，成功时记录应答内容
+func (m *Service) CheckDNSResult(data string) (bool, string) {
+	var r DNSProbeResult
+	if err := json.Unmarshal([]byte(data), &r); err != nil {
+		return false, data
+	}
+	reason, detail := m.DNS.Check(&r)
+	switch reason {
+	case "":
+		return true, strings.Join(r.Answers, ", ")
+	case DNSProbeFailureRequest:
+		return false, detail
+	default:
+		return false, fmt.Sprintf("[%s] %s", reason, detail)
+	}
+}
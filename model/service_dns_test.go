@@ -0,0 +1,76 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSProbeValidate(t *testing.T) {
+	p := &DNSProbe{RecordType: "aaaa", Resolver: "[2606:4700:4700::1111]:53", Expected: []string{"::1"}, ExactMatch: true}
+	require.NoError(t, p.Validate())
+	require.Equal(t, "AAAA", p.RecordType)
+	require.NoError(t, (&DNSProbe{Resolver: "ns1.example.com"}).Validate())
+	require.NoError(t, (&DNSProbe{Resolver: "1.1.1.1"}).Validate())
+
+	for _, p := range []*DNSProbe{
+		{RecordType: "ANY"},
+		{Resolver: "1.1.1.1:0"},
+		{Resolver: "bad resolver"},
+		{ExactMatch: true},
+	} {
+		require.Error(t, p.Validate(), "%+v", p)
+	}
+
+	require.NoError(t, ValidateDNSTarget("_dmarc.example.com."))
+	require.NoError(t, ValidateDNSTarget("*.example.com"))
+	for _, name := range []string{"", "example..com", "exa mple.com", "https://example.com"} {
+		require.Error(t, ValidateDNSTarget(name), name)
+	}
+}
+
+func TestDNSProbeCheck(t *testing.T) {
+	cases := []struct {
+		probe  *DNSProbe
+		result DNSProbeResult
+		reason string
+	}{
+		{nil, DNSProbeResult{Rcode: "NOERROR", Answers: []string{"1.1.1.1"}}, ""},
+		{nil, DNSProbeResult{Rcode: "NOERROR"}, DNSProbeFailureAnswer},
+		{nil, DNSProbeResult{Rcode: "nxdomain"}, DNSProbeFailureRcode},
+		{nil, DNSProbeResult{Error: "i/o timeout"}, DNSProbeFailureRequest},
+		{&DNSProbe{Expected: []string{"ns1.example.com"}}, DNSProbeResult{Answers: []string{"NS1.example.com.", "ns2.example.com."}}, ""},
+		{&DNSProbe{Expected: []string{"ns1.example.com"}, ExactMatch: true}, DNSProbeResult{Answers: []string{"ns1.example.com.", "ns2.example.com."}}, DNSProbeFailureAnswer},
+		{&DNSProbe{Expected: []string{"10.0.0.1", "10.0.0.2"}}, DNSProbeResult{Answers: []string{"10.0.0.1"}}, DNSProbeFailureAnswer},
+	}
+	for i, c := range cases {
+		reason, detail := c.probe.Check(&c.result)
+		require.Equal(t, c.reason, reason, "case %d: %s", i, detail)
+	}
+}
+
+func TestServiceDNSTask(t *testing.T) {
+	s := &Service{Common: Common{ID: 3}, Type: TaskTypeDNS, Target: "example.com", DNS: &DNSProbe{RecordType: "MX", Resolver: "ns1.example.com:53"}}
+	require.NoError(t, s.BeforeSave(nil))
+
+	task := s.PB()
+	require.Equal(t, uint64(TaskTypeDNS), task.GetType())
+	var payload DNSProbeTask
+	require.NoError(t, json.Unmarshal([]byte(task.GetData()), &payload))
+	require.Equal(t, DNSProbeTask{Name: "example.com", RecordType: "MX", Resolver: "ns1.example.com:53"}, payload)
+
+	s.DNS = nil
+	require.NoError(t, json.Unmarshal([]byte(s.PB().GetData()), &payload))
+	require.Equal(t, "A", payload.RecordType)
+
+	s.Target = "https://example.com"
+	require.Error(t, s.BeforeSave(nil))
+
+	ok, data := s.CheckResult(false, `{"rcode":"NOERROR","answers":["10 mx1.example.com.","20 mx2.example.com."]}`)
+	require.True(t, ok)
+	require.Equal(t, "10 mx1.example.com., 20 mx2.example.com.", data)
+	ok, data = s.CheckResult(true, `{"rcode":"SERVFAIL"}`)
+	require.False(t, ok)
+	require.Equal(t, "[rcode] SERVFAIL", data)
+}
@@ -32,6 +32,8 @@ type ServerServiceStats struct {
 	ServerID   uint64                `json:"server_id"`
 	ServerName string                `json:"server_name,omitempty"`
 	Stats      ServiceHistorySummary `json:"stats"`
+	// Current 该监测点当前的状态，监测点近期没有上报时为空
+	Current *ServiceReporterStatus `json:"current,omitempty"`
 }
 
 // ServiceHistoryResponse 服务历史查询响应
@@ -52,5 +54,6 @@ type ServerMetricsResponse struct {
 	ServerID   uint64                   `json:"server_id"`
 	ServerName string                   `json:"server_name,omitempty"`
 	Metric     string                   `json:"metric"`
+	Device     string                   `json:"device,omitempty"`
 	DataPoints []ServerMetricsDataPoint `json:"data_points"`
 }
@@ -0,0 +1,298 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/tidwall/gjson"
+	"golang.org/x/net/http/httpguts"
+
+	pb "github.com/nezhahq/nezha/proto"
+)
+
+// AgentFeatureHTTPProbeTask agent 在 Host.Features 中上报此能力，表示能执行 HTTPProbeTask。
+// 未上报的 agent 会把整个 JSON 载荷当作 URL 请求导致监控失败，因此只向它们下发 Target：
+// 此时请求方法、请求头、请求体与跳转策略不生效，agent 回传的也不是 HTTPProbeResult，
+// 状态码与内容断言无法判定，仅按旧行为记录请求是否成功。
+const AgentFeatureHTTPProbeTask = "http_probe_task"
+
+const (
+	HTTPRedirectFollow = iota // 跟随跳转，最多 MaxRedirects 次
+	HTTPRedirectNone          // 不跟随跳转，3xx 响应即为最终响应
+	HTTPRedirectFail          // 发生跳转即视为失败
+)
+
+const (
+	HTTPProbeDefaultMaxRedirects = 10
+	HTTPProbeMaxRedirects        = 30
+	HTTPProbeMaxRequestBody      = 64 * 1024
+	// HTTPProbeMaxResponseBody agent 回传响应体的上限，断言只针对这部分内容
+	HTTPProbeMaxResponseBody = 64 * 1024
+)
+
+// HTTP 监控失败原因，写入监控结果与通知，便于区分请求失败与断言失败
+const (
+	HTTPProbeFailureRequest  = "request"
+	HTTPProbeFailureRedirect = "redirect"
+	HTTPProbeFailureStatus   = "status"
+	HTTPProbeFailureKeyword  = "keyword"
+	HTTPProbeFailureRegex    = "regex"
+	HTTPProbeFailureJSONPath = "json_path"
+)
+
+var httpProbeMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// HTTPProbe HTTP(S) 监控的请求与断言配置，未配置时沿用只请求 Target 的旧行为
+type HTTPProbe struct {
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+
+	// AcceptedStatusCodes 可接受的状态码，支持 "200"、"2xx" 与 "200-299"，为空时接受 2xx 与 3xx
+	AcceptedStatusCodes []string `json:"accepted_status_codes,omitempty"`
+	Keyword             string   `json:"keyword,omitempty"`
+	KeywordInvert       bool     `json:"keyword_invert,omitempty"` // 响应体包含 Keyword 时视为失败
+	Regex               string   `json:"regex,omitempty"`
+	// JSONPath 使用 gjson 路径语法，兼容 "$." 前缀；JSONValue 为空时只要求路径存在
+	JSONPath  string `json:"json_path,omitempty"`
+	JSONValue string `json:"json_value,omitempty"`
+
+	Redirect     uint8 `json:"redirect,omitempty"`
+	MaxRedirects int   `json:"max_redirects,omitempty"`
+}
+
+// HTTPProbeTask 配置了 HTTPProbe 的监控通过 pb.Task.Data 下发的载荷（JSON）
+type HTTPProbeTask struct {
+	URL string `json:"url"`
+	HTTPProbe
+}
+
+// HTTPProbeResult agent 执行 HTTPProbeTask 后通过 TaskResult.Data 回传的结果（JSON）。
+// 只有配置了内容断言时才需要回传响应体，断言统一由面板判定。
+type HTTPProbeResult struct {
+	StatusCode int    `json:"status_code,omitempty"`
+	Redirects  int    `json:"redirects,omitempty"`
+	FinalURL   string `json:"final_url,omitempty"`
+	Body       string `json:"body,omitempty"`
+	Cert       string `json:"cert,omitempty"` // 与旧版结果相同的 "颁发者|过期时间" 格式
+	Error      string `json:"error,omitempty"`
+	// TLS 完整的证书信息，证书校验失败时同样回传
+	TLS *TLSCertificateInfo `json:"tls,omitempty"`
+}
+
+// IsZero 是否未配置任何选项
+func (p *HTTPProbe) IsZero() bool {
+	return p == nil || (p.Method == "" && len(p.Headers) == 0 && p.Body == "" &&
+		len(p.AcceptedStatusCodes) == 0 && p.Keyword == "" && p.Regex == "" &&
+		p.JSONPath == "" && p.Redirect == HTTPRedirectFollow && p.MaxRedirects == 0)
+}
+
+// HasBodyAssertion 是否需要根据响应体判定
+func (p *HTTPProbe) HasBodyAssertion() bool {
+	return p != nil && (p.Keyword != "" || p.Regex != "" || p.JSONPath != "")
+}
+
+func (p *HTTPProbe) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.Method != "" {
+		p.Method = strings.ToUpper(p.Method)
+		valid := false
+		for _, m := range httpProbeMethods {
+			if m == p.Method {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("unsupported http method: %s", p.Method)
+		}
+	}
+	for k, v := range p.Headers {
+		if !httpguts.ValidHeaderFieldName(k) {
+			return fmt.Errorf("invalid header name: %q", k)
+		}
+		if !httpguts.ValidHeaderFieldValue(v) {
+			return fmt.Errorf("invalid value of header %s", k)
+		}
+	}
+	if len(p.Body) > HTTPProbeMaxRequestBody {
+		return fmt.Errorf("request body is longer than %d bytes", HTTPProbeMaxRequestBody)
+	}
+	for _, s := range p.AcceptedStatusCodes {
+		if _, _, err := parseStatusCodeRange(s); err != nil {
+			return err
+		}
+	}
+	if p.Regex != "" {
+		if _, err := regexp.Compile(p.Regex); err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+	if p.JSONValue != "" && p.JSONPath == "" {
+		return errors.New("json_value requires json_path")
+	}
+	if p.Redirect > HTTPRedirectFail {
+		return fmt.Errorf("unknown redirect policy: %d", p.Redirect)
+	}
+	if p.MaxRedirects < 0 || p.MaxRedirects > HTTPProbeMaxRedirects {
+		return fmt.Errorf("max_redirects must be between 0 and %d", HTTPProbeMaxRedirects)
+	}
+	return nil
+}
+
+// RedirectLimit 跟随跳转的最大次数
+func (p *HTTPProbe) RedirectLimit() int {
+	if p == nil || p.MaxRedirects == 0 {
+		return HTTPProbeDefaultMaxRedirects
+	}
+	return p.MaxRedirects
+}
+
+// Check 判定一次请求结果，成功时 reason 为空
+func (p *HTTPProbe) Check(r *HTTPProbeResult) (reason, detail string) {
+	if r.Error != "" {
+		return HTTPProbeFailureRequest, r.Error
+	}
+	if p == nil {
+		p = &HTTPProbe{}
+	}
+	switch {
+	case p.Redirect == HTTPRedirectFail && r.Redirects > 0:
+		return HTTPProbeFailureRedirect, fmt.Sprintf("redirected to %s", r.FinalURL)
+	case p.Redirect == HTTPRedirectFollow && r.Redirects > p.RedirectLimit():
+		return HTTPProbeFailureRedirect, fmt.Sprintf("stopped after %d redirects", p.RedirectLimit())
+	}
+	if !p.acceptStatus(r.StatusCode) {
+		return HTTPProbeFailureStatus, fmt.Sprintf("unexpected status code %d", r.StatusCode)
+	}
+	if p.Keyword != "" {
+		found := strings.Contains(r.Body, p.Keyword)
+		if found && p.KeywordInvert {
+			return HTTPProbeFailureKeyword, fmt.Sprintf("response contains %q", p.Keyword)
+		}
+		if !found && !p.KeywordInvert {
+			return HTTPProbeFailureKeyword, fmt.Sprintf("response does not contain %q", p.Keyword)
+		}
+	}
+	if p.Regex != "" {
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return HTTPProbeFailureRegex, err.Error()
+		}
+		if !re.MatchString(r.Body) {
+			return HTTPProbeFailureRegex, fmt.Sprintf("response does not match %q", p.Regex)
+		}
+	}
+	if p.JSONPath != "" {
+		if !gjson.Valid(r.Body) {
+			return HTTPProbeFailureJSONPath, "response is not valid JSON"
+		}
+		v := gjson.Get(r.Body, strings.TrimPrefix(p.JSONPath, "$."))
+		if !v.Exists() {
+			return HTTPProbeFailureJSONPath, fmt.Sprintf("%s not found", p.JSONPath)
+		}
+		if p.JSONValue != "" && v.String() != p.JSONValue {
+			return HTTPProbeFailureJSONPath, fmt.Sprintf("%s is %q, expected %q", p.JSONPath, v.String(), p.JSONValue)
+		}
+	}
+	return "", ""
+}
+
+func (p *HTTPProbe) acceptStatus(code int) bool {
+	if len(p.AcceptedStatusCodes) == 0 {
+		return code >= 200 && code < 400
+	}
+	for _, s := range p.AcceptedStatusCodes {
+		lo, hi, err := parseStatusCodeRange(s)
+		if err == nil && code >= lo && code <= hi {
+			return true
+		}
+	}
+	return false
+}
+
+// parseStatusCodeRange 解析 "200"、"2xx" 与 "200-299" 形式的状态码范围
+func parseStatusCodeRange(s string) (int, int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	invalid := fmt.Errorf("invalid status code: %q", s)
+	if len(s) == 3 && strings.HasSuffix(s, "xx") {
+		d := int(s[0] - '0')
+		if d < 1 || d > 5 {
+			return 0, 0, invalid
+		}
+		return d * 100, d*100 + 99, nil
+	}
+	from, to, isRange := strings.Cut(s, "-")
+	lo, err := strconv.Atoi(strings.TrimSpace(from))
+	if err != nil {
+		return 0, 0, invalid
+	}
+	hi := lo
+	if isRange {
+		if hi, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+			return 0, 0, invalid
+		}
+	}
+	if lo < 100 || hi > 599 || lo > hi {
+		return 0, 0, invalid
+	}
+	return lo, hi, nil
+}
+
+// httpTaskData 配置了 HTTPProbe 时下发 JSON 载荷，否则下发 Target 以兼容旧版 agent
+func (m *Service) httpTaskData() string {
+	if m.HTTP.IsZero() {
+		return m.Target
+	}
+	data, err := json.Marshal(HTTPProbeTask{URL: m.Target, HTTPProbe: *m.HTTP})
+	if err != nil {
+		return m.Target
+	}
+	return string(data)
+}
+
+// PBForServer 返回下发给 s 的监控任务，HTTP 监控按 agent 上报的能力选择载荷，见 AgentFeatureHTTPProbeTask
+func (m *Service) PBForServer(s *Server) *pb.Task {
+	task := m.PB()
+	if task == nil || m.Type != TaskTypeHTTPGet || task.Data == m.Target {
+		return task
+	}
+	host := s.RuntimeSnapshot().Host
+	if host == nil || !slices.Contains(host.Features, AgentFeatureHTTPProbeTask) {
+		task.Data = m.Target
+	}
+	return task
+}
+
+// CheckHTTPResult 按监控配置判定 agent 回传的 HTTP 监控结果，返回是否成功与写入监控记录的内容。
+// 旧版 agent 回传的不是 HTTPProbeResult，此时原样返回。
+func (m *Service) CheckHTTPResult(successful bool, data string) (bool, string) {
+	if !strings.HasPrefix(data, "{") {
+		return successful, data
+	}
+	var r HTTPProbeResult
+	if err := json.Unmarshal([]byte(data), &r); err != nil {
+		return successful, data
+	}
+	reason, detail := m.HTTP.Check(&r)
+	switch reason {
+	case "":
+		return true, r.Cert
+	case HTTPProbeFailureRequest:
+		// 保留原始错误，TLS 证书错误等仍按旧格式处理
+		return false, detail
+	default:
+		return false, fmt.Sprintf("[%s] %s", reason, detail)
+	}
+}
@@ -0,0 +1,152 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func TestHTTPProbeValidate(t *testing.T) {
+	valid := &HTTPProbe{
+		Method:              "post",
+		Headers:             map[string]string{"Authorization": "Bearer x"},
+		AcceptedStatusCodes: []string{"200", "3xx", "401-403"},
+		Regex:               `"ok":\s*true`,
+		JSONPath:            "$.status",
+		JSONValue:           "up",
+		Redirect:            HTTPRedirectNone,
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if valid.Method != "POST" {
+		t.Fatalf("expected method to be normalized, got %s", valid.Method)
+	}
+
+	cases := []*HTTPProbe{
+		{Method: "CONNECT"},
+		{Headers: map[string]string{"Bad Header": "x"}},
+		{Headers: map[string]string{"X-Test": "a\r\nInjected: 1"}},
+		{AcceptedStatusCodes: []string{"6xx"}},
+		{AcceptedStatusCodes: []string{"299-200"}},
+		{AcceptedStatusCodes: []string{"abc"}},
+		{Regex: "("},
+		{JSONValue: "up"},
+		{Redirect: 3},
+		{MaxRedirects: HTTPProbeMaxRedirects + 1},
+		{Body: strings.Repeat("a", HTTPProbeMaxRequestBody+1)},
+	}
+	for _, c := range cases {
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected error for %+v", c)
+		}
+	}
+}
+
+func TestHTTPProbeCheck(t *testing.T) {
+	cases := []struct {
+		probe  *HTTPProbe
+		result HTTPProbeResult
+		reason string
+	}{
+		{nil, HTTPProbeResult{StatusCode: 200}, ""},
+		{nil, HTTPProbeResult{StatusCode: 301}, ""},
+		{nil, HTTPProbeResult{StatusCode: 500}, HTTPProbeFailureStatus},
+		{nil, HTTPProbeResult{Error: "dial tcp: i/o timeout"}, HTTPProbeFailureRequest},
+		{&HTTPProbe{AcceptedStatusCodes: []string{"401"}}, HTTPProbeResult{StatusCode: 401}, ""},
+		{&HTTPProbe{AcceptedStatusCodes: []string{"2xx"}}, HTTPProbeResult{StatusCode: 302}, HTTPProbeFailureStatus},
+		{&HTTPProbe{Redirect: HTTPRedirectFail}, HTTPProbeResult{StatusCode: 200, Redirects: 1, FinalURL: "https://login.example.com"}, HTTPProbeFailureRedirect},
+		{&HTTPProbe{MaxRedirects: 2}, HTTPProbeResult{StatusCode: 200, Redirects: 3}, HTTPProbeFailureRedirect},
+		{&HTTPProbe{Keyword: "healthy"}, HTTPProbeResult{StatusCode: 200, Body: "all healthy"}, ""},
+		{&HTTPProbe{Keyword: "healthy"}, HTTPProbeResult{StatusCode: 200, Body: "degraded"}, HTTPProbeFailureKeyword},
+		{&HTTPProbe{Keyword: "error", KeywordInvert: true}, HTTPProbeResult{StatusCode: 200, Body: "an error occurred"}, HTTPProbeFailureKeyword},
+		{&HTTPProbe{Regex: `version: \d+\.\d+`}, HTTPProbeResult{StatusCode: 200, Body: "version: 1.2"}, ""},
+		{&HTTPProbe{Regex: `version: \d+\.\d+`}, HTTPProbeResult{StatusCode: 200, Body: "version: dev"}, HTTPProbeFailureRegex},
+		{&HTTPProbe{JSONPath: "$.db.status", JSONValue: "up"}, HTTPProbeResult{StatusCode: 200, Body: `{"db":{"status":"up"}}`}, ""},
+		{&HTTPProbe{JSONPath: "db.status", JSONValue: "up"}, HTTPProbeResult{StatusCode: 200, Body: `{"db":{"status":"down"}}`}, HTTPProbeFailureJSONPath},
+		{&HTTPProbe{JSONPath: "checks.#"}, HTTPProbeResult{StatusCode: 200, Body: `{"checks":[]}`}, ""},
+		{&HTTPProbe{JSONPath: "db"}, HTTPProbeResult{StatusCode: 200, Body: `{}`}, HTTPProbeFailureJSONPath},
+		{&HTTPProbe{JSONPath: "db"}, HTTPProbeResult{StatusCode: 200, Body: `<html>`}, HTTPProbeFailureJSONPath},
+	}
+	for i, c := range cases {
+		reason, detail := c.probe.Check(&c.result)
+		if reason != c.reason {
+			t.Errorf("case %d: expected reason %q, got %q (%s)", i, c.reason, reason, detail)
+		}
+	}
+}
+
+func TestServiceHTTPTaskData(t *testing.T) {
+	s := &Service{Common: Common{ID: 1}, Type: TaskTypeHTTPGet, Target: "https://example.com/health"}
+	if data := s.PB().Data; data != s.Target {
+		t.Fatalf("expected plain target without probe options, got %s", data)
+	}
+
+	s.HTTP = &HTTPProbe{Method: "POST", Body: `{"ping":1}`, Keyword: "pong"}
+	var task HTTPProbeTask
+	if err := json.Unmarshal([]byte(s.PB().Data), &task); err != nil {
+		t.Fatal(err)
+	}
+	if task.URL != s.Target || task.Method != "POST" || task.Body != `{"ping":1}` || task.Keyword != "pong" {
+		t.Fatalf("unexpected task payload %+v", task)
+	}
+
+	// 其他类型的监控不下发 HTTP 配置
+	s.Type = TaskTypeTCPPing
+	s.Target = "example.com:443"
+	if data := s.PB().Data; data != s.Target {
+		t.Fatalf("expected plain target for tcp ping, got %s", data)
+	}
+}
+
+func TestServiceHTTPTaskDataForOldAgents(t *testing.T) {
+	s := &Service{Common: Common{ID: 1}, Type: TaskTypeHTTPGet, Target: "https://example.com/health", HTTP: &HTTPProbe{Keyword: "pong"}}
+	cases := []struct {
+		host *Host
+		json bool
+	}{
+		{nil, false},
+		{&Host{}, false},
+		{&Host{Version: "1.13.0"}, false},
+		{&Host{Version: "v9.0.0", Features: []string{"other"}}, false},
+		{&Host{Features: []string{AgentFeatureHTTPProbeTask}}, true},
+		{&Host{Version: "dev", Features: []string{"other", AgentFeatureHTTPProbeTask}}, true},
+	}
+	for _, c := range cases {
+		server := &Server{Host: c.host}
+		data := s.PBForServer(server).Data
+		if got := data != s.Target; got != c.json {
+			t.Errorf("host %+v: expected json payload %v, got %s", c.host, c.json, data)
+		}
+	}
+
+	// 其他类型的监控与 agent 能力无关
+	s.Type = TaskTypeDNS
+	s.Target = "example.com"
+	if data := s.PBForServer(&Server{}).Data; data != s.PB().Data {
+		t.Fatalf("expected dns payload regardless of agent features, got %s", data)
+	}
+}
+
+func TestServiceCheckHTTPResult(t *testing.T) {
+	s := &Service{Type: TaskTypeHTTPGet, HTTP: &HTTPProbe{Keyword: "ok"}}
+
+	ok, data := s.CheckHTTPResult(false, `{"status_code":200,"body":"ok","cert":"R3|2030-01-01 00:00:00 +0000 UTC"}`)
+	if !ok || data != "R3|2030-01-01 00:00:00 +0000 UTC" {
+		t.Fatalf("expected success with cert info, got %v %q", ok, data)
+	}
+	ok, data = s.CheckHTTPResult(true, `{"status_code":200,"body":"fail"}`)
+	if ok || !strings.HasPrefix(data, "[keyword] ") {
+		t.Fatalf("expected keyword failure, got %v %q", ok, data)
+	}
+	ok, data = s.CheckHTTPResult(false, `{"error":"SSL证书错误：x509: certificate has expired"}`)
+	if ok || data != "SSL证书错误：x509: certificate has expired" {
+		t.Fatalf("expected request error to be kept verbatim, got %v %q", ok, data)
+	}
+	// 旧版 agent 回传的结果原样保留
+	ok, data = s.CheckHTTPResult(false, "unsupported protocol scheme")
+	if ok || data != "unsupported protocol scheme" {
+		t.Fatalf("expected legacy result to pass through, got %v %q", ok, data)
+	}
+}
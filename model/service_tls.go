@@ -0,0 +1,195 @@
+package model
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"golang.org/x/crypto/ocsp"
+)
+
+// DefaultTLSWarnDays 未配置时证书剩余天数低于这些值时提醒
+var DefaultTLSWarnDays = []uint32{30, 14, 7, 1}
+
+const TLSMaxWarnDays = 3650
+
+// OCSP 状态
+const (
+	OCSPStatusGood    = "good"
+	OCSPStatusRevoked = "revoked"
+	OCSPStatusUnknown = "unknown"
+)
+
+// legacyTLSTimeLayout 旧版结果 "颁发者|过期时间" 中过期时间的格式
+const legacyTLSTimeLayout = "2006-01-02 15:04:05 -0700 MST"
+
+// TLSCertificate 证书链中的一张证书
+type TLSCertificate struct {
+	Subject      string    `json:"subject,omitempty"`
+	Issuer       string    `json:"issuer"`
+	SANs         []string  `json:"sans,omitempty"`
+	SerialNumber string    `json:"serial_number,omitempty"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+}
+
+// TLSCertificateInfo HTTPS 监控获取到的证书信息，旧版 agent 只能提供颁发者与过期时间
+type TLSCertificateInfo struct {
+	Chain       []TLSCertificate `json:"chain"` // 第一张为站点证书
+	ChainValid  bool             `json:"chain_valid"`
+	ChainError  string           `json:"chain_error,omitempty"`
+	OCSPStapled bool             `json:"ocsp_stapled,omitempty"`
+	OCSPStatus  string           `json:"ocsp_status,omitempty"` // 仅在服务端附带 OCSP 响应时有值
+}
+
+// NewTLSCertificate 转换 x509 证书
+func NewTLSCertificate(cert *x509.Certificate) TLSCertificate {
+	sans := slices.Clone(cert.DNSNames)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return TLSCertificate{
+		Subject:      cert.Subject.CommonName,
+		Issuer:       cert.Issuer.CommonName,
+		SANs:         sans,
+		SerialNumber: fmt.Sprintf("%X", cert.SerialNumber),
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+	}
+}
+
+// NewTLSCertificateInfo 根据 TLS 连接状态生成证书信息，verifyErr 为证书链校验结果
+func NewTLSCertificateInfo(state *tls.ConnectionState, verifyErr error) *TLSCertificateInfo {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	info := &TLSCertificateInfo{ChainValid: verifyErr == nil}
+	if verifyErr != nil {
+		info.ChainError = verifyErr.Error()
+	}
+	for _, cert := range state.PeerCertificates {
+		info.Chain = append(info.Chain, NewTLSCertificate(cert))
+	}
+	if len(state.OCSPResponse) > 0 {
+		info.OCSPStapled = true
+		info.OCSPStatus = OCSPStatusUnknown
+		var issuer *x509.Certificate
+		if len(state.PeerCertificates) > 1 {
+			issuer = state.PeerCertificates[1]
+		}
+		if resp, err := ocsp.ParseResponseForCert(state.OCSPResponse, state.PeerCertificates[0], issuer); err == nil {
+			switch resp.Status {
+			case ocsp.Good:
+				info.OCSPStatus = OCSPStatusGood
+			case ocsp.Revoked:
+				info.OCSPStatus = OCSPStatusRevoked
+			}
+		}
+	}
+	return info
+}
+
+// ParseLegacyTLSCertificate 解析旧版结果中的 "颁发者|过期时间"
+func ParseLegacyTLSCertificate(data string) *TLSCertificateInfo {
+	issuer, expires, ok := strings.Cut(data, "|")
+	if !ok {
+		return nil
+	}
+	notAfter, err := time.Parse(legacyTLSTimeLayout, expires)
+	if err != nil {
+		return nil
+	}
+	// 旧版 agent 只在证书校验通过时回传证书信息
+	return &TLSCertificateInfo{
+		Chain:      []TLSCertificate{{Issuer: issuer, NotAfter: notAfter}},
+		ChainValid: true,
+	}
+}
+
+// Leaf 站点证书
+func (c *TLSCertificateInfo) Leaf() *TLSCertificate {
+	if c == nil || len(c.Chain) == 0 {
+		return nil
+	}
+	return &c.Chain[0]
+}
+
+// DaysLeft 站点证书剩余的天数，已过期时为负数
+func (c *TLSCertificateInfo) DaysLeft(now time.Time) int {
+	leaf := c.Leaf()
+	if leaf == nil {
+		return 0
+	}
+	return int(leaf.NotAfter.Sub(now).Hours() / 24)
+}
+
+// ReplacedBy 证书是否已更换。颁发者与过期时间都变化才视为更换，
+// 避免多个监测点取得同一颁发者的不同证书时反复提醒
+func (c *TLSCertificateInfo) ReplacedBy(n *TLSCertificateInfo) bool {
+	old, cur := c.Leaf(), n.Leaf()
+	if old == nil || cur == nil {
+		return false
+	}
+	return old.Issuer != cur.Issuer && !old.NotAfter.Equal(cur.NotAfter)
+}
+
+// TLSCertificate 从 HTTP 监控结果中取出证书信息，兼容新版的 HTTPProbeResult 与旧版的 "颁发者|过期时间"
+func (m *Service) TLSCertificate(data string) *TLSCertificateInfo {
+	if m.Type != TaskTypeHTTPGet {
+		return nil
+	}
+	if !strings.HasPrefix(data, "{") {
+		return ParseLegacyTLSCertificate(data)
+	}
+	var r HTTPProbeResult
+	if err := json.Unmarshal([]byte(data), &r); err != nil {
+		return nil
+	}
+	if r.TLS != nil && len(r.TLS.Chain) > 0 {
+		return r.TLS
+	}
+	return ParseLegacyTLSCertificate(r.Cert)
+}
+
+// TLSWarnThreshold 证书剩余时间已低于的最小提醒天数，未低于任何提醒天数时返回 0
+func (m *Service) TLSWarnThreshold(notAfter, now time.Time) uint32 {
+	days := m.TLSWarnDays
+	if len(days) == 0 {
+		days = DefaultTLSWarnDays
+	}
+	var threshold uint32
+	for _, d := range days {
+		if notAfter.Before(now.AddDate(0, 0, int(d))) && (threshold == 0 || d < threshold) {
+			threshold = d
+		}
+	}
+	return threshold
+}
+
+// ValidateTLSWarnDays 校验证书到期提醒天数
+func ValidateTLSWarnDays(days []uint32) error {
+	if len(days) > 10 {
+		return fmt.Errorf("at most 10 tls warning thresholds are allowed")
+	}
+	for _, d := range days {
+		if d == 0 || d > TLSMaxWarnDays {
+			return fmt.Errorf("tls warning threshold must be between 1 and %d days", TLSMaxWarnDays)
+		}
+	}
+	return nil
+}
+
+// ServiceCertificate 服务监控最近一次获取到的证书
+type ServiceCertificate struct {
+	ServiceID   uint64              `json:"service_id"`
+	ServiceName string              `json:"service_name"`
+	Target      string              `json:"target"`
+	ReporterID  uint64              `json:"reporter_id"` // 0 为面板自身
+	CheckedAt   time.Time           `json:"checked_at"`
+	DaysLeft    int                 `json:"days_left"`
+	Certificate *TLSCertificateInfo `json:"certificate"`
+}
@@ -0,0 +1,145 @@
+package model
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+func TestParseLegacyTLSCertificate(t *testing.T) {
+	info := ParseLegacyTLSCertificate("R3|2030-01-02 15:04:05 +0000 UTC")
+	if info == nil || !info.ChainValid {
+		t.Fatalf("expected legacy cert to be parsed, got %+v", info)
+	}
+	if leaf := info.Leaf(); leaf.Issuer != "R3" || !leaf.NotAfter.Equal(time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Fatalf("unexpected leaf %+v", leaf)
+	}
+	for _, data := range []string{"", "dial tcp: i/o timeout", "a|b", "SSL证书错误：x509: certificate has expired"} {
+		if info := ParseLegacyTLSCertificate(data); info != nil {
+			t.Errorf("expected %q not to be parsed, got %+v", data, info)
+		}
+	}
+}
+
+func TestServiceTLSCertificate(t *testing.T) {
+	s := &Service{Type: TaskTypeHTTPGet}
+	if leaf := s.TLSCertificate("R3|2030-01-02 15:04:05 +0000 UTC").Leaf(); leaf == nil || leaf.Issuer != "R3" {
+		t.Fatalf("expected legacy result to carry cert, got %+v", leaf)
+	}
+	if leaf := s.TLSCertificate(`{"status_code":200,"cert":"R3|2030-01-02 15:04:05 +0000 UTC"}`).Leaf(); leaf == nil || leaf.Issuer != "R3" {
+		t.Fatalf("expected cert summary to be used without structured info, got %+v", leaf)
+	}
+
+	structured := HTTPProbeResult{
+		Error: "SSL证书错误：x509: certificate signed by unknown authority",
+		TLS: &TLSCertificateInfo{
+			Chain:      []TLSCertificate{{Subject: "example.com", Issuer: "Evil CA", SerialNumber: "01"}},
+			ChainError: "x509: certificate signed by unknown authority",
+		},
+	}
+	data, _ := json.Marshal(structured)
+	info := s.TLSCertificate(string(data))
+	if info == nil || info.ChainValid || info.Leaf().Subject != "example.com" {
+		t.Fatalf("expected structured cert info, got %+v", info)
+	}
+
+	s.Type = TaskTypeDNS
+	if info := s.TLSCertificate(`"v=spf1|2030-01-02 15:04:05 +0000 UTC"`); info != nil {
+		t.Fatalf("expected no cert for dns monitor, got %+v", info)
+	}
+}
+
+func TestServiceTLSWarnThreshold(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := &Service{}
+	cases := map[time.Duration]uint32{
+		60 * 24 * time.Hour: 0,
+		20 * 24 * time.Hour: 30,
+		10 * 24 * time.Hour: 14,
+		3 * 24 * time.Hour:  7,
+		time.Hour:           1,
+		-time.Hour:          1,
+	}
+	for left, want := range cases {
+		if got := s.TLSWarnThreshold(now.Add(left), now); got != want {
+			t.Errorf("%v left: expected threshold %d, got %d", left, want, got)
+		}
+	}
+
+	s.TLSWarnDays = []uint32{45, 3}
+	if got := s.TLSWarnThreshold(now.Add(20*24*time.Hour), now); got != 45 {
+		t.Fatalf("expected custom threshold 45, got %d", got)
+	}
+	if err := ValidateTLSWarnDays([]uint32{0}); err == nil {
+		t.Fatal("expected zero threshold to be rejected")
+	}
+	if err := ValidateTLSWarnDays([]uint32{TLSMaxWarnDays + 1}); err == nil {
+		t.Fatal("expected threshold above limit to be rejected")
+	}
+}
+
+func TestTLSCertificateInfoReplacedBy(t *testing.T) {
+	expires := time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC)
+	old := &TLSCertificateInfo{Chain: []TLSCertificate{{Issuer: "R3", NotAfter: expires}}}
+	if old.ReplacedBy(&TLSCertificateInfo{Chain: []TLSCertificate{{Issuer: "R3", NotAfter: expires.AddDate(0, 3, 0)}}}) {
+		t.Fatal("same issuer should not count as replaced")
+	}
+	if !old.ReplacedBy(&TLSCertificateInfo{Chain: []TLSCertificate{{Issuer: "E5", NotAfter: expires.AddDate(0, 3, 0)}}}) {
+		t.Fatal("expected new issuer and expiry to count as replaced")
+	}
+	if old.ReplacedBy(nil) {
+		t.Fatal("missing cert should not count as replaced")
+	}
+}
+
+func TestNewTLSCertificateInfo(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(0xbeef),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		Issuer:       pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com", "www.example.com"},
+		IPAddresses:  []net.IP{net.ParseIP("192.0.2.1")},
+		NotBefore:    time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2030, 4, 1, 0, 0, 0, 0, time.UTC),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if NewTLSCertificateInfo(&tls.ConnectionState{}, nil) != nil {
+		t.Fatal("expected nil info without peer certificates")
+	}
+	info := NewTLSCertificateInfo(&tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}, errors.New("unknown authority"))
+	leaf := info.Leaf()
+	if info.ChainValid || info.ChainError != "unknown authority" || info.OCSPStapled {
+		t.Fatalf("unexpected chain state %+v", info)
+	}
+	if leaf.Subject != "example.com" || leaf.SerialNumber != "BEEF" || !leaf.NotAfter.Equal(template.NotAfter) {
+		t.Fatalf("unexpected leaf %+v", leaf)
+	}
+	if len(leaf.SANs) != 3 || leaf.SANs[2] != "192.0.2.1" {
+		t.Fatalf("unexpected SANs %v", leaf.SANs)
+	}
+	if days := info.DaysLeft(template.NotAfter.Add(-72 * time.Hour)); days != 3 {
+		t.Fatalf("expected 3 days left, got %d", days)
+	}
+}
@@ -7,7 +7,7 @@ import (
 )
 
 func TestServiceMonitorTypeAllowlist(t *testing.T) {
-	for _, taskType := range []uint64{TaskTypeHTTPGet, TaskTypeICMPPing, TaskTypeTCPPing} {
+	for _, taskType := range []uint64{TaskTypeHTTPGet, TaskTypeICMPPing, TaskTypeTCPPing, TaskTypeDNS} {
 		require.True(t, IsServiceMonitorType(taskType), "probe type %d must remain allowed", taskType)
 		require.NoError(t, ValidateServiceMonitorType(taskType))
 		require.True(t, IsServiceSentinelNeeded(taskType))
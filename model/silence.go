@@ -0,0 +1,74 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Silence 人工静默：在 ExpiresAt 之前抑制命中的通知。AlertRuleID / ServerID /
+// ServiceID / ServerGroupID 为 0 表示不限，非 0 的条件需同时满足；至少指定一项。
+type Silence struct {
+	Common
+	AlertRuleID   uint64    `json:"alert_rule_id,omitempty"`
+	ServerID      uint64    `json:"server_id,omitempty"`
+	ServiceID     uint64    `json:"service_id,omitempty"`
+	ServerGroupID uint64    `json:"server_group_id,omitempty"`
+	IncidentID    uint64    `json:"incident_id,omitempty"` // 由确认报警事件创建时关联的事件
+	Reason        string    `json:"reason"`
+	ExpiresAt     time.Time `gorm:"index" json:"expires_at"`
+}
+
+// NotificationSubject 描述一条通知关联的对象，用于匹配 Silence
+type NotificationSubject struct {
+	AlertRuleID    uint64
+	ServerID       uint64
+	ServiceID      uint64
+	ServerGroupIDs []uint64
+}
+
+// IsActive 静默尚未过期
+func (s *Silence) IsActive(now time.Time) bool {
+	return now.Before(s.ExpiresAt)
+}
+
+// Matches 判断通知是否被该静默覆盖
+func (s *Silence) Matches(subject NotificationSubject) bool {
+	if s.AlertRuleID == 0 && s.ServerID == 0 && s.ServiceID == 0 && s.ServerGroupID == 0 {
+		return false
+	}
+	if s.AlertRuleID != 0 && s.AlertRuleID != subject.AlertRuleID {
+		return false
+	}
+	if s.ServerID != 0 && s.ServerID != subject.ServerID {
+		return false
+	}
+	if s.ServiceID != 0 && s.ServiceID != subject.ServiceID {
+		return false
+	}
+	if s.ServerGroupID != 0 && !slices.Contains(subject.ServerGroupIDs, s.ServerGroupID) {
+		return false
+	}
+	return true
+}
+
+// HasPermission 在 owner/admin 之上叠加 PAT 的 server_ids 白名单：
+// 受限 PAT 只能看到 / 删除限定在白名单内某台服务器上的静默。
+func (s *Silence) HasPermission(ctx *gin.Context) bool {
+	if !s.Common.HasPermission(ctx) {
+		return false
+	}
+	v, ok := ctx.Get(CtxKeyAPIToken)
+	if !ok {
+		return true
+	}
+	tok, ok := v.(APITokenAccessor)
+	if !ok || tok == nil {
+		return true
+	}
+	if wl, ok := tok.(APITokenWhitelistView); ok && len(wl.ServerIDs()) == 0 {
+		return true
+	}
+	return s.ServerID != 0 && tok.CanAccessServer(s.ServerID)
+}
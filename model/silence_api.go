@@ -0,0 +1,17 @@
+package model
+
+const SilenceMaxDuration = 30 * 24 * 60 * 60 // 静默时长上限 (秒)
+
+type SilenceForm struct {
+	AlertRuleID   uint64 `json:"alert_rule_id,omitempty" validate:"optional"`
+	ServerID      uint64 `json:"server_id,omitempty" validate:"optional"`
+	ServiceID     uint64 `json:"service_id,omitempty" validate:"optional"`
+	ServerGroupID uint64 `json:"server_group_id,omitempty" validate:"optional"`
+	Reason        string `json:"reason,omitempty" validate:"optional"`
+	Duration      uint64 `json:"duration,omitempty"` // 静默时长 (秒)
+}
+
+type IncidentAcknowledgeForm struct {
+	Reason   string `json:"reason,omitempty" validate:"optional"`
+	Duration uint64 `json:"duration,omitempty"` // 确认后静默该报警事件的时长 (秒)
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSilenceMatches(t *testing.T) {
+	subject := NotificationSubject{AlertRuleID: 1, ServerID: 2, ServerGroupIDs: []uint64{5}}
+
+	cases := []struct {
+		msg     string
+		silence Silence
+		exp     bool
+	}{
+		{"Empty", Silence{}, false},
+		{"Alert", Silence{AlertRuleID: 1}, true},
+		{"AlertAndServer", Silence{AlertRuleID: 1, ServerID: 2}, true},
+		{"OtherServer", Silence{AlertRuleID: 1, ServerID: 3}, false},
+		{"Group", Silence{ServerGroupID: 5}, true},
+		{"OtherGroup", Silence{ServerGroupID: 6}, false},
+		{"Service", Silence{ServiceID: 9}, false},
+	}
+	for _, c := range cases {
+		assertEq(t, c.msg, c.exp, c.silence.Matches(subject))
+	}
+
+	now := time.Now()
+	s := Silence{ServerID: 2, ExpiresAt: now.Add(time.Minute)}
+	assertEq(t, "Active", true, s.IsActive(now))
+	assertEq(t, "Expired", false, s.IsActive(now.Add(2*time.Minute)))
+}
@@ -0,0 +1,624 @@
+// Package ruleexpr 实现告警规则使用的受限表达式语言。
+//
+// 语言只包含数值/布尔字面量、变量、四则运算、比较、逻辑运算以及少量白名单
+// 函数（min/max/abs），没有循环、赋值或任何外部访问能力；求值开销与表达式
+// 长度线性相关，并在编译阶段限制源码长度、节点数与嵌套深度，保证用户提交的
+// 规则无法拖垮告警协程。
+//
+//	cpu > 90 && load5 > cores * 2
+//	disk_free_bytes < 10GiB || !(memory < 95)
+package ruleexpr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	MaxSourceLength = 1024
+	MaxNodes        = 256
+	MaxDepth        = 32
+)
+
+var (
+	ErrEmpty          = errors.New("ruleexpr: empty expression")
+	ErrTooLong        = fmt.Errorf("ruleexpr: expression longer than %d bytes", MaxSourceLength)
+	ErrTooComplex     = fmt.Errorf("ruleexpr: expression exceeds %d nodes or %d levels of nesting", MaxNodes, MaxDepth)
+	ErrDivisionByZero = errors.New("ruleexpr: division by zero")
+)
+
+// Env 是求值时可用的变量表。
+type Env map[string]float64
+
+// Program 是编译后的表达式，可并发只读地重复求值。
+type Program struct {
+	src  string
+	root node
+	vars []string
+}
+
+// Compile 解析并类型检查表达式，顶层结果必须是布尔值。
+func Compile(src string) (*Program, error) {
+	src = strings.TrimSpace(src)
+	if src == "" {
+		return nil, ErrEmpty
+	}
+	if len(src) > MaxSourceLength {
+		return nil, ErrTooLong
+	}
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	root, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("ruleexpr: unexpected %q at offset %d", tok.text, tok.pos)
+	}
+	if root.kind() != kindBool {
+		return nil, errors.New("ruleexpr: expression must evaluate to a boolean")
+	}
+	vars := make([]string, 0, len(p.vars))
+	for v := range p.vars {
+		vars = append(vars, v)
+	}
+	slices.Sort(vars)
+	return &Program{src: src, root: root, vars: vars}, nil
+}
+
+// Source 返回编译时使用的（去除首尾空白的）源码。
+func (p *Program) Source() string {
+	return p.src
+}
+
+// Variables 返回表达式引用的全部变量名（已排序去重）。
+func (p *Program) Variables() []string {
+	return slices.Clone(p.vars)
+}
+
+// Eval 对 env 求值。引用了 env 中不存在的变量或出现除零时返回错误。
+func (p *Program) Eval(env Env) (bool, error) {
+	v, err := p.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	return v.b, nil
+}
+
+type valueKind uint8
+
+const (
+	kindNumber valueKind = iota
+	kindBool
+)
+
+type value struct {
+	n float64
+	b bool
+}
+
+type node interface {
+	kind() valueKind
+	eval(Env) (value, error)
+}
+
+type numberNode float64
+
+func (numberNode) kind() valueKind { return kindNumber }
+func (n numberNode) eval(Env) (value, error) {
+	return value{n: float64(n)}, nil
+}
+
+type boolNode bool
+
+func (boolNode) kind() valueKind { return kindBool }
+func (n boolNode) eval(Env) (value, error) {
+	return value{b: bool(n)}, nil
+}
+
+type varNode string
+
+func (varNode) kind() valueKind { return kindNumber }
+func (n varNode) eval(env Env) (value, error) {
+	v, ok := env[string(n)]
+	if !ok {
+		return value{}, fmt.Errorf("ruleexpr: variable %q is not available", string(n))
+	}
+	return value{n: v}, nil
+}
+
+type unaryNode struct {
+	op string
+	x  node
+}
+
+func (n *unaryNode) kind() valueKind {
+	if n.op == "!" {
+		return kindBool
+	}
+	return kindNumber
+}
+
+func (n *unaryNode) eval(env Env) (value, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return value{}, err
+	}
+	if n.op == "!" {
+		return value{b: !x.b}, nil
+	}
+	return value{n: -x.n}, nil
+}
+
+type binaryNode struct {
+	op   string
+	l, r node
+}
+
+func (n *binaryNode) kind() valueKind {
+	switch n.op {
+	case "+", "-", "*", "/", "%":
+		return kindNumber
+	default:
+		return kindBool
+	}
+}
+
+func (n *binaryNode) eval(env Env) (value, error) {
+	l, err := n.l.eval(env)
+	if err != nil {
+		return value{}, err
+	}
+	// 逻辑运算短路求值
+	switch n.op {
+	case "&&":
+		if !l.b {
+			return value{}, nil
+		}
+	case "||":
+		if l.b {
+			return value{b: true}, nil
+		}
+	}
+	r, err := n.r.eval(env)
+	if err != nil {
+		return value{}, err
+	}
+	switch n.op {
+	case "&&", "||":
+		return value{b: r.b}, nil
+	case "+":
+		return value{n: l.n + r.n}, nil
+	case "-":
+		return value{n: l.n - r.n}, nil
+	case "*":
+		return value{n: l.n * r.n}, nil
+	case "/":
+		if r.n == 0 {
+			return value{}, ErrDivisionByZero
+		}
+		return value{n: l.n / r.n}, nil
+	case "%":
+		if r.n == 0 {
+			return value{}, ErrDivisionByZero
+		}
+		return value{n: math.Mod(l.n, r.n)}, nil
+	case ">":
+		return value{b: l.n > r.n}, nil
+	case ">=":
+		return value{b: l.n >= r.n}, nil
+	case "<":
+		return value{b: l.n < r.n}, nil
+	case "<=":
+		return value{b: l.n <= r.n}, nil
+	case "==":
+		if n.l.kind() == kindBool {
+			return value{b: l.b == r.b}, nil
+		}
+		return value{b: l.n == r.n}, nil
+	case "!=":
+		if n.l.kind() == kindBool {
+			return value{b: l.b != r.b}, nil
+		}
+		return value{b: l.n != r.n}, nil
+	}
+	return value{}, fmt.Errorf("ruleexpr: unknown operator %q", n.op)
+}
+
+type callNode struct {
+	fn   string
+	args []node
+}
+
+func (*callNode) kind() valueKind { return kindNumber }
+
+func (n *callNode) eval(env Env) (value, error) {
+	args := make([]float64, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(env)
+		if err != nil {
+			return value{}, err
+		}
+		args[i] = v.n
+	}
+	switch n.fn {
+	case "abs":
+		return value{n: math.Abs(args[0])}, nil
+	case "min":
+		return value{n: slices.Min(args)}, nil
+	case "max":
+		return value{n: slices.Max(args)}, nil
+	}
+	return value{}, fmt.Errorf("ruleexpr: unknown function %q", n.fn)
+}
+
+// functions 列出允许调用的函数及其最少参数个数；abs 只接受一个参数。
+var functions = map[string]int{
+	"abs": 1,
+	"min": 1,
+	"max": 1,
+}
+
+// units 为数值字面量支持的容量后缀，十进制与二进制单位均可，大小写不敏感。
+var units = map[string]float64{
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"m":   1e6,
+	"mb":  1e6,
+	"g":   1e9,
+	"gb":  1e9,
+	"t":   1e12,
+	"tb":  1e12,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+type tokenKind uint8
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9' || c == '.':
+			start := i
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			n, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("ruleexpr: invalid number %q at offset %d", src[start:i], start)
+			}
+			unitStart := i
+			for i < len(src) && isLetter(src[i]) {
+				i++
+			}
+			if unitStart != i {
+				mul, ok := units[strings.ToLower(src[unitStart:i])]
+				if !ok {
+					return nil, fmt.Errorf("ruleexpr: unknown unit %q at offset %d", src[unitStart:i], unitStart)
+				}
+				n *= mul
+			}
+			toks = append(toks, token{kind: tokNumber, text: src[start:i], num: n, pos: start})
+		case isLetter(c):
+			start := i
+			for i < len(src) && (isLetter(src[i]) || src[i] >= '0' && src[i] <= '9') {
+				i++
+			}
+			toks = append(toks, token{kind: tokIdent, text: src[start:i], pos: start})
+		case c == '(':
+			toks = append(toks, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			toks = append(toks, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case c == ',':
+			toks = append(toks, token{kind: tokComma, text: ",", pos: i})
+			i++
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", ">=", "<=", "==", "!=", ">", "<", "!", "+", "-", "*", "/", "%"} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("ruleexpr: unexpected character %q at offset %d", rune(c), i)
+			}
+			toks = append(toks, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(toks, token{kind: tokEOF, text: "end of expression", pos: len(src)}), nil
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || c < unicode.MaxASCII && unicode.IsLetter(rune(c))
+}
+
+type parser struct {
+	toks  []token
+	pos   int
+	nodes int
+	vars  map[string]struct{}
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.toks[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) acceptOp(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind == tokOp && slices.Contains(ops, tok.text) {
+		p.pos++
+		return tok.text, true
+	}
+	return "", false
+}
+
+func (p *parser) grow(depth int) error {
+	p.nodes++
+	if p.nodes > MaxNodes || depth > MaxDepth {
+		return ErrTooComplex
+	}
+	return nil
+}
+
+func (p *parser) binary(op string, l, r node, depth int) (node, error) {
+	if err := p.grow(depth); err != nil {
+		return nil, err
+	}
+	switch op {
+	case "&&", "||":
+		if l.kind() != kindBool || r.kind() != kindBool {
+			return nil, fmt.Errorf("ruleexpr: operator %s requires boolean operands", op)
+		}
+	case "==", "!=":
+		if l.kind() != r.kind() {
+			return nil, fmt.Errorf("ruleexpr: operator %s compares mismatched types", op)
+		}
+	default:
+		if l.kind() != kindNumber || r.kind() != kindNumber {
+			return nil, fmt.Errorf("ruleexpr: operator %s requires numeric operands", op)
+		}
+	}
+	return &binaryNode{op: op, l: l, r: r}, nil
+}
+
+func (p *parser) parseOr(depth int) (node, error) {
+	l, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("||")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		if l, err = p.binary(op, l, r, depth); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseAnd(depth int) (node, error) {
+	l, err := p.parseNot(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("&&")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseNot(depth)
+		if err != nil {
+			return nil, err
+		}
+		if l, err = p.binary(op, l, r, depth); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseNot(depth int) (node, error) {
+	if _, ok := p.acceptOp("!"); ok {
+		if err := p.grow(depth + 1); err != nil {
+			return nil, err
+		}
+		x, err := p.parseNot(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if x.kind() != kindBool {
+			return nil, errors.New("ruleexpr: operator ! requires a boolean operand")
+		}
+		return &unaryNode{op: "!", x: x}, nil
+	}
+	return p.parseCompare(depth)
+}
+
+func (p *parser) parseCompare(depth int) (node, error) {
+	l, err := p.parseAdd(depth)
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.acceptOp(">", ">=", "<", "<=", "==", "!=")
+	if !ok {
+		return l, nil
+	}
+	r, err := p.parseAdd(depth)
+	if err != nil {
+		return nil, err
+	}
+	return p.binary(op, l, r, depth)
+}
+
+func (p *parser) parseAdd(depth int) (node, error) {
+	l, err := p.parseMul(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("+", "-")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseMul(depth)
+		if err != nil {
+			return nil, err
+		}
+		if l, err = p.binary(op, l, r, depth); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseMul(depth int) (node, error) {
+	l, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("*", "/", "%")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		if l, err = p.binary(op, l, r, depth); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseUnary(depth int) (node, error) {
+	if _, ok := p.acceptOp("-"); ok {
+		if err := p.grow(depth + 1); err != nil {
+			return nil, err
+		}
+		x, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if x.kind() != kindNumber {
+			return nil, errors.New("ruleexpr: unary - requires a numeric operand")
+		}
+		return &unaryNode{op: "-", x: x}, nil
+	}
+	return p.parsePrimary(depth)
+}
+
+func (p *parser) parsePrimary(depth int) (node, error) {
+	if err := p.grow(depth); err != nil {
+		return nil, err
+	}
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		return numberNode(tok.num), nil
+	case tokLParen:
+		x, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokRParen {
+			return nil, fmt.Errorf("ruleexpr: missing ) for ( at offset %d", tok.pos)
+		}
+		return x, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return boolNode(true), nil
+		case "false":
+			return boolNode(false), nil
+		}
+		if p.peek().kind == tokLParen {
+			return p.parseCall(tok, depth)
+		}
+		if p.vars == nil {
+			p.vars = make(map[string]struct{})
+		}
+		p.vars[tok.text] = struct{}{}
+		return varNode(tok.text), nil
+	}
+	return nil, fmt.Errorf("ruleexpr: unexpected %q at offset %d", tok.text, tok.pos)
+}
+
+func (p *parser) parseCall(fn token, depth int) (node, error) {
+	minArgs, ok := functions[fn.text]
+	if !ok {
+		return nil, fmt.Errorf("ruleexpr: unknown function %q", fn.text)
+	}
+	p.next() // (
+	var args []node
+	if p.peek().kind != tokRParen {
+		for {
+			arg, err := p.parseAdd(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if arg.kind() != kindNumber {
+				return nil, fmt.Errorf("ruleexpr: %s() requires numeric arguments", fn.text)
+			}
+			args = append(args, arg)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if p.next().kind != tokRParen {
+		return nil, fmt.Errorf("ruleexpr: missing ) for %s( at offset %d", fn.text, fn.pos)
+	}
+	if len(args) < minArgs || fn.text == "abs" && len(args) != 1 {
+		return nil, fmt.Errorf("ruleexpr: wrong number of arguments for %s()", fn.text)
+	}
+	return &callNode{fn: fn.text, args: args}, nil
+}
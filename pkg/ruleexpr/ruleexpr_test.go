@@ -0,0 +1,100 @@
+package ruleexpr
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	env := Env{
+		"cpu":             95,
+		"load5":           9,
+		"cores":           4,
+		"disk_free_bytes": 5 << 30,
+		"memory":          40,
+	}
+
+	cases := []struct {
+		src  string
+		want bool
+	}{
+		{"cpu > 90 && load5 > cores*2", true},
+		{"cpu > 90 && load5 > cores*3", false},
+		{"disk_free_bytes < 10GiB", true},
+		{"disk_free_bytes < 5GiB", false},
+		{"disk_free_bytes <= 5gib", true},
+		{"!(memory < 95)", false},
+		{"memory >= 40 || cpu / 0 > 1", true}, // 短路：右侧不求值
+		{"max(cpu, memory, 10) == 95", true},
+		{"min(cpu, memory) == 40 && abs(-3) == 3", true},
+		{"-cpu + 100 == 5", true},
+		{"cpu % 10 == 5", true},
+		{"(cpu > 90) == true", true},
+		{"1.5k == 1500", true},
+	}
+	for _, c := range cases {
+		prog, err := Compile(c.src)
+		if err != nil {
+			t.Fatalf("Compile(%q): %v", c.src, err)
+		}
+		got, err := prog.Eval(env)
+		if err != nil {
+			t.Fatalf("Eval(%q): %v", c.src, err)
+		}
+		if got != c.want {
+			t.Errorf("Eval(%q) = %v, want %v", c.src, got, c.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	cases := []string{
+		"",
+		"cpu",
+		"cpu + 1",
+		"cpu > 90 &&",
+		"cpu > 90 && load5",
+		"(cpu > 90",
+		"cpu > 90)",
+		"cpu > 10XB",
+		"exec(1) > 0",
+		"abs(1, 2) > 0",
+		"min() > 0",
+		"cpu > 1 > 0",
+		"!cpu",
+		"-(cpu > 1)",
+		"cpu == (load5 > 1)",
+		"cpu > 'a'",
+		strings.Repeat("(", MaxDepth+1) + "cpu > 1" + strings.Repeat(")", MaxDepth+1),
+		"cpu > " + strings.Repeat("1 + ", MaxNodes) + "1",
+		"cpu > " + strings.Repeat("1", MaxSourceLength),
+	}
+	for _, src := range cases {
+		if _, err := Compile(src); err == nil {
+			t.Errorf("Compile(%q) should fail", src)
+		}
+	}
+}
+
+func TestEvalRuntimeErrors(t *testing.T) {
+	prog, err := Compile("cpu > 1 && load1 > 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(prog.Variables(), []string{"cpu", "load1"}) {
+		t.Fatalf("unexpected variables %v", prog.Variables())
+	}
+	if _, err := prog.Eval(Env{"cpu": 2}); err == nil {
+		t.Fatal("missing variable should fail evaluation")
+	}
+
+	prog, err = Compile("cpu / load1 > 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := prog.Eval(Env{"cpu": 2, "load1": 0}); !errors.Is(err, ErrDivisionByZero) {
+		t.Fatalf("expected ErrDivisionByZero, got %v", err)
+	}
+}
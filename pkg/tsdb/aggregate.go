@@ -0,0 +1,181 @@
+package tsdb
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+// Aggregation 时间窗口聚合方式
+type Aggregation string
+
+const (
+	AggregationAvg      Aggregation = "avg"
+	AggregationMin      Aggregation = "min"
+	AggregationMax      Aggregation = "max"
+	AggregationLast     Aggregation = "last"
+	AggregationP50      Aggregation = "p50"
+	AggregationP90      Aggregation = "p90"
+	AggregationP95      Aggregation = "p95"
+	AggregationP99      Aggregation = "p99"
+	AggregationIncrease Aggregation = "increase" // 累积型指标在窗口内的增量，自动处理计数器归零
+	AggregationRate     Aggregation = "rate"     // increase 按采样跨度折算的每秒增量
+)
+
+// ParseAggregation 解析聚合方式
+func ParseAggregation(s string) (Aggregation, error) {
+	switch agg := Aggregation(s); agg {
+	case AggregationAvg, AggregationMin, AggregationMax, AggregationLast,
+		AggregationP50, AggregationP90, AggregationP95, AggregationP99,
+		AggregationIncrease, AggregationRate:
+		return agg, nil
+	default:
+		return "", fmt.Errorf("invalid aggregation: %s", s)
+	}
+}
+
+// IsCounter 该聚合方式是否只对累积型指标有意义
+func (a Aggregation) IsCounter() bool {
+	return a == AggregationIncrease || a == AggregationRate
+}
+
+// QueryServerMetricAggregate 对服务器指标在 [now-window, now] 内的原始采样点做聚合。
+// 传入多个指标时按时间戳对齐后求和（如 net_in_speed + net_out_speed）。
+// 窗口内没有足够数据时返回 ok=false（increase / rate 至少需要两个采样点）。
+func (db *TSDB) QueryServerMetricAggregate(serverID uint64, window time.Duration, agg Aggregation, metrics ...MetricType) (float64, bool, error) {
+	return db.QueryServerDeviceMetricAggregate(serverID, "", window, agg, metrics...)
+}
+
+// QueryServerDeviceMetricAggregate 与 QueryServerMetricAggregate 相同，按设备区分的指标只聚合 device 的序列
+func (db *TSDB) QueryServerDeviceMetricAggregate(serverID uint64, device string, window time.Duration, agg Aggregation, metrics ...MetricType) (float64, bool, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.closed {
+		return 0, false, fmt.Errorf("TSDB is closed")
+	}
+	if window <= 0 {
+		return 0, false, fmt.Errorf("invalid window: %s", window)
+	}
+
+	now := time.Now()
+	tr := storage.TimeRange{
+		MinTimestamp: now.Add(-window).UnixMilli(),
+		MaxTimestamp: now.UnixMilli(),
+	}
+
+	var points []rawDataPoint
+	for i, metric := range metrics {
+		series, err := db.queryServerMetricPoints(serverID, metric, device, tr)
+		if err != nil {
+			return 0, false, err
+		}
+		if i == 0 {
+			points = series
+		} else {
+			points = sumAlignedPoints(points, series)
+		}
+	}
+
+	value, ok := aggregatePoints(points, agg)
+	return value, ok, nil
+}
+
+// sumAlignedPoints 只保留两组采样都存在的时间戳，并把对应值相加
+func sumAlignedPoints(a, b []rawDataPoint) []rawDataPoint {
+	values := make(map[int64]float64, len(b))
+	for _, p := range b {
+		values[p.timestamp] = p.value
+	}
+	result := make([]rawDataPoint, 0, len(a))
+	for _, p := range a {
+		if v, ok := values[p.timestamp]; ok {
+			result = append(result, rawDataPoint{timestamp: p.timestamp, value: p.value + v})
+		}
+	}
+	return result
+}
+
+func aggregatePoints(points []rawDataPoint, agg Aggregation) (float64, bool) {
+	if len(points) == 0 {
+		return 0, false
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].timestamp < points[j].timestamp
+	})
+
+	switch agg {
+	case AggregationAvg:
+		var total float64
+		for _, p := range points {
+			total += p.value
+		}
+		return total / float64(len(points)), true
+	case AggregationMin:
+		v := math.Inf(1)
+		for _, p := range points {
+			v = min(v, p.value)
+		}
+		return v, true
+	case AggregationMax:
+		v := math.Inf(-1)
+		for _, p := range points {
+			v = max(v, p.value)
+		}
+		return v, true
+	case AggregationLast:
+		return points[len(points)-1].value, true
+	case AggregationP50:
+		return percentile(points, 0.50), true
+	case AggregationP90:
+		return percentile(points, 0.90), true
+	case AggregationP95:
+		return percentile(points, 0.95), true
+	case AggregationP99:
+		return percentile(points, 0.99), true
+	case AggregationIncrease, AggregationRate:
+		if len(points) < 2 {
+			return 0, false
+		}
+		var increase float64
+		for i := 1; i < len(points); i++ {
+			delta := points[i].value - points[i-1].value
+			if delta < 0 {
+				// 计数器归零（Agent 重启），归零后的值即为新增量
+				delta = points[i].value
+			}
+			increase += delta
+		}
+		if agg == AggregationIncrease {
+			return increase, true
+		}
+		span := float64(points[len(points)-1].timestamp-points[0].timestamp) / 1000
+		if span <= 0 {
+			return 0, false
+		}
+		return increase / span, true
+	default:
+		return 0, false
+	}
+}
+
+// percentile 线性插值计算分位数，q 取值 [0, 1]
+func percentile(points []rawDataPoint, q float64) float64 {
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.value
+	}
+	slices.Sort(values)
+
+	pos := q * float64(len(values)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	if lower == upper {
+		return values[lower]
+	}
+	return values[lower] + (values[upper]-values[lower])*(pos-float64(lower))
+}
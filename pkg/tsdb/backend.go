@@ -0,0 +1,199 @@
+package tsdb
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+const (
+	// BackendLocal 嵌入式 VictoriaMetrics 存储
+	BackendLocal = "local"
+	// BackendRemote 通过 Prometheus remote write 写入外部存储，并通过其 Prometheus HTTP API 读回
+	BackendRemote = "remote"
+)
+
+// seriesFilters 序列选择条件，外层各组之间为 or，组内各条件为 and，与 metricsql.MetricExpr.LabelFilterss 相同
+type seriesFilters [][]metricsql.LabelFilter
+
+// sample 一个待写入的采样点
+type sample struct {
+	labels    []prompb.Label
+	timestamp int64 // 毫秒
+	value     float64
+}
+
+// backend 存储后端，TSDB 的读写都经由它完成，调用方需持有 TSDB 的读锁
+type backend interface {
+	add(samples []sample)
+	// search 读取 tr 内匹配 filters 的原始采样点，每个序列的采样点按时间排序
+	search(filters seriesFilters, tr storage.TimeRange, maxSeries int, deadline uint64) ([]*rawSeries, error)
+	seriesLabels(filters seriesFilters, tr storage.TimeRange, deadline uint64) ([]map[string]string, error)
+	labelNames(filters seriesFilters, tr storage.TimeRange, deadline uint64) ([]string, error)
+	labelValues(name string, filters seriesFilters, tr storage.TimeRange, deadline uint64) ([]string, error)
+	flush()
+	close()
+}
+
+// metricFilters 按指标名与一个标签精确匹配
+func metricFilters(metric MetricType, label, value string) seriesFilters {
+	return seriesFilters{{
+		{Label: "__name__", Value: string(metric)},
+		{Label: label, Value: value},
+	}}
+}
+
+// localBackend 嵌入式 VictoriaMetrics 存储
+type localBackend struct {
+	storage *storage.Storage
+}
+
+func (b *localBackend) add(samples []sample) {
+	rows := make([]storage.MetricRow, len(samples))
+	for i, s := range samples {
+		rows[i] = storage.MetricRow{
+			MetricNameRaw: storage.MarshalMetricNameRaw(nil, s.labels),
+			Timestamp:     s.timestamp,
+			Value:         s.value,
+		}
+	}
+	b.storage.AddRows(rows, 64)
+}
+
+func (b *localBackend) search(filters seriesFilters, tr storage.TimeRange, maxSeries int, deadline uint64) ([]*rawSeries, error) {
+	tfss, err := tagFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var search storage.Search
+	search.Init(nil, b.storage, tfss, tr, maxSeries, deadline)
+	defer search.MustClose()
+
+	byName := make(map[string]*rawSeries)
+	var result []*rawSeries
+	var timestamps []int64
+	var values []float64
+
+	for search.NextMetricBlock() {
+		mbr := search.MetricBlockRef
+		rs, ok := byName[string(mbr.MetricName)]
+		if !ok {
+			var mn storage.MetricName
+			if err := mn.Unmarshal(mbr.MetricName); err != nil {
+				log.Printf("NEZHA>> TSDB: failed to unmarshal metric name: %v", err)
+				continue
+			}
+			rs = &rawSeries{labels: metricNameLabels(&mn)}
+			byName[string(mbr.MetricName)] = rs
+			result = append(result, rs)
+		}
+
+		var block storage.Block
+		mbr.BlockRef.MustReadBlock(&block)
+		if err := block.UnmarshalData(); err != nil {
+			log.Printf("NEZHA>> TSDB: failed to unmarshal block data: %v", err)
+			continue
+		}
+		timestamps = timestamps[:0]
+		values = values[:0]
+		timestamps, values = block.AppendRowsWithTimeRangeFilter(timestamps, values, tr)
+		for i := range timestamps {
+			rs.points = append(rs.points, rawDataPoint{timestamp: timestamps[i], value: values[i]})
+		}
+	}
+	if err := search.Error(); err != nil {
+		return nil, err
+	}
+
+	for _, rs := range result {
+		sort.Slice(rs.points, func(i, j int) bool {
+			return rs.points[i].timestamp < rs.points[j].timestamp
+		})
+	}
+	return result, nil
+}
+
+func (b *localBackend) seriesLabels(filters seriesFilters, tr storage.TimeRange, deadline uint64) ([]map[string]string, error) {
+	tfss, err := tagFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+	names, err := b.storage.SearchMetricNames(nil, tfss, tr, PromMaxSeries, deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]string, 0, len(names))
+	var mn storage.MetricName
+	for _, name := range names {
+		if err := mn.UnmarshalString(name); err != nil {
+			log.Printf("NEZHA>> TSDB: failed to unmarshal metric name: %v", err)
+			continue
+		}
+		result = append(result, metricNameLabels(&mn))
+	}
+	return result, nil
+}
+
+func (b *localBackend) labelNames(filters seriesFilters, tr storage.TimeRange, deadline uint64) ([]string, error) {
+	tfss, err := tagFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+	return b.storage.SearchLabelNames(nil, tfss, tr, PromMaxLabels, PromMaxSeries, deadline)
+}
+
+func (b *localBackend) labelValues(name string, filters seriesFilters, tr storage.TimeRange, deadline uint64) ([]string, error) {
+	tfss, err := tagFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+	return b.storage.SearchLabelValues(nil, name, tfss, tr, PromMaxLabels, PromMaxSeries, deadline)
+}
+
+func (b *localBackend) flush() {
+	b.storage.DebugFlush()
+}
+
+func (b *localBackend) close() {
+	b.storage.MustClose()
+}
+
+// tagFilters 把选择条件转换为 VictoriaMetrics 的标签过滤条件，每组条件对应一个 TagFilters
+func tagFilters(filters seriesFilters) ([]*storage.TagFilters, error) {
+	tfss := make([]*storage.TagFilters, 0, len(filters))
+	for _, lfs := range filters {
+		tfs := storage.NewTagFilters()
+		for _, lf := range lfs {
+			var key []byte
+			if lf.Label != "__name__" {
+				key = []byte(lf.Label)
+			}
+			if err := tfs.Add(key, []byte(lf.Value), lf.IsNegative, lf.IsRegexp); err != nil {
+				return nil, fmt.Errorf("invalid label filter %s: %w", lf.AppendString(nil), err)
+			}
+		}
+		tfss = append(tfss, tfs)
+	}
+	return tfss, nil
+}
+
+// selector 把一组条件渲染为 PromQL 选择器，例如 {__name__="nezha_server_cpu",server_id="1"}
+func selector(lfs []metricsql.LabelFilter) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, lf := range lfs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(lf.AppendString(nil))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
@@ -0,0 +1,315 @@
+package tsdb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/goccy/go-json"
+)
+
+const (
+	// ExportFormatCSV 每行一个采样点
+	ExportFormatCSV = "csv"
+	// ExportFormatJSONLines 每行一个序列在一段时间内的采样点，与 VictoriaMetrics 的 /api/v1/export 格式相同
+	ExportFormatJSONLines = "jsonl"
+)
+
+// snapshotChunk 快照与导出按时间分段读取，避免一次性把全部数据读入内存
+const snapshotChunk = 24 * time.Hour
+
+// exportedSeries 一个序列在一段时间内的采样点，即 VictoriaMetrics 的 JSON line 导出格式
+type exportedSeries struct {
+	Metric     map[string]string `json:"metric"`
+	Values     []float64         `json:"values"`
+	Timestamps []int64           `json:"timestamps"`
+}
+
+// BackupStats 快照、恢复与导出处理的数据量
+type BackupStats struct {
+	Series  int `json:"series"` // 按时间分段计数，同一序列在不同分段中各计一次
+	Samples int `json:"samples"`
+	Skipped int `json:"skipped,omitempty"` // 恢复时没有对应降采样层级而跳过的采样点
+}
+
+// ExportOptions 导出单台服务器的数据，包括服务器指标与该服务器作为监测点的服务指标
+type ExportOptions struct {
+	ServerID uint64
+	From     time.Time
+	To       time.Time
+	Format   string
+}
+
+// Snapshot 把开始时已写入的原始数据与各降采样层级的数据以 gzip 压缩的 JSON lines 写入 w。
+// 解压后可直接导入 VictoriaMetrics 的 /api/v1/import，也可以用 Restore 恢复。
+//
+// 快照对应开始时的状态：原始数据包含时间戳不晚于开始时间的采样点，各降采样层级只包含
+// 开始时汇总进度之前的完整汇总桶，读取期间暂停后台汇总。恢复后汇总从快照的进度继续，
+// 与原始数据衔接。
+//
+// 数据先写入临时文件，读取期间持有读锁；写入 w 时已释放读锁，慢速的下载不会阻塞关闭存储
+func (db *TSDB) Snapshot(w io.Writer) (BackupStats, error) {
+	// 临时文件与数据目录放在同一磁盘，避免系统临时目录空间不足
+	var dir string
+	if db.config.DataPath != "" {
+		dir = filepath.Dir(db.config.DataPath)
+	}
+	f, err := os.CreateTemp(dir, "nezha-tsdb-snapshot-*.jsonl.gz")
+	if err != nil {
+		return BackupStats{}, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	stats, err := db.snapshot(f)
+	if err != nil {
+		return stats, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return stats, err
+	}
+	_, err = io.Copy(w, f)
+	return stats, err
+}
+
+// snapshot 持有读锁生成快照并写入 w
+func (db *TSDB) snapshot(w io.Writer) (BackupStats, error) {
+	// 暂停汇总后再刷盘，汇总进度之前的汇总桶都已可以读取
+	db.rollupPassMu.Lock()
+	defer db.rollupPassMu.Unlock()
+
+	// 先把写入缓冲刷入存储，快照包含调用前写入的全部数据
+	db.Flush()
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.closed {
+		return BackupStats{}, fmt.Errorf("TSDB is closed")
+	}
+
+	gw := gzip.NewWriter(w)
+	bw := bufio.NewWriter(gw)
+	var stats BackupStats
+
+	now := time.Now()
+	end := now.UnixMilli()
+	from := now.AddDate(0, 0, -int(db.config.RetentionDays)).UnixMilli()
+	if err := db.exportSeries(bw, db.backend, rawMetricFilters, from, end, snapshotChunk, &stats); err != nil {
+		return stats, err
+	}
+	for _, t := range db.rollups {
+		filters := seriesFilters{{{Label: "__name__", Value: "nezha_[a-z0-9_]+:" + t.name, IsRegexp: true}}}
+		from := now.AddDate(0, 0, -t.RetentionDays).UnixMilli()
+		// 汇总进度（不含）之后的桶尚未汇总或不完整，尚未汇总过时不导出该层级
+		to := min(end, db.rollupWatermark(t)-1)
+		if err := db.exportSeries(bw, t.backend, filters, from, to, max(snapshotChunk, t.Interval*288), &stats); err != nil {
+			return stats, err
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return stats, err
+	}
+	return stats, gw.Close()
+}
+
+// exportSeries 按 chunk 分段读取 b 中匹配 filters 的序列，以 JSON lines 写入 w
+func (db *TSDB) exportSeries(w io.Writer, b backend, filters seriesFilters, from, to int64, chunk time.Duration, stats *BackupStats) error {
+	enc := json.NewEncoder(w)
+	return db.walkSeries(b, filters, from, to, chunk, func(rs *rawSeries) error {
+		line := exportedSeries{
+			Metric:     rs.labels,
+			Values:     make([]float64, len(rs.points)),
+			Timestamps: make([]int64, len(rs.points)),
+		}
+		for i, p := range rs.points {
+			line.Values[i] = p.value
+			line.Timestamps[i] = p.timestamp
+		}
+		stats.Series++
+		stats.Samples += len(rs.points)
+		return enc.Encode(line)
+	})
+}
+
+// walkSeries 按 chunk 分段读取 b 中匹配 filters 的序列，每段内按标签排序后依次调用 fn
+func (db *TSDB) walkSeries(b backend, filters seriesFilters, from, to int64, chunk time.Duration, fn func(rs *rawSeries) error) error {
+	for start := from; start <= to; start += chunk.Milliseconds() {
+		tr := storage.TimeRange{MinTimestamp: start, MaxTimestamp: min(to, start+chunk.Milliseconds()-1)}
+		deadline := uint64(time.Now().Add(time.Minute).Unix())
+		series, err := b.search(filters, tr, rollupMaxSeries, deadline)
+		if err != nil {
+			return err
+		}
+		sort.Slice(series, func(i, j int) bool {
+			return labelsSignature(series[i].labels, nil, false) < labelsSignature(series[j].labels, nil, false)
+		})
+		for _, rs := range series {
+			if len(rs.points) == 0 {
+				continue
+			}
+			if err := fn(rs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Restore 把 Snapshot 生成的快照写入当前存储，降采样序列写入同名的层级，
+// 未启用的层级的数据会被跳过。超出保留期的数据会被存储丢弃。
+// 恢复期间暂停后台汇总，否则汇总会读到只恢复了一部分的原始数据，
+// 写入与快照中汇总数据时间戳相同的桶，去重后只保留其中一个
+func (db *TSDB) Restore(r io.Reader) (BackupStats, error) {
+	db.rollupPassMu.Lock()
+	defer db.rollupPassMu.Unlock()
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.closed {
+		return BackupStats{}, fmt.Errorf("TSDB is closed")
+	}
+
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return BackupStats{}, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	defer gr.Close()
+
+	tiers := make(map[string]*rollupTier, len(db.rollups))
+	for _, t := range db.rollups {
+		tiers[t.name] = t
+	}
+	// 各层级已恢复到的时间，恢复完成后作为汇总进度，避免重复汇总
+	restored := make(map[*rollupTier]int64)
+
+	var stats BackupStats
+	dec := json.NewDecoder(bufio.NewReader(gr))
+	for {
+		var line exportedSeries
+		if err := dec.Decode(&line); err == io.EOF {
+			break
+		} else if err != nil {
+			return stats, fmt.Errorf("invalid snapshot line %d: %w", stats.Series+1, err)
+		}
+		if len(line.Values) != len(line.Timestamps) {
+			return stats, fmt.Errorf("invalid snapshot line %d: %d values but %d timestamps", stats.Series+1, len(line.Values), len(line.Timestamps))
+		}
+		stats.Series++
+
+		target := db.backend
+		name := line.Metric["__name__"]
+		if i := strings.LastIndexByte(name, ':'); i >= 0 {
+			t, ok := tiers[name[i+1:]]
+			if !ok {
+				stats.Skipped += len(line.Values)
+				continue
+			}
+			target = t.backend
+			if len(line.Timestamps) > 0 {
+				restored[t] = max(restored[t], line.Timestamps[len(line.Timestamps)-1]+t.Interval.Milliseconds())
+			}
+		}
+
+		labels := make([]prompb.Label, 0, len(line.Metric))
+		labels = append(labels, prompb.Label{Name: "__name__", Value: name})
+		for k, v := range line.Metric {
+			if k != "__name__" {
+				labels = append(labels, prompb.Label{Name: k, Value: v})
+			}
+		}
+		samples := make([]sample, len(line.Values))
+		for i := range line.Values {
+			samples[i] = sample{labels: labels, timestamp: line.Timestamps[i], value: line.Values[i]}
+		}
+		target.add(samples)
+		stats.Samples += len(samples)
+	}
+
+	for t, ts := range restored {
+		if ts > db.rollupWatermark(t) {
+			if err := db.setRollupWatermark(t, ts); err != nil {
+				return stats, err
+			}
+		}
+	}
+	db.flushBackends()
+	return stats, nil
+}
+
+// Export 把一台服务器在 [From, To] 内的原始数据以 CSV 或 JSON lines 写入 w，供离线分析
+func (db *TSDB) Export(w io.Writer, opts ExportOptions) (BackupStats, error) {
+	if opts.Format != ExportFormatCSV && opts.Format != ExportFormatJSONLines {
+		return BackupStats{}, fmt.Errorf("unsupported export format: %s", opts.Format)
+	}
+	if !opts.From.Before(opts.To) {
+		return BackupStats{}, fmt.Errorf("from must be before to")
+	}
+	db.Flush()
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.closed {
+		return BackupStats{}, fmt.Errorf("TSDB is closed")
+	}
+
+	filters := seriesFilters{{
+		rawMetricFilters[0][0],
+		{Label: "server_id", Value: strconv.FormatUint(opts.ServerID, 10)},
+	}}
+	from, to := opts.From.UnixMilli(), opts.To.UnixMilli()
+
+	var stats BackupStats
+	bw := bufio.NewWriter(w)
+	if opts.Format == ExportFormatJSONLines {
+		if err := db.exportSeries(bw, db.backend, filters, from, to, snapshotChunk, &stats); err != nil {
+			return stats, err
+		}
+		return stats, bw.Flush()
+	}
+
+	cw := csv.NewWriter(bw)
+	if err := cw.Write([]string{"timestamp", "metric", "server_id", "service_id", "device", "value"}); err != nil {
+		return stats, err
+	}
+	err := db.walkSeries(db.backend, filters, from, to, snapshotChunk, func(rs *rawSeries) error {
+		metric := MetricType(rs.labels["__name__"])
+		var device string
+		if label := metric.DeviceLabel(); label != "" {
+			device = rs.labels[label]
+		}
+		for _, p := range rs.points {
+			if err := cw.Write([]string{
+				time.UnixMilli(p.timestamp).UTC().Format(time.RFC3339Nano),
+				string(metric),
+				rs.labels["server_id"],
+				rs.labels["service_id"],
+				device,
+				strconv.FormatFloat(p.value, 'f', -1, 64),
+			}); err != nil {
+				return err
+			}
+		}
+		stats.Series++
+		stats.Samples += len(rs.points)
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return stats, err
+	}
+	return stats, bw.Flush()
+}
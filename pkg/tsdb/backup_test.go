@@ -0,0 +1,333 @@
+package tsdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openBackupTestDB(t *testing.T) *TSDB {
+	t.Helper()
+	tempDir, err := os.MkdirTemp("", "tsdb_test")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+
+	db, err := Open(&Config{
+		DataPath:           filepath.Join(tempDir, "tsdb"),
+		RetentionDays:      1,
+		MinFreeDiskSpaceGB: 1,
+		DedupInterval:      time.Second,
+		Rollups:            []RollupTier{{Interval: 5 * time.Minute, RetentionDays: 30}},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func writeBackupTestData(t *testing.T, db *TSDB, base time.Time) {
+	t.Helper()
+	for i := 0; i < 10; i++ {
+		ts := base.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, db.WriteServerMetrics(&ServerMetrics{
+			ServerID:  1,
+			Timestamp: ts,
+			CPU:       float64(10 * (i + 1)),
+			Disks:     []DiskMetrics{{Mountpoint: "/data", Used: 512, Total: 2048}},
+		}))
+		require.NoError(t, db.WriteServerMetrics(&ServerMetrics{ServerID: 2, Timestamp: ts, CPU: 99}))
+		require.NoError(t, db.WriteServiceMetrics(&ServiceMetrics{
+			ServiceID:  3,
+			ServerID:   1,
+			Timestamp:  ts,
+			Delay:      20,
+			Successful: i%2 == 0,
+		}))
+	}
+	db.Flush()
+}
+
+func TestTSDB_SnapshotRestore(t *testing.T) {
+	db := openBackupTestDB(t)
+	now := time.Now()
+	base := now.Add(-time.Hour).Truncate(5 * time.Minute)
+	writeBackupTestData(t, db, base)
+	require.NoError(t, db.setRollupWatermark(db.rollups[0], base.UnixMilli()))
+	db.rollup(now)
+	db.Flush()
+
+	var buf bytes.Buffer
+	stats, err := db.Snapshot(&buf)
+	require.NoError(t, err)
+	assert.Positive(t, stats.Series)
+
+	// 快照解压后为 VictoriaMetrics 的 JSON line 导出格式，包含原始与汇总序列
+	gr, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	data, err := io.ReadAll(gr)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, stats.Series)
+	var hasRaw, hasRollup bool
+	samples := 0
+	for _, line := range lines {
+		var s exportedSeries
+		require.NoError(t, json.Unmarshal([]byte(line), &s))
+		require.Len(t, s.Values, len(s.Timestamps))
+		samples += len(s.Values)
+		hasRaw = hasRaw || s.Metric["__name__"] == string(MetricServerCPU)
+		hasRollup = hasRollup || s.Metric["__name__"] == string(MetricServerCPU)+":5m"
+	}
+	assert.True(t, hasRaw)
+	assert.True(t, hasRollup)
+	assert.Equal(t, stats.Samples, samples)
+
+	restoredDB := openBackupTestDB(t)
+	restored, err := restoredDB.Restore(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, stats.Samples, restored.Samples)
+	assert.Zero(t, restored.Skipped)
+	// 汇总进度恢复到最后一个汇总桶的末尾
+	assert.Equal(t, base.Add(10*time.Minute).UnixMilli(), restoredDB.rollupWatermark(restoredDB.rollups[0]))
+
+	r := QueryRange{From: base, To: now, Step: time.Minute}
+	want, err := db.QueryServerMetricsRange(1, MetricServerCPU, r)
+	require.NoError(t, err)
+	got, err := restoredDB.QueryServerMetricsRange(1, MetricServerCPU, r)
+	require.NoError(t, err)
+	require.Len(t, got, 10)
+	assert.Equal(t, want, got)
+
+	// 早于原始数据保留期的查询读取恢复的汇总数据
+	r = QueryRange{From: now.AddDate(0, 0, -7), To: now, Step: 5 * time.Minute, Aggregation: AggregationMax}
+	want, err = db.QueryServerMetricsRange(1, MetricServerCPU, r)
+	require.NoError(t, err)
+	got, err = restoredDB.QueryServerMetricsRange(1, MetricServerCPU, r)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	history, err := restoredDB.QueryServiceHistoryRange(3, QueryRange{From: base, To: now})
+	require.NoError(t, err)
+	require.Len(t, history.Servers, 1)
+	assert.Equal(t, uint64(5), history.Servers[0].Stats.TotalUp)
+	assert.Equal(t, uint64(5), history.Servers[0].Stats.TotalDown)
+
+	_, err = restoredDB.Restore(strings.NewReader("not a snapshot"))
+	assert.Error(t, err)
+}
+
+func TestTSDB_SnapshotStopsAtRollupWatermark(t *testing.T) {
+	db := openBackupTestDB(t)
+	now := time.Now()
+	base := now.Add(-time.Hour).Truncate(5 * time.Minute)
+	writeBackupTestData(t, db, base)
+	tier := db.rollups[0]
+
+	// 尚未汇总过时不导出汇总数据
+	var buf bytes.Buffer
+	_, err := db.Snapshot(&buf)
+	require.NoError(t, err)
+	assert.NotContains(t, snapshotMetricNames(t, buf.Bytes()), string(MetricServerCPU)+":5m")
+
+	// 汇总进度之后写入的桶不属于快照
+	require.NoError(t, db.setRollupWatermark(tier, base.UnixMilli()))
+	db.rollup(now)
+	require.NoError(t, db.setRollupWatermark(tier, base.Add(5*time.Minute).UnixMilli()))
+	db.Flush()
+	buf.Reset()
+	_, err = db.Snapshot(&buf)
+	require.NoError(t, err)
+	gr, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	dec := json.NewDecoder(gr)
+	var rollupSamples int
+	for dec.More() {
+		var s exportedSeries
+		require.NoError(t, dec.Decode(&s))
+		if !strings.HasSuffix(s.Metric["__name__"], ":5m") {
+			continue
+		}
+		for _, ts := range s.Timestamps {
+			assert.Less(t, ts, base.Add(5*time.Minute).UnixMilli())
+			rollupSamples++
+		}
+	}
+	assert.Positive(t, rollupSamples)
+}
+
+func snapshotMetricNames(t *testing.T, data []byte) []string {
+	t.Helper()
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	dec := json.NewDecoder(gr)
+	var names []string
+	for dec.More() {
+		var s exportedSeries
+		require.NoError(t, dec.Decode(&s))
+		names = append(names, s.Metric["__name__"])
+	}
+	return names
+}
+
+// blockingReader 第一次读取时阻塞到 release 关闭，模拟恢复进行到一半
+type blockingReader struct {
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+	r       io.Reader
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	r.once.Do(func() {
+		close(r.started)
+		<-r.release
+	})
+	return r.r.Read(p)
+}
+
+func TestTSDB_RestorePausesRollups(t *testing.T) {
+	db := openBackupTestDB(t)
+	now := time.Now()
+	base := now.Add(-time.Hour).Truncate(5 * time.Minute)
+	writeBackupTestData(t, db, base)
+	db.rollup(now)
+	db.Flush()
+	var buf bytes.Buffer
+	_, err := db.Snapshot(&buf)
+	require.NoError(t, err)
+
+	restoredDB := openBackupTestDB(t)
+	r := &blockingReader{started: make(chan struct{}), release: make(chan struct{}), r: &buf}
+	restoreDone := make(chan error, 1)
+	go func() {
+		_, err := restoredDB.Restore(r)
+		restoreDone <- err
+	}()
+	<-r.started
+
+	// 恢复期间汇总等待，结束后从恢复的汇总进度继续，不会用部分原始数据覆盖已恢复的汇总桶
+	rollupDone := make(chan struct{})
+	go func() {
+		restoredDB.rollup(now)
+		close(rollupDone)
+	}()
+	select {
+	case <-rollupDone:
+		t.Fatal("rollup ran while restoring")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(r.release)
+	require.NoError(t, <-restoreDone)
+	<-rollupDone
+	restoredDB.Flush()
+
+	q := QueryRange{From: now.AddDate(0, 0, -7), To: now, Step: 5 * time.Minute, Aggregation: AggregationMin}
+	want, err := db.QueryServerMetricsRange(1, MetricServerCPU, q)
+	require.NoError(t, err)
+	got, err := restoredDB.QueryServerMetricsRange(1, MetricServerCPU, q)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+// blockingWriter 第一次写入时阻塞到 release 关闭，模拟慢速的下载
+type blockingWriter struct {
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+	buf     bytes.Buffer
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() {
+		close(w.started)
+		<-w.release
+	})
+	return w.buf.Write(p)
+}
+
+func TestTSDB_SnapshotStreamsWithoutLock(t *testing.T) {
+	db := openBackupTestDB(t)
+	writeBackupTestData(t, db, time.Now().Add(-time.Hour).Truncate(time.Minute))
+
+	w := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+	done := make(chan error, 1)
+	go func() {
+		_, err := db.Snapshot(w)
+		done <- err
+	}()
+	<-w.started
+
+	// 向 w 写入期间不持有读锁，关闭存储不会被阻塞
+	locked := make(chan struct{})
+	go func() {
+		db.mu.Lock()
+		db.mu.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("snapshot holds the lock while writing to the client")
+	}
+	close(w.release)
+	require.NoError(t, <-done)
+
+	gr, err := gzip.NewReader(&w.buf)
+	require.NoError(t, err)
+	data, err := io.ReadAll(gr)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"__name__":"nezha_server_cpu"`)
+
+	// 临时文件已删除
+	entries, err := os.ReadDir(filepath.Dir(db.config.DataPath))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "tsdb", entries[0].Name())
+}
+
+func TestTSDB_Export(t *testing.T) {
+	db := openBackupTestDB(t)
+	base := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	writeBackupTestData(t, db, base)
+
+	var buf bytes.Buffer
+	stats, err := db.Export(&buf, ExportOptions{ServerID: 1, From: base, To: base.Add(5 * time.Minute), Format: ExportFormatCSV})
+	require.NoError(t, err)
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, stats.Samples+1)
+	assert.Equal(t, []string{"timestamp", "metric", "server_id", "service_id", "device", "value"}, records[0])
+	assert.Contains(t, records, []string{base.UTC().Format(time.RFC3339Nano), string(MetricServerCPU), "1", "", "", "10"})
+	assert.Contains(t, records, []string{base.UTC().Format(time.RFC3339Nano), string(MetricServerMountUsed), "1", "", "/data", "512"})
+	assert.Contains(t, records, []string{base.Add(5 * time.Minute).UTC().Format(time.RFC3339Nano), string(MetricServiceStatus), "1", "3", "", "0"})
+	for _, record := range records[1:] {
+		assert.Equal(t, "1", record[2], "only the requested server is exported")
+	}
+
+	buf.Reset()
+	stats, err = db.Export(&buf, ExportOptions{ServerID: 1, From: base, To: base.Add(time.Hour), Format: ExportFormatJSONLines})
+	require.NoError(t, err)
+	var s exportedSeries
+	dec := json.NewDecoder(&buf)
+	var samples int
+	for dec.More() {
+		require.NoError(t, dec.Decode(&s))
+		assert.Equal(t, "1", s.Metric["server_id"])
+		samples += len(s.Values)
+	}
+	assert.Equal(t, stats.Samples, samples)
+
+	_, err = db.Export(&buf, ExportOptions{ServerID: 1, From: base, To: base.Add(time.Hour), Format: "xml"})
+	assert.Error(t, err)
+}
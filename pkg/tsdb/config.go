@@ -1,6 +1,12 @@
 package tsdb
 
-import "time"
+import (
+	"cmp"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
 
 // Config TSDB 配置选项
 type Config struct {
@@ -19,6 +25,51 @@ type Config struct {
 	WriteBufferSize int `koanf:"write_buffer_size" json:"write_buffer_size,omitempty"`
 	// WriteBufferFlushInterval 写入缓冲区刷新间隔，默认 5 秒
 	WriteBufferFlushInterval time.Duration `koanf:"write_buffer_flush_interval" json:"write_buffer_flush_interval,omitempty"`
+
+	// Backend 存储后端，local（默认）或 remote
+	Backend string `koanf:"backend" json:"backend,omitempty"`
+	// Remote 远端存储配置，Backend 为 remote 时生效
+	Remote RemoteConfig `koanf:"remote" json:"remote,omitempty"`
+
+	// Rollups 长期保留的降采样层级，为空时使用 DefaultRollups
+	Rollups []RollupTier `koanf:"rollups" json:"rollups,omitempty"`
+	// DisableRollups 不做降采样，超过 RetentionDays 的历史全部删除
+	DisableRollups bool `koanf:"disable_rollups" json:"disable_rollups,omitempty"`
+}
+
+// RollupTier 降采样层级：原始数据按 Interval 汇总为平均值、最小值、最大值、最后值与采样数，
+// 单独保存 RetentionDays 天。远端存储时汇总数据写入同一远端，保留时长由远端决定
+type RollupTier struct {
+	Interval      time.Duration `koanf:"interval" json:"interval,omitempty"`
+	RetentionDays int           `koanf:"retention_days" json:"retention_days,omitempty"`
+}
+
+// DefaultRollups 默认的降采样层级：5 分钟保留 1 年，1 小时保留 5 年
+var DefaultRollups = []RollupTier{
+	{Interval: 5 * time.Minute, RetentionDays: 365},
+	{Interval: time.Hour, RetentionDays: 5 * 365},
+}
+
+// RemoteConfig 远端存储配置。数据通过 Prometheus remote write 协议写入，
+// 历史查询通过远端的 Prometheus HTTP API（/api/v1/query、/api/v1/series 等）读回，
+// VictoriaMetrics 与开启了 remote write receiver 的 Prometheus 均可使用
+type RemoteConfig struct {
+	// WriteURL remote write 地址，例如 http://victoria-metrics:8428/api/v1/write
+	WriteURL string `koanf:"write_url" json:"write_url,omitempty"`
+	// QueryURL Prometheus HTTP API 地址，例如 http://victoria-metrics:8428，
+	// 为空时由 WriteURL 去掉 /api/v1/write 得到
+	QueryURL string `koanf:"query_url" json:"query_url,omitempty"`
+	// BearerToken 与 Username/Password 二选一，用于远端鉴权
+	BearerToken string `koanf:"bearer_token" json:"bearer_token,omitempty"`
+	Username    string `koanf:"username" json:"username,omitempty"`
+	Password    string `koanf:"password" json:"password,omitempty"`
+	// Timeout 单次请求超时，默认 30 秒
+	Timeout time.Duration `koanf:"timeout" json:"timeout,omitempty"`
+	// SpoolPath 远端不可用时暂存待发送数据的目录，默认为 DataPath 下的 remote_write_spool，
+	// DataPath 也为空时为 data/tsdb_spool
+	SpoolPath string `koanf:"spool_path" json:"spool_path,omitempty"`
+	// MaxSpoolMB 暂存目录的容量上限(MB)，超出后丢弃最早的数据，默认 512MB
+	MaxSpoolMB int64 `koanf:"max_spool_mb" json:"max_spool_mb,omitempty"`
 }
 
 // DefaultConfig 返回默认配置（不设置 DataPath，需要显式配置才启用）
@@ -54,10 +105,63 @@ func (c *Config) Validate() {
 	if c.WriteBufferFlushInterval <= 0 {
 		c.WriteBufferFlushInterval = 5 * time.Second
 	}
+	if c.Backend == "" {
+		c.Backend = BackendLocal
+	}
+	c.validateRollups()
+	if c.Backend == BackendRemote {
+		c.Remote.validate(c.DataPath)
+	}
+}
+
+// validateRollups 丢弃间隔不足一分钟或不长于原始数据保留期的层级，按间隔从小到大排列，相同间隔只保留第一个
+func (c *Config) validateRollups() {
+	if c.DisableRollups {
+		c.Rollups = nil
+		return
+	}
+	if len(c.Rollups) == 0 {
+		c.Rollups = slices.Clone(DefaultRollups)
+	}
+	tiers := make([]RollupTier, 0, len(c.Rollups))
+	for _, t := range c.Rollups {
+		if t.Interval < time.Minute || t.RetentionDays <= int(c.RetentionDays) {
+			continue
+		}
+		if slices.ContainsFunc(tiers, func(e RollupTier) bool { return e.Interval == t.Interval }) {
+			continue
+		}
+		tiers = append(tiers, RollupTier{Interval: t.Interval.Truncate(time.Second), RetentionDays: t.RetentionDays})
+	}
+	slices.SortFunc(tiers, func(a, b RollupTier) int { return cmp.Compare(a.Interval, b.Interval) })
+	c.Rollups = tiers
 }
 
-// Enabled 检查是否启用 TSDB
+func (c *RemoteConfig) validate(dataPath string) {
+	if c.QueryURL == "" {
+		c.QueryURL = strings.TrimSuffix(strings.TrimSuffix(c.WriteURL, "/"), "/api/v1/write")
+	}
+	c.QueryURL = strings.TrimSuffix(c.QueryURL, "/")
+	if c.Timeout <= 0 {
+		c.Timeout = 30 * time.Second
+	}
+	if c.SpoolPath == "" {
+		if dataPath != "" {
+			c.SpoolPath = filepath.Join(dataPath, "remote_write_spool")
+		} else {
+			c.SpoolPath = filepath.Join("data", "tsdb_spool")
+		}
+	}
+	if c.MaxSpoolMB <= 0 {
+		c.MaxSpoolMB = 512
+	}
+}
+
+// Enabled 检查是否启用 TSDB：本地存储需要 DataPath，远端存储需要 Remote.WriteURL
 func (c *Config) Enabled() bool {
+	if c.Backend == BackendRemote {
+		return c.Remote.WriteURL != ""
+	}
 	return c.DataPath != ""
 }
 
@@ -12,6 +12,6 @@ func (db *TSDB) Maintenance() {
 	}
 
 	log.Println("NEZHA>> TSDB starting maintenance (flush)...")
-	db.storage.DebugFlush()
+	db.flushBackends()
 	log.Println("NEZHA>> TSDB maintenance completed")
 }
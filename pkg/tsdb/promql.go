@@ -0,0 +1,303 @@
+package tsdb
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+const (
+	// PromLookbackDelta 即时选择器向前查找最近一个采样点的范围
+	PromLookbackDelta = 5 * time.Minute
+	// PromMaxSeries 单次查询最多读取的序列数
+	PromMaxSeries = 10000
+	// PromMaxLabels 标签名、标签值查询最多返回的条数
+	PromMaxLabels = 10000
+
+	promQueryTimeout = 30 * time.Second
+)
+
+// QueryScope 限制查询可见的序列
+type QueryScope struct {
+	// Restricted 为 true 时只能看到 server_id 属于 ServerIDs 的序列，
+	// 带 service_id 标签的服务指标还要求 service_id 属于 ServiceIDs
+	Restricted bool
+	ServerIDs  []uint64
+	ServiceIDs []uint64
+}
+
+// PromSeries 查询结果中的一个序列，Values 与 Timestamps（毫秒）一一对应
+type PromSeries struct {
+	Labels     map[string]string
+	Timestamps []int64
+	Values     []float64
+}
+
+// PromResult PromQL 查询结果，Scalar 为 true 时 Series 只有一个没有标签的序列
+type PromResult struct {
+	Scalar bool
+	Series []PromSeries
+}
+
+// ParsePromTime 解析 Prometheus API 的时间参数，支持 Unix 时间戳（可带小数）与 RFC3339
+func ParsePromTime(s string) (time.Time, error) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse %q to a valid timestamp", s)
+	}
+	return t, nil
+}
+
+// ParsePromDuration 解析 Prometheus API 的时长参数，支持秒数与 "5m" 形式
+func ParsePromDuration(s string) (time.Duration, error) {
+	ms, err := metricsql.PositiveDurationValue(s, 0)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %q to a valid duration", s)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// PromQuery 在 ts 时刻执行即时查询
+func (db *TSDB) PromQuery(query string, ts time.Time, scope QueryScope) (*PromResult, error) {
+	return db.promEval(query, ts.UnixMilli(), ts.UnixMilli(), 0, scope)
+}
+
+// PromQueryRange 在 [start, end] 内每隔 step 执行一次查询，每个序列最多 MaxQueryPoints 个点
+func (db *TSDB) PromQueryRange(query string, start, end time.Time, step time.Duration, scope QueryScope) (*PromResult, error) {
+	if step <= 0 {
+		return nil, fmt.Errorf("zero or negative query resolution step widths are not accepted")
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end timestamp must not be before start time")
+	}
+	if end.Sub(start)/step >= MaxQueryPoints {
+		return nil, fmt.Errorf("exceeded maximum resolution of %d points per timeseries, try decreasing the query resolution", MaxQueryPoints)
+	}
+	return db.promEval(query, start.UnixMilli(), end.UnixMilli(), step.Milliseconds(), scope)
+}
+
+func (db *TSDB) promEval(query string, start, end, step int64, scope QueryScope) (*PromResult, error) {
+	expr, err := metricsql.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.closed {
+		return nil, fmt.Errorf("TSDB is closed")
+	}
+
+	ev := &promEvaluator{
+		db:       db,
+		scope:    scope,
+		step:     step,
+		deadline: uint64(time.Now().Add(promQueryTimeout).Unix()),
+	}
+	for ts := start; ts <= end; ts += max(step, 1) {
+		ev.timestamps = append(ev.timestamps, ts)
+	}
+
+	v, err := ev.eval(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PromResult{Scalar: v.scalar}
+	for _, s := range v.series {
+		ps := PromSeries{Labels: s.labels}
+		for i, value := range s.values {
+			if math.IsNaN(value) {
+				continue
+			}
+			ps.Timestamps = append(ps.Timestamps, ev.timestamps[i])
+			ps.Values = append(ps.Values, value)
+		}
+		if len(ps.Values) > 0 || v.scalar {
+			result.Series = append(result.Series, ps)
+		}
+	}
+	slices.SortFunc(result.Series, func(a, b PromSeries) int {
+		return strings.Compare(labelsSignature(a.Labels, nil, false), labelsSignature(b.Labels, nil, false))
+	})
+	return result, nil
+}
+
+// PromSeriesLabels 返回匹配任一选择器的序列标签
+func (db *TSDB) PromSeriesLabels(matchers []string, start, end time.Time, scope QueryScope) ([]map[string]string, error) {
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("no match[] parameter provided")
+	}
+	filters, err := matcherFilters(matchers)
+	if err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.closed {
+		return nil, fmt.Errorf("TSDB is closed")
+	}
+
+	filters, ok := scope.apply(filters)
+	if !ok {
+		return nil, nil
+	}
+	result, err := db.backend.seriesLabels(filters, promTimeRange(start, end), promDeadline())
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(result, func(a, b map[string]string) int {
+		return strings.Compare(labelsSignature(a, nil, false), labelsSignature(b, nil, false))
+	})
+	return result, nil
+}
+
+// PromLabelNames 返回范围内出现过的标签名，matchers 为空时不限制序列
+func (db *TSDB) PromLabelNames(matchers []string, start, end time.Time, scope QueryScope) ([]string, error) {
+	filters, err := matcherFilters(matchers)
+	if err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.closed {
+		return nil, fmt.Errorf("TSDB is closed")
+	}
+
+	filters, ok := scope.apply(filters)
+	if !ok {
+		return []string{}, nil
+	}
+	names, err := db.backend.labelNames(filters, promTimeRange(start, end), promDeadline())
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// PromLabelValues 返回范围内标签 name 的取值，matchers 为空时不限制序列
+func (db *TSDB) PromLabelValues(name string, matchers []string, start, end time.Time, scope QueryScope) ([]string, error) {
+	filters, err := matcherFilters(matchers)
+	if err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.closed {
+		return nil, fmt.Errorf("TSDB is closed")
+	}
+
+	filters, ok := scope.apply(filters)
+	if !ok {
+		return []string{}, nil
+	}
+	values, err := db.backend.labelValues(name, filters, promTimeRange(start, end), promDeadline())
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(values)
+	return values, nil
+}
+
+func promTimeRange(start, end time.Time) storage.TimeRange {
+	return storage.TimeRange{MinTimestamp: start.UnixMilli(), MaxTimestamp: end.UnixMilli()}
+}
+
+func promDeadline() uint64 {
+	return uint64(time.Now().Add(promQueryTimeout).Unix())
+}
+
+// matcherFilters 把 match[] 中的选择器转换为选择条件
+func matcherFilters(matchers []string) (seriesFilters, error) {
+	var filters seriesFilters
+	for _, m := range matchers {
+		expr, err := metricsql.Parse(m)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match[] %q: %w", m, err)
+		}
+		me, ok := expr.(*metricsql.MetricExpr)
+		if !ok {
+			return nil, fmt.Errorf("invalid match[] %q: expected a series selector", m)
+		}
+		filters = append(filters, me.LabelFilterss...)
+	}
+	return filters, nil
+}
+
+// apply 为每组条件追加 server_id 与 service_id 限制；受限且没有任何可见服务器时返回 ok=false。
+// 没有条件时以这两个限制作为唯一条件
+func (s QueryScope) apply(filters seriesFilters) (seriesFilters, bool) {
+	if !s.Restricted {
+		return filters, true
+	}
+	if len(s.ServerIDs) == 0 {
+		return nil, false
+	}
+	// 正则条件自动锚定首尾；空值匹配没有 service_id 标签的服务器指标
+	restrictions := []metricsql.LabelFilter{
+		{Label: "server_id", Value: joinIDs(s.ServerIDs), IsRegexp: true},
+		{Label: "service_id", Value: "|" + joinIDs(s.ServiceIDs), IsRegexp: true},
+	}
+	if len(filters) == 0 {
+		return seriesFilters{restrictions}, true
+	}
+	// 复制而不是原地追加，避免改写解析结果中共享的切片
+	result := make(seriesFilters, len(filters))
+	for i, lfs := range filters {
+		result[i] = append(slices.Clone(lfs), restrictions...)
+	}
+	return result, true
+}
+
+func joinIDs(ids []uint64) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.FormatUint(id, 10)
+	}
+	return strings.Join(s, "|")
+}
+
+func metricNameLabels(mn *storage.MetricName) map[string]string {
+	labels := make(map[string]string, len(mn.Tags)+1)
+	if len(mn.MetricGroup) > 0 {
+		labels["__name__"] = string(mn.MetricGroup)
+	}
+	for _, tag := range mn.Tags {
+		labels[string(tag.Key)] = string(tag.Value)
+	}
+	return labels
+}
+
+// labelsSignature 按标签名排序后拼接，only 为 true 时只取 names 中的标签，否则排除 names 中的标签
+func labelsSignature(labels map[string]string, names []string, only bool) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		if slices.Contains(names, k) != only {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[k]))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
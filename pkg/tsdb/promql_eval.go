@@ -0,0 +1,741 @@
+package tsdb
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+// promEvaluator 在 timestamps 的每个时刻对表达式求值。
+// 支持的子集：选择器与 offset、常用 *_over_time 与计数器函数、数学函数、
+// sum/avg/min/max/count/group/topk/bottomk/quantile 聚合、标量与一对一向量运算以及 and/or/unless
+type promEvaluator struct {
+	db         *TSDB
+	scope      QueryScope
+	step       int64 // 毫秒，即时查询为 0
+	timestamps []int64
+	deadline   uint64
+}
+
+// promValue 求值结果，values 与 timestamps 对齐，NaN 表示该时刻没有值
+type promValue struct {
+	scalar bool
+	series []*promSeries
+}
+
+type promSeries struct {
+	labels map[string]string
+	values []float64
+}
+
+type rawSeries struct {
+	labels map[string]string
+	points []rawDataPoint
+}
+
+// counterRollupFuncs 计算时需要窗口前的最后一个采样点，与 VictoriaMetrics 一样不做外推
+var counterRollupFuncs = []string{"rate", "increase", "irate", "delta", "idelta", "changes", "resets"}
+
+var promRollupFuncs = append([]string{
+	"avg_over_time", "min_over_time", "max_over_time", "sum_over_time", "count_over_time",
+	"last_over_time", "present_over_time", "quantile_over_time",
+}, counterRollupFuncs...)
+
+var promMathFuncs = map[string]func(float64) float64{
+	"abs":   math.Abs,
+	"ceil":  math.Ceil,
+	"floor": math.Floor,
+	"exp":   math.Exp,
+	"ln":    math.Log,
+	"log2":  math.Log2,
+	"log10": math.Log10,
+	"sqrt":  math.Sqrt,
+	"round": math.Round,
+	"sgn": func(v float64) float64 {
+		switch {
+		case v > 0:
+			return 1
+		case v < 0:
+			return -1
+		default:
+			return v
+		}
+	},
+}
+
+func (ev *promEvaluator) eval(expr metricsql.Expr) (promValue, error) {
+	switch e := expr.(type) {
+	case *metricsql.NumberExpr:
+		return ev.constant(e.N), nil
+	case *metricsql.MetricExpr:
+		return ev.rollup("", &metricsql.RollupExpr{Expr: e}, nil)
+	case *metricsql.RollupExpr:
+		if e.Window != nil || e.ForSubquery() {
+			return promValue{}, fmt.Errorf("range vector %s must be passed to a function", e.AppendString(nil))
+		}
+		return ev.rollup("", e, nil)
+	case *metricsql.FuncExpr:
+		return ev.evalFunc(e)
+	case *metricsql.AggrFuncExpr:
+		return ev.evalAggr(e)
+	case *metricsql.BinaryOpExpr:
+		return ev.evalBinaryOp(e)
+	default:
+		return promValue{}, fmt.Errorf("unsupported expression %s", expr.AppendString(nil))
+	}
+}
+
+func (ev *promEvaluator) newValues(v float64) []float64 {
+	values := make([]float64, len(ev.timestamps))
+	for i := range values {
+		values[i] = v
+	}
+	return values
+}
+
+func (ev *promEvaluator) constant(n float64) promValue {
+	return promValue{scalar: true, series: []*promSeries{{labels: map[string]string{}, values: ev.newValues(n)}}}
+}
+
+// evalScalar 求值并要求结果为标量
+func (ev *promEvaluator) evalScalar(expr metricsql.Expr) ([]float64, error) {
+	v, err := ev.eval(expr)
+	if err != nil {
+		return nil, err
+	}
+	if !v.scalar {
+		return nil, fmt.Errorf("expected a scalar, got %s", expr.AppendString(nil))
+	}
+	return v.series[0].values, nil
+}
+
+// fetch 读取 tr 内匹配选择器的原始采样点，按序列分组并按时间排序
+func (ev *promEvaluator) fetch(me *metricsql.MetricExpr, tr storage.TimeRange) ([]*rawSeries, error) {
+	filters, ok := ev.scope.apply(me.LabelFilterss)
+	if !ok {
+		return nil, nil
+	}
+	return ev.db.backend.search(filters, tr, PromMaxSeries, ev.deadline)
+}
+
+// rollup 对选择器在每个时刻的窗口 (t-offset-window, t-offset] 内的采样点计算 name 函数，
+// name 为空时取窗口内最后一个值（即时选择器）
+func (ev *promEvaluator) rollup(name string, re *metricsql.RollupExpr, phi []float64) (promValue, error) {
+	me, ok := re.Expr.(*metricsql.MetricExpr)
+	if !ok || re.ForSubquery() {
+		return promValue{}, fmt.Errorf("subqueries are not supported")
+	}
+	if re.At != nil {
+		return promValue{}, fmt.Errorf("@ modifier is not supported")
+	}
+
+	window := PromLookbackDelta.Milliseconds()
+	if re.Window != nil {
+		window = re.Window.Duration(ev.step)
+	} else if name != "" && ev.step > 0 {
+		window = ev.step
+	}
+	if window <= 0 {
+		return promValue{}, fmt.Errorf("window must be positive")
+	}
+	offset := re.Offset.Duration(ev.step)
+	counter := slices.Contains(counterRollupFuncs, name)
+
+	tr := storage.TimeRange{
+		MinTimestamp: ev.timestamps[0] - offset - window,
+		MaxTimestamp: ev.timestamps[len(ev.timestamps)-1] - offset,
+	}
+	if counter {
+		tr.MinTimestamp -= window
+	}
+	raw, err := ev.fetch(me, tr)
+	if err != nil {
+		return promValue{}, err
+	}
+
+	keepName := name == "" || name == "last_over_time"
+	result := promValue{series: make([]*promSeries, 0, len(raw))}
+	for _, rs := range raw {
+		s := &promSeries{labels: rs.labels, values: ev.newValues(math.NaN())}
+		if !keepName {
+			s.labels = dropMetricName(rs.labels)
+		}
+		lo, hi := 0, 0
+		for i, t := range ev.timestamps {
+			end := t - offset
+			for hi < len(rs.points) && rs.points[hi].timestamp <= end {
+				hi++
+			}
+			for lo < hi && rs.points[lo].timestamp <= end-window {
+				lo++
+			}
+			from := lo
+			if counter && from > 0 {
+				from--
+			}
+			var q float64
+			if phi != nil {
+				q = phi[i]
+			}
+			if v, ok := rollupPoints(name, rs.points[from:hi], hi-lo, q); ok {
+				s.values[i] = v
+			}
+		}
+		result.series = append(result.series, s)
+	}
+	return result, nil
+}
+
+// rollupPoints 计算窗口内的函数值，inWindow 为窗口内（不含之前那个点）的采样点数
+func rollupPoints(name string, points []rawDataPoint, inWindow int, phi float64) (float64, bool) {
+	if inWindow == 0 {
+		return 0, false
+	}
+	switch name {
+	case "", "last_over_time":
+		return points[len(points)-1].value, true
+	case "avg_over_time":
+		return aggregatePoints(points, AggregationAvg)
+	case "min_over_time":
+		return aggregatePoints(points, AggregationMin)
+	case "max_over_time":
+		return aggregatePoints(points, AggregationMax)
+	case "sum_over_time":
+		var sum float64
+		for _, p := range points {
+			sum += p.value
+		}
+		return sum, true
+	case "count_over_time":
+		return float64(len(points)), true
+	case "present_over_time":
+		return 1, true
+	case "quantile_over_time":
+		if phi < 0 || phi > 1 || math.IsNaN(phi) {
+			return math.Copysign(math.Inf(1), phi), true
+		}
+		return percentile(points, phi), true
+	case "rate":
+		return aggregatePoints(points, AggregationRate)
+	case "increase":
+		return aggregatePoints(points, AggregationIncrease)
+	case "irate", "idelta":
+		if len(points) < 2 {
+			return 0, false
+		}
+		prev, last := points[len(points)-2], points[len(points)-1]
+		delta := last.value - prev.value
+		if name == "idelta" {
+			return delta, true
+		}
+		if delta < 0 {
+			delta = last.value
+		}
+		return delta / (float64(last.timestamp-prev.timestamp) / 1000), true
+	case "delta":
+		if len(points) < 2 {
+			return 0, false
+		}
+		return points[len(points)-1].value - points[0].value, true
+	case "changes", "resets":
+		var n float64
+		for i := 1; i < len(points); i++ {
+			if (name == "changes" && points[i].value != points[i-1].value) ||
+				(name == "resets" && points[i].value < points[i-1].value) {
+				n++
+			}
+		}
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+func dropMetricName(labels map[string]string) map[string]string {
+	if _, ok := labels["__name__"]; !ok {
+		return labels
+	}
+	result := maps.Clone(labels)
+	delete(result, "__name__")
+	return result
+}
+
+func (ev *promEvaluator) evalFunc(fe *metricsql.FuncExpr) (promValue, error) {
+	name := strings.ToLower(fe.Name)
+	switch {
+	case slices.Contains(promRollupFuncs, name):
+		var phi []float64
+		args := fe.Args
+		if name == "quantile_over_time" {
+			if len(args) != 2 {
+				return promValue{}, fmt.Errorf("%s expects 2 arguments", name)
+			}
+			var err error
+			if phi, err = ev.evalScalar(args[0]); err != nil {
+				return promValue{}, err
+			}
+			args = args[1:]
+		}
+		if len(args) != 1 {
+			return promValue{}, fmt.Errorf("%s expects 1 argument", name)
+		}
+		switch arg := args[0].(type) {
+		case *metricsql.RollupExpr:
+			return ev.rollup(name, arg, phi)
+		case *metricsql.MetricExpr:
+			return ev.rollup(name, &metricsql.RollupExpr{Expr: arg}, phi)
+		default:
+			return promValue{}, fmt.Errorf("%s expects a range vector", name)
+		}
+	case promMathFuncs[name] != nil:
+		if len(fe.Args) != 1 {
+			return promValue{}, fmt.Errorf("%s expects 1 argument", name)
+		}
+		v, err := ev.eval(fe.Args[0])
+		if err != nil {
+			return promValue{}, err
+		}
+		f := promMathFuncs[name]
+		return mapValues(v, func(_ int, x float64) float64 { return f(x) }), nil
+	case name == "clamp_min" || name == "clamp_max":
+		if len(fe.Args) != 2 {
+			return promValue{}, fmt.Errorf("%s expects 2 arguments", name)
+		}
+		v, err := ev.eval(fe.Args[0])
+		if err != nil {
+			return promValue{}, err
+		}
+		bound, err := ev.evalScalar(fe.Args[1])
+		if err != nil {
+			return promValue{}, err
+		}
+		return mapValues(v, func(i int, x float64) float64 {
+			if name == "clamp_min" {
+				return max(x, bound[i])
+			}
+			return min(x, bound[i])
+		}), nil
+	case name == "time":
+		v := ev.constant(0)
+		for i, ts := range ev.timestamps {
+			v.series[0].values[i] = float64(ts) / 1000
+		}
+		return v, nil
+	case name == "vector":
+		if len(fe.Args) != 1 {
+			return promValue{}, fmt.Errorf("vector expects 1 argument")
+		}
+		values, err := ev.evalScalar(fe.Args[0])
+		if err != nil {
+			return promValue{}, err
+		}
+		return promValue{series: []*promSeries{{labels: map[string]string{}, values: values}}}, nil
+	case name == "scalar":
+		if len(fe.Args) != 1 {
+			return promValue{}, fmt.Errorf("scalar expects 1 argument")
+		}
+		v, err := ev.eval(fe.Args[0])
+		if err != nil {
+			return promValue{}, err
+		}
+		result := ev.constant(math.NaN())
+		for i := range ev.timestamps {
+			var n int
+			for _, s := range v.series {
+				if !math.IsNaN(s.values[i]) {
+					n++
+					result.series[0].values[i] = s.values[i]
+				}
+			}
+			if n != 1 {
+				result.series[0].values[i] = math.NaN()
+			}
+		}
+		return result, nil
+	default:
+		return promValue{}, fmt.Errorf("unsupported function %s", fe.Name)
+	}
+}
+
+// mapValues 对每个值应用 f，向量结果去掉指标名
+func mapValues(v promValue, f func(i int, x float64) float64) promValue {
+	result := promValue{scalar: v.scalar, series: make([]*promSeries, len(v.series))}
+	for j, s := range v.series {
+		values := make([]float64, len(s.values))
+		for i, x := range s.values {
+			if math.IsNaN(x) {
+				values[i] = x
+				continue
+			}
+			values[i] = f(i, x)
+		}
+		result.series[j] = &promSeries{labels: dropMetricName(s.labels), values: values}
+	}
+	return result
+}
+
+func (ev *promEvaluator) evalAggr(ae *metricsql.AggrFuncExpr) (promValue, error) {
+	name := strings.ToLower(ae.Name)
+	args := ae.Args
+	var param []float64
+	switch name {
+	case "sum", "avg", "min", "max", "count", "group":
+	case "topk", "bottomk", "quantile":
+		if len(args) != 2 {
+			return promValue{}, fmt.Errorf("%s expects 2 arguments", name)
+		}
+		var err error
+		if param, err = ev.evalScalar(args[0]); err != nil {
+			return promValue{}, err
+		}
+		args = args[1:]
+	default:
+		return promValue{}, fmt.Errorf("unsupported aggregation %s", ae.Name)
+	}
+	if len(args) != 1 {
+		return promValue{}, fmt.Errorf("%s expects 1 argument", name)
+	}
+	v, err := ev.eval(args[0])
+	if err != nil {
+		return promValue{}, err
+	}
+	if v.scalar {
+		return promValue{}, fmt.Errorf("%s expects an instant vector", name)
+	}
+
+	var keys []string
+	groups := make(map[string][]*promSeries)
+	groupLabels := make(map[string]map[string]string)
+	for _, s := range v.series {
+		labels := aggrGroupLabels(s.labels, ae.Modifier)
+		key := labelsSignature(labels, nil, false)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+			groupLabels[key] = labels
+		}
+		groups[key] = append(groups[key], s)
+	}
+
+	result := promValue{}
+	for _, key := range keys {
+		members := groups[key]
+		if name == "topk" || name == "bottomk" {
+			result.series = append(result.series, ev.selectK(members, param, name == "topk")...)
+			continue
+		}
+		s := &promSeries{labels: groupLabels[key], values: ev.newValues(math.NaN())}
+		var values []float64
+		for i := range ev.timestamps {
+			values = values[:0]
+			for _, m := range members {
+				if !math.IsNaN(m.values[i]) {
+					values = append(values, m.values[i])
+				}
+			}
+			if len(values) == 0 {
+				continue
+			}
+			var q float64
+			if param != nil {
+				q = param[i]
+			}
+			s.values[i] = aggregateValues(name, values, q)
+		}
+		result.series = append(result.series, s)
+	}
+	return result, nil
+}
+
+// aggrGroupLabels 按 by/without 计算分组标签，没有修饰符时所有序列归为一组
+func aggrGroupLabels(labels map[string]string, modifier metricsql.ModifierExpr) map[string]string {
+	result := make(map[string]string)
+	switch strings.ToLower(modifier.Op) {
+	case "by":
+		for _, name := range modifier.Args {
+			if v, ok := labels[name]; ok {
+				result[name] = v
+			}
+		}
+	case "without":
+		for k, v := range labels {
+			if k != "__name__" && !slices.Contains(modifier.Args, k) {
+				result[k] = v
+			}
+		}
+	}
+	return result
+}
+
+func aggregateValues(name string, values []float64, q float64) float64 {
+	switch name {
+	case "sum", "avg":
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		if name == "avg" {
+			return sum / float64(len(values))
+		}
+		return sum
+	case "min":
+		return slices.Min(values)
+	case "max":
+		return slices.Max(values)
+	case "count":
+		return float64(len(values))
+	case "group":
+		return 1
+	case "quantile":
+		if q < 0 || q > 1 || math.IsNaN(q) {
+			return math.Copysign(math.Inf(1), q)
+		}
+		points := make([]rawDataPoint, len(values))
+		for i, v := range values {
+			points[i].value = v
+		}
+		return percentile(points, q)
+	default:
+		return math.NaN()
+	}
+}
+
+// selectK 每个时刻只保留组内最大（top）或最小的 k 个值，序列保留原标签
+func (ev *promEvaluator) selectK(members []*promSeries, k []float64, top bool) []*promSeries {
+	result := make([]*promSeries, len(members))
+	for j, m := range members {
+		result[j] = &promSeries{labels: m.labels, values: ev.newValues(math.NaN())}
+	}
+	idx := make([]int, 0, len(members))
+	for i := range ev.timestamps {
+		idx = idx[:0]
+		for j, m := range members {
+			if !math.IsNaN(m.values[i]) {
+				idx = append(idx, j)
+			}
+		}
+		sort.SliceStable(idx, func(a, b int) bool {
+			va, vb := members[idx[a]].values[i], members[idx[b]].values[i]
+			if top {
+				return va > vb
+			}
+			return va < vb
+		})
+		n := min(len(idx), max(int(k[i]), 0))
+		for _, j := range idx[:n] {
+			result[j].values[i] = members[j].values[i]
+		}
+	}
+	return result
+}
+
+var promArithmeticOps = map[string]func(l, r float64) float64{
+	"+": func(l, r float64) float64 { return l + r },
+	"-": func(l, r float64) float64 { return l - r },
+	"*": func(l, r float64) float64 { return l * r },
+	"/": func(l, r float64) float64 { return l / r },
+	"%": math.Mod,
+	"^": math.Pow,
+}
+
+var promComparisonOps = map[string]func(l, r float64) bool{
+	"==": func(l, r float64) bool { return l == r },
+	"!=": func(l, r float64) bool { return l != r },
+	">":  func(l, r float64) bool { return l > r },
+	"<":  func(l, r float64) bool { return l < r },
+	">=": func(l, r float64) bool { return l >= r },
+	"<=": func(l, r float64) bool { return l <= r },
+}
+
+func (ev *promEvaluator) evalBinaryOp(be *metricsql.BinaryOpExpr) (promValue, error) {
+	op := strings.ToLower(be.Op)
+	if be.JoinModifier.Op != "" {
+		return promValue{}, fmt.Errorf("%s is not supported", be.JoinModifier.Op)
+	}
+	arith, cmp := promArithmeticOps[op], promComparisonOps[op]
+	setOp := op == "and" || op == "or" || op == "unless"
+	if arith == nil && cmp == nil && !setOp {
+		return promValue{}, fmt.Errorf("unsupported binary operator %s", be.Op)
+	}
+
+	left, err := ev.eval(be.Left)
+	if err != nil {
+		return promValue{}, err
+	}
+	right, err := ev.eval(be.Right)
+	if err != nil {
+		return promValue{}, err
+	}
+	if setOp {
+		if left.scalar || right.scalar {
+			return promValue{}, fmt.Errorf("set operator %s not allowed in binary scalar expression", op)
+		}
+		return ev.setOperation(op, left, right, be.GroupModifier), nil
+	}
+	if cmp != nil && left.scalar && right.scalar && !be.Bool {
+		return promValue{}, fmt.Errorf("comparisons between scalars must use BOOL modifier")
+	}
+
+	// apply 计算一个时刻的值，vector 为向量一侧的值（比较运算不带 bool 时保留该值）
+	apply := func(l, r, vector float64) float64 {
+		if math.IsNaN(l) || math.IsNaN(r) {
+			return math.NaN()
+		}
+		if arith != nil {
+			return arith(l, r)
+		}
+		ok := cmp(l, r)
+		switch {
+		case be.Bool && ok:
+			return 1
+		case be.Bool:
+			return 0
+		case ok:
+			return vector
+		default:
+			return math.NaN()
+		}
+	}
+	dropName := arith != nil || be.Bool
+
+	switch {
+	case left.scalar && right.scalar:
+		l, r := left.series[0].values, right.series[0].values
+		result := ev.constant(math.NaN())
+		for i := range ev.timestamps {
+			result.series[0].values[i] = apply(l[i], r[i], l[i])
+		}
+		return result, nil
+	case left.scalar || right.scalar:
+		vector, scalar := left, right.series[0].values
+		if left.scalar {
+			vector, scalar = right, left.series[0].values
+		}
+		result := promValue{series: make([]*promSeries, len(vector.series))}
+		for j, s := range vector.series {
+			rs := &promSeries{labels: s.labels, values: make([]float64, len(s.values))}
+			if dropName {
+				rs.labels = dropMetricName(s.labels)
+			}
+			for i, x := range s.values {
+				if left.scalar {
+					rs.values[i] = apply(scalar[i], x, x)
+				} else {
+					rs.values[i] = apply(x, scalar[i], x)
+				}
+			}
+			result.series[j] = rs
+		}
+		return result, nil
+	}
+
+	matching := matchingSignature(be.GroupModifier)
+	rightBySig := make(map[string]*promSeries, len(right.series))
+	for _, s := range right.series {
+		sig := matching(s.labels)
+		if _, ok := rightBySig[sig]; ok {
+			return promValue{}, fmt.Errorf("many-to-many matching not allowed: found duplicate series on the right side of the operation")
+		}
+		rightBySig[sig] = s
+	}
+	matched := make(map[string]bool, len(left.series))
+	result := promValue{}
+	for _, s := range left.series {
+		sig := matching(s.labels)
+		r, ok := rightBySig[sig]
+		if !ok {
+			continue
+		}
+		if matched[sig] {
+			return promValue{}, fmt.Errorf("many-to-many matching not allowed: found duplicate series on the left side of the operation")
+		}
+		matched[sig] = true
+		rs := &promSeries{labels: resultLabels(s.labels, be.GroupModifier, dropName), values: make([]float64, len(s.values))}
+		for i := range s.values {
+			rs.values[i] = apply(s.values[i], r.values[i], s.values[i])
+		}
+		result.series = append(result.series, rs)
+	}
+	return result, nil
+}
+
+// matchingSignature 向量匹配使用的标签，与 Prometheus 一样始终忽略指标名
+func matchingSignature(modifier metricsql.ModifierExpr) func(map[string]string) string {
+	switch strings.ToLower(modifier.Op) {
+	case "on":
+		return func(labels map[string]string) string {
+			return labelsSignature(labels, modifier.Args, true)
+		}
+	case "ignoring":
+		ignored := append(slices.Clone(modifier.Args), "__name__")
+		return func(labels map[string]string) string {
+			return labelsSignature(labels, ignored, false)
+		}
+	default:
+		return func(labels map[string]string) string {
+			return labelsSignature(labels, []string{"__name__"}, false)
+		}
+	}
+}
+
+func resultLabels(labels map[string]string, modifier metricsql.ModifierExpr, dropName bool) map[string]string {
+	result := maps.Clone(labels)
+	if dropName {
+		delete(result, "__name__")
+	}
+	switch strings.ToLower(modifier.Op) {
+	case "on":
+		maps.DeleteFunc(result, func(k, _ string) bool { return !slices.Contains(modifier.Args, k) })
+	case "ignoring":
+		maps.DeleteFunc(result, func(k, _ string) bool { return slices.Contains(modifier.Args, k) })
+	}
+	return result
+}
+
+// setOperation 按时刻计算 and/or/unless：只要另一侧同签名的序列在该时刻有值即视为匹配
+func (ev *promEvaluator) setOperation(op string, left, right promValue, modifier metricsql.ModifierExpr) promValue {
+	matching := matchingSignature(modifier)
+	present := func(v promValue) map[string][]bool {
+		result := make(map[string][]bool)
+		for _, s := range v.series {
+			sig := matching(s.labels)
+			if result[sig] == nil {
+				result[sig] = make([]bool, len(ev.timestamps))
+			}
+			for i, x := range s.values {
+				if !math.IsNaN(x) {
+					result[sig][i] = true
+				}
+			}
+		}
+		return result
+	}
+	filter := func(v promValue, other map[string][]bool, keep bool) []*promSeries {
+		series := make([]*promSeries, 0, len(v.series))
+		for _, s := range v.series {
+			has := other[matching(s.labels)]
+			rs := &promSeries{labels: s.labels, values: slices.Clone(s.values)}
+			for i := range rs.values {
+				if (has != nil && has[i]) != keep {
+					rs.values[i] = math.NaN()
+				}
+			}
+			series = append(series, rs)
+		}
+		return series
+	}
+
+	switch op {
+	case "and":
+		return promValue{series: filter(left, present(right), true)}
+	case "unless":
+		return promValue{series: filter(left, present(right), false)}
+	default:
+		return promValue{series: append(slices.Clone(left.series), filter(right, present(left), false)...)}
+	}
+}
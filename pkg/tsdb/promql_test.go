@@ -0,0 +1,220 @@
+package tsdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupPromTestDB 写入两台服务器 10 分钟的数据，每分钟一个点；
+// server 1 的 CPU 为 10..55，server 2 为 20..65，出站流量每分钟增加 60
+func setupPromTestDB(t *testing.T) (*TSDB, time.Time) {
+	tempDir, err := os.MkdirTemp("", "tsdb_test")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+
+	db, err := Open(&Config{
+		DataPath:           filepath.Join(tempDir, "tsdb"),
+		RetentionDays:      1,
+		MinFreeDiskSpaceGB: 1,
+		DedupInterval:      time.Second,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	base := time.Now().Truncate(time.Minute).Add(-time.Hour)
+	for i := 0; i < 10; i++ {
+		for serverID := uint64(1); serverID <= 2; serverID++ {
+			require.NoError(t, db.WriteServerMetrics(&ServerMetrics{
+				ServerID:       serverID,
+				Timestamp:      base.Add(time.Duration(i) * time.Minute),
+				CPU:            float64(serverID*10 + uint64(i)*5),
+				NetOutTransfer: uint64(60 * i),
+			}))
+		}
+	}
+	db.Flush()
+	return db, base.Add(9 * time.Minute)
+}
+
+func promValueOf(t *testing.T, r *PromResult, serverID string) float64 {
+	t.Helper()
+	for _, s := range r.Series {
+		if s.Labels["server_id"] == serverID {
+			require.NotEmpty(t, s.Values)
+			return s.Values[len(s.Values)-1]
+		}
+	}
+	t.Fatalf("series for server %s not found", serverID)
+	return 0
+}
+
+func TestTSDB_PromQuery(t *testing.T) {
+	db, last := setupPromTestDB(t)
+
+	r, err := db.PromQuery(`nezha_server_cpu`, last, QueryScope{})
+	require.NoError(t, err)
+	require.Len(t, r.Series, 2)
+	assert.Equal(t, "nezha_server_cpu", r.Series[0].Labels["__name__"])
+	assert.Equal(t, float64(55), promValueOf(t, r, "1"))
+	assert.Equal(t, float64(65), promValueOf(t, r, "2"))
+
+	r, err = db.PromQuery(`nezha_server_cpu{server_id="2"} offset 5m`, last, QueryScope{})
+	require.NoError(t, err)
+	require.Len(t, r.Series, 1)
+	assert.Equal(t, float64(40), promValueOf(t, r, "2"))
+
+	r, err = db.PromQuery(`max_over_time(nezha_server_cpu{server_id=~"1"}[3m])`, last.Add(-time.Minute), QueryScope{})
+	require.NoError(t, err)
+	require.Len(t, r.Series, 1)
+	assert.NotContains(t, r.Series[0].Labels, "__name__")
+	assert.Equal(t, float64(50), promValueOf(t, r, "1"))
+
+	r, err = db.PromQuery(`rate(nezha_server_net_out_transfer[5m])`, last, QueryScope{})
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), promValueOf(t, r, "1"))
+
+	r, err = db.PromQuery(`sum(nezha_server_cpu)`, last, QueryScope{})
+	require.NoError(t, err)
+	require.Len(t, r.Series, 1)
+	assert.Empty(t, r.Series[0].Labels)
+	assert.Equal(t, []float64{120}, r.Series[0].Values)
+
+	r, err = db.PromQuery(`topk(1, nezha_server_cpu)`, last, QueryScope{})
+	require.NoError(t, err)
+	require.Len(t, r.Series, 1)
+	assert.Equal(t, "2", r.Series[0].Labels["server_id"])
+
+	r, err = db.PromQuery(`nezha_server_cpu / 100 > 0.6`, last, QueryScope{})
+	require.NoError(t, err)
+	require.Len(t, r.Series, 1)
+	assert.Equal(t, 0.65, promValueOf(t, r, "2"))
+
+	r, err = db.PromQuery(`nezha_server_cpu - on(server_id) nezha_server_net_out_transfer`, last, QueryScope{})
+	require.NoError(t, err)
+	require.Len(t, r.Series, 2)
+	assert.Equal(t, float64(55-540), promValueOf(t, r, "1"))
+
+	r, err = db.PromQuery(`nezha_server_cpu unless nezha_server_cpu{server_id="1"}`, last, QueryScope{})
+	require.NoError(t, err)
+	require.Len(t, r.Series, 1)
+	assert.Equal(t, "2", r.Series[0].Labels["server_id"])
+
+	r, err = db.PromQuery(`1 + 2`, last, QueryScope{})
+	require.NoError(t, err)
+	assert.True(t, r.Scalar)
+	assert.Equal(t, []float64{3}, r.Series[0].Values)
+
+	for _, q := range []string{
+		`nezha_server_cpu[5m]`,
+		`rate(nezha_server_cpu[5m:1m])`,
+		`histogram_quantile(0.9, nezha_server_cpu)`,
+		`nezha_server_cpu * on() group_left nezha_server_load1`,
+		`nezha_server_cpu{`,
+	} {
+		_, err := db.PromQuery(q, last, QueryScope{})
+		assert.Error(t, err, q)
+	}
+}
+
+func TestTSDB_PromQueryRange(t *testing.T) {
+	db, last := setupPromTestDB(t)
+
+	r, err := db.PromQueryRange(`avg(nezha_server_cpu) by (server_id)`, last.Add(-4*time.Minute), last, time.Minute, QueryScope{})
+	require.NoError(t, err)
+	require.Len(t, r.Series, 2)
+	assert.Equal(t, []float64{35, 40, 45, 50, 55}, r.Series[0].Values)
+	assert.Equal(t, last.UnixMilli(), r.Series[0].Timestamps[4])
+
+	_, err = db.PromQueryRange(`nezha_server_cpu`, last.Add(-24*time.Hour), last, time.Second, QueryScope{})
+	assert.Error(t, err)
+	_, err = db.PromQueryRange(`nezha_server_cpu`, last, last.Add(-time.Minute), time.Minute, QueryScope{})
+	assert.Error(t, err)
+}
+
+func TestTSDB_PromQueryScope(t *testing.T) {
+	db, last := setupPromTestDB(t)
+	scope := QueryScope{Restricted: true, ServerIDs: []uint64{2}, ServiceIDs: []uint64{3}}
+
+	r, err := db.PromQuery(`nezha_server_cpu`, last, scope)
+	require.NoError(t, err)
+	require.Len(t, r.Series, 1)
+	assert.Equal(t, "2", r.Series[0].Labels["server_id"])
+
+	r, err = db.PromQuery(`nezha_server_cpu{server_id="1"}`, last, scope)
+	require.NoError(t, err)
+	assert.Empty(t, r.Series)
+
+	r, err = db.PromQuery(`nezha_server_cpu`, last, QueryScope{Restricted: true})
+	require.NoError(t, err)
+	assert.Empty(t, r.Series)
+
+	series, err := db.PromSeriesLabels([]string{`{__name__=~"nezha_server_cpu|nezha_server_load1"}`}, last.Add(-time.Hour), last, scope)
+	require.NoError(t, err)
+	require.Len(t, series, 2)
+	for _, s := range series {
+		assert.Equal(t, "2", s["server_id"])
+	}
+
+	names, err := db.PromLabelNames(nil, last.Add(-time.Hour), last, QueryScope{})
+	require.NoError(t, err)
+	assert.Contains(t, names, "server_id")
+
+	values, err := db.PromLabelValues("server_id", nil, last.Add(-time.Hour), last, QueryScope{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, values)
+
+	values, err = db.PromLabelValues("server_id", nil, last.Add(-time.Hour), last, scope)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2"}, values)
+
+	_, err = db.PromSeriesLabels(nil, last.Add(-time.Hour), last, scope)
+	assert.Error(t, err)
+
+	// 服务指标按上报的服务器与服务同时限制
+	for serviceID := uint64(3); serviceID <= 4; serviceID++ {
+		require.NoError(t, db.WriteServiceMetrics(&ServiceMetrics{ServiceID: serviceID, ServerID: 2, Timestamp: last, Delay: 10, Successful: true}))
+	}
+	db.Flush()
+	r, err = db.PromQuery(`nezha_service_delay`, last, QueryScope{})
+	require.NoError(t, err)
+	assert.Len(t, r.Series, 2)
+	r, err = db.PromQuery(`nezha_service_delay`, last, scope)
+	require.NoError(t, err)
+	require.Len(t, r.Series, 1)
+	assert.Equal(t, "3", r.Series[0].Labels["service_id"])
+	r, err = db.PromQuery(`nezha_service_delay`, last, QueryScope{Restricted: true, ServerIDs: []uint64{2}})
+	require.NoError(t, err)
+	assert.Empty(t, r.Series)
+	values, err = db.PromLabelValues("service_id", nil, last.Add(-time.Hour), last, scope)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3"}, values)
+}
+
+func TestParsePromTimeAndDuration(t *testing.T) {
+	ts, err := ParsePromTime("1700000000.5")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1700000000500), ts.UnixMilli())
+
+	ts, err = ParsePromTime("2023-11-14T22:13:20Z")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1700000000), ts.Unix())
+
+	_, err = ParsePromTime("yesterday")
+	assert.Error(t, err)
+
+	d, err := ParsePromDuration("15")
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Second, d)
+
+	d, err = ParsePromDuration("1m30s")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, d)
+
+	_, err = ParsePromDuration("-5m")
+	assert.Error(t, err)
+}
@@ -3,11 +3,13 @@ package tsdb
 import (
 	"fmt"
 	"log"
+	"math"
 	"sort"
 	"strconv"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metricsql"
 
 	"github.com/nezhahq/nezha/model"
 )
@@ -77,31 +79,52 @@ type rawDataPoint struct {
 	status    float64
 	hasDelay  bool
 	hasStatus bool
+	samples   uint64 // 降采样数据代表的原始采样数，原始数据为 0
+}
+
+// weight 该点代表的原始采样数
+func (p rawDataPoint) weight() uint64 {
+	return max(p.samples, 1)
+}
+
+// upCount 该点代表的原始采样中状态正常的数量，降采样数据的 status 为正常比例
+func (p rawDataPoint) upCount() uint64 {
+	return uint64(math.Round(p.status * float64(p.weight())))
 }
 
 func (db *TSDB) QueryServiceHistory(serviceID uint64, period QueryPeriod) (*ServiceHistoryResult, error) {
+	return db.QueryServiceHistoryRange(serviceID, period.Range(time.Now()))
+}
+
+// QueryServiceHistoryRange 查询服务在指定范围内各监测点的历史，延迟按 r 的步长与聚合方式降采样
+func (db *TSDB) QueryServiceHistoryRange(serviceID uint64, r QueryRange) (*ServiceHistoryResult, error) {
 	db.mu.RLock()
 	defer db.mu.RUnlock()
 	if db.closed {
 		return nil, fmt.Errorf("TSDB is closed")
 	}
+	if r.Aggregation.IsCounter() {
+		return nil, fmt.Errorf("aggregation %s is not supported for service history", r.Aggregation)
+	}
 
-	now := time.Now()
+	r = r.Normalized()
 	tr := storage.TimeRange{
-		MinTimestamp: now.Add(-period.Duration()).UnixMilli(),
-		MaxTimestamp: now.UnixMilli(),
+		MinTimestamp: r.From.UnixMilli(),
+		MaxTimestamp: r.To.UnixMilli(),
 	}
 
 	serviceIDStr := strconv.FormatUint(serviceID, 10)
 
-	delayData, err := db.queryMetricByServiceID(MetricServiceDelay, serviceIDStr, tr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query delay data: %w", err)
+	var serverPoints map[uint64][]rawDataPoint
+	var err error
+	if tier := db.rollupTierFor(r.From, time.Now()); tier != nil {
+		r.Step = max(r.Step, tier.Interval)
+		serverPoints, err = db.queryServiceRollupPoints(tier, "service_id", serviceIDStr, "server_id", tr)
+	} else {
+		serverPoints, err = db.queryServicePoints("service_id", serviceIDStr, "server_id", tr)
 	}
-
-	statusData, err := db.queryMetricByServiceID(MetricServiceStatus, serviceIDStr, tr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query status data: %w", err)
+		return nil, err
 	}
 
 	result := &ServiceHistoryResult{
@@ -109,45 +132,8 @@ func (db *TSDB) QueryServiceHistory(serviceID uint64, period QueryPeriod) (*Serv
 		Servers:   make([]ServerServiceStats, 0),
 	}
 
-	serverDataMap := make(map[uint64]map[int64]*rawDataPoint)
-
-	for serverID, points := range delayData {
-		if serverDataMap[serverID] == nil {
-			serverDataMap[serverID] = make(map[int64]*rawDataPoint)
-		}
-		for _, p := range points {
-			serverDataMap[serverID][p.timestamp] = &rawDataPoint{
-				timestamp: p.timestamp,
-				value:     p.value,
-				hasDelay:  true,
-			}
-		}
-	}
-
-	for serverID, points := range statusData {
-		if serverDataMap[serverID] == nil {
-			serverDataMap[serverID] = make(map[int64]*rawDataPoint)
-		}
-		for _, p := range points {
-			if existing, ok := serverDataMap[serverID][p.timestamp]; ok {
-				existing.status = p.value
-				existing.hasStatus = true
-			} else {
-				serverDataMap[serverID][p.timestamp] = &rawDataPoint{
-					timestamp: p.timestamp,
-					status:    p.value,
-					hasStatus: true,
-				}
-			}
-		}
-	}
-
-	for serverID, pointsMap := range serverDataMap {
-		points := make([]rawDataPoint, 0, len(pointsMap))
-		for _, p := range pointsMap {
-			points = append(points, *p)
-		}
-		stats := calculateStats(points, period.DownsampleInterval())
+	for serverID, points := range serverPoints {
+		stats := calculateStats(points, r.Step, r.aggregation(AggregationAvg))
 		result.Servers = append(result.Servers, ServerServiceStats{
 			ServerID: serverID,
 			Stats:    stats,
@@ -229,75 +215,99 @@ type metricPoint struct {
 }
 
 func (db *TSDB) queryMetricByServiceID(metric MetricType, serviceID string, tr storage.TimeRange) (map[uint64][]metricPoint, error) {
-	tfs := storage.NewTagFilters()
-	if err := tfs.Add(nil, []byte(metric), false, false); err != nil {
-		return nil, err
-	}
-	if err := tfs.Add([]byte("service_id"), []byte(serviceID), false, false); err != nil {
-		return nil, err
-	}
+	return db.queryMetricGroupedBy(metric, "service_id", serviceID, "server_id", tr)
+}
 
+// queryMetricGroupedBy 读取 label=value 的全部序列，按 groupLabel 的取值分组
+func (db *TSDB) queryMetricGroupedBy(metric MetricType, label, value, groupLabel string, tr storage.TimeRange) (map[uint64][]metricPoint, error) {
 	deadline := uint64(time.Now().Add(30 * time.Second).Unix())
+	series, err := db.backend.search(metricFilters(metric, label, value), tr, 100000, deadline)
+	if err != nil {
+		return nil, err
+	}
+	return groupSeries(series, groupLabel), nil
+}
 
-	var search storage.Search
-	search.Init(nil, db.storage, []*storage.TagFilters{tfs}, tr, 100000, deadline)
-	defer search.MustClose()
-
+// groupSeries 按 groupLabel 的取值合并序列的采样点
+func groupSeries(series []*rawSeries, groupLabel string) map[uint64][]metricPoint {
 	result := make(map[uint64][]metricPoint)
-	var timestamps []int64
-	var values []float64
-
-	for search.NextMetricBlock() {
-		mbr := search.MetricBlockRef
-		var block storage.Block
-		mbr.BlockRef.MustReadBlock(&block)
-
-		mn := storage.GetMetricName()
-		if err := mn.Unmarshal(mbr.MetricName); err != nil {
-			log.Printf("NEZHA>> TSDB: failed to unmarshal metric name: %v", err)
-			storage.PutMetricName(mn)
+	for _, rs := range series {
+		groupValue := rs.labels[groupLabel]
+		if groupValue == "" {
 			continue
 		}
-
-		serverIDBytes := mn.GetTagValue("server_id")
-		if len(serverIDBytes) == 0 {
-			storage.PutMetricName(mn)
-			continue
-		}
-
-		serverID, err := strconv.ParseUint(string(serverIDBytes), 10, 64)
+		id, err := strconv.ParseUint(groupValue, 10, 64)
 		if err != nil {
-			log.Printf("NEZHA>> TSDB: failed to parse server_id %q: %v", string(serverIDBytes), err)
-			storage.PutMetricName(mn)
+			log.Printf("NEZHA>> TSDB: failed to parse %s %q: %v", groupLabel, groupValue, err)
 			continue
 		}
-		storage.PutMetricName(mn)
-
-		if err := block.UnmarshalData(); err != nil {
-			log.Printf("NEZHA>> TSDB: failed to unmarshal block data: %v", err)
-			continue
+		for _, p := range rs.points {
+			result[id] = append(result[id], metricPoint{
+				timestamp: p.timestamp,
+				value:     p.value,
+			})
 		}
+	}
+	return result
+}
 
-		timestamps = timestamps[:0]
-		values = values[:0]
-		timestamps, values = block.AppendRowsWithTimeRangeFilter(timestamps, values, tr)
+// queryServicePoints 读取 label=value 的服务延迟与状态，按 groupLabel 分组并按时间戳合并
+func (db *TSDB) queryServicePoints(label, value, groupLabel string, tr storage.TimeRange) (map[uint64][]rawDataPoint, error) {
+	delayData, err := db.queryMetricGroupedBy(MetricServiceDelay, label, value, groupLabel, tr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query delay data: %w", err)
+	}
 
-		for i := range timestamps {
-			result[serverID] = append(result[serverID], metricPoint{
-				timestamp: timestamps[i],
-				value:     values[i],
-			})
+	statusData, err := db.queryMetricGroupedBy(MetricServiceStatus, label, value, groupLabel, tr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query status data: %w", err)
+	}
+
+	dataMap := make(map[uint64]map[int64]*rawDataPoint)
+
+	for id, points := range delayData {
+		if dataMap[id] == nil {
+			dataMap[id] = make(map[int64]*rawDataPoint)
+		}
+		for _, p := range points {
+			dataMap[id][p.timestamp] = &rawDataPoint{
+				timestamp: p.timestamp,
+				value:     p.value,
+				hasDelay:  true,
+			}
 		}
 	}
 
-	if err := search.Error(); err != nil {
-		return nil, err
+	for id, points := range statusData {
+		if dataMap[id] == nil {
+			dataMap[id] = make(map[int64]*rawDataPoint)
+		}
+		for _, p := range points {
+			if existing, ok := dataMap[id][p.timestamp]; ok {
+				existing.status = p.value
+				existing.hasStatus = true
+			} else {
+				dataMap[id][p.timestamp] = &rawDataPoint{
+					timestamp: p.timestamp,
+					status:    p.value,
+					hasStatus: true,
+				}
+			}
+		}
 	}
 
+	result := make(map[uint64][]rawDataPoint, len(dataMap))
+	for id, pointsMap := range dataMap {
+		points := make([]rawDataPoint, 0, len(pointsMap))
+		for _, p := range pointsMap {
+			points = append(points, *p)
+		}
+		result[id] = points
+	}
 	return result, nil
 }
 
-func calculateStats(points []rawDataPoint, downsampleInterval time.Duration) ServiceHistorySummary {
+func calculateStats(points []rawDataPoint, downsampleInterval time.Duration, agg Aggregation) ServiceHistorySummary {
 	if len(points) == 0 {
 		return ServiceHistorySummary{}
 	}
@@ -307,20 +317,18 @@ func calculateStats(points []rawDataPoint, downsampleInterval time.Duration) Ser
 	})
 
 	var totalDelay float64
-	var delayCount int
+	var delayCount uint64
 	var totalUp, totalDown uint64
 
 	for _, p := range points {
 		if p.hasDelay {
-			totalDelay += p.value
-			delayCount++
+			totalDelay += p.value * float64(p.weight())
+			delayCount += p.weight()
 		}
 		if p.hasStatus {
-			if p.status >= 0.5 {
-				totalUp++
-			} else {
-				totalDown++
-			}
+			up := p.upCount()
+			totalUp += up
+			totalDown += p.weight() - up
 		}
 	}
 
@@ -337,12 +345,13 @@ func calculateStats(points []rawDataPoint, downsampleInterval time.Duration) Ser
 		summary.UpPercent = float32(totalUp) / float32(totalUp+totalDown) * 100
 	}
 
-	summary.DataPoints = downsample(points, downsampleInterval)
+	summary.DataPoints = downsample(points, downsampleInterval, agg)
 
 	return summary
 }
 
-func downsample(points []rawDataPoint, interval time.Duration) []DataPoint {
+// downsample 按 interval 分桶，延迟按 agg 聚合，状态取多数
+func downsample(points []rawDataPoint, interval time.Duration, agg Aggregation) []DataPoint {
 	if len(points) == 0 {
 		return nil
 	}
@@ -352,14 +361,11 @@ func downsample(points []rawDataPoint, interval time.Duration) []DataPoint {
 
 	// points 已排序，线性扫描分桶
 	bucketStart := (points[0].timestamp / intervalMs) * intervalMs
-	var totalDelay float64
-	var delayCount, upCount, statusCount int
+	var delays []rawDataPoint
+	var upCount, statusCount uint64
 
 	flushBucket := func() {
-		var avgDelay float64
-		if delayCount > 0 {
-			avgDelay = totalDelay / float64(delayCount)
-		}
+		avgDelay, _ := aggregatePoints(delays, agg)
 		var status uint8
 		if statusCount > 0 && upCount > statusCount/2 {
 			status = 1
@@ -376,20 +382,16 @@ func downsample(points []rawDataPoint, interval time.Duration) []DataPoint {
 		if key != bucketStart {
 			flushBucket()
 			bucketStart = key
-			totalDelay = 0
-			delayCount = 0
+			delays = delays[:0]
 			upCount = 0
 			statusCount = 0
 		}
 		if p.hasDelay {
-			totalDelay += p.value
-			delayCount++
+			delays = append(delays, rawDataPoint{timestamp: p.timestamp, value: p.value})
 		}
 		if p.hasStatus {
-			statusCount++
-			if p.status >= 0.5 {
-				upCount++
-			}
+			statusCount += p.weight()
+			upCount += p.upCount()
 		}
 	}
 	flushBucket()
@@ -397,7 +399,8 @@ func downsample(points []rawDataPoint, interval time.Duration) []DataPoint {
 	return result
 }
 
-func downsampleMetrics(points []rawDataPoint, interval time.Duration, useLastValue bool) []MetricDataPoint {
+// downsampleMetrics 按 interval 分桶并按 agg 聚合，桶内数据不足以计算时（如 rate 只有一个点）跳过该桶
+func downsampleMetrics(points []rawDataPoint, interval time.Duration, agg Aggregation) []MetricDataPoint {
 	if len(points) == 0 {
 		return nil
 	}
@@ -410,21 +413,15 @@ func downsampleMetrics(points []rawDataPoint, interval time.Duration, useLastVal
 	result := make([]MetricDataPoint, 0)
 
 	bucketStart := (points[0].timestamp / intervalMs) * intervalMs
-	var total float64
-	var count int
-	var last rawDataPoint
+	bucket := make([]rawDataPoint, 0)
 
 	flushBucket := func() {
-		var value float64
-		if useLastValue {
-			value = last.value
-		} else if count > 0 {
-			value = total / float64(count)
+		if value, ok := aggregatePoints(bucket, agg); ok {
+			result = append(result, MetricDataPoint{
+				Timestamp: bucketStart,
+				Value:     value,
+			})
 		}
-		result = append(result, MetricDataPoint{
-			Timestamp: bucketStart,
-			Value:     value,
-		})
 	}
 
 	for _, p := range points {
@@ -432,12 +429,9 @@ func downsampleMetrics(points []rawDataPoint, interval time.Duration, useLastVal
 		if key != bucketStart {
 			flushBucket()
 			bucketStart = key
-			total = 0
-			count = 0
+			bucket = bucket[:0]
 		}
-		total += p.value
-		count++
-		last = p
+		bucket = append(bucket, p)
 	}
 	flushBucket()
 
@@ -447,7 +441,8 @@ func downsampleMetrics(points []rawDataPoint, interval time.Duration, useLastVal
 // isCumulativeMetric 判断指标是否为累积型（单调递增）
 func isCumulativeMetric(metric MetricType) bool {
 	switch metric {
-	case MetricServerNetInTransfer, MetricServerNetOutTransfer, MetricServerUptime:
+	case MetricServerNetInTransfer, MetricServerNetOutTransfer, MetricServerUptime,
+		MetricServerNICInTransfer, MetricServerNICOutTransfer:
 		return true
 	default:
 		return false
@@ -455,65 +450,88 @@ func isCumulativeMetric(metric MetricType) bool {
 }
 
 func (db *TSDB) QueryServerMetrics(serverID uint64, metric MetricType, period QueryPeriod) ([]MetricDataPoint, error) {
+	return db.QueryServerMetricsRange(serverID, metric, period.Range(time.Now()))
+}
+
+// QueryServerMetricsRange 查询服务器指标在指定范围内的历史，按 r 的步长与聚合方式降采样
+func (db *TSDB) QueryServerMetricsRange(serverID uint64, metric MetricType, r QueryRange) ([]MetricDataPoint, error) {
+	return db.QueryServerDeviceMetricsRange(serverID, metric, "", r)
+}
+
+// QueryServerDeviceMetricsRange 与 QueryServerMetricsRange 相同，按设备区分的指标只查询 device 的序列
+func (db *TSDB) QueryServerDeviceMetricsRange(serverID uint64, metric MetricType, device string, r QueryRange) ([]MetricDataPoint, error) {
 	db.mu.RLock()
 	defer db.mu.RUnlock()
 	if db.closed {
 		return nil, fmt.Errorf("TSDB is closed")
 	}
+	if r.Aggregation.IsCounter() && !isCumulativeMetric(metric) {
+		return nil, fmt.Errorf("aggregation %s only applies to cumulative metrics", r.Aggregation)
+	}
 
-	now := time.Now()
+	r = r.Normalized()
 	tr := storage.TimeRange{
-		MinTimestamp: now.Add(-period.Duration()).UnixMilli(),
-		MaxTimestamp: now.UnixMilli(),
+		MinTimestamp: r.From.UnixMilli(),
+		MaxTimestamp: r.To.UnixMilli(),
 	}
 
-	serverIDStr := strconv.FormatUint(serverID, 10)
+	agg := AggregationAvg
+	if isCumulativeMetric(metric) {
+		agg = AggregationLast
+	}
+	agg = r.aggregation(agg)
 
-	tfs := storage.NewTagFilters()
-	if err := tfs.Add(nil, []byte(metric), false, false); err != nil {
+	filters, err := serverMetricFilters(serverID, metric, device)
+	if err != nil {
 		return nil, err
 	}
-	if err := tfs.Add([]byte("server_id"), []byte(serverIDStr), false, false); err != nil {
+	var points []rawDataPoint
+	if tier := db.rollupTierFor(r.From, time.Now()); tier != nil {
+		r.Step = max(r.Step, tier.Interval)
+		points, err = db.queryRollupPoints(tier, filters, rollupStat(agg), tr)
+	} else {
+		points, err = db.searchPoints(db.backend, filters, tr)
+	}
+	if err != nil {
 		return nil, err
 	}
+	return downsampleMetrics(points, r.Step, agg), nil
+}
 
-	deadline := uint64(time.Now().Add(30 * time.Second).Unix())
-
-	var search storage.Search
-	search.Init(nil, db.storage, []*storage.TagFilters{tfs}, tr, 100000, deadline)
-	defer search.MustClose()
-
-	var points []rawDataPoint
-	var timestamps []int64
-	var values []float64
-
-	for search.NextMetricBlock() {
-		mbr := search.MetricBlockRef
-		var block storage.Block
-		mbr.BlockRef.MustReadBlock(&block)
-
-		if err := block.UnmarshalData(); err != nil {
-			log.Printf("NEZHA>> TSDB: failed to unmarshal block data: %v", err)
-			continue
+// serverMetricFilters 单台服务器某项指标的选择条件，按设备区分的指标必须指定 device，其他指标忽略 device
+func serverMetricFilters(serverID uint64, metric MetricType, device string) (seriesFilters, error) {
+	filters := metricFilters(metric, "server_id", strconv.FormatUint(serverID, 10))
+	if label := metric.DeviceLabel(); label != "" {
+		if device == "" {
+			return nil, fmt.Errorf("metric %s requires a %s", metric, label)
 		}
+		filters[0] = append(filters[0], metricsql.LabelFilter{Label: label, Value: device})
+	}
+	return filters, nil
+}
 
-		timestamps = timestamps[:0]
-		values = values[:0]
-		timestamps, values = block.AppendRowsWithTimeRangeFilter(timestamps, values, tr)
-
-		for i := range timestamps {
-			points = append(points, rawDataPoint{
-				timestamp: timestamps[i],
-				value:     values[i],
-			})
-		}
+// queryServerMetricPoints 读取单台服务器某项指标在 tr 内的原始采样点，调用方需持有读锁
+func (db *TSDB) queryServerMetricPoints(serverID uint64, metric MetricType, device string, tr storage.TimeRange) ([]rawDataPoint, error) {
+	filters, err := serverMetricFilters(serverID, metric, device)
+	if err != nil {
+		return nil, err
 	}
+	return db.searchPoints(db.backend, filters, tr)
+}
 
-	if err := search.Error(); err != nil {
+// searchPoints 读取 b 中匹配 filters 的全部采样点，不区分序列
+func (db *TSDB) searchPoints(b backend, filters seriesFilters, tr storage.TimeRange) ([]rawDataPoint, error) {
+	deadline := uint64(time.Now().Add(30 * time.Second).Unix())
+	series, err := b.search(filters, tr, 100000, deadline)
+	if err != nil {
 		return nil, err
 	}
 
-	return downsampleMetrics(points, period.DownsampleInterval(), isCumulativeMetric(metric)), nil
+	var points []rawDataPoint
+	for _, rs := range series {
+		points = append(points, rs.points...)
+	}
+	return points, nil
 }
 
 func (db *TSDB) QueryServiceHistoryByServerID(serverID uint64, period QueryPeriod) (map[uint64]*ServiceHistoryResult, error) {
@@ -531,57 +549,23 @@ func (db *TSDB) QueryServiceHistoryByServerID(serverID uint64, period QueryPerio
 
 	serverIDStr := strconv.FormatUint(serverID, 10)
 
-	delayData, err := db.queryMetricByServerID(MetricServiceDelay, serverIDStr, tr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query delay data: %w", err)
+	step := period.DownsampleInterval()
+	var servicePoints map[uint64][]rawDataPoint
+	var err error
+	if tier := db.rollupTierFor(now.Add(-period.Duration()), now); tier != nil {
+		step = max(step, tier.Interval)
+		servicePoints, err = db.queryServiceRollupPoints(tier, "server_id", serverIDStr, "service_id", tr)
+	} else {
+		servicePoints, err = db.queryServicePoints("server_id", serverIDStr, "service_id", tr)
 	}
-
-	statusData, err := db.queryMetricByServerID(MetricServiceStatus, serverIDStr, tr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query status data: %w", err)
-	}
-
-	serviceDataMap := make(map[uint64]map[int64]*rawDataPoint)
-
-	for serviceID, points := range delayData {
-		if serviceDataMap[serviceID] == nil {
-			serviceDataMap[serviceID] = make(map[int64]*rawDataPoint)
-		}
-		for _, p := range points {
-			serviceDataMap[serviceID][p.timestamp] = &rawDataPoint{
-				timestamp: p.timestamp,
-				value:     p.value,
-				hasDelay:  true,
-			}
-		}
-	}
-
-	for serviceID, points := range statusData {
-		if serviceDataMap[serviceID] == nil {
-			serviceDataMap[serviceID] = make(map[int64]*rawDataPoint)
-		}
-		for _, p := range points {
-			if existing, ok := serviceDataMap[serviceID][p.timestamp]; ok {
-				existing.status = p.value
-				existing.hasStatus = true
-			} else {
-				serviceDataMap[serviceID][p.timestamp] = &rawDataPoint{
-					timestamp: p.timestamp,
-					status:    p.value,
-					hasStatus: true,
-				}
-			}
-		}
+		return nil, err
 	}
 
 	results := make(map[uint64]*ServiceHistoryResult)
 
-	for serviceID, pointsMap := range serviceDataMap {
-		points := make([]rawDataPoint, 0, len(pointsMap))
-		for _, p := range pointsMap {
-			points = append(points, *p)
-		}
-		stats := calculateStats(points, period.DownsampleInterval())
+	for serviceID, points := range servicePoints {
+		stats := calculateStats(points, step, AggregationAvg)
 		results[serviceID] = &ServiceHistoryResult{
 			ServiceID: serviceID,
 			Servers: []ServerServiceStats{{
@@ -593,72 +577,3 @@ func (db *TSDB) QueryServiceHistoryByServerID(serverID uint64, period QueryPerio
 
 	return results, nil
 }
-
-func (db *TSDB) queryMetricByServerID(metric MetricType, serverID string, tr storage.TimeRange) (map[uint64][]metricPoint, error) {
-	tfs := storage.NewTagFilters()
-	if err := tfs.Add(nil, []byte(metric), false, false); err != nil {
-		return nil, err
-	}
-	if err := tfs.Add([]byte("server_id"), []byte(serverID), false, false); err != nil {
-		return nil, err
-	}
-
-	deadline := uint64(time.Now().Add(30 * time.Second).Unix())
-
-	var search storage.Search
-	search.Init(nil, db.storage, []*storage.TagFilters{tfs}, tr, 100000, deadline)
-	defer search.MustClose()
-
-	result := make(map[uint64][]metricPoint)
-	var timestamps []int64
-	var values []float64
-
-	for search.NextMetricBlock() {
-		mbr := search.MetricBlockRef
-		var block storage.Block
-		mbr.BlockRef.MustReadBlock(&block)
-
-		mn := storage.GetMetricName()
-		if err := mn.Unmarshal(mbr.MetricName); err != nil {
-			log.Printf("NEZHA>> TSDB: failed to unmarshal metric name: %v", err)
-			storage.PutMetricName(mn)
-			continue
-		}
-
-		serviceIDBytes := mn.GetTagValue("service_id")
-		if len(serviceIDBytes) == 0 {
-			storage.PutMetricName(mn)
-			continue
-		}
-
-		serviceID, err := strconv.ParseUint(string(serviceIDBytes), 10, 64)
-		if err != nil {
-			log.Printf("NEZHA>> TSDB: failed to parse service_id %q: %v", string(serviceIDBytes), err)
-			storage.PutMetricName(mn)
-			continue
-		}
-		storage.PutMetricName(mn)
-
-		if err := block.UnmarshalData(); err != nil {
-			log.Printf("NEZHA>> TSDB: failed to unmarshal block data: %v", err)
-			continue
-		}
-
-		timestamps = timestamps[:0]
-		values = values[:0]
-		timestamps, values = block.AppendRowsWithTimeRangeFilter(timestamps, values, tr)
-
-		for i := range timestamps {
-			result[serviceID] = append(result[serviceID], metricPoint{
-				timestamp: timestamps[i],
-				value:     values[i],
-			})
-		}
-	}
-
-	if err := search.Error(); err != nil {
-		return nil, err
-	}
-
-	return result, nil
-}
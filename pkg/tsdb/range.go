@@ -0,0 +1,134 @@
+package tsdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxQueryPoints 单个序列最多返回的数据点数，步长过小时自动放大
+	MaxQueryPoints = 3000
+	// MinQueryStep 最小步长
+	MinQueryStep = time.Second
+)
+
+// QueryRange 历史查询的时间范围、步长与聚合方式
+type QueryRange struct {
+	From time.Time
+	To   time.Time
+	Step time.Duration
+	// Aggregation 每个步长内的聚合方式，为空时服务延迟取平均值，
+	// 服务器指标中累积型指标取最后一个值、其余取平均值
+	Aggregation Aggregation
+}
+
+// Range 返回截至 now 的时间段对应的查询范围
+func (p QueryPeriod) Range(now time.Time) QueryRange {
+	return QueryRange{
+		From: now.Add(-p.Duration()),
+		To:   now,
+		Step: p.DownsampleInterval(),
+	}
+}
+
+// ParseQueryRange 解析历史查询参数。
+// to 默认为 now，from 默认为 to 之前 period 的时长；from/to 支持 RFC3339 与 Unix 时间戳（秒或毫秒）；
+// step 支持 "5m" 形式的时长或秒数，默认按时间跨度选择与 period 相同的步长。
+func ParseQueryRange(period, from, to, step, aggregation string, now time.Time) (QueryRange, error) {
+	p, err := ParseQueryPeriod(period)
+	if err != nil {
+		return QueryRange{}, err
+	}
+	r := QueryRange{To: now}
+	if to != "" {
+		if r.To, err = parseQueryTime(to); err != nil {
+			return QueryRange{}, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	r.From = r.To.Add(-p.Duration())
+	if from != "" {
+		if r.From, err = parseQueryTime(from); err != nil {
+			return QueryRange{}, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if !r.From.Before(r.To) {
+		return QueryRange{}, fmt.Errorf("from must be before to")
+	}
+
+	r.Step = defaultQueryStep(r.To.Sub(r.From))
+	if step != "" {
+		if r.Step, err = parseQueryStep(step); err != nil {
+			return QueryRange{}, fmt.Errorf("invalid step: %w", err)
+		}
+		if r.Step < MinQueryStep {
+			return QueryRange{}, fmt.Errorf("step must be at least %s", MinQueryStep)
+		}
+	}
+	if aggregation != "" {
+		if r.Aggregation, err = ParseAggregation(aggregation); err != nil {
+			return QueryRange{}, err
+		}
+	}
+	return r, nil
+}
+
+// defaultQueryStep 与 QueryPeriod 的降采样间隔保持一致
+func defaultQueryStep(span time.Duration) time.Duration {
+	switch {
+	case span <= Period1Day.Duration():
+		return Period1Day.DownsampleInterval()
+	case span <= Period7Days.Duration():
+		return Period7Days.DownsampleInterval()
+	default:
+		return Period30Days.DownsampleInterval()
+	}
+}
+
+func parseQueryTime(s string) (time.Time, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		// 超过 10^12 的视为毫秒
+		if n > 1e12 {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func parseQueryStep(s string) (time.Duration, error) {
+	if n, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.ParseUint(days, 10, 16); err == nil {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+// Span 查询的时间跨度
+func (r QueryRange) Span() time.Duration {
+	return r.To.Sub(r.From)
+}
+
+// Normalized 补全默认步长，并在数据点超过 MaxQueryPoints 时放大步长（按秒取整）
+func (r QueryRange) Normalized() QueryRange {
+	if r.Step <= 0 {
+		r.Step = defaultQueryStep(r.Span())
+	}
+	if minStep := r.Span() / MaxQueryPoints; r.Step < minStep {
+		r.Step = (minStep + time.Second - 1).Truncate(time.Second)
+	}
+	r.Step = max(r.Step, MinQueryStep)
+	return r
+}
+
+func (r QueryRange) aggregation(fallback Aggregation) Aggregation {
+	if r.Aggregation == "" {
+		return fallback
+	}
+	return r.Aggregation
+}
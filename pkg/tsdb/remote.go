@@ -0,0 +1,461 @@
+package tsdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/goccy/go-json"
+	"github.com/golang/snappy"
+)
+
+const (
+	// remoteQueueSize 等待发送的批次数，队列满时直接写入暂存目录
+	remoteQueueSize = 64
+	// remoteMaxRetries 每个批次失败后立即重试的次数，仍失败则写入暂存目录
+	remoteMaxRetries = 3
+	// remoteRetryBaseDelay 首次重试的等待时间，之后每次翻倍
+	remoteRetryBaseDelay = time.Second
+	// remoteSpoolRetryInterval 重新发送暂存数据的间隔
+	remoteSpoolRetryInterval = 30 * time.Second
+
+	remoteSpoolExt = ".snappy"
+)
+
+// remoteBackend 通过 Prometheus remote write 写入外部存储，通过其 Prometheus HTTP API 读回。
+// 写入由单独的协程按批发送，失败重试后仍不可用的批次暂存到本地目录，远端恢复后按时间顺序补发
+type remoteBackend struct {
+	config     *RemoteConfig
+	client     *http.Client
+	retryDelay time.Duration
+
+	queue   chan []byte // snappy 压缩后的 WriteRequest
+	flushCh chan chan struct{}
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+
+	spoolMu      sync.Mutex
+	spoolSeq     atomic.Uint64
+	spoolPending atomic.Bool
+}
+
+func openRemote(config *Config) (*TSDB, error) {
+	rc := &config.Remote
+	for name, raw := range map[string]string{"write_url": rc.WriteURL, "query_url": rc.QueryURL} {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid remote %s %q", name, raw)
+		}
+	}
+	if err := os.MkdirAll(rc.SpoolPath, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	b := &remoteBackend{
+		config:     rc,
+		client:     &http.Client{Timeout: rc.Timeout},
+		retryDelay: remoteRetryBaseDelay,
+		queue:      make(chan []byte, remoteQueueSize),
+		flushCh:    make(chan chan struct{}),
+		stopCh:     make(chan struct{}),
+	}
+	if files, _ := b.spoolFiles(); len(files) > 0 {
+		log.Printf("NEZHA>> TSDB: %d spooled remote write batches will be resent", len(files))
+		b.spoolPending.Store(true)
+	}
+	b.wg.Add(1)
+	go b.run()
+
+	db := &TSDB{
+		backend: b,
+		config:  config,
+	}
+	db.writer = newBufferedWriter(db, config.WriteBufferSize, config.WriteBufferFlushInterval)
+
+	log.Printf("NEZHA>> TSDB writing to remote %s, querying %s, spool: %s", rc.WriteURL, rc.QueryURL, rc.SpoolPath)
+	return db, nil
+}
+
+func (b *remoteBackend) add(samples []sample) {
+	if len(samples) == 0 {
+		return
+	}
+	payload := encodeWriteRequest(samples)
+	select {
+	case b.queue <- payload:
+	default:
+		b.spool(payload)
+	}
+}
+
+// encodeWriteRequest 把同一序列的采样点合并后编码为 snappy 压缩的 WriteRequest
+func encodeWriteRequest(samples []sample) []byte {
+	var wr prompb.WriteRequest
+	index := make(map[string]int)
+	for _, s := range samples {
+		key := prompb.LabelsToString(s.labels)
+		i, ok := index[key]
+		if !ok {
+			i = len(wr.Timeseries)
+			index[key] = i
+			wr.Timeseries = append(wr.Timeseries, prompb.TimeSeries{Labels: s.labels})
+		}
+		wr.Timeseries[i].Samples = append(wr.Timeseries[i].Samples, prompb.Sample{Value: s.value, Timestamp: s.timestamp})
+	}
+	return snappy.Encode(nil, wr.MarshalProtobuf(nil))
+}
+
+func (b *remoteBackend) run() {
+	defer b.wg.Done()
+	ticker := time.NewTicker(remoteSpoolRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case payload := <-b.queue:
+			b.deliver(payload)
+		case <-ticker.C:
+			b.drainSpool()
+		case done := <-b.flushCh:
+			b.drainQueue()
+			b.drainSpool()
+			close(done)
+		case <-b.stopCh:
+			// 退出前不再等待重试，发送失败的批次留在暂存目录中，下次启动后补发
+			b.drainQueue()
+			return
+		}
+	}
+}
+
+func (b *remoteBackend) drainQueue() {
+	for {
+		select {
+		case payload := <-b.queue:
+			b.deliver(payload)
+		default:
+			return
+		}
+	}
+}
+
+// deliver 发送一个批次，远端不可用时写入暂存目录；发送成功说明远端已恢复，顺带补发暂存数据
+func (b *remoteBackend) deliver(payload []byte) {
+	retryable, err := b.sendWithRetry(payload)
+	if err == nil {
+		b.drainSpool()
+		return
+	}
+	if retryable {
+		log.Printf("NEZHA>> TSDB: remote write failed, spooling batch: %v", err)
+		b.spool(payload)
+		return
+	}
+	// 远端明确拒绝的数据重试也不会成功
+	log.Printf("NEZHA>> TSDB: remote write rejected, dropping batch: %v", err)
+}
+
+func (b *remoteBackend) sendWithRetry(payload []byte) (bool, error) {
+	for attempt := 0; ; attempt++ {
+		retryable, err := b.send(payload)
+		if err == nil || !retryable || attempt >= remoteMaxRetries {
+			return retryable, err
+		}
+		select {
+		case <-time.After(b.retryDelay << attempt):
+		case <-b.stopCh:
+			return retryable, err
+		}
+	}
+}
+
+// send 发送一次，返回的 retryable 表示失败是否可以重试（网络错误、429 与 5xx）
+func (b *remoteBackend) send(payload []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, b.config.WriteURL, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	b.setAuth(req)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+func (b *remoteBackend) setAuth(req *http.Request) {
+	if b.config.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+b.config.BearerToken)
+	} else if b.config.Username != "" {
+		req.SetBasicAuth(b.config.Username, b.config.Password)
+	}
+}
+
+// spool 把批次写入暂存目录，文件名按写入时间排序；超出容量上限时删除最早的文件
+func (b *remoteBackend) spool(payload []byte) {
+	b.spoolMu.Lock()
+	defer b.spoolMu.Unlock()
+
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), b.spoolSeq.Add(1)%1000000, remoteSpoolExt)
+	path := filepath.Join(b.config.SpoolPath, name)
+	if err := os.WriteFile(path+".tmp", payload, 0600); err != nil {
+		log.Printf("NEZHA>> TSDB: failed to spool remote write batch, dropping it: %v", err)
+		return
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		log.Printf("NEZHA>> TSDB: failed to spool remote write batch, dropping it: %v", err)
+		os.Remove(path + ".tmp")
+		return
+	}
+	b.spoolPending.Store(true)
+
+	files, err := b.spoolFiles()
+	if err != nil {
+		return
+	}
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	limit := b.config.MaxSpoolMB * 1024 * 1024
+	for i := 0; total > limit && i < len(files)-1; i++ {
+		log.Printf("NEZHA>> TSDB: remote write spool exceeds %d MB, dropping %s", b.config.MaxSpoolMB, files[i].name)
+		os.Remove(filepath.Join(b.config.SpoolPath, files[i].name))
+		total -= files[i].size
+	}
+}
+
+type spoolFile struct {
+	name string
+	size int64
+}
+
+// spoolFiles 按时间顺序列出暂存文件
+func (b *remoteBackend) spoolFiles() ([]spoolFile, error) {
+	entries, err := os.ReadDir(b.config.SpoolPath)
+	if err != nil {
+		return nil, err
+	}
+	var files []spoolFile
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), remoteSpoolExt) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, spoolFile{name: e.Name(), size: info.Size()})
+	}
+	slices.SortFunc(files, func(a, b spoolFile) int { return strings.Compare(a.name, b.name) })
+	return files, nil
+}
+
+// drainSpool 按时间顺序补发暂存数据，遇到可重试的失败时停止，等待下一轮
+func (b *remoteBackend) drainSpool() {
+	if !b.spoolPending.Load() {
+		return
+	}
+	b.spoolMu.Lock()
+	files, err := b.spoolFiles()
+	b.spoolMu.Unlock()
+	if err != nil {
+		log.Printf("NEZHA>> TSDB: failed to list remote write spool: %v", err)
+		return
+	}
+
+	var sent int
+	for _, f := range files {
+		path := filepath.Join(b.config.SpoolPath, f.name)
+		payload, err := os.ReadFile(path)
+		if err != nil {
+			// 可能刚被容量限制删除
+			continue
+		}
+		retryable, err := b.send(payload)
+		if err != nil && retryable {
+			if sent > 0 {
+				log.Printf("NEZHA>> TSDB: resent %d spooled remote write batches", sent)
+			}
+			return
+		}
+		if err != nil {
+			log.Printf("NEZHA>> TSDB: spooled remote write batch rejected, dropping it: %v", err)
+		} else {
+			sent++
+		}
+		os.Remove(path)
+	}
+	if sent > 0 {
+		log.Printf("NEZHA>> TSDB: resent %d spooled remote write batches", sent)
+	}
+	b.spoolMu.Lock()
+	if files, err := b.spoolFiles(); err == nil && len(files) == 0 {
+		b.spoolPending.Store(false)
+	}
+	b.spoolMu.Unlock()
+}
+
+func (b *remoteBackend) flush() {
+	done := make(chan struct{})
+	select {
+	case b.flushCh <- done:
+		<-done
+	case <-b.stopCh:
+	}
+}
+
+func (b *remoteBackend) close() {
+	close(b.stopCh)
+	b.wg.Wait()
+}
+
+// promAPIResponse 远端 Prometheus HTTP API 的响应
+type promAPIResponse struct {
+	Status string          `json:"status"`
+	Data   json.RawMessage `json:"data"`
+	Error  string          `json:"error"`
+}
+
+// queryAPI 调用远端 Prometheus HTTP API 并把 data 解析到 out
+func (b *remoteBackend) queryAPI(path string, params url.Values, deadline uint64, out any) error {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Unix(int64(deadline), 0))
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.config.QueryURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	b.setAuth(req)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("remote query failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var r promAPIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("remote query failed with status %d: %w", resp.StatusCode, err)
+	}
+	if r.Status != "success" {
+		return fmt.Errorf("remote query failed: %s", r.Error)
+	}
+	return json.Unmarshal(r.Data, out)
+}
+
+// search 对每组条件执行一次范围选择器的即时查询 {...}[range]，得到范围内的原始采样点
+func (b *remoteBackend) search(filters seriesFilters, tr storage.TimeRange, maxSeries int, deadline uint64) ([]*rawSeries, error) {
+	byLabels := make(map[string]*rawSeries)
+	var result []*rawSeries
+	for _, lfs := range filters {
+		// 范围选择器的窗口为 (time-range, time]，多加 1 毫秒使 MinTimestamp 落在窗口内
+		params := url.Values{
+			"query": {fmt.Sprintf("%s[%dms]", selector(lfs), tr.MaxTimestamp-tr.MinTimestamp+1)},
+			"time":  {strconv.FormatFloat(float64(tr.MaxTimestamp)/1000, 'f', 3, 64)},
+		}
+		var data struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Values [][2]any          `json:"values"`
+			} `json:"result"`
+		}
+		if err := b.queryAPI("/api/v1/query", params, deadline, &data); err != nil {
+			return nil, err
+		}
+		if data.ResultType != "matrix" {
+			return nil, fmt.Errorf("remote query returned %s instead of matrix", data.ResultType)
+		}
+
+		for _, s := range data.Result {
+			key := labelsSignature(s.Metric, nil, false)
+			if _, ok := byLabels[key]; ok {
+				continue
+			}
+			if len(result) >= maxSeries {
+				return nil, fmt.Errorf("the number of matching series exceeds %d", maxSeries)
+			}
+			rs := &rawSeries{labels: s.Metric, points: make([]rawDataPoint, 0, len(s.Values))}
+			for _, v := range s.Values {
+				p, err := parseRemoteSample(v)
+				if err != nil {
+					return nil, err
+				}
+				rs.points = append(rs.points, p)
+			}
+			byLabels[key] = rs
+			result = append(result, rs)
+		}
+	}
+	return result, nil
+}
+
+func parseRemoteSample(v [2]any) (rawDataPoint, error) {
+	ts, ok := v[0].(float64)
+	if !ok {
+		return rawDataPoint{}, fmt.Errorf("invalid sample timestamp %v", v[0])
+	}
+	s, ok := v[1].(string)
+	if !ok {
+		return rawDataPoint{}, fmt.Errorf("invalid sample value %v", v[1])
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return rawDataPoint{}, fmt.Errorf("invalid sample value %q", s)
+	}
+	return rawDataPoint{timestamp: int64(math.Round(ts * 1000)), value: value}, nil
+}
+
+func remoteSeriesParams(filters seriesFilters, tr storage.TimeRange) url.Values {
+	params := url.Values{
+		"start": {strconv.FormatFloat(float64(tr.MinTimestamp)/1000, 'f', 3, 64)},
+		"end":   {strconv.FormatFloat(float64(tr.MaxTimestamp)/1000, 'f', 3, 64)},
+	}
+	for _, lfs := range filters {
+		params.Add("match[]", selector(lfs))
+	}
+	return params
+}
+
+func (b *remoteBackend) seriesLabels(filters seriesFilters, tr storage.TimeRange, deadline uint64) ([]map[string]string, error) {
+	var result []map[string]string
+	err := b.queryAPI("/api/v1/series", remoteSeriesParams(filters, tr), deadline, &result)
+	return result, err
+}
+
+func (b *remoteBackend) labelNames(filters seriesFilters, tr storage.TimeRange, deadline uint64) ([]string, error) {
+	var result []string
+	err := b.queryAPI("/api/v1/labels", remoteSeriesParams(filters, tr), deadline, &result)
+	return result, err
+}
+
+func (b *remoteBackend) labelValues(name string, filters seriesFilters, tr storage.TimeRange, deadline uint64) ([]string, error) {
+	var result []string
+	err := b.queryAPI("/api/v1/label/"+url.PathEscape(name)+"/values", remoteSeriesParams(filters, tr), deadline, &result)
+	return result, err
+}
@@ -0,0 +1,289 @@
+package tsdb
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/metricsql"
+	"github.com/goccy/go-json"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRemote 最小的 remote write 接收端，/api/v1/query 只支持 {...}[range] 形式的查询
+type fakeRemote struct {
+	status atomic.Int32 // 非 0 时 remote write 返回该状态码
+
+	mu     sync.Mutex
+	series map[string]*rawSeries
+}
+
+func newFakeRemote(t *testing.T) (*fakeRemote, *httptest.Server) {
+	r := &fakeRemote{series: make(map[string]*rawSeries)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/write", r.write)
+	mux.HandleFunc("GET /api/v1/query", r.query)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return r, srv
+}
+
+func (r *fakeRemote) write(w http.ResponseWriter, req *http.Request) {
+	if status := r.status.Load(); status != 0 {
+		w.WriteHeader(int(status))
+		return
+	}
+	if req.Header.Get("Content-Encoding") != "snappy" || req.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	compressed, _ := io.ReadAll(req.Body)
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	wru := prompb.GetWriteRequestUnmarshaler()
+	defer prompb.PutWriteRequestUnmarshaler(wru)
+	wr, err := wru.UnmarshalProtobuf(data)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, ts := range wr.Timeseries {
+		labels := make(map[string]string, len(ts.Labels))
+		for _, l := range ts.Labels {
+			labels[l.Name] = l.Value
+		}
+		key := labelsSignature(labels, nil, false)
+		rs, ok := r.series[key]
+		if !ok {
+			rs = &rawSeries{labels: labels}
+			r.series[key] = rs
+		}
+		for _, s := range ts.Samples {
+			rs.points = append(rs.points, rawDataPoint{timestamp: s.Timestamp, value: s.Value})
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *fakeRemote) query(w http.ResponseWriter, req *http.Request) {
+	expr, err := metricsql.Parse(req.URL.Query().Get("query"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	re := expr.(*metricsql.RollupExpr)
+	me := re.Expr.(*metricsql.MetricExpr)
+	end, _ := strconv.ParseFloat(req.URL.Query().Get("time"), 64)
+	endMs := int64(end * 1000)
+	startMs := endMs - re.Window.Duration(0)
+
+	type result struct {
+		Metric map[string]string `json:"metric"`
+		Values [][2]any          `json:"values"`
+	}
+	results := []result{}
+	r.mu.Lock()
+	for _, rs := range r.series {
+		if !matchesAll(rs.labels, me.LabelFilterss[0]) {
+			continue
+		}
+		res := result{Metric: rs.labels}
+		for _, p := range rs.points {
+			if p.timestamp > startMs && p.timestamp <= endMs {
+				res.Values = append(res.Values, [2]any{float64(p.timestamp) / 1000, strconv.FormatFloat(p.value, 'f', -1, 64)})
+			}
+		}
+		if len(res.Values) > 0 {
+			results = append(results, res)
+		}
+	}
+	r.mu.Unlock()
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status": "success",
+		"data":   map[string]any{"resultType": "matrix", "result": results},
+	})
+}
+
+// matchesAll 只支持精确匹配与 a|b 形式的正则
+func matchesAll(labels map[string]string, lfs []metricsql.LabelFilter) bool {
+	for _, lf := range lfs {
+		v := labels[lf.Label]
+		if lf.IsRegexp {
+			matched := false
+			for _, alt := range splitAlternatives(lf.Value) {
+				matched = matched || alt == v
+			}
+			if !matched {
+				return false
+			}
+		} else if v != lf.Value {
+			return false
+		}
+	}
+	return true
+}
+
+func splitAlternatives(s string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i <= len(s); i++ {
+		if i == len(s) || s[i] == '|' {
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return parts
+}
+
+func (r *fakeRemote) sampleCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int
+	for _, rs := range r.series {
+		n += len(rs.points)
+	}
+	return n
+}
+
+func openRemoteTestDB(t *testing.T, srv *httptest.Server) *TSDB {
+	db, err := Open(&Config{
+		DataPath: t.TempDir(),
+		Backend:  BackendRemote,
+		Remote: RemoteConfig{
+			WriteURL:    srv.URL + "/api/v1/write",
+			BearerToken: "secret",
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.backend.(*remoteBackend).retryDelay = time.Millisecond
+	return db
+}
+
+func TestRemoteBackend_WriteAndQuery(t *testing.T) {
+	remote, srv := newFakeRemote(t)
+	db := openRemoteTestDB(t, srv)
+	assert.Equal(t, srv.URL, db.Config().Remote.QueryURL)
+	assert.Nil(t, db.Storage())
+
+	base := time.Now().Truncate(time.Minute).Add(-10 * time.Minute)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, db.WriteServerMetrics(&ServerMetrics{
+			ServerID:  1,
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+			CPU:       float64(10 * (i + 1)),
+		}))
+		require.NoError(t, db.WriteServiceMetrics(&ServiceMetrics{
+			ServiceID:  3,
+			ServerID:   1,
+			Timestamp:  base.Add(time.Duration(i) * time.Minute),
+			Delay:      20,
+			Successful: i != 0,
+		}))
+	}
+	db.Flush()
+	// 每次写入 17 个服务器指标与 2 个服务指标
+	assert.Equal(t, 5*19, remote.sampleCount())
+
+	points, err := db.QueryServerMetricsRange(1, MetricServerCPU, QueryRange{From: base, To: base.Add(10 * time.Minute), Step: time.Minute})
+	require.NoError(t, err)
+	require.Len(t, points, 5)
+	assert.Equal(t, float64(50), points[4].Value)
+
+	history, err := db.QueryServiceHistory(3, Period1Day)
+	require.NoError(t, err)
+	require.Len(t, history.Servers, 1)
+	assert.Equal(t, uint64(4), history.Servers[0].Stats.TotalUp)
+	assert.Equal(t, uint64(1), history.Servers[0].Stats.TotalDown)
+
+	r, err := db.PromQuery(`max_over_time(nezha_server_cpu[10m])`, base.Add(5*time.Minute), QueryScope{Restricted: true, ServerIDs: []uint64{1}})
+	require.NoError(t, err)
+	require.Len(t, r.Series, 1)
+	assert.Equal(t, []float64{50}, r.Series[0].Values)
+}
+
+func TestRemoteBackend_SpoolWhenRemoteIsDown(t *testing.T) {
+	remote, srv := newFakeRemote(t)
+	db := openRemoteTestDB(t, srv)
+	spoolPath := db.Config().Remote.SpoolPath
+
+	remote.status.Store(http.StatusServiceUnavailable)
+	require.NoError(t, db.WriteServerMetrics(&ServerMetrics{ServerID: 1, Timestamp: time.Now(), CPU: 1}))
+	db.Flush()
+	files, err := os.ReadDir(spoolPath)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Zero(t, remote.sampleCount())
+
+	// 远端恢复后，下一次发送成功时补发暂存数据
+	remote.status.Store(0)
+	require.NoError(t, db.WriteServerMetrics(&ServerMetrics{ServerID: 1, Timestamp: time.Now().Add(time.Second), CPU: 2}))
+	db.Flush()
+	files, err = os.ReadDir(spoolPath)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+	assert.Equal(t, 2*17, remote.sampleCount())
+
+	// 远端明确拒绝的数据直接丢弃，不会暂存
+	remote.status.Store(http.StatusBadRequest)
+	require.NoError(t, db.WriteServerMetrics(&ServerMetrics{ServerID: 1, Timestamp: time.Now().Add(2 * time.Second), CPU: 3}))
+	db.Flush()
+	files, err = os.ReadDir(spoolPath)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestRemoteBackend_ResendSpoolAfterRestart(t *testing.T) {
+	remote, srv := newFakeRemote(t)
+	config := &Config{
+		DataPath: t.TempDir(),
+		Backend:  BackendRemote,
+		Remote:   RemoteConfig{WriteURL: srv.URL + "/api/v1/write", BearerToken: "secret"},
+	}
+
+	// 远端不可用时关闭，未发送的数据留在暂存目录
+	remote.status.Store(http.StatusBadGateway)
+	db, err := Open(config)
+	require.NoError(t, err)
+	db.backend.(*remoteBackend).retryDelay = time.Millisecond
+	require.NoError(t, db.WriteServerMetrics(&ServerMetrics{ServerID: 1, Timestamp: time.Now(), CPU: 1}))
+	require.NoError(t, db.Close())
+	assert.Zero(t, remote.sampleCount())
+
+	remote.status.Store(0)
+	db, err = Open(config)
+	require.NoError(t, err)
+	defer db.Close()
+	db.Flush()
+	assert.Equal(t, 17, remote.sampleCount())
+}
+
+func TestRemoteConfigValidate(t *testing.T) {
+	c := &Config{Backend: BackendRemote, Remote: RemoteConfig{WriteURL: "http://vm:8428/api/v1/write"}}
+	assert.True(t, c.Enabled())
+	c.Validate()
+	assert.Equal(t, "http://vm:8428", c.Remote.QueryURL)
+	assert.Equal(t, "data/tsdb_spool", c.Remote.SpoolPath)
+	assert.Equal(t, 30*time.Second, c.Remote.Timeout)
+
+	_, err := Open(&Config{Backend: BackendRemote, Remote: RemoteConfig{WriteURL: "vm:8428"}})
+	assert.Error(t, err)
+	_, err = Open(&Config{DataPath: t.TempDir(), Backend: "s3"})
+	assert.Error(t, err)
+}
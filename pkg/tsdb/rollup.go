@@ -0,0 +1,407 @@
+package tsdb
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metricsql"
+	"github.com/goccy/go-json"
+)
+
+const (
+	// rollupCheckInterval 检查是否有新的完整时间段需要汇总的间隔
+	rollupCheckInterval = time.Minute
+	// rollupDelay 时间段结束后等待写入缓冲与存储刷盘的时长，之后再汇总
+	rollupDelay = time.Minute
+	// rollupMaxChunksPerPass 每轮最多汇总的时间段数，首次启动补算历史时分多轮完成
+	rollupMaxChunksPerPass = 7 * 24
+	rollupMaxSeries        = 100000
+	rollupStateFile        = "rollup_state.json"
+)
+
+// 汇总序列的统计量，记录在 stat 标签中
+const (
+	rollupStatAvg   = "avg"
+	rollupStatMin   = "min"
+	rollupStatMax   = "max"
+	rollupStatLast  = "last"
+	rollupStatCount = "count"
+)
+
+// rawMetricFilters 匹配全部原始指标，汇总序列的名称带有冒号，不会被匹配
+var rawMetricFilters = seriesFilters{{{Label: "__name__", Value: "nezha_[a-z0-9_]+", IsRegexp: true}}}
+
+// rollupTier 一个已打开的降采样层级
+type rollupTier struct {
+	RollupTier
+	name    string  // 如 5m、1h，作为汇总序列名称的后缀
+	backend backend // 本地存储时为独立的存储，远端存储时与原始数据共用
+}
+
+func rollupTierName(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+}
+
+// metricName 汇总序列的名称，例如 nezha_server_cpu:5m
+func (t *rollupTier) metricName(metric string) string {
+	return metric + ":" + t.name
+}
+
+// filters 把原始指标的选择条件改写为该层级某个统计量的选择条件，指标名必须为精确匹配
+func (t *rollupTier) filters(filters seriesFilters, stat string) seriesFilters {
+	result := make(seriesFilters, len(filters))
+	for i, lfs := range filters {
+		group := make([]metricsql.LabelFilter, 0, len(lfs)+1)
+		for _, lf := range lfs {
+			if lf.Label == "__name__" {
+				lf.Value = t.metricName(lf.Value)
+			}
+			group = append(group, lf)
+		}
+		result[i] = append(group, metricsql.LabelFilter{Label: "stat", Value: stat})
+	}
+	return result
+}
+
+// openRollups 打开各降采样层级的存储，读取汇总进度并启动后台汇总
+func (db *TSDB) openRollups() error {
+	if len(db.config.Rollups) == 0 {
+		return nil
+	}
+
+	var dir string
+	if db.storage != nil {
+		dataPath, err := filepath.Abs(db.config.DataPath)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		dir = filepath.Join(dataPath, "rollup")
+	} else {
+		dir = db.config.Remote.SpoolPath
+	}
+	db.rollupPath = filepath.Join(dir, rollupStateFile)
+
+	for _, tier := range db.config.Rollups {
+		t := &rollupTier{RollupTier: tier, name: rollupTierName(tier.Interval)}
+		if db.storage != nil {
+			stor := storage.MustOpenStorage(filepath.Join(dir, t.name), storage.OpenOptions{
+				Retention: time.Duration(tier.RetentionDays) * 24 * time.Hour,
+			})
+			t.backend = &localBackend{storage: stor}
+		} else {
+			t.backend = db.backend
+		}
+		db.rollups = append(db.rollups, t)
+		log.Printf("NEZHA>> TSDB rollup tier %s enabled, retention: %d days", t.name, t.RetentionDays)
+	}
+
+	db.rollupState = make(map[string]int64)
+	data, err := os.ReadFile(db.rollupPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read rollup state: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &db.rollupState); err != nil {
+			log.Printf("NEZHA>> TSDB: ignoring corrupted rollup state %s: %v", db.rollupPath, err)
+		}
+	}
+
+	db.rollupStopCh = make(chan struct{})
+	db.rollupWG.Add(1)
+	go db.rollupLoop()
+	return nil
+}
+
+func (db *TSDB) rollupLoop() {
+	defer db.rollupWG.Done()
+	ticker := time.NewTicker(rollupCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			db.rollup(time.Now())
+		case <-db.rollupStopCh:
+			return
+		}
+	}
+}
+
+func (db *TSDB) stopRollups() {
+	db.stopOnce.Do(func() {
+		if db.rollupStopCh != nil {
+			close(db.rollupStopCh)
+			db.rollupWG.Wait()
+		}
+	})
+}
+
+// rollup 把截至 now 已结束的时间段汇总到各层级
+func (db *TSDB) rollup(now time.Time) {
+	db.rollupPassMu.Lock()
+	defer db.rollupPassMu.Unlock()
+	for _, t := range db.rollups {
+		for range rollupMaxChunksPerPass {
+			select {
+			case <-db.rollupStopCh:
+				return
+			default:
+			}
+			done, err := db.rollupChunk(t, now)
+			if err != nil {
+				log.Printf("NEZHA>> TSDB: rollup %s failed: %v", t.name, err)
+				break
+			}
+			if done {
+				break
+			}
+		}
+	}
+}
+
+// rollupChunk 从上次的进度开始汇总一段（至少一小时）原始数据，返回是否已追上 now
+func (db *TSDB) rollupChunk(t *rollupTier, now time.Time) (bool, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.closed {
+		return true, nil
+	}
+
+	intervalMs := t.Interval.Milliseconds()
+	end := now.Add(-rollupDelay).UnixMilli() / intervalMs * intervalMs
+	from := db.rollupWatermark(t)
+	if from == 0 {
+		// 首次汇总时补算原始数据中仍保留的全部历史
+		from = now.AddDate(0, 0, -int(db.config.RetentionDays)).UnixMilli() / intervalMs * intervalMs
+	}
+	if from >= end {
+		return true, nil
+	}
+	to := min(end, from+intervalMs*max(1, time.Hour.Milliseconds()/intervalMs))
+
+	deadline := uint64(time.Now().Add(time.Minute).Unix())
+	series, err := db.backend.search(rawMetricFilters, storage.TimeRange{MinTimestamp: from, MaxTimestamp: to - 1}, rollupMaxSeries, deadline)
+	if err != nil {
+		return false, err
+	}
+	if samples := t.rollupSamples(series); len(samples) > 0 {
+		t.backend.add(samples)
+	}
+
+	if err := db.setRollupWatermark(t, to); err != nil {
+		return false, err
+	}
+	return to >= end, nil
+}
+
+// rollupSamples 按层级间隔分桶汇总各序列，时间戳为桶的起点
+func (t *rollupTier) rollupSamples(series []*rawSeries) []sample {
+	intervalMs := t.Interval.Milliseconds()
+	var samples []sample
+	for _, rs := range series {
+		name := rs.labels["__name__"]
+		if name == "" || len(rs.points) == 0 {
+			continue
+		}
+		keys := make([]string, 0, len(rs.labels))
+		for k := range rs.labels {
+			if k != "__name__" {
+				keys = append(keys, k)
+			}
+		}
+		keys = append(keys, "stat")
+		slices.Sort(keys)
+
+		emit := func(bucket int64, stat string, value float64) {
+			labels := make([]prompb.Label, 0, len(keys)+1)
+			labels = append(labels, prompb.Label{Name: "__name__", Value: t.metricName(name)})
+			for _, k := range keys {
+				v := rs.labels[k]
+				if k == "stat" {
+					v = stat
+				}
+				labels = append(labels, prompb.Label{Name: k, Value: v})
+			}
+			samples = append(samples, sample{labels: labels, timestamp: bucket, value: value})
+		}
+
+		for start := 0; start < len(rs.points); {
+			bucket := rs.points[start].timestamp / intervalMs * intervalMs
+			sum, lo, hi := 0.0, math.Inf(1), math.Inf(-1)
+			end := start
+			for ; end < len(rs.points) && rs.points[end].timestamp < bucket+intervalMs; end++ {
+				v := rs.points[end].value
+				sum += v
+				lo = min(lo, v)
+				hi = max(hi, v)
+			}
+			n := float64(end - start)
+			emit(bucket, rollupStatAvg, sum/n)
+			emit(bucket, rollupStatMin, lo)
+			emit(bucket, rollupStatMax, hi)
+			emit(bucket, rollupStatLast, rs.points[end-1].value)
+			emit(bucket, rollupStatCount, n)
+			start = end
+		}
+	}
+	return samples
+}
+
+// rollupWatermark 返回该层级已汇总到的时间（毫秒，不含），尚未汇总过时为 0
+func (db *TSDB) rollupWatermark(t *rollupTier) int64 {
+	db.rollupMu.Lock()
+	defer db.rollupMu.Unlock()
+	return db.rollupState[t.name]
+}
+
+func (db *TSDB) setRollupWatermark(t *rollupTier, ts int64) error {
+	db.rollupMu.Lock()
+	defer db.rollupMu.Unlock()
+	db.rollupState[t.name] = ts
+
+	data, err := json.Marshal(db.rollupState)
+	if err != nil {
+		return err
+	}
+	tmp := db.rollupPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to save rollup state: %w", err)
+	}
+	return os.Rename(tmp, db.rollupPath)
+}
+
+// rollupTierFor 原始数据已不包含 from 时，返回保留期覆盖 from 的最细层级；
+// 所有层级都不覆盖时返回保留最久的层级。原始数据覆盖 from 时返回 nil
+func (db *TSDB) rollupTierFor(from, now time.Time) *rollupTier {
+	if len(db.rollups) == 0 || !from.Before(now.AddDate(0, 0, -int(db.config.RetentionDays))) {
+		return nil
+	}
+	var longest *rollupTier
+	for _, t := range db.rollups {
+		if !from.Before(now.AddDate(0, 0, -t.RetentionDays)) {
+			return t
+		}
+		if longest == nil || t.RetentionDays > longest.RetentionDays {
+			longest = t
+		}
+	}
+	return longest
+}
+
+// rollupStat 与聚合方式对应的统计量：百分位数按各桶的平均值近似计算，increase / rate 使用各桶的最后值
+func rollupStat(agg Aggregation) string {
+	switch agg {
+	case AggregationMin:
+		return rollupStatMin
+	case AggregationMax:
+		return rollupStatMax
+	case AggregationLast, AggregationIncrease, AggregationRate:
+		return rollupStatLast
+	default:
+		return rollupStatAvg
+	}
+}
+
+// splitRollupRange 把 tr 拆分为已汇总的部分与尚未汇总、需要读取原始数据的部分
+func (db *TSDB) splitRollupRange(t *rollupTier, tr storage.TimeRange) (rolled, raw storage.TimeRange, hasRolled, hasRaw bool) {
+	watermark := db.rollupWatermark(t)
+	rolled = storage.TimeRange{MinTimestamp: tr.MinTimestamp, MaxTimestamp: min(tr.MaxTimestamp, watermark-1)}
+	raw = storage.TimeRange{MinTimestamp: max(tr.MinTimestamp, watermark), MaxTimestamp: tr.MaxTimestamp}
+	return rolled, raw, rolled.MinTimestamp <= rolled.MaxTimestamp, raw.MinTimestamp <= raw.MaxTimestamp
+}
+
+// queryRollupPoints 读取 filters 在层级 t 中统计量 stat 的采样点，尚未汇总的部分读取原始数据
+func (db *TSDB) queryRollupPoints(t *rollupTier, filters seriesFilters, stat string, tr storage.TimeRange) ([]rawDataPoint, error) {
+	rolled, raw, hasRolled, hasRaw := db.splitRollupRange(t, tr)
+
+	var points []rawDataPoint
+	if hasRolled {
+		rolledPoints, err := db.searchPoints(t.backend, t.filters(filters, stat), rolled)
+		if err != nil {
+			return nil, err
+		}
+		points = rolledPoints
+	}
+	if hasRaw {
+		rawPoints, err := db.searchPoints(db.backend, filters, raw)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, rawPoints...)
+	}
+	return points, nil
+}
+
+// queryServiceRollupPoints 与 queryServicePoints 相同，但读取层级 t 的汇总数据，
+// 每个桶合并为一个采样点，samples 记录桶内的原始采样数
+func (db *TSDB) queryServiceRollupPoints(t *rollupTier, label, value, groupLabel string, tr storage.TimeRange) (map[uint64][]rawDataPoint, error) {
+	rolled, raw, hasRolled, hasRaw := db.splitRollupRange(t, tr)
+
+	result := make(map[uint64][]rawDataPoint)
+	if hasRaw {
+		rawPoints, err := db.queryServicePoints(label, value, groupLabel, raw)
+		if err != nil {
+			return nil, err
+		}
+		result = rawPoints
+	}
+	if !hasRolled {
+		return result, nil
+	}
+
+	deadline := uint64(time.Now().Add(30 * time.Second).Unix())
+	query := func(metric MetricType, stat string) (map[uint64][]metricPoint, error) {
+		series, err := t.backend.search(t.filters(metricFilters(metric, label, value), stat), rolled, 100000, deadline)
+		if err != nil {
+			return nil, err
+		}
+		return groupSeries(series, groupLabel), nil
+	}
+	delays, err := query(MetricServiceDelay, rollupStatAvg)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := query(MetricServiceStatus, rollupStatAvg)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := query(MetricServiceStatus, rollupStatCount)
+	if err != nil {
+		return nil, err
+	}
+
+	for id, points := range statuses {
+		delayAt := make(map[int64]float64, len(delays[id]))
+		for _, p := range delays[id] {
+			delayAt[p.timestamp] = p.value
+		}
+		countAt := make(map[int64]float64, len(counts[id]))
+		for _, p := range counts[id] {
+			countAt[p.timestamp] = p.value
+		}
+		for _, p := range points {
+			rp := rawDataPoint{
+				timestamp: p.timestamp,
+				status:    p.value,
+				hasStatus: true,
+				samples:   uint64(max(countAt[p.timestamp], 1)),
+			}
+			rp.value, rp.hasDelay = delayAt[p.timestamp]
+			result[id] = append(result[id], rp)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,125 @@
+package tsdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Rollups(t *testing.T) {
+	c := &Config{DataPath: "data/tsdb", RetentionDays: 30}
+	c.Validate()
+	assert.Equal(t, DefaultRollups, c.Rollups)
+
+	c = &Config{DataPath: "data/tsdb", RetentionDays: 30, Rollups: []RollupTier{
+		{Interval: time.Hour, RetentionDays: 400},
+		{Interval: 30 * time.Second, RetentionDays: 90}, // 间隔过短
+		{Interval: 10 * time.Minute, RetentionDays: 7},  // 保留期不长于原始数据
+		{Interval: 5 * time.Minute, RetentionDays: 90},
+		{Interval: time.Hour, RetentionDays: 800}, // 重复的间隔
+	}}
+	c.Validate()
+	assert.Equal(t, []RollupTier{
+		{Interval: 5 * time.Minute, RetentionDays: 90},
+		{Interval: time.Hour, RetentionDays: 400},
+	}, c.Rollups)
+
+	c = &Config{DataPath: "data/tsdb", DisableRollups: true, Rollups: DefaultRollups}
+	c.Validate()
+	assert.Empty(t, c.Rollups)
+}
+
+func TestTSDB_RollupTierFor(t *testing.T) {
+	db := &TSDB{config: &Config{RetentionDays: 30}}
+	for _, tier := range []RollupTier{{Interval: 5 * time.Minute, RetentionDays: 365}, {Interval: time.Hour, RetentionDays: 1825}} {
+		db.rollups = append(db.rollups, &rollupTier{RollupTier: tier, name: rollupTierName(tier.Interval)})
+	}
+
+	now := time.Now()
+	assert.Nil(t, db.rollupTierFor(now.AddDate(0, 0, -7), now))
+	assert.Equal(t, "5m", db.rollupTierFor(now.AddDate(0, 0, -90), now).name)
+	assert.Equal(t, "1h", db.rollupTierFor(now.AddDate(-2, 0, 0), now).name)
+	assert.Equal(t, "1h", db.rollupTierFor(now.AddDate(-10, 0, 0), now).name)
+}
+
+func TestTSDB_Rollup(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tsdb_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	config := &Config{
+		DataPath:           filepath.Join(tempDir, "tsdb"),
+		RetentionDays:      1,
+		MinFreeDiskSpaceGB: 1,
+		DedupInterval:      time.Second,
+		Rollups:            []RollupTier{{Interval: 5 * time.Minute, RetentionDays: 30}},
+	}
+
+	db, err := Open(config)
+	require.NoError(t, err)
+	defer db.Close()
+	require.Len(t, db.rollups, 1)
+	tier := db.rollups[0]
+
+	// 两个完整的 5 分钟桶，CPU 分别为 10..50 与 60..100，服务每两次检测失败一次
+	now := time.Now()
+	base := now.Add(-time.Hour).Truncate(5 * time.Minute)
+	for i := 0; i < 10; i++ {
+		ts := base.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, db.WriteServerMetrics(&ServerMetrics{ServerID: 1, Timestamp: ts, CPU: float64(10 * (i + 1))}))
+		require.NoError(t, db.WriteServiceMetrics(&ServiceMetrics{
+			ServiceID:  3,
+			ServerID:   1,
+			Timestamp:  ts,
+			Delay:      float64(10 * (i + 1)),
+			Successful: i%2 == 0,
+		}))
+	}
+	db.Flush()
+
+	// 原始数据在测试中无法早于保留期写入，从 base 开始汇总
+	require.NoError(t, db.setRollupWatermark(tier, base.UnixMilli()))
+	db.rollup(now)
+	db.Flush()
+	watermark := db.rollupWatermark(tier)
+	assert.Greater(t, watermark, base.Add(10*time.Minute).UnixMilli())
+	assert.FileExists(t, filepath.Join(config.DataPath, "rollup", rollupStateFile))
+	assert.DirExists(t, filepath.Join(config.DataPath, "rollup", "5m"))
+
+	// 查询范围早于原始数据的保留期时读取汇总数据
+	r := QueryRange{From: now.AddDate(0, 0, -7), To: now, Step: time.Minute, Aggregation: AggregationMax}
+	require.Same(t, tier, db.rollupTierFor(r.From, now))
+	points, err := db.QueryServerMetricsRange(1, MetricServerCPU, r)
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	assert.Equal(t, base.UnixMilli(), points[0].Timestamp)
+	assert.Equal(t, float64(50), points[0].Value)
+	assert.Equal(t, float64(100), points[1].Value)
+
+	r.Aggregation = AggregationAvg
+	points, err = db.QueryServerMetricsRange(1, MetricServerCPU, r)
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	assert.Equal(t, float64(30), points[0].Value)
+	assert.Equal(t, float64(80), points[1].Value)
+
+	// 汇总后检测次数仍然准确
+	history, err := db.QueryServiceHistoryRange(3, r)
+	require.NoError(t, err)
+	require.Len(t, history.Servers, 1)
+	stats := history.Servers[0].Stats
+	assert.Equal(t, uint64(5), stats.TotalUp)
+	assert.Equal(t, uint64(5), stats.TotalDown)
+	assert.InDelta(t, 55, stats.AvgDelay, 0.01)
+
+	// 重新打开后从保存的进度继续
+	require.NoError(t, db.Close())
+	db, err = Open(config)
+	require.NoError(t, err)
+	defer db.Close()
+	assert.Equal(t, watermark, db.rollupWatermark(db.rollups[0]))
+}
@@ -10,14 +10,24 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
 )
 
-// TSDB 封装 VictoriaMetrics 存储
+// TSDB 封装时序存储，默认为嵌入式 VictoriaMetrics，也可以写入远端 remote write 接口
 type TSDB struct {
-	storage *storage.Storage
+	storage *storage.Storage // 仅本地存储时非空
+	backend backend
 	config  *Config
 	mu      sync.RWMutex
 	closed  bool
 
 	writer *bufferedWriter
+
+	rollups      []*rollupTier
+	rollupMu     sync.Mutex // 保护 rollupState
+	rollupPassMu sync.Mutex // 汇总与恢复互斥，恢复期间原始数据与汇总进度都不完整，不能汇总
+	rollupState  map[string]int64
+	rollupPath   string // 降采样进度文件
+	rollupStopCh chan struct{}
+	rollupWG     sync.WaitGroup
+	stopOnce     sync.Once
 }
 
 // InitGlobalSettings 初始化 VictoriaMetrics 包级别的全局设置。
@@ -42,6 +52,28 @@ func Open(config *Config) (*TSDB, error) {
 
 	config.Validate()
 
+	var db *TSDB
+	var err error
+	switch config.Backend {
+	case BackendLocal:
+		db, err = openLocal(config)
+	case BackendRemote:
+		db, err = openRemote(config)
+	default:
+		return nil, fmt.Errorf("unknown TSDB backend %q", config.Backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.openRollups(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func openLocal(config *Config) (*TSDB, error) {
 	dataPath := config.DataPath
 	if !filepath.IsAbs(dataPath) {
 		absPath, err := filepath.Abs(dataPath)
@@ -61,6 +93,7 @@ func Open(config *Config) (*TSDB, error) {
 
 	db := &TSDB{
 		storage: stor,
+		backend: &localBackend{storage: stor},
 		config:  config,
 	}
 
@@ -74,6 +107,9 @@ func Open(config *Config) (*TSDB, error) {
 
 // Close 关闭 TSDB 存储
 func (db *TSDB) Close() error {
+	// 降采样协程会获取读锁，需要在加写锁之前停止
+	db.stopRollups()
+
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
@@ -85,13 +121,18 @@ func (db *TSDB) Close() error {
 		db.writer.stop()
 	}
 
-	db.storage.MustClose()
+	for _, t := range db.rollups {
+		if t.backend != db.backend {
+			t.backend.close()
+		}
+	}
+	db.backend.close()
 	db.closed = true
 	log.Println("NEZHA>> TSDB closed")
 	return nil
 }
 
-// Storage 返回底层存储对象（用于高级查询）
+// Storage 返回底层存储对象（用于高级查询），远端存储时为 nil
 func (db *TSDB) Storage() *storage.Storage {
 	return db.storage
 }
@@ -113,5 +154,15 @@ func (db *TSDB) Flush() {
 	if db.writer != nil {
 		db.writer.flush()
 	}
-	db.storage.DebugFlush()
+	db.flushBackends()
+}
+
+// flushBackends 刷盘原始数据与各降采样层级的存储
+func (db *TSDB) flushBackends() {
+	db.backend.flush()
+	for _, t := range db.rollups {
+		if t.backend != db.backend {
+			t.backend.flush()
+		}
+	}
 }
@@ -367,6 +367,73 @@ func TestTSDB_QueryServerMetrics(t *testing.T) {
 	require.NotEmpty(t, result, "expected data points")
 }
 
+func TestTSDB_DeviceMetrics(t *testing.T) {
+	config := &Config{
+		DataPath:           filepath.Join(t.TempDir(), "tsdb"),
+		RetentionDays:      1,
+		MinFreeDiskSpaceGB: 1,
+		DedupInterval:      time.Second,
+	}
+
+	db, err := Open(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		err := db.WriteServerMetrics(&ServerMetrics{
+			ServerID:  1,
+			Timestamp: now.Add(-time.Duration(i) * time.Minute),
+			Disks: []DiskMetrics{
+				{Mountpoint: "/", Used: 100, Total: 1000},
+				{Mountpoint: "/data", Used: uint64(500 + i), Total: 2000},
+			},
+			NetInterfaces: []NetInterfaceMetrics{
+				{Name: "eth0", InSpeed: 10, OutSpeed: 20},
+				{Name: "eth1", InSpeed: 300, OutSpeed: 400},
+			},
+			Temperatures: []TemperatureMetrics{{Sensor: "cpu", Temperature: 60}, {Sensor: "nvme", Temperature: 40}},
+			GPUs:         []float64{15, 85},
+		})
+		require.NoError(t, err)
+	}
+	db.Flush()
+
+	r := QueryRange{From: now.Add(-time.Hour), To: now, Step: time.Minute}
+	points, err := db.QueryServerDeviceMetricsRange(1, MetricServerMountUsed, "/data", r)
+	require.NoError(t, err)
+	require.Len(t, points, 5)
+	assert.Equal(t, float64(500), points[4].Value)
+
+	points, err = db.QueryServerDeviceMetricsRange(1, MetricServerSensorTemperature, "nvme", r)
+	require.NoError(t, err)
+	require.Len(t, points, 5)
+	assert.Equal(t, float64(40), points[0].Value)
+
+	points, err = db.QueryServerDeviceMetricsRange(1, MetricServerGPUUsage, "1", r)
+	require.NoError(t, err)
+	require.Len(t, points, 5)
+	assert.Equal(t, float64(85), points[0].Value)
+
+	// 按设备区分的指标必须指定设备
+	_, err = db.QueryServerMetricsRange(1, MetricServerMountUsed, r)
+	assert.Error(t, err)
+
+	v, ok, err := db.QueryServerDeviceMetricAggregate(1, "eth1", time.Hour, AggregationMax, MetricServerNICInSpeed, MetricServerNICOutSpeed)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, float64(700), v)
+
+	_, ok, err = db.QueryServerDeviceMetricAggregate(1, "wlan0", time.Hour, AggregationMax, MetricServerNICInSpeed)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// 标签也可以直接用 PromQL 查询
+	result, err := db.PromQuery(`sum by (interface) (nezha_server_nic_in_speed)`, now, QueryScope{})
+	require.NoError(t, err)
+	assert.Len(t, result.Series, 2)
+}
+
 func TestTSDB_QueryEmptyResult(t *testing.T) {
 	tempDir, err := os.MkdirTemp("", "tsdb_test")
 	require.NoError(t, err)
@@ -428,7 +495,7 @@ func TestDownsample(t *testing.T) {
 		{timestamp: 4000, value: 50, status: 1, hasDelay: true, hasStatus: true},
 	}
 
-	result := downsample(points, 2*time.Second)
+	result := downsample(points, 2*time.Second, AggregationAvg)
 
 	assert.Len(t, result, 3)
 
@@ -445,7 +512,7 @@ func TestCalculateStats(t *testing.T) {
 		{timestamp: 4000, value: 40, status: 1, hasDelay: true, hasStatus: true},
 	}
 
-	stats := calculateStats(points, 5*time.Minute)
+	stats := calculateStats(points, 5*time.Minute, AggregationAvg)
 
 	assert.Equal(t, uint64(3), stats.TotalUp)
 	assert.Equal(t, uint64(1), stats.TotalDown)
@@ -459,7 +526,7 @@ func TestCalculateStats_ZeroDelay(t *testing.T) {
 		{timestamp: 2000, value: 10, status: 1, hasDelay: true, hasStatus: true},
 	}
 
-	stats := calculateStats(points, 5*time.Minute)
+	stats := calculateStats(points, 5*time.Minute, AggregationAvg)
 
 	assert.Equal(t, float64(5), stats.AvgDelay)
 	assert.Equal(t, uint64(2), stats.TotalUp)
@@ -467,7 +534,7 @@ func TestCalculateStats_ZeroDelay(t *testing.T) {
 
 func TestCalculateStatsEmpty(t *testing.T) {
 	points := []rawDataPoint{}
-	stats := calculateStats(points, 5*time.Minute)
+	stats := calculateStats(points, 5*time.Minute, AggregationAvg)
 
 	assert.Equal(t, uint64(0), stats.TotalUp)
 	assert.Equal(t, uint64(0), stats.TotalDown)
@@ -620,3 +687,244 @@ func TestTSDB_QueryServiceHistoryByServerID_ClosedDB(t *testing.T) {
 	_, err = db.QueryServiceHistoryByServerID(1, Period1Day)
 	assert.Error(t, err)
 }
+
+func TestAggregatePoints(t *testing.T) {
+	points := func() []rawDataPoint {
+		return []rawDataPoint{
+			{timestamp: 4000, value: 40},
+			{timestamp: 1000, value: 10},
+			{timestamp: 3000, value: 30},
+			{timestamp: 2000, value: 20},
+			{timestamp: 5000, value: 50},
+		}
+	}
+
+	cases := []struct {
+		agg  Aggregation
+		want float64
+	}{
+		{AggregationAvg, 30},
+		{AggregationMin, 10},
+		{AggregationMax, 50},
+		{AggregationLast, 50},
+		{AggregationP50, 30},
+		{AggregationP90, 46},
+		{AggregationIncrease, 40},
+		{AggregationRate, 10},
+	}
+	for _, c := range cases {
+		v, ok := aggregatePoints(points(), c.agg)
+		assert.True(t, ok, c.agg)
+		assert.InDelta(t, c.want, v, 1e-9, c.agg)
+	}
+
+	// 计数器归零后的值计入增量
+	reset := []rawDataPoint{
+		{timestamp: 1000, value: 100},
+		{timestamp: 2000, value: 150},
+		{timestamp: 3000, value: 20},
+		{timestamp: 4000, value: 50},
+	}
+	v, ok := aggregatePoints(reset, AggregationIncrease)
+	assert.True(t, ok)
+	assert.Equal(t, float64(100), v)
+
+	_, ok = aggregatePoints(nil, AggregationAvg)
+	assert.False(t, ok)
+	_, ok = aggregatePoints([]rawDataPoint{{timestamp: 1000, value: 1}}, AggregationRate)
+	assert.False(t, ok)
+}
+
+func TestParseAggregation(t *testing.T) {
+	agg, err := ParseAggregation("p95")
+	require.NoError(t, err)
+	assert.Equal(t, AggregationP95, agg)
+	assert.False(t, agg.IsCounter())
+	assert.True(t, AggregationIncrease.IsCounter())
+
+	_, err = ParseAggregation("median")
+	assert.Error(t, err)
+}
+
+func TestTSDB_QueryServerMetricAggregate(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tsdb_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	config := &Config{
+		DataPath:           filepath.Join(tempDir, "tsdb"),
+		RetentionDays:      1,
+		MinFreeDiskSpaceGB: 1,
+		DedupInterval:      time.Second,
+	}
+
+	db, err := Open(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		err := db.WriteServerMetrics(&ServerMetrics{
+			ServerID:       1,
+			Timestamp:      now.Add(-time.Duration(i) * time.Minute),
+			CPU:            float64(10 + i*5),
+			NetInSpeed:     100,
+			NetOutSpeed:    50,
+			NetOutTransfer: uint64(1000 - i*100),
+		})
+		require.NoError(t, err)
+	}
+	db.Flush()
+
+	// 最近 4.5 分钟内的采样点为 i=0..4
+	window := 4*time.Minute + 30*time.Second
+	v, ok, err := db.QueryServerMetricAggregate(1, window, AggregationAvg, MetricServerCPU)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, float64(20), v)
+
+	v, ok, err = db.QueryServerMetricAggregate(1, time.Hour, AggregationMax, MetricServerNetInSpeed, MetricServerNetOutSpeed)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, float64(150), v)
+
+	v, ok, err = db.QueryServerMetricAggregate(1, time.Hour, AggregationIncrease, MetricServerNetOutTransfer)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, float64(900), v)
+
+	_, ok, err = db.QueryServerMetricAggregate(2, time.Hour, AggregationAvg, MetricServerCPU)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	db.Close()
+	_, _, err = db.QueryServerMetricAggregate(1, time.Hour, AggregationAvg, MetricServerCPU)
+	assert.Error(t, err)
+}
+
+func TestParseQueryRange(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	r, err := ParseQueryRange("1d", "", "", "", "", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-24*time.Hour), r.From)
+	assert.Equal(t, now, r.To)
+	assert.Equal(t, Period1Day.DownsampleInterval(), r.Step)
+	assert.Equal(t, Aggregation(""), r.Aggregation)
+
+	r, err = ParseQueryRange("1d", "1699990000", "2023-11-14T22:13:20Z", "5m", "p95", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1699990000, 0), r.From)
+	assert.True(t, r.To.Equal(now))
+	assert.Equal(t, 5*time.Minute, r.Step)
+	assert.Equal(t, AggregationP95, r.Aggregation)
+
+	r, err = ParseQueryRange("1d", "1699000000000", "", "60", "", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.UnixMilli(1699000000000), r.From)
+	assert.Equal(t, time.Minute, r.Step)
+	assert.Equal(t, Period30Days.DownsampleInterval(), defaultQueryStep(r.Span()))
+
+	r, err = ParseQueryRange("30d", "", "", "1d", "", now)
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, r.Step)
+
+	for _, tc := range [][5]string{
+		{"2d", "", "", "", ""},
+		{"1d", "yesterday", "", "", ""},
+		{"1d", "", "tomorrow", "", ""},
+		{"1d", "1700000000", "1699990000", "", ""},
+		{"1d", "", "", "500ms", ""},
+		{"1d", "", "", "soon", ""},
+		{"1d", "", "", "", "median"},
+	} {
+		_, err := ParseQueryRange(tc[0], tc[1], tc[2], tc[3], tc[4], now)
+		assert.Error(t, err, tc)
+	}
+}
+
+func TestQueryRange_Normalized(t *testing.T) {
+	now := time.Now()
+
+	r := QueryRange{From: now.Add(-time.Hour), To: now, Step: time.Minute}.Normalized()
+	assert.Equal(t, time.Minute, r.Step)
+
+	// 30 天按 1 秒步长会超过上限，步长随之放大
+	r = QueryRange{From: now.Add(-30 * 24 * time.Hour), To: now, Step: time.Second}.Normalized()
+	assert.LessOrEqual(t, int64(r.Span()/r.Step), int64(MaxQueryPoints))
+	assert.Equal(t, time.Duration(0), r.Step%time.Second)
+
+	r = QueryRange{From: now.Add(-time.Hour), To: now}.Normalized()
+	assert.Equal(t, Period1Day.DownsampleInterval(), r.Step)
+}
+
+func TestTSDB_QueryRangeAggregation(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tsdb_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	config := &Config{
+		DataPath:           filepath.Join(tempDir, "tsdb"),
+		RetentionDays:      1,
+		MinFreeDiskSpaceGB: 1,
+		DedupInterval:      time.Second,
+	}
+
+	db, err := Open(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// 所有采样点落在同一个 1 小时的桶内
+	base := time.Now().Truncate(time.Hour).Add(-2 * time.Hour)
+	for i := 0; i < 10; i++ {
+		ts := base.Add(time.Duration(i+1) * time.Minute)
+		require.NoError(t, db.WriteServerMetrics(&ServerMetrics{
+			ServerID:       1,
+			Timestamp:      ts,
+			CPU:            float64(10 + i*5),
+			NetOutTransfer: uint64(100 * (i + 1)),
+		}))
+		require.NoError(t, db.WriteServiceMetrics(&ServiceMetrics{
+			ServiceID:  1,
+			ServerID:   1,
+			Timestamp:  ts,
+			Delay:      float64(10 * (i + 1)),
+			Successful: true,
+		}))
+	}
+	db.Flush()
+
+	r := QueryRange{From: base, To: base.Add(time.Hour - time.Second), Step: time.Hour}
+
+	r.Aggregation = AggregationMax
+	points, err := db.QueryServerMetricsRange(1, MetricServerCPU, r)
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, float64(55), points[0].Value)
+
+	r.Aggregation = AggregationMin
+	points, err = db.QueryServerMetricsRange(1, MetricServerCPU, r)
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, float64(10), points[0].Value)
+
+	r.Aggregation = AggregationIncrease
+	points, err = db.QueryServerMetricsRange(1, MetricServerNetOutTransfer, r)
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, float64(900), points[0].Value)
+
+	_, err = db.QueryServerMetricsRange(1, MetricServerCPU, r)
+	assert.Error(t, err, "counter aggregations only apply to cumulative metrics")
+
+	r.Aggregation = AggregationMax
+	history, err := db.QueryServiceHistoryRange(1, r)
+	require.NoError(t, err)
+	require.Len(t, history.Servers, 1)
+	require.Len(t, history.Servers[0].Stats.DataPoints, 1)
+	assert.Equal(t, float64(100), history.Servers[0].Stats.DataPoints[0].Delay)
+
+	r.Aggregation = AggregationRate
+	_, err = db.QueryServiceHistoryRange(1, r)
+	assert.Error(t, err)
+}
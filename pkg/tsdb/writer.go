@@ -7,12 +7,11 @@ import (
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
-	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
 )
 
 type bufferedWriter struct {
 	db          *TSDB
-	buffer      []storage.MetricRow
+	buffer      []sample
 	mu          sync.Mutex
 	maxSize     int
 	flushTicker *time.Ticker
@@ -23,7 +22,7 @@ type bufferedWriter struct {
 func newBufferedWriter(db *TSDB, maxSize int, flushInterval time.Duration) *bufferedWriter {
 	w := &bufferedWriter{
 		db:          db,
-		buffer:      make([]storage.MetricRow, 0, maxSize),
+		buffer:      make([]sample, 0, maxSize),
 		maxSize:     maxSize,
 		flushTicker: time.NewTicker(flushInterval),
 		stopCh:      make(chan struct{}),
@@ -46,14 +45,14 @@ func (w *bufferedWriter) flushLoop() {
 	}
 }
 
-func (w *bufferedWriter) write(rows []storage.MetricRow) {
+func (w *bufferedWriter) write(rows []sample) {
 	w.mu.Lock()
 	w.buffer = append(w.buffer, rows...)
 	if len(w.buffer) >= w.maxSize {
 		rows := w.buffer
-		w.buffer = make([]storage.MetricRow, 0, w.maxSize)
+		w.buffer = make([]sample, 0, w.maxSize)
 		w.mu.Unlock()
-		w.db.storage.AddRows(rows, 64)
+		w.db.backend.add(rows)
 		return
 	}
 	w.mu.Unlock()
@@ -66,10 +65,10 @@ func (w *bufferedWriter) flush() {
 		return
 	}
 	rows := w.buffer
-	w.buffer = make([]storage.MetricRow, 0, w.maxSize)
+	w.buffer = make([]sample, 0, w.maxSize)
 	w.mu.Unlock()
 
-	w.db.storage.AddRows(rows, 64)
+	w.db.backend.add(rows)
 }
 
 func (w *bufferedWriter) stop() {
@@ -101,12 +100,60 @@ const (
 	MetricServerUptime         MetricType = "nezha_server_uptime"
 	MetricServerGPU            MetricType = "nezha_server_gpu"
 
+	// 按设备区分的服务器指标，设备名记录在 DeviceLabel 对应的标签中
+	MetricServerMountUsed         MetricType = "nezha_server_mount_used"
+	MetricServerMountTotal        MetricType = "nezha_server_mount_total"
+	MetricServerNICInSpeed        MetricType = "nezha_server_nic_in_speed"
+	MetricServerNICOutSpeed       MetricType = "nezha_server_nic_out_speed"
+	MetricServerNICInTransfer     MetricType = "nezha_server_nic_in_transfer"
+	MetricServerNICOutTransfer    MetricType = "nezha_server_nic_out_transfer"
+	MetricServerSensorTemperature MetricType = "nezha_server_sensor_temperature"
+	MetricServerGPUUsage          MetricType = "nezha_server_gpu_usage"
+
 	// 服务监控指标
 	MetricServiceDelay  MetricType = "nezha_service_delay"
 	MetricServiceStatus MetricType = "nezha_service_status"
 )
 
-// ServerMetrics 服务器指标数据
+// DeviceLabel 返回按设备区分的指标记录设备名的标签，其他指标返回空字符串
+func (m MetricType) DeviceLabel() string {
+	switch m {
+	case MetricServerMountUsed, MetricServerMountTotal:
+		return "mountpoint"
+	case MetricServerNICInSpeed, MetricServerNICOutSpeed, MetricServerNICInTransfer, MetricServerNICOutTransfer:
+		return "interface"
+	case MetricServerSensorTemperature:
+		return "sensor"
+	case MetricServerGPUUsage:
+		return "gpu"
+	default:
+		return ""
+	}
+}
+
+// DiskMetrics 单个挂载点的磁盘用量
+type DiskMetrics struct {
+	Mountpoint string
+	Used       uint64
+	Total      uint64
+}
+
+// NetInterfaceMetrics 单个网卡的速率与流量
+type NetInterfaceMetrics struct {
+	Name        string
+	InSpeed     uint64
+	OutSpeed    uint64
+	InTransfer  uint64
+	OutTransfer uint64
+}
+
+// TemperatureMetrics 单个传感器的温度
+type TemperatureMetrics struct {
+	Sensor      string
+	Temperature float64
+}
+
+// ServerMetrics 服务器指标数据，Temperature 与 GPU 为各传感器、各 GPU 的最大值
 type ServerMetrics struct {
 	ServerID       uint64
 	Timestamp      time.Time
@@ -127,6 +174,10 @@ type ServerMetrics struct {
 	Temperature    float64
 	Uptime         uint64
 	GPU            float64
+	Disks          []DiskMetrics
+	NetInterfaces  []NetInterfaceMetrics
+	Temperatures   []TemperatureMetrics
+	GPUs           []float64
 }
 
 // ServiceMetrics 服务监控指标数据
@@ -145,33 +196,12 @@ func (db *TSDB) WriteServerMetrics(m *ServerMetrics) error {
 		return fmt.Errorf("TSDB is closed")
 	}
 
-	ts := m.Timestamp.UnixMilli()
-	serverIDStr := strconv.FormatUint(m.ServerID, 10)
-
-	rows := []storage.MetricRow{
-		makeServerMetricRow(MetricServerCPU, serverIDStr, ts, m.CPU),
-		makeServerMetricRow(MetricServerMemory, serverIDStr, ts, float64(m.MemUsed)),
-		makeServerMetricRow(MetricServerSwap, serverIDStr, ts, float64(m.SwapUsed)),
-		makeServerMetricRow(MetricServerDisk, serverIDStr, ts, float64(m.DiskUsed)),
-		makeServerMetricRow(MetricServerNetInSpeed, serverIDStr, ts, float64(m.NetInSpeed)),
-		makeServerMetricRow(MetricServerNetOutSpeed, serverIDStr, ts, float64(m.NetOutSpeed)),
-		makeServerMetricRow(MetricServerNetInTransfer, serverIDStr, ts, float64(m.NetInTransfer)),
-		makeServerMetricRow(MetricServerNetOutTransfer, serverIDStr, ts, float64(m.NetOutTransfer)),
-		makeServerMetricRow(MetricServerLoad1, serverIDStr, ts, m.Load1),
-		makeServerMetricRow(MetricServerLoad5, serverIDStr, ts, m.Load5),
-		makeServerMetricRow(MetricServerLoad15, serverIDStr, ts, m.Load15),
-		makeServerMetricRow(MetricServerTCPConn, serverIDStr, ts, float64(m.TCPConnCount)),
-		makeServerMetricRow(MetricServerUDPConn, serverIDStr, ts, float64(m.UDPConnCount)),
-		makeServerMetricRow(MetricServerProcessCount, serverIDStr, ts, float64(m.ProcessCount)),
-		makeServerMetricRow(MetricServerTemperature, serverIDStr, ts, m.Temperature),
-		makeServerMetricRow(MetricServerUptime, serverIDStr, ts, float64(m.Uptime)),
-		makeServerMetricRow(MetricServerGPU, serverIDStr, ts, m.GPU),
-	}
+	rows := appendServerMetricRows(nil, m)
 
 	if db.writer != nil {
 		db.writer.write(rows)
 	} else {
-		db.storage.AddRows(rows, 64)
+		db.backend.add(rows)
 	}
 	return nil
 }
@@ -192,7 +222,7 @@ func (db *TSDB) WriteServiceMetrics(m *ServiceMetrics) error {
 		status = 1
 	}
 
-	rows := []storage.MetricRow{
+	rows := []sample{
 		makeServiceMetricRow(MetricServiceDelay, serviceIDStr, serverIDStr, ts, m.Delay),
 		makeServiceMetricRow(MetricServiceStatus, serviceIDStr, serverIDStr, ts, status),
 	}
@@ -200,34 +230,83 @@ func (db *TSDB) WriteServiceMetrics(m *ServiceMetrics) error {
 	if db.writer != nil {
 		db.writer.write(rows)
 	} else {
-		db.storage.AddRows(rows, 64)
+		db.backend.add(rows)
 	}
 	return nil
 }
 
-func makeServerMetricRow(metric MetricType, serverID string, timestamp int64, value float64) storage.MetricRow {
+// appendServerMetricRows 把一次上报的全部服务器指标追加到 rows
+func appendServerMetricRows(rows []sample, m *ServerMetrics) []sample {
+	ts := m.Timestamp.UnixMilli()
+	serverIDStr := strconv.FormatUint(m.ServerID, 10)
+
+	rows = append(rows,
+		makeServerMetricRow(MetricServerCPU, serverIDStr, ts, m.CPU),
+		makeServerMetricRow(MetricServerMemory, serverIDStr, ts, float64(m.MemUsed)),
+		makeServerMetricRow(MetricServerSwap, serverIDStr, ts, float64(m.SwapUsed)),
+		makeServerMetricRow(MetricServerDisk, serverIDStr, ts, float64(m.DiskUsed)),
+		makeServerMetricRow(MetricServerNetInSpeed, serverIDStr, ts, float64(m.NetInSpeed)),
+		makeServerMetricRow(MetricServerNetOutSpeed, serverIDStr, ts, float64(m.NetOutSpeed)),
+		makeServerMetricRow(MetricServerNetInTransfer, serverIDStr, ts, float64(m.NetInTransfer)),
+		makeServerMetricRow(MetricServerNetOutTransfer, serverIDStr, ts, float64(m.NetOutTransfer)),
+		makeServerMetricRow(MetricServerLoad1, serverIDStr, ts, m.Load1),
+		makeServerMetricRow(MetricServerLoad5, serverIDStr, ts, m.Load5),
+		makeServerMetricRow(MetricServerLoad15, serverIDStr, ts, m.Load15),
+		makeServerMetricRow(MetricServerTCPConn, serverIDStr, ts, float64(m.TCPConnCount)),
+		makeServerMetricRow(MetricServerUDPConn, serverIDStr, ts, float64(m.UDPConnCount)),
+		makeServerMetricRow(MetricServerProcessCount, serverIDStr, ts, float64(m.ProcessCount)),
+		makeServerMetricRow(MetricServerTemperature, serverIDStr, ts, m.Temperature),
+		makeServerMetricRow(MetricServerUptime, serverIDStr, ts, float64(m.Uptime)),
+		makeServerMetricRow(MetricServerGPU, serverIDStr, ts, m.GPU),
+	)
+	for _, d := range m.Disks {
+		rows = append(rows,
+			makeDeviceMetricRow(MetricServerMountUsed, serverIDStr, d.Mountpoint, ts, float64(d.Used)),
+			makeDeviceMetricRow(MetricServerMountTotal, serverIDStr, d.Mountpoint, ts, float64(d.Total)),
+		)
+	}
+	for _, n := range m.NetInterfaces {
+		rows = append(rows,
+			makeDeviceMetricRow(MetricServerNICInSpeed, serverIDStr, n.Name, ts, float64(n.InSpeed)),
+			makeDeviceMetricRow(MetricServerNICOutSpeed, serverIDStr, n.Name, ts, float64(n.OutSpeed)),
+			makeDeviceMetricRow(MetricServerNICInTransfer, serverIDStr, n.Name, ts, float64(n.InTransfer)),
+			makeDeviceMetricRow(MetricServerNICOutTransfer, serverIDStr, n.Name, ts, float64(n.OutTransfer)),
+		)
+	}
+	for _, t := range m.Temperatures {
+		rows = append(rows, makeDeviceMetricRow(MetricServerSensorTemperature, serverIDStr, t.Sensor, ts, t.Temperature))
+	}
+	for i, usage := range m.GPUs {
+		rows = append(rows, makeDeviceMetricRow(MetricServerGPUUsage, serverIDStr, strconv.Itoa(i), ts, usage))
+	}
+	return rows
+}
+
+func makeServerMetricRow(metric MetricType, serverID string, timestamp int64, value float64) sample {
 	labels := []prompb.Label{
 		{Name: "__name__", Value: string(metric)},
 		{Name: "server_id", Value: serverID},
 	}
-	return storage.MetricRow{
-		MetricNameRaw: storage.MarshalMetricNameRaw(nil, labels),
-		Timestamp:     timestamp,
-		Value:         value,
+	return sample{labels: labels, timestamp: timestamp, value: value}
+}
+
+// makeDeviceMetricRow 按设备区分的服务器指标，device 写入 metric.DeviceLabel() 标签
+func makeDeviceMetricRow(metric MetricType, serverID, device string, timestamp int64, value float64) sample {
+	labels := []prompb.Label{
+		{Name: "__name__", Value: string(metric)},
+		{Name: "server_id", Value: serverID},
+		{Name: metric.DeviceLabel(), Value: device},
 	}
+	return sample{labels: labels, timestamp: timestamp, value: value}
 }
 
-func makeServiceMetricRow(metric MetricType, serviceID, serverID string, timestamp int64, value float64) storage.MetricRow {
+func makeServiceMetricRow(metric MetricType, serviceID, serverID string, timestamp int64, value float64) sample {
 	labels := []prompb.Label{
 		{Name: "__name__", Value: string(metric)},
 		{Name: "service_id", Value: serviceID},
 		{Name: "server_id", Value: serverID},
 	}
-	return storage.MetricRow{
-		MetricNameRaw: storage.MarshalMetricNameRaw(nil, labels),
-		Timestamp:     timestamp,
-		Value:         value,
-	}
+	return sample{labels: labels, timestamp: timestamp, value: value}
 }
 
 func (db *TSDB) WriteBatchServerMetrics(metrics []*ServerMetrics) error {
@@ -237,35 +316,15 @@ func (db *TSDB) WriteBatchServerMetrics(metrics []*ServerMetrics) error {
 		return fmt.Errorf("TSDB is closed")
 	}
 
-	rows := make([]storage.MetricRow, 0, len(metrics)*17)
+	rows := make([]sample, 0, len(metrics)*17)
 	for _, m := range metrics {
-		ts := m.Timestamp.UnixMilli()
-		serverIDStr := strconv.FormatUint(m.ServerID, 10)
-		rows = append(rows,
-			makeServerMetricRow(MetricServerCPU, serverIDStr, ts, m.CPU),
-			makeServerMetricRow(MetricServerMemory, serverIDStr, ts, float64(m.MemUsed)),
-			makeServerMetricRow(MetricServerSwap, serverIDStr, ts, float64(m.SwapUsed)),
-			makeServerMetricRow(MetricServerDisk, serverIDStr, ts, float64(m.DiskUsed)),
-			makeServerMetricRow(MetricServerNetInSpeed, serverIDStr, ts, float64(m.NetInSpeed)),
-			makeServerMetricRow(MetricServerNetOutSpeed, serverIDStr, ts, float64(m.NetOutSpeed)),
-			makeServerMetricRow(MetricServerNetInTransfer, serverIDStr, ts, float64(m.NetInTransfer)),
-			makeServerMetricRow(MetricServerNetOutTransfer, serverIDStr, ts, float64(m.NetOutTransfer)),
-			makeServerMetricRow(MetricServerLoad1, serverIDStr, ts, m.Load1),
-			makeServerMetricRow(MetricServerLoad5, serverIDStr, ts, m.Load5),
-			makeServerMetricRow(MetricServerLoad15, serverIDStr, ts, m.Load15),
-			makeServerMetricRow(MetricServerTCPConn, serverIDStr, ts, float64(m.TCPConnCount)),
-			makeServerMetricRow(MetricServerUDPConn, serverIDStr, ts, float64(m.UDPConnCount)),
-			makeServerMetricRow(MetricServerProcessCount, serverIDStr, ts, float64(m.ProcessCount)),
-			makeServerMetricRow(MetricServerTemperature, serverIDStr, ts, m.Temperature),
-			makeServerMetricRow(MetricServerUptime, serverIDStr, ts, float64(m.Uptime)),
-			makeServerMetricRow(MetricServerGPU, serverIDStr, ts, m.GPU),
-		)
+		rows = appendServerMetricRows(rows, m)
 	}
 
 	if db.writer != nil {
 		db.writer.write(rows)
 	} else {
-		db.storage.AddRows(rows, 64)
+		db.backend.add(rows)
 	}
 	return nil
 }
@@ -277,7 +336,7 @@ func (db *TSDB) WriteBatchServiceMetrics(metrics []*ServiceMetrics) error {
 		return fmt.Errorf("TSDB is closed")
 	}
 
-	rows := make([]storage.MetricRow, 0, len(metrics)*2)
+	rows := make([]sample, 0, len(metrics)*2)
 	for _, m := range metrics {
 		ts := m.Timestamp.UnixMilli()
 		serviceIDStr := strconv.FormatUint(m.ServiceID, 10)
@@ -295,7 +354,7 @@ func (db *TSDB) WriteBatchServiceMetrics(metrics []*ServiceMetrics) error {
 	if db.writer != nil {
 		db.writer.write(rows)
 	} else {
-		db.storage.AddRows(rows, 64)
+		db.backend.add(rows)
 	}
 	return nil
 }
@@ -134,31 +134,40 @@ func ResolveAllowedHTTPURL(rawURL string) (*url.URL, net.IP, error) {
 		return nil, nil, ErrHTTPURLTargetNotAllowed
 	}
 
-	host := parsedURL.Hostname()
+	ip, err := ResolveAllowedHost(parsedURL.Hostname())
+	if err != nil {
+		return nil, nil, err
+	}
+	return parsedURL, ip, nil
+}
+
+// ResolveAllowedHost resolves host and rejects it unless every address is a
+// permitted outbound target. Non-HTTP senders (e.g. SMTP notifications) must
+// dial the returned IP instead of re-resolving the host name.
+func ResolveAllowedHost(host string) (net.IP, error) {
 	if host == "" {
-		return nil, nil, ErrHTTPURLTargetNotAllowed
+		return nil, ErrHTTPURLTargetNotAllowed
 	}
 	if ip := net.ParseIP(host); ip != nil {
 		if !HTTPURLTargetIPAllowed(ip) {
-			return nil, nil, ErrHTTPURLTargetNotAllowed
+			return nil, ErrHTTPURLTargetNotAllowed
 		}
-		return parsedURL, ip, nil
+		return ip, nil
 	}
 
 	ips, err := net.LookupIP(host)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	if len(ips) == 0 {
-		return nil, nil, ErrHTTPURLTargetNotAllowed
+		return nil, ErrHTTPURLTargetNotAllowed
 	}
 	for _, ip := range ips {
 		if !HTTPURLTargetIPAllowed(ip) {
-			return nil, nil, ErrHTTPURLTargetNotAllowed
+			return nil, ErrHTTPURLTargetNotAllowed
 		}
 	}
-
-	return parsedURL, ips[0], nil
+	return ips[0], nil
 }
 
 func HTTPURLTargetIPAllowed(ip net.IP) bool {
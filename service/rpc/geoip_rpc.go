@@ -39,9 +39,10 @@ func (s *NezhaHandler) ReportGeoIP(ctx context.Context, report *pb.GeoIP) (*pb.G
 		((singleton.Conf.Cover == model.ConfigCoverAll && !singleton.Conf.IgnoredIPNotificationServerIDs[clientID]) ||
 			(singleton.Conf.Cover == model.ConfigCoverIgnoreAll && singleton.Conf.IgnoredIPNotificationServerIDs[clientID])) &&
 		server.GeoIP.IP.Join() != "" && joinedIP != "" && server.GeoIP.IP != geoIP.IP {
-		singleton.NotificationShared.SendNotification(singleton.Conf.IPChangeNotificationGroupID,
+		singleton.NotificationShared.SendEventNotification(singleton.Conf.IPChangeNotificationGroupID,
 			fmt.Sprintf("[%s] %s, %s => %s", singleton.Localizer.T("IP Changed"), server.Name,
-				singleton.IPDesensitize(server.GeoIP.IP.Join()), singleton.IPDesensitize(joinedIP)), "")
+				singleton.IPDesensitize(server.GeoIP.IP.Join()), singleton.IPDesensitize(joinedIP)), "",
+			model.NotificationSubject{ServerID: clientID}, nil)
 	}
 	ip := geoIP.IP.IPv4Addr
 	if geoIP.IP.IPv6Addr != "" && (report.GetUse6() || ip == "") {
@@ -46,6 +46,50 @@ func writeServerMetricsToTSDB(metrics *tsdb.ServerMetrics) error {
 	return singleton.TSDBShared.WriteServerMetrics(metrics)
 }
 
+// hostStateMetrics 把一次状态上报转换为 TSDB 指标，温度与 GPU 同时记录最大值与各传感器、各 GPU 的值
+func hostStateMetrics(serverID uint64, ts time.Time, state *model.HostState) *tsdb.ServerMetrics {
+	metrics := &tsdb.ServerMetrics{
+		ServerID:       serverID,
+		Timestamp:      ts,
+		CPU:            state.CPU,
+		MemUsed:        state.MemUsed,
+		SwapUsed:       state.SwapUsed,
+		DiskUsed:       state.DiskUsed,
+		NetInSpeed:     state.NetInSpeed,
+		NetOutSpeed:    state.NetOutSpeed,
+		NetInTransfer:  state.NetInTransfer,
+		NetOutTransfer: state.NetOutTransfer,
+		Load1:          state.Load1,
+		Load5:          state.Load5,
+		Load15:         state.Load15,
+		TCPConnCount:   state.TcpConnCount,
+		UDPConnCount:   state.UdpConnCount,
+		ProcessCount:   state.ProcessCount,
+		Uptime:         state.Uptime,
+		GPUs:           state.GPU,
+	}
+	for _, t := range state.Temperatures {
+		metrics.Temperature = max(metrics.Temperature, t.Temperature)
+		metrics.Temperatures = append(metrics.Temperatures, tsdb.TemperatureMetrics{Sensor: t.Name, Temperature: t.Temperature})
+	}
+	for _, g := range state.GPU {
+		metrics.GPU = max(metrics.GPU, g)
+	}
+	for _, d := range state.Disks {
+		metrics.Disks = append(metrics.Disks, tsdb.DiskMetrics{Mountpoint: d.Mountpoint, Used: d.Used, Total: d.Total})
+	}
+	for _, n := range state.NetInterfaces {
+		metrics.NetInterfaces = append(metrics.NetInterfaces, tsdb.NetInterfaceMetrics{
+			Name:        n.Name,
+			InSpeed:     n.InSpeed,
+			OutSpeed:    n.OutSpeed,
+			InTransfer:  n.InTransfer,
+			OutTransfer: n.OutTransfer,
+		})
+	}
+	return metrics
+}
+
 func NewNezhaHandler() *NezhaHandler {
 	handler := &NezhaHandler{
 		Auth:           &authHandler{},
@@ -216,42 +260,8 @@ func (s *NezhaHandler) ReportSystemState(stream pb.NezhaService_ReportSystemStat
 
 		lastActive := time.Now()
 		accepted := lease.UpdateStateWithSideEffect(&innerState, lastActive, func() error {
-			{
-				maxTemp := 0.0
-				for _, t := range innerState.Temperatures {
-					if t.Temperature > maxTemp {
-						maxTemp = t.Temperature
-					}
-				}
-				maxGPU := 0.0
-				for _, g := range innerState.GPU {
-					if g > maxGPU {
-						maxGPU = g
-					}
-				}
-				if err := writeServerMetrics(&tsdb.ServerMetrics{
-					ServerID:       clientID,
-					Timestamp:      lastActive,
-					CPU:            innerState.CPU,
-					MemUsed:        innerState.MemUsed,
-					SwapUsed:       innerState.SwapUsed,
-					DiskUsed:       innerState.DiskUsed,
-					NetInSpeed:     innerState.NetInSpeed,
-					NetOutSpeed:    innerState.NetOutSpeed,
-					NetInTransfer:  innerState.NetInTransfer,
-					NetOutTransfer: innerState.NetOutTransfer,
-					Load1:          innerState.Load1,
-					Load5:          innerState.Load5,
-					Load15:         innerState.Load15,
-					TCPConnCount:   innerState.TcpConnCount,
-					UDPConnCount:   innerState.UdpConnCount,
-					ProcessCount:   innerState.ProcessCount,
-					Temperature:    maxTemp,
-					Uptime:         innerState.Uptime,
-					GPU:            maxGPU,
-				}); err != nil {
-					log.Printf("NEZHA>> Failed to write server metrics to TSDB: %v", err)
-				}
+			if err := writeServerMetrics(hostStateMetrics(clientID, lastActive, &innerState)); err != nil {
+				log.Printf("NEZHA>> Failed to write server metrics to TSDB: %v", err)
 			}
 			return nil
 		})
@@ -40,3 +40,25 @@ func TestStateMetricsWriterRunsOnlyForCurrentGeneration(t *testing.T) {
 	require.Zero(t, oldCalls)
 	require.Equal(t, 1, newCalls)
 }
+
+func TestHostStateMetricsKeepsPerDeviceSeries(t *testing.T) {
+	state := model.PB2State((&model.HostState{
+		CPU:          5,
+		Temperatures: []model.SensorTemperature{{Name: "cpu", Temperature: 70}, {Name: "nvme", Temperature: 45}},
+		GPU:          []float64{20, 90},
+		Disks:        []model.DiskState{{Mountpoint: "/data", Fstype: "ext4", Total: 2000, Used: 500}},
+		NetInterfaces: []model.NetInterfaceState{
+			{Name: "eth0", InSpeed: 1, OutSpeed: 2, InTransfer: 3, OutTransfer: 4},
+		},
+	}).PB())
+
+	metrics := hostStateMetrics(7, time.Unix(100, 0), &state)
+
+	require.Equal(t, uint64(7), metrics.ServerID)
+	require.Equal(t, float64(70), metrics.Temperature)
+	require.Equal(t, float64(90), metrics.GPU)
+	require.Equal(t, []float64{20, 90}, metrics.GPUs)
+	require.Equal(t, []tsdb.TemperatureMetrics{{Sensor: "cpu", Temperature: 70}, {Sensor: "nvme", Temperature: 45}}, metrics.Temperatures)
+	require.Equal(t, []tsdb.DiskMetrics{{Mountpoint: "/data", Used: 500, Total: 2000}}, metrics.Disks)
+	require.Equal(t, []tsdb.NetInterfaceMetrics{{Name: "eth0", InSpeed: 1, OutSpeed: 2, InTransfer: 3, OutTransfer: 4}}, metrics.NetInterfaces)
+}
@@ -71,6 +71,7 @@ func AlertSentinelStart() {
 		alertsPrevState[alert.ID] = make(map[uint64]uint8)
 		addCycleTransferStatsInfo(alert)
 	}
+	loadOpenIncidents()
 	AlertsLock.Unlock()
 
 	time.Sleep(time.Second * 10)
@@ -95,6 +96,8 @@ func OnRefreshOrAddAlert(alert *model.AlertRule) {
 	defer AlertsLock.Unlock()
 	delete(alertsStore, alert.ID)
 	delete(alertsPrevState, alert.ID)
+	resolveAlertIncidents(alert.ID)
+	cancelAlertEscalations(alert.ID)
 	var isEdit bool
 	for i := range Alerts {
 		if Alerts[i].ID == alert.ID {
@@ -117,6 +120,8 @@ func OnDeleteAlert(id []uint64) {
 	for _, i := range id {
 		delete(alertsStore, i)
 		delete(alertsPrevState, i)
+		resolveAlertIncidents(i)
+		cancelAlertEscalations(i)
 		currentAlerts := Alerts[:0]
 		for _, alert := range Alerts {
 			if alert.ID != i {
@@ -133,6 +138,7 @@ func checkStatus() {
 	AlertsLock.RLock()
 	defer AlertsLock.RUnlock()
 	m := ServerShared.GetList()
+	maintenance := MaintenanceWindowShared.ServersUnderMaintenance()
 
 	for _, alert := range Alerts {
 		// 跳过未启用
@@ -152,48 +158,125 @@ func checkStatus() {
 			if alert.UserID != server.GetUserID() && !role.IsAdmin() {
 				continue
 			}
-			alertsStore[alert.ID][server.ID] = append(alertsStore[alert.
-				ID][server.ID], alert.Snapshot(AlertsCycleTransferStatsStore[alert.ID], server, DB))
+			point := alert.Snapshot(AlertsCycleTransferStatsStore[alert.ID], server, DB)
+			alertsStore[alert.ID][server.ID] = append(alertsStore[alert.ID][server.ID], point)
 			// 发送通知，分为触发报警和恢复通知
 			_, passed := alert.Check(alertsStore[alert.ID][server.ID])
+			// 清理旧数据：保留窗口由规则定义决定（各规则 Duration 的最大值），
+			// 而非 Check 的判定结果。window==0 表示没有任何有效规则需要回看历史
+			// （例如全部 Duration<=0），此时清空采样避免切片无限增长。
+			window := alert.RetentionWindow()
+			samples := alertsStore[alert.ID][server.ID]
+			if window <= 0 {
+				alertsStore[alert.ID][server.ID] = samples[:0]
+			} else if window < len(samples) {
+				alertsStore[alert.ID][server.ID] = samples[len(samples)-window:]
+			}
+			// 维护窗口内照常采样，但不改变报警状态、不发送通知也不执行触发任务，
+			// 窗口结束后若仍未恢复会按正常流程报警
+			if _, ok := maintenance[server.ID]; ok {
+				continue
+			}
 			// 保存当前服务器状态信息
 			curServer := model.Server{}
 			copier.Copy(&curServer, server)
 
 			// 本次未通过检查
 			if !passed {
+				// 记录报警事件：上次不为失败时开启新事件，持续失败时只刷新指标值
+				if alertsPrevState[alert.ID][server.ID] != _RuleCheckFail {
+					openIncident(alert, server, point)
+				} else {
+					updateIncidentValue(alert, server.ID, point)
+				}
 				// 始终触发模式或上次检查不为失败时触发报警（跳过单次触发+上次失败的情况）
 				if alert.TriggerMode == model.ModeAlwaysTrigger || alertsPrevState[alert.ID][server.ID] != _RuleCheckFail {
 					alertsPrevState[alert.ID][server.ID] = _RuleCheckFail
-					message := fmt.Sprintf("[%s] %s(%s) %s", Localizer.T("Incident"),
-						server.Name, IPDesensitize(server.GeoIP.IP.Join()), alert.Name)
+					message := alertMessage(Localizer.T("Incident"), alert, server)
+					incident := alertsIncidents[alert.ID][server.ID]
+					event := alertEvent(model.NotificationEventIncident, alert, incident, point)
+					muteLabel := NotificationMuteLabel.ServerIncident(alert.ID, server.ID)
+					subject := model.NotificationSubject{AlertRuleID: alert.ID, ServerID: server.ID}
 					go CronShared.SendTriggerTasks(alert.FailTriggerTasks, curServer.ID, alert.UserID)
-					go NotificationShared.SendNotification(alert.NotificationGroupID, message, NotificationMuteLabel.ServerIncident(server.ID, alert.ID), &curServer)
-					// 清除恢复通知的静音缓存
-					NotificationShared.UnMuteNotification(alert.NotificationGroupID, NotificationMuteLabel.ServerIncidentResolved(server.ID, alert.ID))
+					groups := []uint64{alert.NotificationGroupID}
+					// 绑定了升级策略时由策略决定通知哪些通知组
+					if alert.EscalationPolicyID != 0 {
+						var incidentID uint64
+						if incident != nil {
+							incidentID = incident.ID
+						}
+						key := EscalationKey{AlertRuleID: alert.ID, ServerID: server.ID}
+						if EscalationPolicyShared.Trigger(key, alert.EscalationPolicyID, alert.UserID, incidentID, message, event, muteLabel, &curServer) {
+							groups = nil
+						}
+					}
+					for _, gid := range groups {
+						go NotificationShared.SendEventNotification(gid, message, muteLabel, subject, event, &curServer)
+						// 清除恢复通知的静音缓存
+						NotificationShared.UnMuteNotification(gid, NotificationMuteLabel.ServerIncidentResolved(alert.ID, server.ID))
+					}
 				}
 			} else {
+				// 采样不足一个窗口时 Check 视为通过，重启后从数据库恢复的失败状态需要等窗口填满
+				// 才能判断是否真正恢复，否则会误发恢复通知并结束事件
+				if alertsPrevState[alert.ID][server.ID] == _RuleCheckFail && len(alertsStore[alert.ID][server.ID]) < window {
+					continue
+				}
 				// 本次通过检查但上一次的状态为失败，则发送恢复通知
 				if alertsPrevState[alert.ID][server.ID] == _RuleCheckFail {
-					message := fmt.Sprintf("[%s] %s(%s) %s", Localizer.T("Resolved"),
-						server.Name, IPDesensitize(server.GeoIP.IP.Join()), alert.Name)
+					message := alertMessage(Localizer.T("Resolved"), alert, server)
+					event := alertEvent(model.NotificationEventResolved, alert, alertsIncidents[alert.ID][server.ID], nil)
 					go CronShared.SendTriggerTasks(alert.RecoverTriggerTasks, curServer.ID, alert.UserID)
-					go NotificationShared.SendNotification(alert.NotificationGroupID, message, NotificationMuteLabel.ServerIncidentResolved(server.ID, alert.ID), &curServer)
-					// 清除失败通知的静音缓存
-					NotificationShared.UnMuteNotification(alert.NotificationGroupID, NotificationMuteLabel.ServerIncident(server.ID, alert.ID))
+					subject := model.NotificationSubject{AlertRuleID: alert.ID, ServerID: server.ID}
+					groups := []uint64{alert.NotificationGroupID}
+					// 升级中的报警只通知已经通知过的通知组
+					if notified, ok := EscalationPolicyShared.Resolve(EscalationKey{AlertRuleID: alert.ID, ServerID: server.ID}); ok {
+						groups = notified
+					}
+					for _, gid := range groups {
+						go NotificationShared.SendEventNotification(gid, message, NotificationMuteLabel.ServerIncidentResolved(alert.ID, server.ID), subject, event, &curServer)
+						// 清除失败通知的静音缓存
+						NotificationShared.UnMuteNotification(gid, NotificationMuteLabel.ServerIncident(alert.ID, server.ID))
+					}
+					resolveIncident(alert.ID, server.ID)
 				}
 				alertsPrevState[alert.ID][server.ID] = _RuleCheckPass
 			}
-			// 清理旧数据：保留窗口由规则定义决定（各规则 Duration 的最大值），
-			// 而非 Check 的判定结果。window==0 表示没有任何有效规则需要回看历史
-			// （例如全部 Duration<=0），此时清空采样避免切片无限增长。
-			window := alert.RetentionWindow()
-			samples := alertsStore[alert.ID][server.ID]
-			if window <= 0 {
-				alertsStore[alert.ID][server.ID] = samples[:0]
-			} else if window < len(samples) {
-				alertsStore[alert.ID][server.ID] = samples[len(samples)-window:]
-			}
 		}
 	}
 }
+
+// alertMessage 报警与恢复通知的内容
+func alertMessage(status string, alert *model.AlertRule, server *model.Server) string {
+	return fmt.Sprintf("[%s] %s(%s) %s", status, server.Name, IPDesensitize(server.GeoIP.IP.Join()), alert.Name)
+}
+
+// alertEvent 报警与恢复通知的事件，point 为本次检查结果，用于找出未通过的规则
+func alertEvent(status string, alert *model.AlertRule, incident *model.Incident, point []bool) *model.NotificationEvent {
+	event := &model.NotificationEvent{
+		Status:      status,
+		AlertRuleID: alert.ID,
+		AlertName:   alert.Name,
+	}
+	if rule := alert.FailedRule(point); rule != nil {
+		event.Rule = model.NewNotificationRule(rule)
+	}
+	if incident != nil {
+		openedAt := incident.OpenedAt
+		event.StartedAt = &openedAt
+		if incident.LastValue != nil {
+			v := *incident.LastValue
+			event.Value = &v
+		}
+		event.IncidentID = incident.ID
+		event.IncidentURL = incidentURL(incident.ID)
+	}
+	return event
+}
+
+// cancelAlertEscalations 报警规则状态被重置时停止其全部升级
+func cancelAlertEscalations(alertID uint64) {
+	EscalationPolicyShared.Cancel(func(key EscalationKey, _ uint64) bool {
+		return key.AlertRuleID == alertID
+	})
+}
@@ -2,6 +2,10 @@ package singleton
 
 import (
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/nezhahq/nezha/model"
 )
@@ -117,3 +121,34 @@ func TestCheckStatus_SampleMemoryBounded(t *testing.T) {
 		t.Fatalf("sample capacity grew unbounded: peak cap %d exceeds 4x window %d", peakCap, duration*4)
 	}
 }
+
+// 报警与恢复通知的静音标志按 (报警规则, 服务器) 的顺序生成，恢复时清除的正是报警时写入的标志
+func TestCheckStatusMuteLabels(t *testing.T) {
+	setupIncidentTestDB(t)
+
+	enable := true
+	alert := &model.AlertRule{Common: model.Common{ID: 7, UserID: 3}, Name: "cpu high", Enable: &enable, Rules: []*model.Rule{{Type: "cpu", Max: 50, Duration: 1}}}
+	server := &model.Server{Common: model.Common{ID: 1, UserID: 3}, Name: "web", State: &model.HostState{CPU: 90}, GeoIP: &model.GeoIP{}}
+	setupCheckStatusTest(t, alert, server)
+
+	incidentLabel := NotificationMuteLabel.AppendNotificationGroupName("bf::sei-7-1", "")
+	resolvedLabel := NotificationMuteLabel.AppendNotificationGroupName("bf::seir-7-1", "")
+	require.Equal(t, "bf::sei-7-1", NotificationMuteLabel.ServerIncident(alert.ID, server.ID))
+	require.Equal(t, "bf::seir-7-1", NotificationMuteLabel.ServerIncidentResolved(alert.ID, server.ID))
+
+	checkStatus()
+	assert.Eventually(t, func() bool {
+		_, ok := Cache.Get(incidentLabel)
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, Cache.ItemCount())
+
+	replaceServerSharedForSecurityTest(t, &model.Server{Common: server.Common, Name: server.Name, State: &model.HostState{CPU: 10}, GeoIP: server.GeoIP})
+	checkStatus()
+	assert.Eventually(t, func() bool {
+		_, ok := Cache.Get(resolvedLabel)
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	_, ok := Cache.Get(incidentLabel)
+	assert.False(t, ok, "the incident mute label is cleared on recovery")
+}
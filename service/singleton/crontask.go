@@ -156,10 +156,10 @@ func CanReportCronResult(cr *model.Cron, reporter *model.Server) bool {
 		return false
 	}
 	if cr.Cover == model.CronCoverAll {
-		return !slices.Contains(cr.Servers, reporter.ID)
+		return !cr.Lists(reporter.ID)
 	}
 	if cr.Cover == model.CronCoverIgnoreAll {
-		return slices.Contains(cr.Servers, reporter.ID)
+		return cr.Lists(reporter.ID)
 	}
 	if cr.Cover == model.CronCoverAlertTrigger {
 		return CronShared != nil && CronShared.consumeAlertTriggerCronResult(cr.ID, reporter.ID)
@@ -263,10 +263,6 @@ func ManualTrigger(cr *model.Cron) {
 }
 
 func CronTrigger(cr *model.Cron, triggerServer ...uint64) func() {
-	crIgnoreMap := make(map[uint64]bool)
-	for _, server := range cr.Servers {
-		crIgnoreMap[server] = true
-	}
 	return func() {
 		if cr.Cover == model.CronCoverAlertTrigger {
 			if len(triggerServer) == 0 {
@@ -301,6 +297,11 @@ func CronTrigger(cr *model.Cron, triggerServer ...uint64) func() {
 		// 先在锁内快照 server 列表再逐个 SendTask：ServerShared.Range 会在整个
 		// 回调期间持 listMu.RLock，而 SendTask 走阻塞 gRPC，一个卡死的 agent
 		// 会让需要写锁的 server 编辑/删除被拖死。GetList 克隆后即释放锁。
+		// 服务器分组在每次执行时展开，成员变化无需修改计划任务。
+		crIgnoreMap := make(map[uint64]bool)
+		for _, server := range cr.ServerIDs() {
+			crIgnoreMap[server] = true
+		}
 		for _, s := range ServerShared.GetList() {
 			if s == nil {
 				continue
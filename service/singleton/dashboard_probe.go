@@ -0,0 +1,271 @@
+package singleton
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/pkg/utils"
+	pb "github.com/nezhahq/nezha/proto"
+)
+
+const dashboardProbeTimeout = 10 * time.Second
+
+// resolveProbeHost 面板探测只允许访问公网地址，避免通过服务监控探测面板所在的内网
+var resolveProbeHost = utils.ResolveAllowedHost
+
+// probeRootCAs 校验证书链使用的根证书，为空时使用系统根证书
+var probeRootCAs *x509.CertPool
+
+// dashboardProbe 在面板进程内执行一次探测，结果与 agent 上报的一样交给 Dispatch 处理。
+// 上一次探测尚未结束时跳过本次。
+func (ss *ServiceSentinel) dashboardProbe(m *model.Service) {
+	if _, running := ss.dashboardProbing.LoadOrStore(m.ID, struct{}{}); running {
+		return
+	}
+	defer ss.dashboardProbing.Delete(m.ID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), dashboardProbeTimeout)
+	defer cancel()
+	ss.Dispatch(ReportData{
+		Reporter: model.DashboardReporterID,
+		Data:     runDashboardProbe(ctx, m),
+	})
+}
+
+func runDashboardProbe(ctx context.Context, m *model.Service) *pb.TaskResult {
+	result := &pb.TaskResult{Id: m.ID, Type: uint64(m.Type)}
+	start := time.Now()
+	var err error
+	switch m.Type {
+	case model.TaskTypeHTTPGet:
+		result.Successful, result.Data = probeHTTP(ctx, m)
+	case model.TaskTypeTCPPing:
+		err = probeTCP(ctx, m.Target)
+	case model.TaskTypeICMPPing:
+		err = probeICMP(ctx, m.Target)
+	default:
+		err = fmt.Errorf("service type %d cannot be probed from the dashboard", m.Type)
+	}
+	result.Delay = float32(time.Since(start).Microseconds()) / 1000
+	if m.Type != model.TaskTypeHTTPGet {
+		result.Successful = err == nil
+		if err != nil {
+			result.Data = err.Error()
+		}
+	}
+	return result
+}
+
+func probeDialer() *net.Dialer {
+	return &net.Dialer{Timeout: dashboardProbeTimeout}
+}
+
+// dialProbe 每次建立连接都重新校验目标地址，跳转到其他主机时同样生效
+func dialProbe(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	ip, err := resolveProbeHost(host)
+	if err != nil {
+		return nil, err
+	}
+	return probeDialer().DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+}
+
+func probeTCP(ctx context.Context, target string) error {
+	conn, err := dialProbe(ctx, "tcp", target)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func probeICMP(ctx context.Context, target string) error {
+	ip, err := resolveProbeHost(target)
+	if err != nil {
+		return err
+	}
+
+	network, privilegedNetwork, proto := "udp4", "ip4:icmp", 1
+	var echoType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if ip.To4() == nil {
+		network, privilegedNetwork, proto = "udp6", "ip6:ipv6-icmp", 58
+		echoType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+	// 优先使用无需 root 权限的 ICMP 套接字
+	var dst net.Addr = &net.UDPAddr{IP: ip}
+	conn, err := icmp.ListenPacket(network, "")
+	if err != nil {
+		dst = &net.IPAddr{IP: ip}
+		if conn, err = icmp.ListenPacket(privilegedNetwork, ""); err != nil {
+			return err
+		}
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	id, seq := os.Getpid()&0xffff, int(time.Now().UnixNano()&0xffff)
+	req, err := (&icmp.Message{
+		Type: echoType,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("nezha")},
+	}).Marshal(nil)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.WriteTo(req, dst); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		reply, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil || reply.Type != replyType {
+			continue
+		}
+		// 无权限套接字的 ID 由内核改写，只比较序号
+		if echo, ok := reply.Body.(*icmp.Echo); ok && echo.Seq == seq {
+			return nil
+		}
+	}
+}
+
+// probeHTTP 执行 HTTP 监控，与新版 agent 一样返回 HTTPProbeResult，由 processReport 按配置判定并记录证书
+func probeHTTP(ctx context.Context, m *model.Service) (bool, string) {
+	r := doProbeHTTP(ctx, m.Target, m.HTTP)
+	reason, _ := m.HTTP.Check(r)
+	data, _ := json.Marshal(r)
+	return reason == "", string(data)
+}
+
+// verifyProbeCertificate 校验服务端证书链与域名
+func verifyProbeCertificate(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("no peer certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       cs.ServerName,
+		Roots:         probeRootCAs,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+func doProbeHTTP(ctx context.Context, target string, p *model.HTTPProbe) *model.HTTPProbeResult {
+	if p == nil {
+		p = &model.HTTPProbe{}
+	}
+	method := p.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if p.Body != "" {
+		body = strings.NewReader(p.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return &model.HTTPProbeResult{Error: err.Error()}
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return &model.HTTPProbeResult{Error: utils.ErrHTTPURLTargetNotAllowed.Error()}
+	}
+	for k, v := range p.Headers {
+		req.Header.Set(k, v)
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", "nezha-dashboard-probe")
+	}
+
+	redirects := 0
+	var tlsInfo *model.TLSCertificateInfo
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: dialProbe,
+			TLSClientConfig: &tls.Config{
+				// 自行校验证书链，校验失败时同样记录证书信息
+				InsecureSkipVerify: true,
+				VerifyConnection: func(cs tls.ConnectionState) error {
+					err := verifyProbeCertificate(cs)
+					tlsInfo = model.NewTLSCertificateInfo(&cs, err)
+					if err != nil {
+						return &tls.CertificateVerificationError{UnverifiedCertificates: cs.PeerCertificates, Err: err}
+					}
+					return nil
+				},
+			},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if p.Redirect != model.HTTPRedirectFollow {
+				return http.ErrUseLastResponse
+			}
+			if len(via) > p.RedirectLimit() {
+				return fmt.Errorf("stopped after %d redirects", p.RedirectLimit())
+			}
+			redirects = len(via)
+			return nil
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var certErr *tls.CertificateVerificationError
+		var hostnameErr x509.HostnameError
+		if errors.As(err, &certErr) || errors.As(err, &hostnameErr) {
+			return &model.HTTPProbeResult{Error: "SSL证书错误：" + err.Error(), TLS: tlsInfo}
+		}
+		return &model.HTTPProbeResult{Error: err.Error(), TLS: tlsInfo}
+	}
+	defer resp.Body.Close()
+
+	r := &model.HTTPProbeResult{
+		StatusCode: resp.StatusCode,
+		Redirects:  redirects,
+		FinalURL:   resp.Request.URL.String(),
+		TLS:        tlsInfo,
+	}
+	// 不跟随跳转时 3xx 响应本身就是跳转，FailRedirect 需要据此判定
+	if p.Redirect == model.HTTPRedirectFail && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		r.Redirects = 1
+		r.FinalURL = resp.Header.Get("Location")
+	}
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		cert := resp.TLS.PeerCertificates[0]
+		r.Cert = fmt.Sprintf("%s|%s", cert.Issuer.CommonName, cert.NotAfter)
+	}
+	if p.HasBodyAssertion() {
+		data, err := io.ReadAll(io.LimitReader(resp.Body, model.HTTPProbeMaxResponseBody))
+		if err != nil {
+			r.Error = err.Error()
+		}
+		r.Body = string(bytes.ToValidUTF8(data, nil))
+	}
+	return r
+}
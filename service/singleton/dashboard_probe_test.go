@@ -0,0 +1,220 @@
+package singleton
+
+import (
+	"context"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nezhahq/nezha/model"
+)
+
+// allowLoopbackProbes 允许面板探测访问 httptest 启动的本地服务
+func allowLoopbackProbes(t *testing.T) {
+	original := resolveProbeHost
+	resolveProbeHost = func(host string) (net.IP, error) {
+		if ip := net.ParseIP(host); ip != nil {
+			return ip, nil
+		}
+		ips, err := net.LookupIP(host)
+		if err != nil {
+			return nil, err
+		}
+		return ips[0], nil
+	}
+	t.Cleanup(func() { resolveProbeHost = original })
+}
+
+func TestDashboardProbeRejectsPrivateTargets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	for _, s := range []*model.Service{
+		{Type: model.TaskTypeHTTPGet, Target: srv.URL},
+		{Type: model.TaskTypeTCPPing, Target: srv.Listener.Addr().String()},
+		{Type: model.TaskTypeICMPPing, Target: "127.0.0.1"},
+	} {
+		r := runDashboardProbe(t.Context(), s)
+		assert.False(t, r.Successful, "type %d", s.Type)
+		assert.Contains(t, r.Data, "not allowed")
+	}
+}
+
+func TestDashboardProbeHTTP(t *testing.T) {
+	allowLoopbackProbes(t)
+	var gotMethod, gotBody, gotAuth string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotAuth = r.Method, r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.Write([]byte(`{"status":"up"}`))
+	})
+	mux.Handle("/old", http.RedirectHandler("/health", http.StatusFound))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	s := &model.Service{Type: model.TaskTypeHTTPGet, Target: srv.URL + "/health"}
+	r := runDashboardProbe(t.Context(), s)
+	assert.True(t, r.Successful, r.Data)
+
+	s.HTTP = &model.HTTPProbe{
+		Method:   http.MethodPost,
+		Headers:  map[string]string{"Authorization": "Bearer t"},
+		Body:     `{"ping":1}`,
+		JSONPath: "status", JSONValue: "up",
+	}
+	r = runDashboardProbe(t.Context(), s)
+	var result model.HTTPProbeResult
+	require.NoError(t, json.Unmarshal([]byte(r.Data), &result))
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	assert.Equal(t, `{"status":"up"}`, result.Body)
+	assert.Equal(t, "POST", gotMethod)
+	assert.Equal(t, "Bearer t", gotAuth)
+	assert.Equal(t, `{"ping":1}`, gotBody)
+	ok, _ := s.CheckResult(r.Successful, r.Data)
+	assert.True(t, ok)
+
+	// 跳转策略
+	s.Target = srv.URL + "/old"
+	s.HTTP = &model.HTTPProbe{Keyword: "up"}
+	r = runDashboardProbe(t.Context(), s)
+	ok, data := s.CheckResult(r.Successful, r.Data)
+	assert.True(t, ok, data)
+	s.HTTP = &model.HTTPProbe{Redirect: model.HTTPRedirectFail}
+	r = runDashboardProbe(t.Context(), s)
+	ok, data = s.CheckResult(r.Successful, r.Data)
+	assert.False(t, ok)
+	assert.Equal(t, "[redirect] redirected to /health", data)
+	s.HTTP = &model.HTTPProbe{Redirect: model.HTTPRedirectNone, AcceptedStatusCodes: []string{"302"}}
+	r = runDashboardProbe(t.Context(), s)
+	ok, data = s.CheckResult(r.Successful, r.Data)
+	assert.True(t, ok, data)
+}
+
+func TestDashboardProbeTLSCertificate(t *testing.T) {
+	allowLoopbackProbes(t)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	s := &model.Service{Type: model.TaskTypeHTTPGet, Target: srv.URL}
+
+	// 自签名证书校验失败，仍然记录证书信息
+	r := runDashboardProbe(t.Context(), s)
+	assert.False(t, r.Successful)
+	var result model.HTTPProbeResult
+	require.NoError(t, json.Unmarshal([]byte(r.Data), &result))
+	assert.True(t, strings.HasPrefix(result.Error, "SSL证书错误："), result.Error)
+	require.NotNil(t, result.TLS)
+	assert.False(t, result.TLS.ChainValid)
+	assert.NotEmpty(t, result.TLS.ChainError)
+
+	original := probeRootCAs
+	probeRootCAs = x509.NewCertPool()
+	probeRootCAs.AddCert(srv.Certificate())
+	t.Cleanup(func() { probeRootCAs = original })
+
+	r = runDashboardProbe(t.Context(), s)
+	assert.True(t, r.Successful, r.Data)
+	info := s.TLSCertificate(r.Data)
+	require.NotNil(t, info)
+	assert.True(t, info.ChainValid)
+	assert.Equal(t, srv.Certificate().NotAfter, info.Leaf().NotAfter)
+	assert.Contains(t, info.Leaf().SANs, "127.0.0.1")
+}
+
+func TestDashboardProbeTCP(t *testing.T) {
+	allowLoopbackProbes(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+
+	r := runDashboardProbe(t.Context(), &model.Service{Type: model.TaskTypeTCPPing, Target: addr})
+	assert.True(t, r.Successful, r.Data)
+	assert.Greater(t, r.Delay, float32(0))
+
+	ln.Close()
+	r = runDashboardProbe(t.Context(), &model.Service{Type: model.TaskTypeTCPPing, Target: addr})
+	assert.False(t, r.Successful)
+	assert.NotEmpty(t, r.Data)
+}
+
+func TestDashboardProbeReport(t *testing.T) {
+	allowLoopbackProbes(t)
+	ss := newServiceMonitorSecurityHarness(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// 没有任何 agent 覆盖，只由面板探测
+	service := &model.Service{
+		Common:         model.Common{ID: 10, UserID: 1},
+		Name:           "site",
+		Type:           model.TaskTypeHTTPGet,
+		Target:         srv.URL,
+		Duration:       3600,
+		Cover:          model.ServiceCoverIgnoreAll,
+		DashboardProbe: true,
+	}
+	addServiceMonitorSecurityService(t, ss, service)
+	ss.dashboardProbe(service)
+
+	// 未开启面板探测的服务不接受面板结果
+	other := &model.Service{
+		Common:   model.Common{ID: 20, UserID: 1},
+		Name:     "agent-only",
+		Type:     model.TaskTypeHTTPGet,
+		Target:   srv.URL,
+		Duration: 3600,
+		Cover:    model.ServiceCoverIgnoreAll,
+	}
+	addServiceMonitorSecurityService(t, ss, other)
+	ss.Dispatch(ReportData{Reporter: model.DashboardReporterID, Data: runDashboardProbe(context.Background(), other)})
+	ss.Close()
+
+	ss.serviceResponseDataStoreLock.RLock()
+	defer ss.serviceResponseDataStoreLock.RUnlock()
+	assert.Equal(t, uint64(1), ss.serviceStatusToday[service.ID].Up)
+	assert.Equal(t, uint64(0), ss.serviceStatusToday[other.ID].Up)
+}
+
+func TestDashboardProbeAfterRestart(t *testing.T) {
+	allowLoopbackProbes(t)
+	newServiceMonitorSecurityHarness(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	service := &model.Service{
+		Common:         model.Common{ID: 10, UserID: 1},
+		Name:           "site",
+		Type:           model.TaskTypeHTTPGet,
+		Target:         srv.URL,
+		Duration:       3600,
+		Cover:          model.ServiceCoverIgnoreAll,
+		DashboardProbe: true,
+	}
+	require.NoError(t, DB.Create(service).Error)
+
+	// 面板重启后从数据库加载，定时任务同时下发给 agent 与执行面板探测
+	bus := make(chan *model.Service, 1)
+	ss, err := NewServiceSentinel(bus)
+	require.NoError(t, err)
+	t.Cleanup(func() { ss.Close() })
+	loaded := ss.services[service.ID]
+	require.NotNil(t, loaded)
+
+	CronShared.Entry(loaded.CronJobID).Job.Run()
+	assert.Equal(t, service.ID, (<-bus).ID)
+	assert.Eventually(t, func() bool {
+		ss.serviceResponseDataStoreLock.RLock()
+		defer ss.serviceResponseDataStoreLock.RUnlock()
+		return ss.serviceStatusToday[service.ID].Up == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,357 @@
+package singleton
+
+import (
+	"cmp"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/pkg/utils"
+)
+
+const EscalationCheckSchedule = "@every 10s"
+
+type EscalationPolicyClass struct {
+	class[uint64, *model.EscalationPolicy]
+
+	activeMu sync.Mutex
+	active   map[EscalationKey]*escalation
+}
+
+// EscalationKey 正在升级的报警：报警规则在某台服务器上的报警事件，或某个服务监控的故障
+type EscalationKey struct {
+	AlertRuleID uint64
+	ServerID    uint64
+	ServiceID   uint64
+}
+
+// subject 升级通知关联的对象，用于匹配静默
+func (key EscalationKey) subject() model.NotificationSubject {
+	return model.NotificationSubject{AlertRuleID: key.AlertRuleID, ServerID: key.ServerID, ServiceID: key.ServiceID}
+}
+
+// escalation 一次报警的升级进度
+type escalation struct {
+	policyID     uint64
+	userID       uint64
+	incidentID   uint64
+	startedAt    time.Time
+	reached      int      // 已通知的步骤数
+	notified     []uint64 // 已通知的通知组，重复报警与恢复通知发往这些通知组
+	acknowledged bool
+	message      string
+	event        *model.NotificationEvent
+	muteLabel    string
+	server       *model.Server
+}
+
+// escalationStep 一次待发送的升级通知
+type escalationStep struct {
+	key      EscalationKey
+	e        escalation
+	step     int
+	groupID  uint64
+	policyID uint64
+}
+
+func NewEscalationPolicyClass() *EscalationPolicyClass {
+	var sortedList []*model.EscalationPolicy
+	DB.Find(&sortedList)
+	list := make(map[uint64]*model.EscalationPolicy, len(sortedList))
+	for _, p := range sortedList {
+		list[p.ID] = p
+	}
+
+	return &EscalationPolicyClass{
+		class: class[uint64, *model.EscalationPolicy]{
+			list:       list,
+			sortedList: sortedList,
+		},
+		active: make(map[EscalationKey]*escalation),
+	}
+}
+
+func (c *EscalationPolicyClass) Update(p *model.EscalationPolicy) {
+	c.listMu.Lock()
+	c.list[p.ID] = p
+	c.listMu.Unlock()
+	c.sortList()
+}
+
+// Delete 删除策略并停止使用这些策略的升级，之后的报警回退到报警规则 / 服务监控自身的通知组
+func (c *EscalationPolicyClass) Delete(idList []uint64) {
+	c.listMu.Lock()
+	for _, id := range idList {
+		delete(c.list, id)
+	}
+	c.listMu.Unlock()
+	c.sortList()
+
+	c.Cancel(func(_ EscalationKey, policyID uint64) bool {
+		return slices.Contains(idList, policyID)
+	})
+}
+
+// Trigger 报警触发时调用：首次触发开始升级并立即通知已到期的步骤，
+// 升级进行中再次触发（始终触发模式）则按防骚扰策略通知已通知过的通知组。
+// 策略不存在时返回 false，调用方应回退到自身的通知组。
+func (c *EscalationPolicyClass) Trigger(key EscalationKey, policyID, userID, incidentID uint64, message string, event *model.NotificationEvent, muteLabel string, server *model.Server) bool {
+	if c == nil {
+		return false
+	}
+	policy, ok := c.Get(policyID)
+	if !ok {
+		return false
+	}
+
+	now := time.Now()
+	c.activeMu.Lock()
+	e, ok := c.active[key]
+	if ok {
+		e.policyID = policyID
+		e.message = message
+		e.event = event
+		e.server = server
+		notified := slices.Clone(e.notified)
+		c.activeMu.Unlock()
+		for _, gid := range notified {
+			if server != nil {
+				go NotificationShared.SendEventNotification(gid, message, muteLabel, key.subject(), event, server)
+			} else {
+				go NotificationShared.SendEventNotification(gid, message, muteLabel, key.subject(), event)
+			}
+		}
+		return true
+	}
+	e = &escalation{
+		policyID:   policyID,
+		userID:     userID,
+		incidentID: incidentID,
+		startedAt:  now,
+		message:    message,
+		event:      event,
+		muteLabel:  muteLabel,
+		server:     server,
+	}
+	c.active[key] = e
+	c.activeMu.Unlock()
+
+	// 数据库与静默查询不在 activeMu 内进行，避免阻塞其他报警的升级
+	p := pendingEscalation{key: key, e: e, policy: policy, incidentID: incidentID, held: escalationHeld(key, incidentID)}
+	c.activeMu.Lock()
+	steps := c.resume(p, now)
+	c.activeMu.Unlock()
+
+	for _, s := range steps {
+		go s.deliver()
+	}
+	return true
+}
+
+// Resolve 报警恢复时结束升级，返回应当收到恢复通知的通知组；没有进行中的升级时返回 false
+func (c *EscalationPolicyClass) Resolve(key EscalationKey) ([]uint64, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.activeMu.Lock()
+	defer c.activeMu.Unlock()
+	e, ok := c.active[key]
+	if !ok {
+		return nil, false
+	}
+	delete(c.active, key)
+	return e.notified, true
+}
+
+// Cancel 不发送恢复通知直接停止命中的升级，用于报警规则、服务监控或策略被修改删除时
+func (c *EscalationPolicyClass) Cancel(match func(key EscalationKey, policyID uint64) bool) {
+	if c == nil {
+		return
+	}
+	c.activeMu.Lock()
+	defer c.activeMu.Unlock()
+	for key, e := range c.active {
+		if match(key, e.policyID) {
+			delete(c.active, key)
+		}
+	}
+}
+
+// pendingEscalation 有步骤到期、等待检查能否继续的升级
+type pendingEscalation struct {
+	key        EscalationKey
+	e          *escalation
+	policy     *model.EscalationPolicy
+	incidentID uint64
+	held       escalationHold
+}
+
+// CheckEscalations 定期检查进行中的升级，通知已到期且未被确认的步骤
+func (c *EscalationPolicyClass) CheckEscalations() {
+	now := time.Now()
+	var pending []pendingEscalation
+
+	c.activeMu.Lock()
+	for key, e := range c.active {
+		policy, ok := c.Get(e.policyID)
+		if !ok {
+			delete(c.active, key)
+			continue
+		}
+		if !e.acknowledged && len(policy.DueSteps(e.reached, now.Sub(e.startedAt))) > 0 {
+			pending = append(pending, pendingEscalation{key: key, e: e, policy: policy, incidentID: e.incidentID})
+		}
+	}
+	c.activeMu.Unlock()
+
+	for i := range pending {
+		pending[i].held = escalationHeld(pending[i].key, pending[i].incidentID)
+	}
+
+	var steps []escalationStep
+	c.activeMu.Lock()
+	for _, p := range pending {
+		steps = append(steps, c.resume(p, now)...)
+	}
+	c.activeMu.Unlock()
+
+	for _, s := range steps {
+		s.deliver()
+	}
+}
+
+// escalationHold 升级能否继续
+type escalationHold uint8
+
+const (
+	escalationRunning escalationHold = iota
+	escalationAcknowledged
+	escalationPaused // 被静默或处于维护窗口中
+)
+
+// escalationHeld 检查报警是否已被确认、被静默或处于维护窗口中。
+// 会查询数据库，不能在持有 activeMu 时调用
+func escalationHeld(key EscalationKey, incidentID uint64) escalationHold {
+	if incidentID != 0 && incidentAcknowledged(incidentID) {
+		return escalationAcknowledged
+	}
+	if SilenceShared != nil && SilenceShared.Match(key.subject()) != nil {
+		return escalationPaused
+	}
+	if MaintenanceWindowShared.ServerStatus(key.ServerID) != nil || MaintenanceWindowShared.ServiceStatus(key.ServiceID) != nil {
+		return escalationPaused
+	}
+	return escalationRunning
+}
+
+// resume 按 escalationHeld 的检查结果推进升级。检查期间已恢复、被取消或重新开始的升级不再推进。
+// 调用方需持有 activeMu
+func (c *EscalationPolicyClass) resume(p pendingEscalation, now time.Time) []escalationStep {
+	if c.active[p.key] != p.e {
+		return nil
+	}
+	switch p.held {
+	case escalationAcknowledged:
+		p.e.acknowledged = true
+	case escalationRunning:
+		return c.advance(p.key, p.e, p.policy, now)
+	}
+	return nil
+}
+
+// advance 推进升级进度并返回需要发送的步骤。已确认、被静默或处于维护窗口中的报警由调用方
+// 通过 escalationHeld 排除，静默或维护结束后若仍未恢复则继续。调用方需持有 activeMu。
+func (c *EscalationPolicyClass) advance(key EscalationKey, e *escalation, policy *model.EscalationPolicy, now time.Time) []escalationStep {
+	if e.acknowledged {
+		return nil
+	}
+	due := policy.DueSteps(e.reached, now.Sub(e.startedAt))
+	if len(due) == 0 {
+		return nil
+	}
+
+	steps := make([]escalationStep, 0, len(due))
+	for _, i := range due {
+		gid := policy.Steps[i].NotificationGroupID
+		if !slices.Contains(e.notified, gid) {
+			e.notified = append(e.notified, gid)
+		}
+		steps = append(steps, escalationStep{key: key, e: *e, step: i, groupID: gid, policyID: policy.ID})
+	}
+	e.reached = due[len(due)-1] + 1
+	return steps
+}
+
+// deliver 发送一级升级通知并记录。新升级到的通知组重新开始防骚扰计时，保证本级通知一定发出
+func (s escalationStep) deliver() {
+	NotificationShared.UnMuteNotification(s.groupID, s.e.muteLabel)
+	if s.e.server != nil {
+		NotificationShared.SendEventNotification(s.groupID, s.e.message, s.e.muteLabel, s.key.subject(), s.e.event, s.e.server)
+	} else {
+		NotificationShared.SendEventNotification(s.groupID, s.e.message, s.e.muteLabel, s.key.subject(), s.e.event)
+	}
+
+	delivery := &model.EscalationDelivery{
+		EscalationPolicyID:    s.policyID,
+		Step:                  s.step,
+		NotificationGroupID:   s.groupID,
+		NotificationGroupName: NotificationShared.GetGroupName(s.groupID),
+		AlertRuleID:           s.key.AlertRuleID,
+		ServerID:              s.key.ServerID,
+		ServiceID:             s.key.ServiceID,
+		IncidentID:            s.e.incidentID,
+		Message:               s.e.message,
+	}
+	delivery.UserID = s.e.userID
+	if err := DB.Create(delivery).Error; err != nil {
+		log.Printf("NEZHA>> Failed to save escalation delivery of policy %d step %d: %v", s.policyID, s.step, err)
+	}
+}
+
+// restore 重启后按已记录的发送情况恢复报警事件的升级进度
+func (c *EscalationPolicyClass) restore(key EscalationKey, e *escalation) {
+	if c == nil {
+		return
+	}
+	var deliveries []model.EscalationDelivery
+	if err := DB.Where("incident_id = ?", e.incidentID).Order("id").Find(&deliveries).Error; err != nil {
+		log.Printf("NEZHA>> Failed to load escalation deliveries of incident %d: %v", e.incidentID, err)
+	}
+	for _, d := range deliveries {
+		if d.Step+1 > e.reached {
+			e.reached = d.Step + 1
+		}
+		if !slices.Contains(e.notified, d.NotificationGroupID) {
+			e.notified = append(e.notified, d.NotificationGroupID)
+		}
+	}
+
+	c.activeMu.Lock()
+	c.active[key] = e
+	c.activeMu.Unlock()
+}
+
+func incidentAcknowledged(incidentID uint64) bool {
+	var count int64
+	if err := DB.Model(&model.Incident{}).Where("id = ? AND acknowledged_at IS NOT NULL", incidentID).Count(&count).Error; err != nil {
+		log.Printf("NEZHA>> Failed to check acknowledgement of incident %d: %v", incidentID, err)
+		return false
+	}
+	return count > 0
+}
+
+func (c *EscalationPolicyClass) sortList() {
+	c.listMu.RLock()
+	defer c.listMu.RUnlock()
+
+	sortedList := utils.MapValuesToSlice(c.list)
+	slices.SortFunc(sortedList, func(a, b *model.EscalationPolicy) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	c.sortedListMu.Lock()
+	defer c.sortedListMu.Unlock()
+	c.sortedList = sortedList
+}
@@ -0,0 +1,126 @@
+package singleton
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/nezhahq/nezha/model"
+)
+
+// setupEscalationTest 准备升级策略依赖的数据库与全局状态，创建一个三级的升级策略，
+// 返回读取全部发送记录的函数
+func setupEscalationTest(t *testing.T) func() []model.EscalationDelivery {
+	t.Helper()
+
+	previousDB, previousCache, previousNotification, previousSilence := DB, Cache, NotificationShared, SilenceShared
+	var err error
+	DB, err = gorm.Open(openSQLiteDialector(filepath.Join(t.TempDir(), "dashboard.sqlite")), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := DB.DB()
+	require.NoError(t, err)
+	Cache = cache.New(time.Minute, time.Minute)
+	SilenceShared = nil
+	t.Cleanup(func() {
+		DB, Cache, NotificationShared, SilenceShared = previousDB, previousCache, previousNotification, previousSilence
+		_ = sqlDB.Close()
+	})
+	require.NoError(t, DB.AutoMigrate(&model.Incident{}, &model.EscalationPolicy{}, &model.EscalationDelivery{},
+		&model.Notification{}, &model.NotificationGroup{}, &model.NotificationGroupNotification{}))
+	NotificationShared = NewNotificationClass()
+
+	require.NoError(t, DB.Create(&model.EscalationPolicy{Name: "oncall", Steps: []model.EscalationStep{
+		{Delay: 0, NotificationGroupID: 1},
+		{Delay: 900, NotificationGroupID: 2},
+		{Delay: 3600, NotificationGroupID: 3},
+	}}).Error)
+
+	return func() []model.EscalationDelivery {
+		var d []model.EscalationDelivery
+		require.NoError(t, DB.Order("id").Find(&d).Error)
+		return d
+	}
+}
+
+func TestEscalationPolicyClassEscalates(t *testing.T) {
+	deliveries := setupEscalationTest(t)
+	incident := &model.Incident{AlertRuleID: 7, ServerID: 2, OpenedAt: time.Now()}
+	require.NoError(t, DB.Create(incident).Error)
+
+	c := NewEscalationPolicyClass()
+	key := EscalationKey{AlertRuleID: 7, ServerID: 2}
+
+	assert.False(t, c.Trigger(key, 99, 1, incident.ID, "down", nil, "bf::sei-7-2", nil), "unknown policy falls back")
+	require.True(t, c.Trigger(key, 1, 1, incident.ID, "down", nil, "bf::sei-7-2", nil))
+	require.Eventually(t, func() bool { return len(deliveries()) == 1 }, time.Second, 10*time.Millisecond)
+
+	// 第二级到期后通知第二个通知组
+	c.CheckEscalations()
+	assert.Len(t, deliveries(), 1)
+	c.active[key].startedAt = time.Now().Add(-20 * time.Minute)
+	c.CheckEscalations()
+	d := deliveries()
+	require.Len(t, d, 2)
+	assert.Equal(t, 1, d[1].Step)
+	assert.Equal(t, uint64(2), d[1].NotificationGroupID)
+	assert.Equal(t, incident.ID, d[1].IncidentID)
+	assert.Equal(t, uint64(1), d[1].UserID)
+
+	// 事件被确认后不再升级
+	require.NoError(t, DB.Model(incident).Update("acknowledged_at", time.Now()).Error)
+	c.active[key].startedAt = time.Now().Add(-2 * time.Hour)
+	c.CheckEscalations()
+	assert.Len(t, deliveries(), 2)
+
+	notified, ok := c.Resolve(key)
+	assert.True(t, ok)
+	assert.Equal(t, []uint64{1, 2}, notified)
+	_, ok = c.Resolve(key)
+	assert.False(t, ok)
+
+	// 重启后按发送记录恢复进度
+	c.restore(key, &escalation{policyID: 1, incidentID: incident.ID, startedAt: time.Now().Add(-2 * time.Hour)})
+	assert.Equal(t, 2, c.active[key].reached)
+	assert.Equal(t, []uint64{1, 2}, c.active[key].notified)
+
+	// 删除策略后停止升级
+	c.Delete([]uint64{1})
+	assert.Empty(t, c.active)
+}
+
+// 服务监控故障期间记录事件，确认事件同样可以停止服务监控的升级
+func TestServiceEscalationStopsWhenIncidentAcknowledged(t *testing.T) {
+	deliveries := setupEscalationTest(t)
+	previousIncidents := serviceIncidents
+	t.Cleanup(func() { serviceIncidents = previousIncidents })
+	serviceIncidents = nil
+
+	service := &model.Service{Common: model.Common{ID: 5, UserID: 1}, Name: "api", EscalationPolicyID: 1}
+	incident := openServiceIncident(service)
+	require.NotNil(t, incident)
+	assert.Same(t, incident, openServiceIncident(service), "an ongoing outage keeps its incident")
+
+	c := NewEscalationPolicyClass()
+	key := EscalationKey{ServiceID: service.ID}
+	require.True(t, c.Trigger(key, 1, 1, incident.ID, "down", nil, "bf::ssc-5", nil))
+	require.Eventually(t, func() bool { return len(deliveries()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, incident.ID, deliveries()[0].IncidentID)
+
+	require.NoError(t, DB.Model(incident).Update("acknowledged_at", time.Now()).Error)
+	c.active[key].startedAt = time.Now().Add(-2 * time.Hour)
+	c.CheckEscalations()
+	assert.Len(t, deliveries(), 1)
+	assert.True(t, c.active[key].acknowledged)
+
+	resolveServiceIncident(service.ID)
+	var stored model.Incident
+	require.NoError(t, DB.First(&stored, incident.ID).Error)
+	assert.False(t, stored.IsOpen())
+	assert.Equal(t, service.ID, stored.ServiceID)
+	assert.Equal(t, "api", stored.ServiceName)
+}
@@ -0,0 +1,72 @@
+package singleton
+
+import (
+	"crypto/subtle"
+	"time"
+
+	"github.com/nezhahq/nezha/model"
+	pb "github.com/nezhahq/nezha/proto"
+)
+
+// heartbeatByToken 按心跳地址中的密钥查找心跳监控
+func (ss *ServiceSentinel) heartbeatByToken(token string) *model.Service {
+	if token == "" {
+		return nil
+	}
+	ss.servicesLock.RLock()
+	defer ss.servicesLock.RUnlock()
+	var found *model.Service
+	for _, s := range ss.services {
+		if s.Type == model.TaskTypeHeartbeat && s.HeartbeatToken != "" &&
+			subtle.ConstantTimeCompare([]byte(s.HeartbeatToken), []byte(token)) == 1 {
+			found = s
+		}
+	}
+	return found
+}
+
+// Heartbeat 记录一次心跳，按结果写入监控状态。密钥不匹配任何心跳监控时返回 false
+func (ss *ServiceSentinel) Heartbeat(token string, successful bool, delay float32, data string) bool {
+	s := ss.heartbeatByToken(token)
+	if s == nil {
+		return false
+	}
+
+	ss.serviceResponseDataStoreLock.Lock()
+	ss.heartbeatLastSeen[s.ID] = time.Now()
+	ss.serviceResponseDataStoreLock.Unlock()
+
+	ss.Dispatch(heartbeatReport(s.ID, successful, delay, data))
+	return true
+}
+
+// checkHeartbeat 由定时任务调用，超过期限未收到心跳时记录一次失败，
+// 之后每经过一个期限再记录一次，直到收到新的心跳
+func (ss *ServiceSentinel) checkHeartbeat(s *model.Service) {
+	now := time.Now()
+	ss.serviceResponseDataStoreLock.Lock()
+	last, ok := ss.heartbeatLastSeen[s.ID]
+	missed := ok && now.Sub(last) >= s.HeartbeatDeadline()
+	if missed {
+		ss.heartbeatLastSeen[s.ID] = now
+	}
+	ss.serviceResponseDataStoreLock.Unlock()
+
+	if missed {
+		ss.Dispatch(heartbeatReport(s.ID, false, 0,
+			Localizer.Tf("No heartbeat received since %s", last.In(Loc).Format(time.DateTime))))
+	}
+}
+
+func heartbeatReport(serviceID uint64, successful bool, delay float32, data string) ReportData {
+	return ReportData{
+		Reporter: model.DashboardReporterID,
+		Data: &pb.TaskResult{
+			Id:         serviceID,
+			Type:       model.TaskTypeHeartbeat,
+			Delay:      delay,
+			Data:       data,
+			Successful: successful,
+		},
+	}
+}
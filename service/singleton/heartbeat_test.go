@@ -0,0 +1,115 @@
+package singleton
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/pkg/i18n"
+)
+
+func TestServiceSentinelHeartbeat(t *testing.T) {
+	ss := newServiceMonitorSecurityHarness(t,
+		&model.Server{Common: model.Common{ID: 1, UserID: 1}, Name: "agent"},
+	)
+	originalLocalizer := Localizer
+	Localizer = i18n.NewLocalizer("zh_CN", domain, "translations", i18n.Translations)
+	t.Cleanup(func() { Localizer = originalLocalizer })
+	service := &model.Service{
+		Common:         model.Common{ID: 10, UserID: 1},
+		Name:           "nightly-backup",
+		Type:           model.TaskTypeHeartbeat,
+		Duration:       3600,
+		HeartbeatGrace: 600,
+		HeartbeatToken: "secret-token",
+		Cover:          model.ServiceCoverAll,
+	}
+	addServiceMonitorSecurityService(t, ss, service)
+
+	require.False(t, ss.Heartbeat("wrong-token", true, 0, ""))
+	require.True(t, ss.Heartbeat("secret-token", true, 1500, "done"))
+
+	// agent 不能伪造心跳结果
+	agentReport := serviceMonitorResult(1, service.ID, model.TaskTypeHeartbeat, true)
+	ss.processReport(agentReport, ServerShared)
+
+	// 未超时不记录失败
+	ss.checkHeartbeat(service)
+
+	// 超过期限后记录一次失败
+	ss.serviceResponseDataStoreLock.Lock()
+	ss.heartbeatLastSeen[service.ID] = time.Now().Add(-service.HeartbeatDeadline())
+	ss.serviceResponseDataStoreLock.Unlock()
+	ss.checkHeartbeat(service)
+	ss.checkHeartbeat(service)
+	ss.Close()
+
+	ss.serviceResponseDataStoreLock.RLock()
+	defer ss.serviceResponseDataStoreLock.RUnlock()
+	stats := ss.serviceStatusToday[service.ID]
+	assert.Equal(t, uint64(1), stats.Up)
+	assert.Equal(t, uint64(1), stats.Down)
+	assert.Equal(t, float64(1500), stats.Delay)
+}
+
+func TestHeartbeatServiceAfterRestart(t *testing.T) {
+	newServiceMonitorSecurityHarness(t,
+		&model.Server{Common: model.Common{ID: 1, UserID: 1}, Name: "agent"},
+	)
+	originalLocalizer := Localizer
+	Localizer = i18n.NewLocalizer("zh_CN", domain, "translations", i18n.Translations)
+	t.Cleanup(func() { Localizer = originalLocalizer })
+	service := &model.Service{
+		Common:         model.Common{ID: 10, UserID: 1},
+		Name:           "nightly-backup",
+		Type:           model.TaskTypeHeartbeat,
+		Duration:       3600,
+		HeartbeatToken: "secret-token",
+		Cover:          model.ServiceCoverAll,
+	}
+	require.NoError(t, DB.Create(service).Error)
+
+	// 面板重启后从数据库加载
+	bus := make(chan *model.Service, 1)
+	ss, err := NewServiceSentinel(bus)
+	require.NoError(t, err)
+	t.Cleanup(func() { ss.Close() })
+	loaded := ss.services[service.ID]
+	require.NotNil(t, loaded)
+
+	ss.serviceResponseDataStoreLock.Lock()
+	_, ok := ss.heartbeatLastSeen[service.ID]
+	ss.heartbeatLastSeen[service.ID] = time.Now().Add(-service.HeartbeatDeadline())
+	ss.serviceResponseDataStoreLock.Unlock()
+	require.True(t, ok, "deadline should start counting when the service is loaded")
+
+	// 定时任务检查心跳是否超时，而不是下发给 agent
+	CronShared.Entry(loaded.CronJobID).Job.Run()
+	select {
+	case task := <-bus:
+		t.Fatalf("heartbeat service %d must not be dispatched to agents", task.ID)
+	default:
+	}
+	ss.Close()
+
+	ss.serviceResponseDataStoreLock.RLock()
+	defer ss.serviceResponseDataStoreLock.RUnlock()
+	assert.Equal(t, uint64(1), ss.serviceStatusToday[service.ID].Down)
+}
+
+func TestHeartbeatServiceNotDispatched(t *testing.T) {
+	s := &model.Service{Common: model.Common{ID: 1}, Type: model.TaskTypeHeartbeat, Duration: 86400}
+	assert.NoError(t, model.ValidateServiceMonitorType(uint64(s.Type)))
+	assert.Nil(t, s.PB())
+	assert.False(t, model.IsServiceSentinelNeeded(model.TaskTypeHeartbeat))
+	assert.Equal(t, "@every 60s", s.CronSpec())
+	assert.Equal(t, 86400*time.Second, s.HeartbeatDeadline())
+
+	reporter := &model.Server{Common: model.Common{ID: 1, UserID: 1}}
+	assert.False(t, canReportServiceResult(s, reporter, model.TaskTypeHeartbeat))
+	assert.False(t, canAcceptServiceReport(s, ReportData{Reporter: 1, Data: heartbeatReport(1, true, 0, "").Data}, reporter))
+	assert.True(t, canAcceptServiceReport(s, heartbeatReport(1, true, 0, ""), nil))
+}
@@ -0,0 +1,194 @@
+package singleton
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jinzhu/copier"
+
+	"github.com/nezhahq/nezha/model"
+)
+
+// alertsIncidents [alert_id][server_id] -> 尚未恢复的报警事件，与 alertsPrevState 一样受 AlertsLock 保护，
+// 且只在报警检查协程或持有写锁时修改
+var alertsIncidents map[uint64]map[uint64]*model.Incident
+
+// serviceIncidents [service_id] -> 绑定了升级策略的服务监控尚未恢复的故障事件，
+// 受 ServiceSentinel.serviceResponseDataStoreLock 写锁保护
+var serviceIncidents map[uint64]*model.Incident
+
+// loadOpenIncidents 从数据库恢复未结束的报警事件，使重启后仍能发出恢复通知，
+// 单次触发模式也不会因为状态丢失而重复报警。调用方需持有 AlertsLock 写锁。
+func loadOpenIncidents() {
+	alertsIncidents = make(map[uint64]map[uint64]*model.Incident)
+	for _, alert := range Alerts {
+		alertsIncidents[alert.ID] = make(map[uint64]*model.Incident)
+	}
+
+	// 服务监控的事件由 ServiceSentinel 启动时结束，见 resolveStaleServiceIncidents
+	var incidents []*model.Incident
+	if err := DB.Where("resolved_at IS NULL AND service_id = 0").Find(&incidents).Error; err != nil {
+		log.Printf("NEZHA>> Failed to load open incidents: %v", err)
+		return
+	}
+	var orphans []uint64
+	for _, incident := range incidents {
+		if _, ok := alertsIncidents[incident.AlertRuleID]; !ok {
+			orphans = append(orphans, incident.ID)
+			continue
+		}
+		alertsIncidents[incident.AlertRuleID][incident.ServerID] = incident
+		alertsPrevState[incident.AlertRuleID][incident.ServerID] = _RuleCheckFail
+		restoreIncidentEscalation(incident)
+	}
+	// 报警规则已被删除的事件直接结束
+	if len(orphans) > 0 {
+		if err := DB.Model(&model.Incident{}).Where("id IN (?)", orphans).Update("resolved_at", time.Now()).Error; err != nil {
+			log.Printf("NEZHA>> Failed to resolve orphan incidents: %v", err)
+		}
+	}
+}
+
+// restoreIncidentEscalation 报警规则绑定了升级策略时恢复未结束事件的升级进度
+func restoreIncidentEscalation(incident *model.Incident) {
+	var alert *model.AlertRule
+	for _, a := range Alerts {
+		if a.ID == incident.AlertRuleID {
+			alert = a
+			break
+		}
+	}
+	if alert == nil || alert.EscalationPolicyID == 0 {
+		return
+	}
+	s, ok := ServerShared.Get(incident.ServerID)
+	if !ok {
+		return
+	}
+	server := &model.Server{}
+	copier.Copy(server, s)
+	EscalationPolicyShared.restore(EscalationKey{AlertRuleID: alert.ID, ServerID: server.ID}, &escalation{
+		policyID:     alert.EscalationPolicyID,
+		userID:       alert.UserID,
+		incidentID:   incident.ID,
+		startedAt:    incident.OpenedAt,
+		acknowledged: incident.AcknowledgedAt != nil,
+		message:      alertMessage(Localizer.T("Incident"), alert, server),
+		event:        alertEvent(model.NotificationEventIncident, alert, incident, nil),
+		muteLabel:    NotificationMuteLabel.ServerIncident(alert.ID, server.ID),
+		server:       server,
+	})
+}
+
+// incidentURL 面板中报警事件的地址，未配置面板对外域名时为空
+func incidentURL(incidentID uint64) string {
+	if Conf == nil || Conf.DashboardHost == "" || incidentID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("https://%s/dashboard/incident/%d", Conf.DashboardHost, incidentID)
+}
+
+// openIncident 记录一次新的报警事件
+func openIncident(alert *model.AlertRule, server *model.Server, point []bool) {
+	incident := &model.Incident{
+		AlertRuleID: alert.ID,
+		ServerID:    server.ID,
+		AlertName:   alert.Name,
+		ServerName:  server.Name,
+		OpenedAt:    time.Now(),
+	}
+	incident.UserID = alert.UserID
+	if v, ok := alert.FailedValue(server.ID, point); ok {
+		incident.LastValue = &v
+	}
+	if err := DB.Create(incident).Error; err != nil {
+		log.Printf("NEZHA>> Failed to save incident of alert %d on server %d: %v", alert.ID, server.ID, err)
+	}
+	if alertsIncidents == nil {
+		alertsIncidents = make(map[uint64]map[uint64]*model.Incident)
+	}
+	if alertsIncidents[alert.ID] == nil {
+		alertsIncidents[alert.ID] = make(map[uint64]*model.Incident)
+	}
+	alertsIncidents[alert.ID][server.ID] = incident
+}
+
+// updateIncidentValue 报警持续期间只在内存中刷新最近指标值，恢复时一并落库
+func updateIncidentValue(alert *model.AlertRule, serverID uint64, point []bool) {
+	incident := alertsIncidents[alert.ID][serverID]
+	if incident == nil {
+		return
+	}
+	if v, ok := alert.FailedValue(serverID, point); ok {
+		incident.LastValue = &v
+	}
+}
+
+// resolveIncident 结束报警事件
+func resolveIncident(alertID, serverID uint64) {
+	incident := alertsIncidents[alertID][serverID]
+	if incident == nil {
+		return
+	}
+	delete(alertsIncidents[alertID], serverID)
+	now := time.Now()
+	if err := DB.Model(incident).Updates(map[string]any{
+		"resolved_at": now,
+		"last_value":  incident.LastValue,
+	}).Error; err != nil {
+		log.Printf("NEZHA>> Failed to resolve incident %d: %v", incident.ID, err)
+	}
+}
+
+// resolveAlertIncidents 报警规则被修改或删除时，其状态被重置，未结束的事件一并结束。
+// 调用方需持有 AlertsLock 写锁。
+func resolveAlertIncidents(alertID uint64) {
+	for serverID := range alertsIncidents[alertID] {
+		resolveIncident(alertID, serverID)
+	}
+	delete(alertsIncidents, alertID)
+}
+
+// openServiceIncident 服务监控进入故障时记录事件，故障持续期间沿用同一事件，
+// 使服务监控的升级也能通过确认事件停止。调用方需持有 serviceResponseDataStoreLock 写锁
+func openServiceIncident(service *model.Service) *model.Incident {
+	if incident := serviceIncidents[service.ID]; incident != nil {
+		return incident
+	}
+	incident := &model.Incident{
+		ServiceID:   service.ID,
+		ServiceName: service.Name,
+		OpenedAt:    time.Now(),
+	}
+	incident.UserID = service.UserID
+	if err := DB.Create(incident).Error; err != nil {
+		log.Printf("NEZHA>> Failed to save incident of service %d: %v", service.ID, err)
+		return nil
+	}
+	if serviceIncidents == nil {
+		serviceIncidents = make(map[uint64]*model.Incident)
+	}
+	serviceIncidents[service.ID] = incident
+	return incident
+}
+
+// resolveServiceIncident 结束服务监控的故障事件。调用方需持有 serviceResponseDataStoreLock 写锁
+func resolveServiceIncident(serviceID uint64) {
+	incident := serviceIncidents[serviceID]
+	if incident == nil {
+		return
+	}
+	delete(serviceIncidents, serviceID)
+	if err := DB.Model(incident).Update("resolved_at", time.Now()).Error; err != nil {
+		log.Printf("NEZHA>> Failed to resolve incident %d: %v", incident.ID, err)
+	}
+}
+
+// resolveStaleServiceIncidents 服务监控的状态不会持久化，重启后按新的上报重新判断，
+// 上次运行遗留的故障事件直接结束
+func resolveStaleServiceIncidents() {
+	if err := DB.Model(&model.Incident{}).Where("resolved_at IS NULL AND service_id <> 0").Update("resolved_at", time.Now()).Error; err != nil {
+		log.Printf("NEZHA>> Failed to resolve stale service incidents: %v", err)
+	}
+}
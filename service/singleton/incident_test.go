@@ -0,0 +1,144 @@
+package singleton
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/robfig/cron/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/pkg/i18n"
+)
+
+func setupIncidentTestDB(t *testing.T) {
+	t.Helper()
+
+	previousDB, previousAlerts := DB, Alerts
+	previousPrevState, previousIncidents := alertsPrevState, alertsIncidents
+	var err error
+	DB, err = gorm.Open(openSQLiteDialector(filepath.Join(t.TempDir(), "dashboard.sqlite")), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := DB.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		DB, Alerts = previousDB, previousAlerts
+		alertsPrevState, alertsIncidents = previousPrevState, previousIncidents
+		if err := sqlDB.Close(); err != nil {
+			t.Errorf("close incident test database: %v", err)
+		}
+	})
+
+	require.NoError(t, DB.AutoMigrate(&model.Incident{}))
+}
+
+// setupCheckStatusTest 准备 checkStatus 依赖的全局状态，只检查 alert 在 server 上的报警，
+// 需要先调用 setupIncidentTestDB
+func setupCheckStatusTest(t *testing.T, alert *model.AlertRule, server *model.Server) {
+	t.Helper()
+
+	previousStore, previousConf, previousCache := alertsStore, Conf, Cache
+	previousCron, previousNotification, previousLocalizer := CronShared, NotificationShared, Localizer
+	previousMaintenance, previousEscalation, previousSilence := MaintenanceWindowShared, EscalationPolicyShared, SilenceShared
+	t.Cleanup(func() {
+		alertsStore, Conf, Cache = previousStore, previousConf, previousCache
+		CronShared, NotificationShared, Localizer = previousCron, previousNotification, previousLocalizer
+		MaintenanceWindowShared, EscalationPolicyShared, SilenceShared = previousMaintenance, previousEscalation, previousSilence
+	})
+	Conf = &ConfigClass{Config: &model.Config{}}
+	Cache = cache.New(time.Minute, time.Minute)
+	CronShared = &CronClass{
+		Cron:  cron.New(cron.WithSeconds()),
+		class: class[uint64, *model.Cron]{list: map[uint64]*model.Cron{}},
+	}
+	NotificationShared = NewEmptyNotificationClassForTest()
+	Localizer = i18n.NewLocalizer("zh_CN", domain, "translations", i18n.Translations)
+	MaintenanceWindowShared, EscalationPolicyShared, SilenceShared = nil, nil, nil
+	replaceServerSharedForSecurityTest(t, server)
+	replaceUserInfoMapForSecurityTest(t, map[uint64]model.UserInfo{alert.UserID: {Role: model.RoleMember}})
+
+	Alerts = []*model.AlertRule{alert}
+	alertsStore = map[uint64]map[uint64][][]bool{alert.ID: {}}
+	alertsPrevState = map[uint64]map[uint64]uint8{alert.ID: {}}
+	alertsIncidents = nil
+}
+
+// 未恢复的事件在重启后恢复为失败状态，恢复时写入 ResolvedAt 与最近指标值
+func TestIncidentLifecycleSurvivesRestart(t *testing.T) {
+	setupIncidentTestDB(t)
+
+	alert := &model.AlertRule{Common: model.Common{ID: 7, UserID: 3}, Name: "cpu high", Rules: []*model.Rule{{Type: "cpu", Max: 50, Duration: 3}}}
+	server := &model.Server{Common: model.Common{ID: 1}, Name: "web", State: &model.HostState{CPU: 90}}
+	Alerts = []*model.AlertRule{alert}
+	alertsPrevState = map[uint64]map[uint64]uint8{alert.ID: {}}
+
+	point := alert.Snapshot(nil, server, nil)
+	require.Equal(t, []bool{false}, point)
+	openIncident(alert, server, point)
+
+	var stored model.Incident
+	require.NoError(t, DB.First(&stored).Error)
+	assert.Equal(t, uint64(3), stored.UserID)
+	assert.Equal(t, "web", stored.ServerName)
+	require.NotNil(t, stored.LastValue)
+	assert.Equal(t, float64(90), *stored.LastValue)
+	assert.True(t, stored.IsOpen())
+
+	// 模拟重启：内存状态清空后从数据库恢复
+	alertsPrevState = map[uint64]map[uint64]uint8{alert.ID: {}}
+	alertsIncidents = nil
+	loadOpenIncidents()
+	assert.Equal(t, uint8(_RuleCheckFail), alertsPrevState[alert.ID][server.ID])
+	require.NotNil(t, alertsIncidents[alert.ID][server.ID])
+
+	resolveIncident(alert.ID, server.ID)
+	require.NoError(t, DB.First(&stored, stored.ID).Error)
+	assert.False(t, stored.IsOpen())
+	assert.Nil(t, alertsIncidents[alert.ID][server.ID])
+}
+
+func TestLoadOpenIncidentsResolvesOrphans(t *testing.T) {
+	setupIncidentTestDB(t)
+
+	require.NoError(t, DB.Create(&model.Incident{AlertRuleID: 99, ServerID: 1}).Error)
+	Alerts = nil
+	alertsPrevState = map[uint64]map[uint64]uint8{}
+	loadOpenIncidents()
+
+	var open int64
+	require.NoError(t, DB.Model(&model.Incident{}).Where("resolved_at IS NULL").Count(&open).Error)
+	assert.Zero(t, open)
+}
+
+// 重启后恢复的失败状态没有采样历史，采样填满一个窗口之前不能判定为恢复
+func TestRestoredIncidentWaitsForFullWindow(t *testing.T) {
+	setupIncidentTestDB(t)
+
+	enable := true
+	alert := &model.AlertRule{Common: model.Common{ID: 7, UserID: 3}, Name: "cpu high", Enable: &enable, Rules: []*model.Rule{{Type: "cpu", Max: 50, Duration: 3}}}
+	server := &model.Server{Common: model.Common{ID: 1, UserID: 3}, Name: "web", State: &model.HostState{CPU: 10}, GeoIP: &model.GeoIP{}}
+	setupCheckStatusTest(t, alert, server)
+	require.NoError(t, DB.Create(&model.Incident{Common: model.Common{UserID: 3}, AlertRuleID: alert.ID, ServerID: server.ID}).Error)
+	loadOpenIncidents()
+
+	for range 2 {
+		checkStatus()
+		assert.Equal(t, uint8(_RuleCheckFail), alertsPrevState[alert.ID][server.ID])
+		assert.NotNil(t, alertsIncidents[alert.ID][server.ID])
+	}
+	var open int64
+	require.NoError(t, DB.Model(&model.Incident{}).Where("resolved_at IS NULL").Count(&open).Error)
+	assert.Equal(t, int64(1), open)
+
+	checkStatus()
+	assert.Equal(t, uint8(_RuleCheckPass), alertsPrevState[alert.ID][server.ID])
+	assert.Nil(t, alertsIncidents[alert.ID][server.ID])
+	require.NoError(t, DB.Model(&model.Incident{}).Where("resolved_at IS NULL").Count(&open).Error)
+	assert.Zero(t, open)
+	// 恢复通知异步发送，等它写入防骚扰缓存后再还原全局变量
+	assert.Eventually(t, func() bool { return Cache.ItemCount() == 1 }, time.Second, 10*time.Millisecond)
+}
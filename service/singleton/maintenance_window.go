@@ -0,0 +1,169 @@
+package singleton
+
+import (
+	"cmp"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/pkg/utils"
+)
+
+// maintenanceSnapshotTTL 生效窗口快照的缓存时长，与报警检查周期一致，
+// 避免每次检查都查询服务器分组
+const maintenanceSnapshotTTL = 3 * time.Second
+
+type MaintenanceWindowClass struct {
+	class[uint64, *model.MaintenanceWindow]
+
+	snapshotMu sync.Mutex
+	snapshot   *maintenanceSnapshot
+}
+
+// maintenanceSnapshot 某一时刻处于维护中的服务器与服务
+type maintenanceSnapshot struct {
+	at       time.Time
+	servers  map[uint64]*model.MaintenanceStatus
+	services map[uint64]*model.MaintenanceStatus
+}
+
+func NewMaintenanceWindowClass() *MaintenanceWindowClass {
+	var sortedList []*model.MaintenanceWindow
+	DB.Find(&sortedList)
+	list := make(map[uint64]*model.MaintenanceWindow, len(sortedList))
+	for _, w := range sortedList {
+		list[w.ID] = w
+	}
+
+	return &MaintenanceWindowClass{
+		class: class[uint64, *model.MaintenanceWindow]{
+			list:       list,
+			sortedList: sortedList,
+		},
+	}
+}
+
+func (c *MaintenanceWindowClass) Update(w *model.MaintenanceWindow) {
+	c.listMu.Lock()
+	c.list[w.ID] = w
+	c.listMu.Unlock()
+	c.sortList()
+	c.invalidate()
+}
+
+func (c *MaintenanceWindowClass) Delete(idList []uint64) {
+	c.listMu.Lock()
+	for _, id := range idList {
+		delete(c.list, id)
+	}
+	c.listMu.Unlock()
+	c.sortList()
+	c.invalidate()
+}
+
+// ServerStatus 返回服务器当前所处的维护窗口，不在维护中返回 nil
+func (c *MaintenanceWindowClass) ServerStatus(serverID uint64) *model.MaintenanceStatus {
+	return c.current().servers[serverID]
+}
+
+// ServiceStatus 返回服务当前所处的维护窗口，不在维护中返回 nil
+func (c *MaintenanceWindowClass) ServiceStatus(serviceID uint64) *model.MaintenanceStatus {
+	return c.current().services[serviceID]
+}
+
+// ServersUnderMaintenance 返回当前处于维护中的全部服务器
+func (c *MaintenanceWindowClass) ServersUnderMaintenance() map[uint64]*model.MaintenanceStatus {
+	return c.current().servers
+}
+
+func (c *MaintenanceWindowClass) current() *maintenanceSnapshot {
+	if c == nil {
+		return &maintenanceSnapshot{}
+	}
+	now := time.Now()
+	c.snapshotMu.Lock()
+	defer c.snapshotMu.Unlock()
+	if c.snapshot == nil || now.Sub(c.snapshot.at) >= maintenanceSnapshotTTL {
+		c.snapshot = c.buildSnapshot(now)
+	}
+	return c.snapshot
+}
+
+func (c *MaintenanceWindowClass) invalidate() {
+	c.snapshotMu.Lock()
+	c.snapshot = nil
+	c.snapshotMu.Unlock()
+}
+
+func (c *MaintenanceWindowClass) buildSnapshot(now time.Time) *maintenanceSnapshot {
+	snapshot := &maintenanceSnapshot{
+		at:       now,
+		servers:  make(map[uint64]*model.MaintenanceStatus),
+		services: make(map[uint64]*model.MaintenanceStatus),
+	}
+
+	var groupIDs []uint64
+	active := make(map[*model.MaintenanceWindow]*model.MaintenanceStatus)
+	for _, w := range c.GetSortedList() {
+		until, ok := w.ActiveAt(now.In(Loc))
+		if !ok {
+			continue
+		}
+		active[w] = &model.MaintenanceStatus{WindowID: w.ID, Name: w.Name, Until: until}
+		groupIDs = append(groupIDs, w.ServerGroups...)
+	}
+	if len(active) == 0 {
+		return snapshot
+	}
+
+	// 仅在生效窗口指定了服务器分组时才查询分组成员
+	serverGroups := make(map[uint64][]uint64)
+	if len(groupIDs) > 0 {
+		var members []model.ServerGroupServer
+		if err := DB.Where("server_group_id IN (?)", groupIDs).Find(&members).Error; err != nil {
+			log.Printf("NEZHA>> Failed to load server group members for maintenance windows: %v", err)
+		}
+		for _, m := range members {
+			serverGroups[m.ServerId] = append(serverGroups[m.ServerId], m.ServerGroupId)
+		}
+	}
+
+	for w, status := range active {
+		for _, id := range w.Servers {
+			mergeMaintenanceStatus(snapshot.servers, id, status)
+		}
+		for id, groups := range serverGroups {
+			if w.CoversServer(id, groups) {
+				mergeMaintenanceStatus(snapshot.servers, id, status)
+			}
+		}
+		for _, id := range w.Services {
+			mergeMaintenanceStatus(snapshot.services, id, status)
+		}
+	}
+	return snapshot
+}
+
+// mergeMaintenanceStatus 多个窗口同时命中时展示结束最晚的一个
+func mergeMaintenanceStatus(m map[uint64]*model.MaintenanceStatus, id uint64, status *model.MaintenanceStatus) {
+	if old, ok := m[id]; ok && !status.Until.After(old.Until) {
+		return
+	}
+	m[id] = status
+}
+
+func (c *MaintenanceWindowClass) sortList() {
+	c.listMu.RLock()
+	defer c.listMu.RUnlock()
+
+	sortedList := utils.MapValuesToSlice(c.list)
+	slices.SortFunc(sortedList, func(a, b *model.MaintenanceWindow) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	c.sortedListMu.Lock()
+	defer c.sortedListMu.Unlock()
+	c.sortedList = sortedList
+}
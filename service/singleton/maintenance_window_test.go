@@ -0,0 +1,68 @@
+package singleton
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/nezhahq/nezha/model"
+)
+
+func TestMaintenanceWindowClassSnapshot(t *testing.T) {
+	previousDB, previousLoc := DB, Loc
+	var err error
+	DB, err = gorm.Open(openSQLiteDialector(filepath.Join(t.TempDir(), "dashboard.sqlite")), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := DB.DB()
+	require.NoError(t, err)
+	Loc = time.UTC
+	t.Cleanup(func() {
+		DB, Loc = previousDB, previousLoc
+		_ = sqlDB.Close()
+	})
+	require.NoError(t, DB.AutoMigrate(&model.MaintenanceWindow{}, &model.ServerGroupServer{}))
+	require.NoError(t, DB.Create(&model.ServerGroupServer{ServerGroupId: 5, ServerId: 2}).Error)
+
+	now := time.Now()
+	start, end, later := now.Add(-time.Minute), now.Add(time.Hour), now.Add(2*time.Hour)
+	require.NoError(t, DB.Create(&model.MaintenanceWindow{
+		Name: "servers", Enabled: true, StartAt: &start, EndAt: &end, Servers: []uint64{1},
+	}).Error)
+	require.NoError(t, DB.Create(&model.MaintenanceWindow{
+		Name: "groups", Enabled: true, Scheduler: "@every 1s", Duration: 3600, ServerGroups: []uint64{5}, Services: []uint64{9},
+	}).Error)
+	require.NoError(t, DB.Create(&model.MaintenanceWindow{
+		Name: "disabled", StartAt: &start, EndAt: &later, Servers: []uint64{3},
+	}).Error)
+
+	mc := NewMaintenanceWindowClass()
+	require.Len(t, mc.GetSortedList(), 3)
+
+	if status := mc.ServerStatus(1); assert.NotNil(t, status) {
+		assert.Equal(t, "servers", status.Name)
+		assert.True(t, status.Until.Equal(end))
+	}
+	assert.NotNil(t, mc.ServerStatus(2), "server group members are covered")
+	assert.Nil(t, mc.ServerStatus(3), "disabled windows never apply")
+	assert.NotNil(t, mc.ServiceStatus(9))
+	assert.Nil(t, mc.ServiceStatus(1))
+
+	// 后写入的窗口结束更晚时覆盖展示，更新后立即生效而非等待快照过期
+	longer := &model.MaintenanceWindow{Name: "longer", Enabled: true, StartAt: &start, EndAt: &later, Servers: []uint64{1}}
+	require.NoError(t, DB.Create(longer).Error)
+	mc.Update(longer)
+	if status := mc.ServerStatus(1); assert.NotNil(t, status) {
+		assert.Equal(t, longer.ID, status.WindowID)
+	}
+
+	mc.Delete([]uint64{longer.ID, 1})
+	assert.Nil(t, mc.ServerStatus(1))
+	assert.Len(t, mc.ServersUnderMaintenance(), 1)
+
+	var nilClass *MaintenanceWindowClass
+	assert.Nil(t, nilClass.ServerStatus(1), "sentinels tolerate an uninitialized class")
+}
@@ -24,6 +24,8 @@ type NotificationClass struct {
 
 	groupList map[uint64]string
 	groupMu   sync.RWMutex
+
+	retryMu sync.Mutex
 }
 
 func NewNotificationClass() *NotificationClass {
@@ -198,8 +200,27 @@ func (c *NotificationClass) UnMuteNotification(notificationGroupID uint64, muteL
 	Cache.Delete(fullMuteLabel)
 }
 
-// SendNotification 向指定的通知方式组的所有通知方式发送通知
+// SendNotification 向指定的通知方式组的所有通知方式发送通知，附带的服务器用于匹配静默
 func (c *NotificationClass) SendNotification(notificationGroupID uint64, desc string, muteLabel string, ext ...*model.Server) {
+	var subject model.NotificationSubject
+	if len(ext) > 0 && ext[0] != nil {
+		subject.ServerID = ext[0].ID
+	}
+	c.SendEventNotification(notificationGroupID, desc, muteLabel, subject, nil, ext...)
+}
+
+// SendEventNotification 与 SendNotification 相同，由调用方给出通知关联的对象用于匹配静默，
+// 并附带触发通知的事件供 Go 模板使用
+func (c *NotificationClass) SendEventNotification(notificationGroupID uint64, desc string, muteLabel string, subject model.NotificationSubject, event *model.NotificationEvent, ext ...*model.Server) {
+	// 人工静默优先于防骚扰策略，被静默的通知不推进退避时间
+	if SilenceShared != nil {
+		if silence := SilenceShared.Match(subject); silence != nil {
+			if Conf.Debug {
+				log.Printf("NEZHA>> Notification silenced by silence %d: %s", silence.ID, desc)
+			}
+			return
+		}
+	}
 	if muteLabel != "" {
 		// 将通知方式组名称加入静音标志
 		muteLabel := NotificationMuteLabel.AppendNotificationGroupName(muteLabel, c.GetGroupName(notificationGroupID))
@@ -240,20 +261,12 @@ func (c *NotificationClass) SendNotification(notificationGroupID uint64, desc st
 	for _, n := range c.groupToIDList[notificationGroupID] {
 		log.Printf("NEZHA>> Try to notify %s", n.Name)
 	}
+	var server *model.Server
+	if len(ext) > 0 {
+		server = ext[0]
+	}
 	for _, n := range c.groupToIDList[notificationGroupID] {
-		ns := model.NotificationServerBundle{
-			Notification: n,
-			Server:       nil,
-			Loc:          Loc,
-		}
-		if len(ext) > 0 {
-			ns.Server = ext[0]
-		}
-		if err := ns.Send(desc); err != nil {
-			log.Printf("NEZHA>> Sending notification to %s failed: %v", n.Name, err)
-		} else {
-			log.Printf("NEZHA>> Sending notification to %s succeeded", n.Name)
-		}
+		c.deliver(n, server, desc, event)
 	}
 }
 
@@ -0,0 +1,166 @@
+package singleton
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/jinzhu/copier"
+
+	"github.com/nezhahq/nezha/model"
+)
+
+const (
+	NotificationRetrySchedule      = "@every 10s"
+	NotificationDeliveryGCSchedule = "0 20 3 * * *"
+
+	notificationMaxAttempts        = 5                // 每条通知最多尝试发送的次数
+	notificationRetryBaseDelay     = 30 * time.Second // 首次重试的等待时间，之后每次翻倍
+	notificationRetryBatchSize     = 100
+	notificationDeliveryRetainDays = 30
+
+	// 发送期间记录的 NextAttemptAt 推迟到租约结束，重试任务不会重发正在发送的通知；
+	// 租约需长于单次发送的最长耗时（webhook 请求超时 10 分钟，SMTP 连接与会话共约 90 秒），
+	// 面板在发送期间退出时租约到期后补发
+	notificationAttemptLease = 15 * time.Minute
+)
+
+// 面板启动以来的通知发送失败计数，由 /metrics 导出
+var (
+	notificationAttemptFailures  atomic.Uint64 // 每次发送失败（包括之后重试成功的）
+	notificationDeliveryFailures atomic.Uint64 // 达到次数上限后放弃的通知
+)
+
+// NotificationFailureCounts 返回面板启动以来发送失败的次数与最终放弃的通知数
+func NotificationFailureCounts() (attempts, deliveries uint64) {
+	return notificationAttemptFailures.Load(), notificationDeliveryFailures.Load()
+}
+
+// deliver 向单个通知方式发送通知：先落库再发送，失败时留在队列中等待重试
+func (c *NotificationClass) deliver(n *model.Notification, server *model.Server, message string, event *model.NotificationEvent) {
+	next := time.Now().Add(notificationAttemptLease)
+	d := &model.NotificationDelivery{
+		NotificationID: n.ID,
+		Message:        message,
+		Status:         model.NotificationDeliveryPending,
+		NextAttemptAt:  &next,
+		Event:          event,
+	}
+	d.UserID = n.UserID
+	if server != nil {
+		d.ServerID = server.ID
+	}
+	if err := DB.Create(d).Error; err != nil {
+		// 无法记录时仍然发送一次，只是不会重试
+		log.Printf("NEZHA>> Failed to save delivery of notification %s: %v", n.Name, err)
+	}
+	attemptDelivery(d, n, server)
+}
+
+// RetryDeliveries 重试到期的发送记录，由计划任务定期调用
+func (c *NotificationClass) RetryDeliveries() {
+	// 上一轮重试尚未结束时跳过，避免同一条记录被并发重发
+	if !c.retryMu.TryLock() {
+		return
+	}
+	defer c.retryMu.Unlock()
+
+	var due []*model.NotificationDelivery
+	if err := DB.Where("status = ? AND next_attempt_at <= ?", model.NotificationDeliveryPending, time.Now()).
+		Order("id").Limit(notificationRetryBatchSize).Find(&due).Error; err != nil {
+		log.Printf("NEZHA>> Failed to load pending notification deliveries: %v", err)
+		return
+	}
+
+	for _, d := range due {
+		n, ok := c.Get(d.NotificationID)
+		if !ok {
+			d.Status = model.NotificationDeliveryFailed
+			d.Error = "notification has been deleted"
+			d.NextAttemptAt = nil
+			saveDelivery(d)
+			continue
+		}
+		if !claimDelivery(d) {
+			continue
+		}
+		var server *model.Server
+		if s, ok := ServerShared.Get(d.ServerID); ok {
+			server = &model.Server{}
+			copier.Copy(server, s)
+		}
+		attemptDelivery(d, n, server)
+	}
+}
+
+// claimDelivery 把到期的记录标记为发送中，记录已被其他发送占用时返回 false
+func claimDelivery(d *model.NotificationDelivery) bool {
+	now := time.Now()
+	next := now.Add(notificationAttemptLease)
+	result := DB.Model(&model.NotificationDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", d.ID, model.NotificationDeliveryPending, now).
+		Update("next_attempt_at", next)
+	if result.Error != nil {
+		log.Printf("NEZHA>> Failed to claim notification delivery %d: %v", d.ID, result.Error)
+		return false
+	}
+	if result.RowsAffected == 0 {
+		return false
+	}
+	d.NextAttemptAt = &next
+	return true
+}
+
+// CleanNotificationDeliveries 清理已结束的过期发送记录
+func CleanNotificationDeliveries() {
+	if err := DB.Unscoped().Delete(&model.NotificationDelivery{}, "status <> ? AND created_at < ?",
+		model.NotificationDeliveryPending, time.Now().AddDate(0, 0, -notificationDeliveryRetainDays)).Error; err != nil {
+		log.Printf("NEZHA>> Failed to clean notification deliveries: %v", err)
+	}
+}
+
+// attemptDelivery 发送一次并记录结果，失败且未达到次数上限时按指数退避安排下次重试
+func attemptDelivery(d *model.NotificationDelivery, n *model.Notification, server *model.Server) {
+	ns := model.NotificationServerBundle{
+		Notification: n,
+		Server:       server,
+		Loc:          Loc,
+		Event:        d.Event,
+	}
+	start := time.Now()
+	code, err := ns.Deliver(d.Message)
+	now := time.Now()
+
+	d.Attempts++
+	d.StatusCode = code
+	d.Latency = uint64(now.Sub(start).Milliseconds())
+	d.NextAttemptAt = nil
+	if err == nil {
+		log.Printf("NEZHA>> Sending notification to %s succeeded", n.Name)
+		d.Status = model.NotificationDeliverySucceeded
+		d.Error = ""
+		d.DeliveredAt = &now
+	} else {
+		notificationAttemptFailures.Add(1)
+		d.Error = err.Error()
+		if d.Attempts >= notificationMaxAttempts {
+			log.Printf("NEZHA>> Sending notification to %s failed after %d attempts: %v", n.Name, d.Attempts, err)
+			notificationDeliveryFailures.Add(1)
+			d.Status = model.NotificationDeliveryFailed
+		} else {
+			next := now.Add(notificationRetryBaseDelay << (d.Attempts - 1))
+			log.Printf("NEZHA>> Sending notification to %s failed, retry at %s: %v", n.Name, next.Format(time.DateTime), err)
+			d.NextAttemptAt = &next
+		}
+	}
+	saveDelivery(d)
+}
+
+func saveDelivery(d *model.NotificationDelivery) {
+	if d.ID == 0 {
+		return
+	}
+	if err := DB.Save(d).Error; err != nil {
+		log.Printf("NEZHA>> Failed to update notification delivery %d: %v", d.ID, err)
+	}
+}
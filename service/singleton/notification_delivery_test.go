@@ -0,0 +1,105 @@
+package singleton
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/nezhahq/nezha/model"
+)
+
+func TestNotificationDeliveryRetry(t *testing.T) {
+	previousDB, previousServer, previousLoc := DB, ServerShared, Loc
+	var err error
+	DB, err = gorm.Open(openSQLiteDialector(filepath.Join(t.TempDir(), "dashboard.sqlite")), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := DB.DB()
+	require.NoError(t, err)
+	ServerShared = NewEmptyServerClassForTest()
+	Loc = time.UTC
+	t.Cleanup(func() {
+		DB, ServerShared, Loc = previousDB, previousServer, previousLoc
+		_ = sqlDB.Close()
+	})
+	require.NoError(t, DB.AutoMigrate(&model.NotificationDelivery{}))
+
+	// 回环地址会被出站限制拒绝，用来模拟发送失败
+	nc := NewEmptyNotificationClassForTest()
+	n := &model.Notification{Common: model.Common{ID: 1, UserID: 3}, Name: "hook", URL: "http://127.0.0.1/hook",
+		RequestMethod: model.NotificationRequestMethodGET}
+	nc.InsertForTest(n)
+
+	load := func() model.NotificationDelivery {
+		var d model.NotificationDelivery
+		require.NoError(t, DB.First(&d).Error)
+		return d
+	}
+	expire := func() {
+		require.NoError(t, DB.Model(&model.NotificationDelivery{}).Where("1 = 1").Update("next_attempt_at", time.Now().Add(-time.Second)).Error)
+	}
+
+	attemptsBefore, deliveriesBefore := NotificationFailureCounts()
+	before := time.Now()
+	nc.deliver(n, nil, "disk full", &model.NotificationEvent{Status: model.NotificationEventIncident, AlertName: "disk"})
+	d := load()
+	assert.Equal(t, uint64(3), d.UserID)
+	assert.Equal(t, "disk full", d.Message)
+	// 事件随记录保存，重试时可重新渲染模板
+	require.NotNil(t, d.Event)
+	assert.Equal(t, "disk", d.Event.AlertName)
+	assert.Equal(t, uint8(model.NotificationDeliveryPending), d.Status)
+	assert.Equal(t, uint(1), d.Attempts)
+	assert.NotEmpty(t, d.Error)
+	require.NotNil(t, d.NextAttemptAt)
+	assert.WithinDuration(t, before.Add(notificationRetryBaseDelay), *d.NextAttemptAt, 5*time.Second)
+
+	// 未到期的记录不会被重试
+	nc.RetryDeliveries()
+	assert.Equal(t, uint(1), load().Attempts)
+
+	// 到期后重试，退避时间翻倍
+	expire()
+	before = time.Now()
+	nc.RetryDeliveries()
+	d = load()
+	assert.Equal(t, uint(2), d.Attempts)
+	require.NotNil(t, d.NextAttemptAt)
+	assert.WithinDuration(t, before.Add(2*notificationRetryBaseDelay), *d.NextAttemptAt, 5*time.Second)
+
+	// 达到次数上限后放弃
+	require.NoError(t, DB.Model(&model.NotificationDelivery{}).Where("1 = 1").Update("attempts", notificationMaxAttempts-1).Error)
+	expire()
+	nc.RetryDeliveries()
+	d = load()
+	assert.Equal(t, uint8(model.NotificationDeliveryFailed), d.Status)
+	assert.Equal(t, uint(notificationMaxAttempts), d.Attempts)
+	assert.Nil(t, d.NextAttemptAt)
+	attempts, deliveries := NotificationFailureCounts()
+	assert.Equal(t, attemptsBefore+3, attempts)
+	assert.Equal(t, deliveriesBefore+1, deliveries)
+
+	// 发送中的记录由租约占用，重试任务不会重复发送
+	require.NoError(t, DB.Model(&model.NotificationDelivery{}).Where("1 = 1").Updates(map[string]any{
+		"status": model.NotificationDeliveryPending, "attempts": 1}).Error)
+	expire()
+	stale := load()
+	require.True(t, claimDelivery(&stale))
+	assert.WithinDuration(t, time.Now().Add(notificationAttemptLease), *stale.NextAttemptAt, 5*time.Second)
+	assert.False(t, claimDelivery(&stale), "a delivery is claimed once")
+	nc.RetryDeliveries()
+	assert.Equal(t, uint(1), load().Attempts)
+
+	// 通知方式被删除后不再重试
+	require.NoError(t, DB.Unscoped().Where("1 = 1").Delete(&model.NotificationDelivery{}).Error)
+	nc.deliver(n, nil, "cpu high", nil)
+	nc.Delete([]uint64{1})
+	expire()
+	nc.RetryDeliveries()
+	d = load()
+	assert.Equal(t, uint8(model.NotificationDeliveryFailed), d.Status)
+	assert.Equal(t, uint(1), d.Attempts)
+}
@@ -46,6 +46,7 @@ func NewServerClass() *ServerClass {
 	model.OwnerServerIDsLookup = sc.ownerServerIDs
 	model.AllServerIDsLookup = sc.allServerIDs
 	model.OwnerIsAdminLookup = ownerIsAdmin
+	model.ServerGroupMembersLookup = ServerGroupMembers
 
 	return sc
 }
@@ -0,0 +1,60 @@
+package singleton
+
+import (
+	"log"
+	"slices"
+	"time"
+
+	"github.com/nezhahq/nezha/model"
+)
+
+// serverGroupMembersCacheTTL 分组成员快照的缓存时长。报警检查每 3 秒逐条规则、逐台服务器
+// 展开分组，缓存避免每次都查询数据库；分组变更时会主动清除
+const serverGroupMembersCacheTTL = 10 * time.Second
+
+// ServerGroupMembers 返回 groupIDs 中各分组当前成员的并集，作为 model.ServerGroupMembersLookup 注入
+func ServerGroupMembers(groupIDs []uint64) []uint64 {
+	members := loadServerGroupMembers()
+	var ids []uint64
+	for _, gid := range groupIDs {
+		ids = append(ids, members[gid]...)
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// serverGroupsOf 返回服务器所在的分组，与 ServerGroupMembers 共用成员快照
+func serverGroupsOf(serverID uint64) []uint64 {
+	ids := []uint64{}
+	for gid, members := range loadServerGroupMembers() {
+		if slices.Contains(members, serverID) {
+			ids = append(ids, gid)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// InvalidateServerGroupMembers 分组或其成员变更后调用，使下一次展开读取最新成员
+func InvalidateServerGroupMembers() {
+	Cache.Delete(model.CacheKeyServerGroupMembers)
+}
+
+// loadServerGroupMembers 返回 [group_id] -> 成员服务器 ID
+func loadServerGroupMembers() map[uint64][]uint64 {
+	if cached, has := Cache.Get(model.CacheKeyServerGroupMembers); has {
+		return cached.(map[uint64][]uint64)
+	}
+
+	var rows []model.ServerGroupServer
+	if err := DB.Find(&rows).Error; err != nil {
+		log.Printf("NEZHA>> Failed to load server group members: %v", err)
+		return nil
+	}
+	members := make(map[uint64][]uint64)
+	for _, row := range rows {
+		members[row.ServerGroupId] = append(members[row.ServerGroupId], row.ServerId)
+	}
+	Cache.Set(model.CacheKeyServerGroupMembers, members, serverGroupMembersCacheTTL)
+	return members
+}
@@ -0,0 +1,52 @@
+package singleton
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/nezhahq/nezha/model"
+)
+
+func TestServerGroupMembers(t *testing.T) {
+	previousDB, previousCache := DB, Cache
+	var err error
+	DB, err = gorm.Open(openSQLiteDialector(filepath.Join(t.TempDir(), "dashboard.sqlite")), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := DB.DB()
+	require.NoError(t, err)
+	Cache = cache.New(time.Minute, time.Minute)
+	t.Cleanup(func() {
+		DB, Cache = previousDB, previousCache
+		_ = sqlDB.Close()
+	})
+	require.NoError(t, DB.AutoMigrate(&model.ServerGroupServer{}))
+	require.NoError(t, DB.Create(&model.ServerGroupServer{ServerGroupId: 5, ServerId: 3}).Error)
+	require.NoError(t, DB.Create(&model.ServerGroupServer{ServerGroupId: 5, ServerId: 2}).Error)
+	require.NoError(t, DB.Create(&model.ServerGroupServer{ServerGroupId: 6, ServerId: 2}).Error)
+
+	assert.Equal(t, []uint64{2, 3}, ServerGroupMembers([]uint64{5, 6}))
+	assert.Empty(t, ServerGroupMembers([]uint64{7}))
+
+	// 成员快照被缓存，清除后才读取新的成员
+	require.NoError(t, DB.Create(&model.ServerGroupServer{ServerGroupId: 7, ServerId: 4}).Error)
+	assert.Empty(t, ServerGroupMembers([]uint64{7}))
+	InvalidateServerGroupMembers()
+	assert.Equal(t, []uint64{4}, ServerGroupMembers([]uint64{7}))
+
+	// 服务监控按分组展开后判定上报是否在派发范围内
+	saved := model.ServerGroupMembersLookup
+	model.ServerGroupMembersLookup = ServerGroupMembers
+	t.Cleanup(func() { model.ServerGroupMembersLookup = saved })
+	service := &model.Service{Common: model.Common{UserID: 1}, Type: model.TaskTypeHTTPGet,
+		Cover: model.ServiceCoverIgnoreAll, SkipServerGroups: []uint64{5}}
+	reporter := &model.Server{Common: model.Common{ID: 2, UserID: 1}}
+	assert.True(t, canReportServiceResult(service, reporter, model.TaskTypeHTTPGet))
+	reporter = &model.Server{Common: model.Common{ID: 4, UserID: 1}}
+	assert.False(t, canReportServiceResult(service, reporter, model.TaskTypeHTTPGet))
+}
@@ -0,0 +1,176 @@
+package singleton
+
+import (
+	"time"
+
+	"github.com/nezhahq/nezha/model"
+)
+
+// reporterState 单个监测点对某个服务的最近结果
+type reporterState struct {
+	consecutiveFailures uint32
+	lastReport          time.Time
+	results             []reporterResult // 最近 _CurrentStatusSize 次结果
+}
+
+type reporterResult struct {
+	successful bool
+	delay      float32
+}
+
+func (rs *reporterState) record(successful bool, delay float32) {
+	rs.lastReport = time.Now()
+	if successful {
+		rs.consecutiveFailures = 0
+	} else {
+		rs.consecutiveFailures++
+	}
+	if len(rs.results) == _CurrentStatusSize {
+		rs.results = append(rs.results[:0], rs.results[1:]...)
+	}
+	rs.results = append(rs.results, reporterResult{successful: successful, delay: delay})
+}
+
+// failing 连续失败达到确认次数，未配置时失败一次即算
+func (rs *reporterState) failing(s *model.Service) bool {
+	return rs.consecutiveFailures >= max(s.FailureConfirmations, 1)
+}
+
+// status 监测点的状态，可用率按服务的阈值计算；只有 failing 的监测点为故障，
+// 最近一次已恢复但可用率仍低于阈值时为低可用
+func (rs *reporterState) status(s *model.Service) model.ServiceReporterStatus {
+	r := model.ServiceReporterStatus{
+		ConsecutiveFailures: rs.consecutiveFailures,
+		LastCheck:           rs.lastReport,
+	}
+	for _, result := range rs.results {
+		if result.successful {
+			r.Up++
+			r.Delay = (r.Delay*float64(r.Up-1) + float64(result.delay)) / float64(r.Up)
+		} else {
+			r.Down++
+		}
+	}
+	code := serviceStatusCode(s, r.Up, r.Down)
+	if rs.failing(s) {
+		code = StatusDown
+	} else if code == StatusDown {
+		code = StatusLowAvailability
+	}
+	r.Status = serviceEventStatus(code)
+	return r
+}
+
+// serviceStatusCode 按服务配置的阈值计算状态。未自定义规则时沿用 GetStatusCode，
+// 否则只有没有任何数据时才视为无数据，可用率为 0 时为故障
+func serviceStatusCode(s *model.Service, up, down uint64) uint8 {
+	if !s.HasCustomState() {
+		var upPercent uint64
+		if up+down > 0 {
+			upPercent = up * 100 / (up + down)
+		}
+		return GetStatusCode(upPercent)
+	}
+	if up+down == 0 {
+		return StatusNoData
+	}
+	good, low := s.StatusThresholds()
+	percent := float32(up) * 100 / float32(up+down)
+	switch {
+	case percent > good:
+		return StatusGood
+	case percent > low:
+		return StatusLowAvailability
+	default:
+		return StatusDown
+	}
+}
+
+// reporterActiveSince 超过三个监测周期没有上报的监测点不再参与判定
+func reporterActiveSince(s *model.Service) time.Time {
+	return time.Now().Add(-3 * time.Duration(max(s.Duration, 1)) * time.Second)
+}
+
+// activeReporters 返回服务的监测点状态，顺带清理不再活跃的监测点。
+// 调用方需持有 serviceResponseDataStoreLock
+func (ss *ServiceSentinel) activeReporters(s *model.Service) map[uint64]*reporterState {
+	reporters := ss.serviceReporterState[s.ID]
+	activeSince := reporterActiveSince(s)
+	for id, rs := range reporters {
+		if rs.lastReport.Before(activeSince) {
+			delete(reporters, id)
+		}
+	}
+	return reporters
+}
+
+// confirmServiceState 记录监测点的结果，并在服务要求确认故障时修正 stateCode：
+// 连续失败达到 FailureConfirmations 次的活跃监测点数量达到 DownQuorum 时服务即为故障，
+// 未达到时不会进入故障状态。调用方需持有 serviceResponseDataStoreLock
+func (ss *ServiceSentinel) confirmServiceState(s *model.Service, reporter uint64, successful bool, delay float32, stateCode, lastStatus uint8) uint8 {
+	reporters, ok := ss.serviceReporterState[s.ID]
+	if !ok {
+		reporters = make(map[uint64]*reporterState)
+		ss.serviceReporterState[s.ID] = reporters
+	}
+	rs, ok := reporters[reporter]
+	if !ok {
+		rs = &reporterState{}
+		reporters[reporter] = rs
+	}
+	rs.record(successful, delay)
+
+	reporters = ss.activeReporters(s)
+	if !s.RequiresDownConfirmation() {
+		return stateCode
+	}
+
+	var failing uint32
+	for _, rs := range reporters {
+		if rs.failing(s) {
+			failing++
+		}
+	}
+	quorum := min(max(s.DownQuorum, 1), uint32(len(reporters)))
+	if failing >= quorum {
+		return StatusDown
+	}
+	if stateCode != StatusDown {
+		return stateCode
+	}
+	// 故障尚未确认，保持之前的状态
+	if lastStatus == 0 || lastStatus == StatusNoData || lastStatus == StatusDown {
+		return StatusLowAvailability
+	}
+	return lastStatus
+}
+
+// ReporterStatus 返回服务各监测点的当前状态，键为服务器 ID
+func (ss *ServiceSentinel) ReporterStatus(serviceID uint64) map[uint64]model.ServiceReporterStatus {
+	ss.serviceResponseDataStoreLock.RLock()
+	defer ss.serviceResponseDataStoreLock.RUnlock()
+	ss.servicesLock.RLock()
+	s, ok := ss.services[serviceID]
+	ss.servicesLock.RUnlock()
+	if !ok {
+		return nil
+	}
+	return ss.reporterStatusLocked(s)
+}
+
+// reporterStatusLocked 调用方需持有 serviceResponseDataStoreLock
+func (ss *ServiceSentinel) reporterStatusLocked(s *model.Service) map[uint64]model.ServiceReporterStatus {
+	reporters := ss.serviceReporterState[s.ID]
+	if len(reporters) == 0 {
+		return nil
+	}
+	activeSince := reporterActiveSince(s)
+	result := make(map[uint64]model.ServiceReporterStatus, len(reporters))
+	for id, rs := range reporters {
+		if rs.lastReport.Before(activeSince) {
+			continue
+		}
+		result[id] = rs.status(s)
+	}
+	return result
+}
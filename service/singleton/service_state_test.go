@@ -0,0 +1,136 @@
+package singleton
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nezhahq/nezha/model"
+)
+
+func TestServiceStatusCode(t *testing.T) {
+	legacy := &model.Service{}
+	assert.Equal(t, uint8(StatusNoData), serviceStatusCode(legacy, 0, 0))
+	assert.Equal(t, uint8(StatusNoData), serviceStatusCode(legacy, 0, 5))
+	assert.Equal(t, uint8(StatusGood), serviceStatusCode(legacy, 96, 4))
+	assert.Equal(t, uint8(StatusLowAvailability), serviceStatusCode(legacy, 90, 10))
+	assert.Equal(t, uint8(StatusDown), serviceStatusCode(legacy, 50, 50))
+
+	custom := &model.Service{GoodThreshold: 99, LowThreshold: 50}
+	assert.Equal(t, uint8(StatusNoData), serviceStatusCode(custom, 0, 0))
+	assert.Equal(t, uint8(StatusDown), serviceStatusCode(custom, 0, 5))
+	assert.Equal(t, uint8(StatusLowAvailability), serviceStatusCode(custom, 96, 4))
+	assert.Equal(t, uint8(StatusLowAvailability), serviceStatusCode(custom, 60, 40))
+	assert.Equal(t, uint8(StatusDown), serviceStatusCode(custom, 50, 50))
+}
+
+func TestServiceValidateStateRules(t *testing.T) {
+	assert.NoError(t, (&model.Service{}).ValidateStateRules())
+	assert.NoError(t, (&model.Service{GoodThreshold: 99.5}).ValidateStateRules())
+	assert.Error(t, (&model.Service{GoodThreshold: 101}).ValidateStateRules())
+	assert.Error(t, (&model.Service{LowThreshold: 96}).ValidateStateRules())
+	assert.Error(t, (&model.Service{GoodThreshold: 50, LowThreshold: 50}).ValidateStateRules())
+}
+
+func TestConfirmServiceStateConsecutiveFailures(t *testing.T) {
+	ss := &ServiceSentinel{serviceReporterState: make(map[uint64]map[uint64]*reporterState)}
+	s := &model.Service{Common: model.Common{ID: 1}, Duration: 30, FailureConfirmations: 3}
+
+	// 前两次失败不足以确认故障，保持之前的状态
+	assert.Equal(t, uint8(StatusGood), ss.confirmServiceState(s, 1, false, 10, StatusDown, StatusGood))
+	assert.Equal(t, uint8(StatusGood), ss.confirmServiceState(s, 1, false, 10, StatusDown, StatusGood))
+	assert.Equal(t, uint8(StatusDown), ss.confirmServiceState(s, 1, false, 10, StatusDown, StatusGood))
+
+	// 成功一次后重新计数
+	assert.Equal(t, uint8(StatusLowAvailability), ss.confirmServiceState(s, 1, true, 10, StatusLowAvailability, StatusDown))
+	assert.Equal(t, uint8(StatusLowAvailability), ss.confirmServiceState(s, 1, false, 10, StatusDown, StatusDown))
+
+	// 可用率尚未降到故障时，连续失败确认后同样视为故障
+	ss.confirmServiceState(s, 1, false, 10, StatusGood, StatusGood)
+	assert.Equal(t, uint8(StatusDown), ss.confirmServiceState(s, 1, false, 10, StatusGood, StatusGood))
+}
+
+func TestConfirmServiceStateQuorum(t *testing.T) {
+	ss := &ServiceSentinel{serviceReporterState: make(map[uint64]map[uint64]*reporterState)}
+	s := &model.Service{Common: model.Common{ID: 1}, Duration: 30, DownQuorum: 2}
+
+	for reporter := uint64(1); reporter <= 5; reporter++ {
+		ss.confirmServiceState(s, reporter, true, 10, StatusGood, StatusGood)
+	}
+	assert.Equal(t, uint8(StatusGood), ss.confirmServiceState(s, 1, false, 10, StatusDown, StatusGood))
+	assert.Equal(t, uint8(StatusDown), ss.confirmServiceState(s, 2, false, 10, StatusDown, StatusLowAvailability))
+	assert.Equal(t, uint8(StatusLowAvailability), ss.confirmServiceState(s, 2, true, 10, StatusDown, StatusDown))
+
+	// 只有一个监测点时法定数量随之降低
+	single := &model.Service{Common: model.Common{ID: 2}, Duration: 30, DownQuorum: 2}
+	assert.Equal(t, uint8(StatusDown), ss.confirmServiceState(single, 1, false, 10, StatusDown, StatusGood))
+}
+
+func TestProcessReportDownQuorum(t *testing.T) {
+	ss := newServiceMonitorSecurityHarness(t,
+		&model.Server{Common: model.Common{ID: 1, UserID: 1}, Name: "a"},
+		&model.Server{Common: model.Common{ID: 2, UserID: 1}, Name: "b"},
+		&model.Server{Common: model.Common{ID: 3, UserID: 1}, Name: "c"},
+	)
+	service := &model.Service{
+		Common:      model.Common{ID: 10, UserID: 1},
+		Name:        "www",
+		Type:        model.TaskTypeTCPPing,
+		Target:      "www.example.invalid:443",
+		Duration:    3600,
+		Cover:       model.ServiceCoverIgnoreAll,
+		SkipServers: map[uint64]bool{1: true, 2: true, 3: true},
+		DownQuorum:  2,
+	}
+	addServiceMonitorSecurityService(t, ss, service)
+
+	ss.Dispatch(serviceMonitorResult(1, service.ID, model.TaskTypeTCPPing, true))
+	ss.Dispatch(serviceMonitorResult(2, service.ID, model.TaskTypeTCPPing, true))
+	ss.Dispatch(serviceMonitorResult(3, service.ID, model.TaskTypeTCPPing, false))
+	ss.Close()
+
+	ss.serviceResponseDataStoreLock.RLock()
+	defer ss.serviceResponseDataStoreLock.RUnlock()
+	status := ss.serviceCurrentStatusData[service.ID]
+	require.NotNil(t, status)
+	// 只有一个监测点失败，未达到法定数量，不会进入故障状态
+	assert.NotEqual(t, uint8(StatusDown), status.lastStatus)
+	assert.Equal(t, uint32(1), ss.serviceReporterState[service.ID][3].consecutiveFailures)
+}
+
+func TestReporterStatusPerLocation(t *testing.T) {
+	ss := newServiceMonitorSecurityHarness(t,
+		&model.Server{Common: model.Common{ID: 1, UserID: 1}, Name: "Frankfurt"},
+		&model.Server{Common: model.Common{ID: 2, UserID: 1}, Name: "Tokyo"},
+	)
+	service := &model.Service{
+		Common:      model.Common{ID: 10, UserID: 1},
+		Name:        "www",
+		Type:        model.TaskTypeTCPPing,
+		Target:      "www.example.invalid:443",
+		Duration:    3600,
+		Cover:       model.ServiceCoverIgnoreAll,
+		SkipServers: map[uint64]bool{1: true, 2: true},
+		DownQuorum:  2,
+	}
+	addServiceMonitorSecurityService(t, ss, service)
+
+	ss.Dispatch(serviceMonitorResult(1, service.ID, model.TaskTypeTCPPing, false))
+	ss.Dispatch(serviceMonitorResult(2, service.ID, model.TaskTypeTCPPing, true))
+	ss.Dispatch(serviceMonitorResult(2, service.ID, model.TaskTypeTCPPing, true))
+	ss.Close()
+
+	status := ss.ReporterStatus(service.ID)
+	require.Len(t, status, 2)
+	assert.Equal(t, model.NotificationEventServiceDown, status[1].Status)
+	assert.Equal(t, uint32(1), status[1].ConsecutiveFailures)
+	assert.Equal(t, model.NotificationEventServiceGood, status[2].Status)
+	assert.Equal(t, uint64(2), status[2].Up)
+	assert.Equal(t, float64(12), status[2].Delay)
+
+	stats := ss.CopyStats()[service.ID]
+	assert.Equal(t, status, stats.Reporters)
+	// 只有一个监测点故障，未达到法定数量
+	assert.NotEqual(t, model.NotificationEventServiceDown, stats.Status)
+}
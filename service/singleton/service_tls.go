@@ -0,0 +1,115 @@
+package singleton
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nezhahq/nezha/model"
+	pb "github.com/nezhahq/nezha/proto"
+)
+
+// serviceCertificate 服务最近一次获取到的证书
+type serviceCertificate struct {
+	info      *model.TLSCertificateInfo
+	reporter  uint64
+	checkedAt time.Time
+}
+
+// checkTLSCertificate 记录 HTTP 监控获取到的证书，并发送获取失败、即将过期、吊销与更换提醒。
+// 调用方需持有 serviceResponseDataStoreLock
+func (ss *ServiceSentinel) checkTLSCertificate(reporter uint64, cs *model.Service, mh *pb.TaskResult, cert *model.TLSCertificateInfo, enableNotify bool) {
+	var previous *model.TLSCertificateInfo
+	if c, ok := ss.tlsCertCache[cs.ID]; ok {
+		previous = c.info
+	}
+	if cert != nil {
+		ss.tlsCertCache[cs.ID] = &serviceCertificate{info: cert, reporter: reporter, checkedAt: time.Now()}
+	}
+	subject := model.NotificationSubject{ServiceID: cs.ID}
+
+	if strings.HasPrefix(mh.Data, "SSL证书错误：") {
+		// i/o timeout、connection timeout、EOF 错误
+		if !strings.HasSuffix(mh.Data, "timeout") &&
+			!strings.HasSuffix(mh.Data, "EOF") &&
+			!strings.HasSuffix(mh.Data, "timed out") && enableNotify {
+			muteLabel := NotificationMuteLabel.ServiceTLS(cs.ID, "network")
+			go NotificationShared.SendEventNotification(cs.NotificationGroupID, Localizer.Tf("[TLS] Fetch cert info failed, Reporter: %s, Error: %s", cs.Name, mh.Data), muteLabel, subject, nil)
+		}
+		return
+	}
+	// 清除网络错误静音缓存
+	NotificationShared.UnMuteNotification(cs.NotificationGroupID, NotificationMuteLabel.ServiceTLS(cs.ID, "network"))
+
+	leaf := cert.Leaf()
+	if leaf == nil || !enableNotify {
+		return
+	}
+	notificationGroupID := cs.NotificationGroupID
+	serviceName := cs.Name
+	expiresTimeStr := leaf.NotAfter.Format(time.DateTime)
+
+	// 证书过期提醒，每个提醒天数只提醒一次
+	// 静音规则：服务 id + 提醒天数 + 证书过期时间，用于避免多个监测点对相同证书同时报警
+	now := time.Now()
+	if threshold := cs.TLSWarnThreshold(leaf.NotAfter, now); threshold > 0 {
+		var errMsg string
+		if leaf.NotAfter.Before(now) {
+			errMsg = Localizer.Tf("The TLS certificate has expired. Expiration time: %s", expiresTimeStr)
+		} else {
+			errMsg = Localizer.Tf("The TLS certificate will expire within %d days. Expiration time: %s", threshold, expiresTimeStr)
+		}
+		muteLabel := NotificationMuteLabel.ServiceTLS(cs.ID, fmt.Sprintf("expire_%d_%s", threshold, expiresTimeStr))
+		go NotificationShared.SendEventNotification(notificationGroupID, fmt.Sprintf("[TLS] %s %s", serviceName, errMsg), muteLabel, subject, nil)
+	}
+
+	if cert.OCSPStatus == model.OCSPStatusRevoked {
+		errMsg := Localizer.Tf("The TLS certificate has been revoked, serial number: %s", leaf.SerialNumber)
+		muteLabel := NotificationMuteLabel.ServiceTLS(cs.ID, "revoked_"+leaf.SerialNumber)
+		go NotificationShared.SendEventNotification(notificationGroupID, fmt.Sprintf("[TLS] %s %s", serviceName, errMsg), muteLabel, subject, nil)
+	}
+
+	// 证书变更提醒，证书变更后缓存随之更新，所以不需要静音
+	if previous != nil && previous.ReplacedBy(cert) {
+		old := previous.Leaf()
+		errMsg := Localizer.Tf(
+			"TLS certificate changed, old: issuer %s, expires at %s; new: issuer %s, expires at %s",
+			old.Issuer, old.NotAfter.Format(time.DateTime), leaf.Issuer, expiresTimeStr)
+		go NotificationShared.SendEventNotification(notificationGroupID, fmt.Sprintf("[TLS] %s %s", serviceName, errMsg), "", subject, nil)
+	}
+}
+
+// ListCertificates 返回所有获取到证书的服务，按过期时间先后排序
+func (ss *ServiceSentinel) ListCertificates() []model.ServiceCertificate {
+	ss.serviceResponseDataStoreLock.RLock()
+	defer ss.serviceResponseDataStoreLock.RUnlock()
+	ss.servicesLock.RLock()
+	defer ss.servicesLock.RUnlock()
+
+	now := time.Now()
+	list := make([]model.ServiceCertificate, 0, len(ss.tlsCertCache))
+	for id, c := range ss.tlsCertCache {
+		s, ok := ss.services[id]
+		if !ok || c.info.Leaf() == nil {
+			continue
+		}
+		list = append(list, model.ServiceCertificate{
+			ServiceID:   id,
+			ServiceName: s.Name,
+			Target:      s.Target,
+			ReporterID:  c.reporter,
+			CheckedAt:   c.checkedAt,
+			DaysLeft:    c.info.DaysLeft(now),
+			Certificate: c.info,
+		})
+	}
+	slices.SortFunc(list, func(a, b model.ServiceCertificate) int {
+		return cmp.Or(
+			a.Certificate.Leaf().NotAfter.Compare(b.Certificate.Leaf().NotAfter),
+			cmp.Compare(a.ServiceID, b.ServiceID),
+		)
+	})
+	return list
+}
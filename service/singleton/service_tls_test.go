@@ -0,0 +1,75 @@
+package singleton
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nezhahq/nezha/model"
+)
+
+func TestListCertificatesSortedByExpiry(t *testing.T) {
+	ss := newServiceMonitorSecurityHarness(t,
+		&model.Server{Common: model.Common{ID: 1, UserID: 1}, Name: "reporter"},
+	)
+	newService := func(id uint64, name string) *model.Service {
+		s := &model.Service{
+			Common:      model.Common{ID: id, UserID: 1},
+			Name:        name,
+			Type:        model.TaskTypeHTTPGet,
+			Target:      "https://" + name + ".example.invalid",
+			Duration:    3600,
+			Cover:       model.ServiceCoverIgnoreAll,
+			SkipServers: map[uint64]bool{1: true},
+		}
+		addServiceMonitorSecurityService(t, ss, s)
+		return s
+	}
+	legacy := newService(10, "legacy")
+	structured := newService(20, "structured")
+	plain := newService(30, "plain")
+
+	// 旧版 agent 回传 "颁发者|过期时间"
+	r := serviceMonitorResult(1, legacy.ID, model.TaskTypeHTTPGet, true)
+	r.Data.Data = "R3|2030-06-01 00:00:00 +0000 UTC"
+	ss.Dispatch(r)
+
+	// 新版结果携带完整证书链
+	notAfter := time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC)
+	data, err := json.Marshal(model.HTTPProbeResult{
+		StatusCode: 200,
+		Cert:       "E5|2030-03-01 00:00:00 +0000 UTC",
+		TLS: &model.TLSCertificateInfo{
+			Chain: []model.TLSCertificate{
+				{Subject: "structured.example.invalid", Issuer: "E5", SerialNumber: "0A", NotAfter: notAfter},
+				{Subject: "E5", Issuer: "ISRG Root X1"},
+			},
+			ChainValid: true,
+		},
+	})
+	require.NoError(t, err)
+	r = serviceMonitorResult(1, structured.ID, model.TaskTypeHTTPGet, true)
+	r.Data.Data = string(data)
+	ss.Dispatch(r)
+
+	// 没有证书信息的结果不会出现在列表中
+	r = serviceMonitorResult(1, plain.ID, model.TaskTypeHTTPGet, false)
+	r.Data.Data = "dial tcp: i/o timeout"
+	ss.Dispatch(r)
+	ss.Close()
+
+	list := ss.ListCertificates()
+	require.Len(t, list, 2)
+	assert.Equal(t, structured.ID, list[0].ServiceID)
+	assert.Equal(t, uint64(1), list[0].ReporterID)
+	assert.Len(t, list[0].Certificate.Chain, 2)
+	assert.Equal(t, notAfter, list[0].Certificate.Leaf().NotAfter)
+	assert.Equal(t, legacy.ID, list[1].ServiceID)
+	assert.Equal(t, "R3", list[1].Certificate.Leaf().Issuer)
+
+	ss.Delete([]uint64{structured.ID})
+	assert.Len(t, ss.ListCertificates(), 1)
+}
@@ -7,7 +7,6 @@ import (
 	"log"
 	"maps"
 	"slices"
-	"strings"
 	"sync"
 	"time"
 
@@ -74,8 +73,11 @@ type ServiceSentinel struct {
 	serviceCurrentStatusData     map[uint64]*serviceTaskStatus    // 当前任务结果缓存
 	serviceResponseDataStore     map[uint64]serviceResponseData   // 当前数据
 
-	serviceResponsePing                   map[uint64]map[uint64]*pingStore // guarded by serviceResponseDataStoreLock; [service_id] -> ClientID -> delay
-	tlsCertCache                          map[uint64]string                // guarded by serviceResponseDataStoreLock
+	serviceResponsePing                   map[uint64]map[uint64]*pingStore     // guarded by serviceResponseDataStoreLock; [service_id] -> ClientID -> delay
+	tlsCertCache                          map[uint64]*serviceCertificate       // guarded by serviceResponseDataStoreLock
+	heartbeatLastSeen                     map[uint64]time.Time                 // guarded by serviceResponseDataStoreLock; 心跳监控最近一次收到心跳或判定超时的时间
+	serviceReporterState                  map[uint64]map[uint64]*reporterState // guarded by serviceResponseDataStoreLock; [service_id] -> ClientID -> 连续失败情况
+	dashboardProbing                      sync.Map                             // [service_id] 正在执行的面板探测
 	serviceReportValidatedHook            func(uint64)
 	loadStatsResponseLockedHook           func()
 	serviceReportBeforeTLSSideEffectsHook func(uint64)
@@ -109,12 +111,16 @@ func NewServiceSentinel(serviceSentinelDispatchBus chan<- *model.Service) (*Serv
 		serviceResponseDataStore: make(map[uint64]serviceResponseData),
 		serviceResponsePing:      make(map[uint64]map[uint64]*pingStore),
 		services:                 make(map[uint64]*model.Service),
-		tlsCertCache:             make(map[uint64]string),
+		tlsCertCache:             make(map[uint64]*serviceCertificate),
+		heartbeatLastSeen:        make(map[uint64]time.Time),
+		serviceReporterState:     make(map[uint64]map[uint64]*reporterState),
 		// 30天数据缓存
 		monthlyStatus: make(map[uint64]*serviceResponseItem),
 		dispatchBus:   serviceSentinelDispatchBus,
 	}
 
+	resolveStaleServiceIncidents()
+
 	// 加载历史记录
 	err := ss.loadServiceHistory()
 	if err != nil {
@@ -218,14 +224,13 @@ func (ss *ServiceSentinel) loadServiceHistory() error {
 			log.Printf("NEZHA>> quarantining service %d: %v", service.ID, err)
 			continue
 		}
-		task := service
-		// 通过cron定时将服务监控任务传递给任务调度管道
-		service.CronJobID, err = CronShared.AddFunc(task.CronSpec(), func() {
-			ss.dispatchBus <- task
-		})
-		if err != nil {
+		if err := ss.addServiceCronJob(service); err != nil {
 			return err
 		}
+		// 重启后从加载时开始计算心跳期限
+		if service.Type == model.TaskTypeHeartbeat {
+			ss.heartbeatLastSeen[service.ID] = time.Now()
+		}
 		ss.services[service.ID] = service
 		ss.serviceCurrentStatusData[service.ID] = new(serviceTaskStatus)
 		ss.serviceCurrentStatusData[service.ID].result = make([]*pb.TaskResult, 0, _CurrentStatusSize)
@@ -348,6 +353,25 @@ func (ss *ServiceSentinel) loadTodayStats(today time.Time) {
 	}
 }
 
+// addServiceCronJob 注册服务监控的定时任务，加载与修改时共用：心跳监控定时检查是否超时，
+// 其它监控通过任务调度管道下发给 agent，开启面板探测时同时由面板执行
+func (ss *ServiceSentinel) addServiceCronJob(m *model.Service) error {
+	var err error
+	if m.Type == model.TaskTypeHeartbeat {
+		m.CronJobID, err = CronShared.AddFunc(m.CronSpec(), func() {
+			ss.checkHeartbeat(m)
+		})
+		return err
+	}
+	m.CronJobID, err = CronShared.AddFunc(m.CronSpec(), func() {
+		if m.DashboardProbe {
+			go ss.dashboardProbe(m)
+		}
+		ss.dispatchBus <- m
+	})
+	return err
+}
+
 func (ss *ServiceSentinel) Update(m *model.Service) error {
 	if m == nil {
 		return fmt.Errorf("service is nil")
@@ -363,14 +387,18 @@ func (ss *ServiceSentinel) Update(m *model.Service) error {
 	ss.servicesLock.Lock()
 	defer ss.servicesLock.Unlock()
 
-	var err error
 	// 写入新任务
-	m.CronJobID, err = CronShared.AddFunc(m.CronSpec(), func() {
-		ss.dispatchBus <- m
-	})
-	if err != nil {
+	if err := ss.addServiceCronJob(m); err != nil {
 		return err
 	}
+	if m.Type == model.TaskTypeHeartbeat {
+		// 从加载或修改时开始计算期限
+		if _, ok := ss.heartbeatLastSeen[m.ID]; !ok || ss.services[m.ID] == nil || ss.services[m.ID].Type != m.Type {
+			ss.heartbeatLastSeen[m.ID] = time.Now()
+		}
+	} else {
+		delete(ss.heartbeatLastSeen, m.ID)
+	}
 	if ss.services[m.ID] != nil {
 		// 停掉旧任务
 		CronShared.Remove(ss.services[m.ID].CronJobID)
@@ -392,6 +420,13 @@ func (ss *ServiceSentinel) Update(m *model.Service) error {
 	}
 	// 更新这个任务
 	ss.services[m.ID] = m
+	// 更换或解绑升级策略后，进行中的升级不再继续，故障事件一并结束
+	EscalationPolicyShared.Cancel(func(key EscalationKey, policyID uint64) bool {
+		return key.ServiceID == m.ID && policyID != m.EscalationPolicyID
+	})
+	if m.EscalationPolicyID == 0 {
+		resolveServiceIncident(m.ID)
+	}
 	return nil
 }
 
@@ -408,6 +443,8 @@ func (ss *ServiceSentinel) Delete(ids []uint64) {
 		delete(ss.serviceResponseDataStore, id)
 		delete(ss.serviceResponsePing, id)
 		delete(ss.tlsCertCache, id)
+		delete(ss.heartbeatLastSeen, id)
+		delete(ss.serviceReporterState, id)
 		delete(ss.serviceStatusToday, id)
 
 		// 停掉定时任务
@@ -424,7 +461,12 @@ func (ss *ServiceSentinel) Delete(ids []uint64) {
 		delete(ss.services, id)
 
 		delete(ss.monthlyStatus, id)
+		resolveServiceIncident(id)
 	}
+
+	EscalationPolicyShared.Cancel(func(key EscalationKey, _ uint64) bool {
+		return key.ServiceID != 0 && slices.Contains(ids, key.ServiceID)
+	})
 }
 
 func (ss *ServiceSentinel) LoadStats() map[uint64]*serviceResponseItem {
@@ -454,6 +496,13 @@ func (ss *ServiceSentinel) LoadStats() map[uint64]*serviceResponseItem {
 		ss.monthlyStatus[k].Up[29] = v.Up
 		ss.monthlyStatus[k].Down[29] = v.Down
 		ss.monthlyStatus[k].Delay[29] = v.Delay
+
+		// 各监测点状态每次重新生成，已返回的数据不会被修改
+		ss.monthlyStatus[k].Reporters = ss.reporterStatusLocked(ss.services[k])
+		ss.monthlyStatus[k].Status = ""
+		if cs, ok := ss.serviceCurrentStatusData[k]; ok {
+			ss.monthlyStatus[k].Status = serviceEventStatus(cs.lastStatus)
+		}
 	}
 
 	// 最后 5 分钟的状态 与 service 对象填充
@@ -514,17 +563,28 @@ func (ss *ServiceSentinel) CheckPermission(c *gin.Context, idList iter.Seq[uint6
 	return true
 }
 
+// canAcceptServiceReport 在 canReportServiceResult 之外接受面板自身产生的结果：
+// Reporter 为 DashboardReporterID 的只能是心跳或面板探测，agent 的 ID 不会为 0
+func canAcceptServiceReport(service *model.Service, r ReportData, reporter *model.Server) bool {
+	if r.Reporter == model.DashboardReporterID {
+		return service != nil && uint64(service.Type) == r.Data.GetType() &&
+			(service.Type == model.TaskTypeHeartbeat || service.DashboardProbe)
+	}
+	return canReportServiceResult(service, reporter, r.Data.GetType())
+}
+
 func canReportServiceResult(service *model.Service, reporter *model.Server, taskType uint64) bool {
-	if service == nil || reporter == nil || uint64(service.Type) != taskType {
+	if service == nil || reporter == nil || uint64(service.Type) != taskType ||
+		service.Type == model.TaskTypeHeartbeat {
 		return false
 	}
 	switch service.Cover {
 	case model.ServiceCoverAll:
-		if service.SkipServers[reporter.ID] {
+		if service.Skips(reporter.ID) {
 			return false
 		}
 	case model.ServiceCoverIgnoreAll:
-		if !service.SkipServers[reporter.ID] {
+		if !service.Skips(reporter.ID) {
 			return false
 		}
 	default:
@@ -581,7 +641,7 @@ func (ss *ServiceSentinel) processReport(r ReportData, serverShared *ServerClass
 	cs, _ := ss.Get(r.Data.GetId())
 	reporter, _ := serverShared.Get(r.Reporter)
 	// 入站结果必须匹配出站任务派发边界，避免 agent 伪造其他服务 ID 写入监控状态。
-	if !canReportServiceResult(cs, reporter, r.Data.GetType()) {
+	if !canAcceptServiceReport(cs, r, reporter) {
 		log.Printf("NEZHA>> Incorrect service monitor report %+v", r)
 		return
 	}
@@ -598,13 +658,17 @@ func (ss *ServiceSentinel) processReport(r ReportData, serverShared *ServerClass
 	serviceCurrentStatusData := ss.serviceCurrentStatusData[mh.GetId()]
 	currentService, serviceExists := ss.Get(mh.GetId())
 	if serviceStatusToday == nil || serviceCurrentStatusData == nil || !serviceExists ||
-		!canReportServiceResult(currentService, reporter, mh.GetType()) {
+		!canAcceptServiceReport(currentService, r, reporter) {
 		return
 	}
 	cs = currentService
+	// 判定前取出证书信息，判定后 Data 只保留证书摘要或失败原因
+	cert := cs.TLSCertificate(mh.Data)
+	// HTTP 断言与 DNS 应答由面板判定结果，失败原因写入 Data
+	mh.Successful, mh.Data = cs.CheckResult(mh.Successful, mh.Data)
 
-	if mh.Type == model.TaskTypeTCPPing || mh.Type == model.TaskTypeICMPPing {
-		// TCP/ICMP Ping 使用平均值计算后再写入
+	if mh.Type == model.TaskTypeTCPPing || mh.Type == model.TaskTypeICMPPing || mh.Type == model.TaskTypeDNS {
+		// TCP/ICMP Ping 与 DNS 按监测点使用平均值计算后再写入
 		serviceTcpMap, ok := ss.serviceResponsePing[mh.GetId()]
 		if !ok {
 			serviceTcpMap = make(map[uint64]*pingStore)
@@ -699,13 +763,11 @@ func (ss *ServiceSentinel) processReport(r ReportData, serverShared *ServerClass
 	// 计算在线率，
 	var stateCode uint8
 	{
-		upPercent := uint64(0)
 		rd := ss.serviceResponseDataStore[mh.GetId()]
-		if rd.Down+rd.Up > 0 {
-			upPercent = rd.Up * 100 / (rd.Down + rd.Up)
-		}
-		stateCode = GetStatusCode(upPercent)
+		stateCode = serviceStatusCode(cs, rd.Up, rd.Down)
 	}
+	// 按连续失败次数与监测点数量确认故障
+	stateCode = ss.confirmServiceState(cs, r.Reporter, mh.Successful, mh.Delay, stateCode, serviceCurrentStatusData.lastStatus)
 
 	if len(serviceCurrentStatusData.result) == _CurrentStatusSize {
 		serviceCurrentStatusData.t = currentTime
@@ -724,13 +786,18 @@ func (ss *ServiceSentinel) processReport(r ReportData, serverShared *ServerClass
 		serviceCurrentStatusData.result = serviceCurrentStatusData.result[:0]
 	}
 
+	// 服务或监测点处于维护窗口时只记录数据，不报警也不执行触发任务，
+	// 状态变更同样暂缓，窗口结束后按维护前的状态重新判断
+	underMaintenance := MaintenanceWindowShared.ServiceStatus(cs.ID) != nil ||
+		MaintenanceWindowShared.ServerStatus(r.Reporter) != nil
+
 	// 延迟报警
-	if mh.Delay > 0 {
+	if mh.Delay > 0 && !underMaintenance {
 		delayCheck(&r, m, cs, mh)
 	}
 
 	// 状态变更报警+触发任务执行
-	if stateCode == StatusDown || stateCode != serviceCurrentStatusData.lastStatus {
+	if !underMaintenance && (stateCode == StatusDown || stateCode != serviceCurrentStatusData.lastStatus) {
 		lastStatus := serviceCurrentStatusData.lastStatus
 		// 存储新的状态值
 		serviceCurrentStatusData.lastStatus = stateCode
@@ -742,73 +809,11 @@ func (ss *ServiceSentinel) processReport(r ReportData, serverShared *ServerClass
 	if ss.serviceReportBeforeTLSSideEffectsHook != nil {
 		ss.serviceReportBeforeTLSSideEffectsHook(mh.GetId())
 	}
-	var errMsg string
-	if strings.HasPrefix(mh.Data, "SSL证书错误：") {
-		// i/o timeout、connection timeout、EOF 错误
-		if !strings.HasSuffix(mh.Data, "timeout") &&
-			!strings.HasSuffix(mh.Data, "EOF") &&
-			!strings.HasSuffix(mh.Data, "timed out") {
-			errMsg = mh.Data
-			if cs.Notify {
-				muteLabel := NotificationMuteLabel.ServiceTLS(mh.GetId(), "network")
-				go NotificationShared.SendNotification(cs.NotificationGroupID, Localizer.Tf("[TLS] Fetch cert info failed, Reporter: %s, Error: %s", cs.Name, errMsg), muteLabel)
-			}
-		}
-	} else {
-		// 清除网络错误静音缓存
-		NotificationShared.UnMuteNotification(cs.NotificationGroupID, NotificationMuteLabel.ServiceTLS(mh.GetId(), "network"))
-
-		var newCert = strings.Split(mh.Data, "|")
-		if len(newCert) > 1 {
-			enableNotify := cs.Notify
-
-			// 首次获取证书信息时，缓存证书信息
-			if ss.tlsCertCache[mh.GetId()] == "" {
-				ss.tlsCertCache[mh.GetId()] = mh.Data
-			}
-
-			oldCert := strings.Split(ss.tlsCertCache[mh.GetId()], "|")
-			isCertChanged := false
-			expiresOld, _ := time.Parse("2006-01-02 15:04:05 -0700 MST", oldCert[1])
-			expiresNew, _ := time.Parse("2006-01-02 15:04:05 -0700 MST", newCert[1])
-
-			// 证书变更时，更新缓存
-			if oldCert[0] != newCert[0] && !expiresNew.Equal(expiresOld) {
-				isCertChanged = true
-				ss.tlsCertCache[mh.GetId()] = mh.Data
-			}
-
-			notificationGroupID := cs.NotificationGroupID
-			serviceName := cs.Name
-
-			// 需要发送提醒
-			if enableNotify {
-				// 证书过期提醒
-				if expiresNew.Before(time.Now().AddDate(0, 0, 7)) {
-					expiresTimeStr := expiresNew.Format("2006-01-02 15:04:05")
-					errMsg = Localizer.Tf(
-						"The TLS certificate will expire within seven days. Expiration time: %s",
-						expiresTimeStr,
-					)
-
-					// 静音规则： 服务id+证书过期时间
-					// 用于避免多个监测点对相同证书同时报警
-					muteLabel := NotificationMuteLabel.ServiceTLS(mh.GetId(), fmt.Sprintf("expire_%s", expiresTimeStr))
-					go NotificationShared.SendNotification(notificationGroupID, fmt.Sprintf("[TLS] %s %s", serviceName, errMsg), muteLabel)
-				}
-
-				// 证书变更提醒
-				if isCertChanged {
-					errMsg = Localizer.Tf(
-						"TLS certificate changed, old: issuer %s, expires at %s; new: issuer %s, expires at %s",
-						oldCert[0], expiresOld.Format("2006-01-02 15:04:05"), newCert[0], expiresNew.Format("2006-01-02 15:04:05"))
-
-					// 证书变更后会自动更新缓存，所以不需要静音
-					go NotificationShared.SendNotification(notificationGroupID, fmt.Sprintf("[TLS] %s %s", serviceName, errMsg), "")
-				}
-			}
-		}
+	if mh.Type != model.TaskTypeHTTPGet {
+		// 只有 HTTP 监控会回传证书信息
+		return
 	}
+	ss.checkTLSCertificate(r.Reporter, cs, mh, cert, cs.Notify && !underMaintenance)
 }
 
 func delayCheck(r *ReportData, m map[uint64]*model.Server, ss *model.Service, mh *pb.TaskResult) {
@@ -821,7 +826,7 @@ func delayCheck(r *ReportData, m map[uint64]*model.Server, ss *model.Service, mh
 	// ServerShared has its own independent lock, so a concurrent batch-delete of
 	// the reporter's server can remove the entry between the pre-lock validation
 	// and this point.  Guard against the nil pointer before using the server.
-	reporterServer := m[r.Reporter]
+	reporterServer := reportServer(r, m, ss)
 	if reporterServer == nil {
 		return
 	}
@@ -832,11 +837,11 @@ func delayCheck(r *ReportData, m map[uint64]*model.Server, ss *model.Service, mh
 	if mh.Delay > ss.MaxLatency {
 		// 延迟超过最大值
 		msg := Localizer.Tf("[Latency] %s %2f > %2f, Reporter: %s", ss.Name, mh.Delay, ss.MaxLatency, reporterServer.Name)
-		go NotificationShared.SendNotification(notificationGroupID, msg, minMuteLabel)
+		go NotificationShared.SendEventNotification(notificationGroupID, msg, minMuteLabel, model.NotificationSubject{ServiceID: ss.ID}, nil)
 	} else if mh.Delay < ss.MinLatency {
 		// 延迟低于最小值
 		msg := Localizer.Tf("[Latency] %s %2f < %2f, Reporter: %s", ss.Name, mh.Delay, ss.MinLatency, reporterServer.Name)
-		go NotificationShared.SendNotification(notificationGroupID, msg, maxMuteLabel)
+		go NotificationShared.SendEventNotification(notificationGroupID, msg, maxMuteLabel, model.NotificationSubject{ServiceID: ss.ID}, nil)
 	} else {
 		// 正常延迟， 清除静音缓存
 		NotificationShared.UnMuteNotification(notificationGroupID, minMuteLabel)
@@ -844,6 +849,18 @@ func delayCheck(r *ReportData, m map[uint64]*model.Server, ss *model.Service, mh
 	}
 }
 
+// reportServer 返回上报结果的服务器。面板自身产生的结果没有对应的服务器，
+// 使用 ID 为 0 的占位，触发任务只会下发给计划任务自身覆盖的服务器
+func reportServer(r *ReportData, m map[uint64]*model.Server, ss *model.Service) *model.Server {
+	if r.Reporter != model.DashboardReporterID {
+		return m[r.Reporter]
+	}
+	if ss.Type == model.TaskTypeHeartbeat {
+		return &model.Server{Name: Localizer.T("Heartbeat")}
+	}
+	return &model.Server{Name: Localizer.T("Dashboard")}
+}
+
 func notifyCheck(r *ReportData, m map[uint64]*model.Server,
 	ss *model.Service, mh *pb.TaskResult, lastStatus, stateCode uint8) {
 	// GHSA-jx78-55p5-rwv5: guard against concurrent server deletion (same TOCTOU
@@ -851,21 +868,54 @@ func notifyCheck(r *ReportData, m map[uint64]*model.Server,
 	// ServerShared has its own lock; m is a snapshot taken outside
 	// serviceResponseDataStoreLock, so the server may have been removed between
 	// the pre-lock validation and here.
-	reporterServer := m[r.Reporter]
+	reporterServer := reportServer(r, m, ss)
 
 	// 判断是否需要发送通知
 	isNeedSendNotification := ss.Notify && (lastStatus != 0 || stateCode == StatusDown)
 	if isNeedSendNotification && reporterServer != nil {
-		notificationGroupID := ss.NotificationGroupID
 		notificationMsg := Localizer.Tf("[%s] %s Reporter: %s, Error: %s", StatusCodeToString(stateCode), ss.Name, reporterServer.Name, mh.Data)
 		muteLabel := NotificationMuteLabel.ServiceStateChanged(mh.GetId())
+		event := &model.NotificationEvent{
+			Status:      serviceEventStatus(stateCode),
+			ServiceID:   ss.ID,
+			ServiceName: ss.Name,
+			Reporter:    reporterServer.Name,
+			Detail:      mh.Data,
+		}
 
-		// 状态变更时，清除静音缓存
-		if stateCode != lastStatus {
-			NotificationShared.UnMuteNotification(notificationGroupID, muteLabel)
+		groups := []uint64{ss.NotificationGroupID}
+		// 绑定了升级策略时，故障期间由策略决定通知哪些通知组，离开故障状态后只通知已经通知过的通知组
+		if ss.EscalationPolicyID != 0 {
+			key := EscalationKey{ServiceID: ss.ID}
+			if stateCode == StatusDown {
+				var incidentID uint64
+				if incident := openServiceIncident(ss); incident != nil {
+					incidentID = incident.ID
+					event.IncidentID = incident.ID
+					event.IncidentURL = incidentURL(incident.ID)
+					openedAt := incident.OpenedAt
+					event.StartedAt = &openedAt
+				}
+				if EscalationPolicyShared.Trigger(key, ss.EscalationPolicyID, ss.UserID, incidentID, notificationMsg, event, muteLabel, nil) {
+					groups = nil
+				}
+			} else if notified, ok := EscalationPolicyShared.Resolve(key); ok {
+				groups = notified
+			}
 		}
 
-		go NotificationShared.SendNotification(notificationGroupID, notificationMsg, muteLabel)
+		for _, notificationGroupID := range groups {
+			// 状态变更时，清除静音缓存
+			if stateCode != lastStatus {
+				NotificationShared.UnMuteNotification(notificationGroupID, muteLabel)
+			}
+
+			go NotificationShared.SendEventNotification(notificationGroupID, notificationMsg, muteLabel, model.NotificationSubject{ServiceID: ss.ID}, event)
+		}
+	}
+
+	if stateCode != StatusDown {
+		resolveServiceIncident(ss.ID)
 	}
 
 	// 判断是否需要触发任务
@@ -902,6 +952,22 @@ func GetStatusCode[T constraints.Float | constraints.Integer](percent T) uint8 {
 	return StatusDown
 }
 
+// serviceEventStatus 通知事件中的服务状态，不随语言变化
+func serviceEventStatus(statusCode uint8) string {
+	switch statusCode {
+	case StatusNoData:
+		return model.NotificationEventServiceNoData
+	case StatusGood:
+		return model.NotificationEventServiceGood
+	case StatusLowAvailability:
+		return model.NotificationEventServiceLow
+	case StatusDown:
+		return model.NotificationEventServiceDown
+	default:
+		return ""
+	}
+}
+
 func StatusCodeToString(statusCode uint8) string {
 	switch statusCode {
 	case StatusNoData:
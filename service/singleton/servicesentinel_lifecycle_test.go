@@ -470,8 +470,9 @@ func TestServiceSentinelWorkerHoldsResponseLockDuringTLSSideEffects(t *testing.T
 	ss.serviceResponseDataStoreLock.RLock()
 	cachedCertificate := ss.tlsCertCache[service.ID]
 	ss.serviceResponseDataStoreLock.RUnlock()
-	if cachedCertificate != report.Data.Data {
-		t.Fatalf("expected TLS cache %q, got %q", report.Data.Data, cachedCertificate)
+	if cachedCertificate == nil || cachedCertificate.info.Leaf().Issuer != "issuer" ||
+		!cachedCertificate.info.Leaf().NotAfter.Equal(time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Fatalf("expected TLS cache for %q, got %+v", report.Data.Data, cachedCertificate)
 	}
 }
 
@@ -334,3 +334,102 @@ func TestLoadMonthlyStatusFromTSDB_NoDoubleCountToday(t *testing.T) {
 
 	assert.Equal(t, totalAfterMonthly+ss.serviceStatusToday[serviceID].Up, totalAfterToday)
 }
+
+func TestProcessReportHTTPProbeAssertions(t *testing.T) {
+	ss := newServiceMonitorSecurityHarness(t,
+		&model.Server{Common: model.Common{ID: 1, UserID: 1}, Name: "reporter"},
+	)
+	service := &model.Service{
+		Common:      model.Common{ID: 10, UserID: 1},
+		Name:        "api",
+		Type:        model.TaskTypeHTTPGet,
+		Target:      "https://api.example.invalid/health",
+		Duration:    3600,
+		Cover:       model.ServiceCoverIgnoreAll,
+		SkipServers: map[uint64]bool{1: true},
+		HTTP:        &model.HTTPProbe{JSONPath: "status", JSONValue: "up"},
+	}
+	addServiceMonitorSecurityService(t, ss, service)
+
+	report := func(data string) ReportData {
+		r := serviceMonitorResult(1, service.ID, model.TaskTypeHTTPGet, true)
+		r.Data.Data = data
+		return r
+	}
+	ss.Dispatch(report(`{"status_code":200,"body":"{\"status\":\"up\"}"}`))
+	ss.Dispatch(report(`{"status_code":200,"body":"{\"status\":\"down\"}"}`))
+	ss.Dispatch(report(`{"status_code":503}`))
+	ss.Close()
+
+	ss.serviceResponseDataStoreLock.RLock()
+	defer ss.serviceResponseDataStoreLock.RUnlock()
+	stats := ss.serviceStatusToday[service.ID]
+	assert.Equal(t, uint64(1), stats.Up)
+	assert.Equal(t, uint64(2), stats.Down)
+
+	// 当前状态按 30 秒间隔采样，这里只会记录第二条结果
+	results := ss.serviceCurrentStatusData[service.ID].result
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Successful)
+	assert.Equal(t, `[json_path] status is "down", expected "up"`, results[0].Data)
+}
+
+func TestServiceHTTPProbePersisted(t *testing.T) {
+	newServiceMonitorSecurityHarness(t)
+	service := &model.Service{
+		Common: model.Common{UserID: 1},
+		Name:   "api",
+		Type:   model.TaskTypeHTTPGet,
+		Target: "https://api.example.invalid/health",
+		HTTP:   &model.HTTPProbe{Method: "POST", Headers: map[string]string{"X-Token": "t"}, AcceptedStatusCodes: []string{"2xx"}},
+	}
+	require.NoError(t, DB.Create(service).Error)
+
+	var loaded model.Service
+	require.NoError(t, DB.First(&loaded, service.ID).Error)
+	assert.Equal(t, service.HTTP, loaded.HTTP)
+
+	service.HTTP = &model.HTTPProbe{Regex: "("}
+	assert.Error(t, DB.Save(service).Error)
+}
+
+func TestProcessReportDNSPerReporter(t *testing.T) {
+	ss := newServiceMonitorSecurityHarness(t,
+		&model.Server{Common: model.Common{ID: 1, UserID: 1}, Name: "inside"},
+		&model.Server{Common: model.Common{ID: 2, UserID: 1}, Name: "outside"},
+	)
+	_, cleanup := setupTestTSDB(t)
+	defer cleanup()
+
+	service := &model.Service{
+		Common:      model.Common{ID: 10, UserID: 1},
+		Name:        "www",
+		Type:        model.TaskTypeDNS,
+		Target:      "www.example.com",
+		Duration:    3600,
+		Cover:       model.ServiceCoverIgnoreAll,
+		SkipServers: map[uint64]bool{1: true, 2: true},
+		DNS:         &model.DNSProbe{Expected: []string{"10.0.0.1"}},
+	}
+	addServiceMonitorSecurityService(t, ss, service)
+
+	report := func(reporter uint64, data string) ReportData {
+		r := serviceMonitorResult(reporter, service.ID, model.TaskTypeDNS, true)
+		r.Data.Data = data
+		return r
+	}
+	// 内外网解析结果不同，只有内网监测点符合预期
+	ss.Dispatch(report(1, `{"rcode":"NOERROR","answers":["10.0.0.1"]}`))
+	ss.Dispatch(report(2, `{"rcode":"NOERROR","answers":["203.0.113.1"]}`))
+	ss.Close()
+	TSDBShared.Flush()
+
+	result, err := TSDBShared.QueryServiceHistory(service.ID, tsdb.Period1Day)
+	require.NoError(t, err)
+	require.Len(t, result.Servers, 2)
+	assert.Equal(t, uint64(1), result.Servers[0].ServerID)
+	assert.Equal(t, uint64(1), result.Servers[0].Stats.TotalUp)
+	assert.Equal(t, uint64(2), result.Servers[1].ServerID)
+	assert.Equal(t, uint64(1), result.Servers[1].Stats.TotalDown)
+	assert.Equal(t, float64(12), result.Servers[0].Stats.AvgDelay)
+}
@@ -0,0 +1,122 @@
+package singleton
+
+import (
+	"cmp"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/nezhahq/nezha/model"
+	"github.com/nezhahq/nezha/pkg/utils"
+)
+
+const SilenceGCSchedule = "@every 10m"
+
+type SilenceClass struct {
+	class[uint64, *model.Silence]
+}
+
+func NewSilenceClass() *SilenceClass {
+	// 启动时顺带清理已过期的静默
+	if err := DB.Unscoped().Delete(&model.Silence{}, "expires_at <= ?", time.Now()).Error; err != nil {
+		log.Printf("NEZHA>> Failed to clean expired silences: %v", err)
+	}
+
+	var sortedList []*model.Silence
+	DB.Find(&sortedList)
+	list := make(map[uint64]*model.Silence, len(sortedList))
+	for _, s := range sortedList {
+		list[s.ID] = s
+	}
+
+	return &SilenceClass{
+		class: class[uint64, *model.Silence]{
+			list:       list,
+			sortedList: sortedList,
+		},
+	}
+}
+
+func (c *SilenceClass) Update(s *model.Silence) {
+	c.listMu.Lock()
+	c.list[s.ID] = s
+	c.listMu.Unlock()
+	c.sortList()
+}
+
+func (c *SilenceClass) Delete(idList []uint64) {
+	c.listMu.Lock()
+	for _, id := range idList {
+		delete(c.list, id)
+	}
+	c.listMu.Unlock()
+	c.sortList()
+}
+
+// Match 返回第一条覆盖 subject 的有效静默，没有则返回 nil
+func (c *SilenceClass) Match(subject model.NotificationSubject) *model.Silence {
+	now := time.Now()
+	var needGroups bool
+
+	c.listMu.RLock()
+	candidates := make([]*model.Silence, 0, len(c.list))
+	for _, s := range c.list {
+		if s.IsActive(now) {
+			candidates = append(candidates, s)
+			needGroups = needGroups || s.ServerGroupID != 0
+		}
+	}
+	c.listMu.RUnlock()
+
+	if needGroups && subject.ServerID != 0 && subject.ServerGroupIDs == nil {
+		subject.ServerGroupIDs = serverGroupsOf(subject.ServerID)
+	}
+	for _, s := range candidates {
+		if s.Matches(subject) {
+			return s
+		}
+	}
+	return nil
+}
+
+// expiredIDs 返回已过期的静默 ID
+func (c *SilenceClass) expiredIDs() []uint64 {
+	now := time.Now()
+	c.listMu.RLock()
+	defer c.listMu.RUnlock()
+
+	var ids []uint64
+	for id, s := range c.list {
+		if !s.IsActive(now) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// CleanExpired 删除已过期的静默
+func (c *SilenceClass) CleanExpired() {
+	ids := c.expiredIDs()
+	if len(ids) == 0 {
+		return
+	}
+	if err := DB.Unscoped().Delete(&model.Silence{}, "id in (?)", ids).Error; err != nil {
+		log.Printf("NEZHA>> Failed to clean expired silences: %v", err)
+		return
+	}
+	c.Delete(ids)
+}
+
+func (c *SilenceClass) sortList() {
+	c.listMu.RLock()
+	defer c.listMu.RUnlock()
+
+	sortedList := utils.MapValuesToSlice(c.list)
+	slices.SortFunc(sortedList, func(a, b *model.Silence) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	c.sortedListMu.Lock()
+	defer c.sortedListMu.Unlock()
+	c.sortedList = sortedList
+}
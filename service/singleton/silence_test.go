@@ -0,0 +1,72 @@
+package singleton
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/nezhahq/nezha/model"
+)
+
+// 静默按调用方给出的通知对象匹配，与静音标志的格式无关
+func TestSendEventNotificationHonorsSilence(t *testing.T) {
+	previousCache, previousConf, previousSilence := Cache, Conf, SilenceShared
+	t.Cleanup(func() {
+		Cache, Conf, SilenceShared = previousCache, previousConf, previousSilence
+	})
+	Cache = cache.New(time.Minute, time.Minute)
+	Conf = &ConfigClass{Config: &model.Config{}}
+	silence := &model.Silence{Common: model.Common{ID: 1}, AlertRuleID: 3, ExpiresAt: time.Now().Add(time.Hour)}
+	SilenceShared = &SilenceClass{class: class[uint64, *model.Silence]{list: map[uint64]*model.Silence{1: silence}}}
+	nc := NewEmptyNotificationClassForTest()
+
+	nc.SendEventNotification(0, "silenced", "label", model.NotificationSubject{AlertRuleID: 3, ServerID: 4}, nil)
+	assert.Zero(t, Cache.ItemCount(), "silenced notifications do not start the mute backoff")
+
+	nc.SendEventNotification(0, "delivered", "label", model.NotificationSubject{AlertRuleID: 4, ServerID: 3}, nil)
+	assert.Equal(t, 1, Cache.ItemCount())
+}
+
+func TestSilenceClassMatch(t *testing.T) {
+	previousDB, previousCache := DB, Cache
+	var err error
+	DB, err = gorm.Open(openSQLiteDialector(filepath.Join(t.TempDir(), "dashboard.sqlite")), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := DB.DB()
+	require.NoError(t, err)
+	Cache = cache.New(time.Minute, time.Minute)
+	t.Cleanup(func() {
+		DB, Cache = previousDB, previousCache
+		_ = sqlDB.Close()
+	})
+	require.NoError(t, DB.AutoMigrate(&model.Silence{}, &model.ServerGroupServer{}))
+	require.NoError(t, DB.Create(&model.ServerGroupServer{ServerGroupId: 5, ServerId: 2}).Error)
+
+	now := time.Now()
+	require.NoError(t, DB.Create(&model.Silence{ServerID: 1, ExpiresAt: now.Add(-time.Minute)}).Error)
+	require.NoError(t, DB.Create(&model.Silence{ServerGroupID: 5, ExpiresAt: now.Add(time.Hour)}).Error)
+
+	sc := NewSilenceClass()
+	assert.Len(t, sc.GetSortedList(), 1, "expired silences are dropped at startup")
+
+	assert.NotNil(t, sc.Match(model.NotificationSubject{ServerID: 2}))
+	assert.Nil(t, sc.Match(model.NotificationSubject{ServerID: 1}))
+	// 分组成员读取缓存的快照，分组变更清除缓存后才生效
+	require.NoError(t, DB.Create(&model.ServerGroupServer{ServerGroupId: 5, ServerId: 1}).Error)
+	assert.Nil(t, sc.Match(model.NotificationSubject{ServerID: 1}))
+	InvalidateServerGroupMembers()
+	assert.NotNil(t, sc.Match(model.NotificationSubject{ServerID: 1}))
+
+	short := &model.Silence{ServiceID: 8, ExpiresAt: now.Add(-time.Second)}
+	require.NoError(t, DB.Create(short).Error)
+	sc.Update(short)
+	assert.Nil(t, sc.Match(model.NotificationSubject{ServiceID: 8}), "expired silences never match")
+	sc.CleanExpired()
+	_, ok := sc.Get(short.ID)
+	assert.False(t, ok)
+}
@@ -27,12 +27,15 @@ var (
 	FrontendTemplates []model.FrontendTemplate
 	DashboardBootTime = uint64(time.Now().Unix())
 
-	ServerShared          *ServerClass
-	ServiceSentinelShared *ServiceSentinel
-	DDNSShared            *DDNSClass
-	NotificationShared    *NotificationClass
-	NATShared             *NATClass
-	CronShared            *CronClass
+	ServerShared            *ServerClass
+	ServiceSentinelShared   *ServiceSentinel
+	DDNSShared              *DDNSClass
+	NotificationShared      *NotificationClass
+	NATShared               *NATClass
+	CronShared              *CronClass
+	SilenceShared           *SilenceClass
+	MaintenanceWindowShared *MaintenanceWindowClass
+	EscalationPolicyShared  *EscalationPolicyClass
 	// ServerTransferShared is initialized in LoadSingleton AFTER ServerShared
 	// (so the in-memory pending index can write back into ServerShared.UserID
 	// on transitions) and AFTER initUser (so PushIfOnline can read secrets
@@ -62,6 +65,21 @@ func LoadSingleton(bus chan<- *model.Service) (err error) {
 	NotificationShared = NewNotificationClass()
 	ServerShared = NewServerClass()
 	CronShared = NewCronClass()
+	SilenceShared = NewSilenceClass()
+	MaintenanceWindowShared = NewMaintenanceWindowClass()
+	if _, err = CronShared.AddFunc(SilenceGCSchedule, SilenceShared.CleanExpired); err != nil {
+		return
+	}
+	if _, err = CronShared.AddFunc(NotificationRetrySchedule, NotificationShared.RetryDeliveries); err != nil {
+		return
+	}
+	if _, err = CronShared.AddFunc(NotificationDeliveryGCSchedule, CleanNotificationDeliveries); err != nil {
+		return
+	}
+	EscalationPolicyShared = NewEscalationPolicyClass()
+	if _, err = CronShared.AddFunc(EscalationCheckSchedule, EscalationPolicyShared.CheckEscalations); err != nil {
+		return
+	}
 	ServerTransferShared = NewServerTransferClass()
 	// 最后初始化 ServiceSentinel
 	ServiceSentinelShared, err = NewServiceSentinel(bus)
@@ -94,7 +112,9 @@ func InitDBFromPath(path string) error {
 		model.Cron{}, model.Transfer{}, model.ServerGroupServer{},
 		model.NAT{}, model.DDNSProfile{}, model.NotificationGroupNotification{},
 		model.WAF{}, model.Oauth2Bind{}, model.ServerTransfer{}, model.JWTSession{},
-		model.APIToken{}, model.MCPAuditLog{})
+		model.APIToken{}, model.MCPAuditLog{}, model.Incident{}, model.Silence{},
+		model.MaintenanceWindow{}, model.EscalationPolicy{}, model.EscalationDelivery{},
+		model.NotificationDelivery{})
 	if err != nil {
 		return err
 	}
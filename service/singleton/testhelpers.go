@@ -68,3 +68,17 @@ func (c *NotificationClass) InsertForTest(n *model.Notification) {
 	c.listMu.Unlock()
 	c.sortList()
 }
+
+// NewEmptyServiceSentinelForTest 构造一个不依赖 DB、不启动 worker 的空 ServiceSentinel，
+// 仅用于需要查询服务列表的单测。
+func NewEmptyServiceSentinelForTest() *ServiceSentinel {
+	return &ServiceSentinel{services: make(map[uint64]*model.Service)}
+}
+
+// InsertForTest 把一个服务直接塞进内存表与排序列表，不注册定时任务。
+func (ss *ServiceSentinel) InsertForTest(s *model.Service) {
+	ss.servicesLock.Lock()
+	ss.services[s.ID] = s
+	ss.servicesLock.Unlock()
+	ss.UpdateServiceList()
+}
@@ -1,6 +1,7 @@
 package singleton
 
 import (
+	"fmt"
 	"log"
 	"time"
 
@@ -10,7 +11,8 @@ import (
 
 var TSDBShared *tsdb.TSDB
 
-func InitTSDB() error {
+// TSDBConfig 根据配置文件生成 TSDB 配置
+func TSDBConfig() *tsdb.Config {
 	config := &tsdb.Config{
 		RetentionDays:      30,
 		MinFreeDiskSpaceGB: 1,
@@ -35,9 +37,33 @@ func InitTSDB() error {
 	if Conf.TSDB.WriteBufferFlushInterval > 0 {
 		config.WriteBufferFlushInterval = time.Duration(Conf.TSDB.WriteBufferFlushInterval) * time.Second
 	}
+	config.Backend = Conf.TSDB.Backend
+	remote := Conf.TSDB.Remote
+	config.Remote = tsdb.RemoteConfig{
+		WriteURL:    remote.WriteURL,
+		QueryURL:    remote.QueryURL,
+		BearerToken: remote.BearerToken,
+		Username:    remote.Username,
+		Password:    remote.Password,
+		Timeout:     time.Duration(remote.Timeout) * time.Second,
+		SpoolPath:   remote.SpoolPath,
+		MaxSpoolMB:  remote.MaxSpoolMB,
+	}
+	config.DisableRollups = Conf.TSDB.DisableRollups
+	for _, r := range Conf.TSDB.Rollups {
+		config.Rollups = append(config.Rollups, tsdb.RollupTier{
+			Interval:      time.Duration(r.Interval) * time.Second,
+			RetentionDays: r.RetentionDays,
+		})
+	}
 
+	return config
+}
+
+func InitTSDB() error {
+	config := TSDBConfig()
 	if !config.Enabled() {
-		log.Println("NEZHA>> TSDB is disabled (tsdb.data_path not configured)")
+		log.Println("NEZHA>> TSDB is disabled (tsdb.data_path or tsdb.remote.write_url not configured)")
 		if DB != nil {
 			return DB.AutoMigrate(model.ServiceHistory{})
 		}
@@ -52,6 +78,8 @@ func InitTSDB() error {
 
 	log.Println("NEZHA>> TSDB initialized successfully")
 
+	model.ServerMetricHistoryLookup = queryServerMetricHistory
+
 	if DB != nil && DB.Migrator().HasTable("service_histories") {
 		log.Println("NEZHA>> Dropping legacy service_histories table (TSDB is now enabled). Historical data will NOT be migrated.")
 		if err := DB.Migrator().DropTable("service_histories"); err != nil {
@@ -62,6 +90,73 @@ func InitTSDB() error {
 	return nil
 }
 
+// ruleTypeMetrics 报警规则类型对应的 TSDB 指标，多个指标按时间戳求和
+var ruleTypeMetrics = map[string][]tsdb.MetricType{
+	"cpu":             {tsdb.MetricServerCPU},
+	"gpu_max":         {tsdb.MetricServerGPU},
+	"memory":          {tsdb.MetricServerMemory},
+	"swap":            {tsdb.MetricServerSwap},
+	"disk":            {tsdb.MetricServerDisk},
+	"net_in_speed":    {tsdb.MetricServerNetInSpeed},
+	"net_out_speed":   {tsdb.MetricServerNetOutSpeed},
+	"net_all_speed":   {tsdb.MetricServerNetInSpeed, tsdb.MetricServerNetOutSpeed},
+	"transfer_in":     {tsdb.MetricServerNetInTransfer},
+	"transfer_out":    {tsdb.MetricServerNetOutTransfer},
+	"transfer_all":    {tsdb.MetricServerNetInTransfer, tsdb.MetricServerNetOutTransfer},
+	"load1":           {tsdb.MetricServerLoad1},
+	"load5":           {tsdb.MetricServerLoad5},
+	"load15":          {tsdb.MetricServerLoad15},
+	"tcp_conn_count":  {tsdb.MetricServerTCPConn},
+	"udp_conn_count":  {tsdb.MetricServerUDPConn},
+	"process_count":   {tsdb.MetricServerProcessCount},
+	"temperature_max": {tsdb.MetricServerTemperature},
+	"disk_mount":      {tsdb.MetricServerMountUsed},
+	"nic_in_speed":    {tsdb.MetricServerNICInSpeed},
+	"nic_out_speed":   {tsdb.MetricServerNICOutSpeed},
+	"nic_all_speed":   {tsdb.MetricServerNICInSpeed, tsdb.MetricServerNICOutSpeed},
+}
+
+// serverMetricHistoryCacheTTL 报警每 3 秒检查一次，窗口聚合结果短时间内复用，
+// 避免每轮都扫描整段历史
+const serverMetricHistoryCacheTTL = 30 * time.Second
+
+type serverMetricHistoryValue struct {
+	value float64
+	ok    bool
+}
+
+func queryServerMetricHistory(serverID uint64, ruleType, target, aggregation string, window time.Duration) (float64, bool) {
+	if !TSDBEnabled() {
+		return 0, false
+	}
+	metrics, has := ruleTypeMetrics[ruleType]
+	if !has {
+		return 0, false
+	}
+	agg, err := tsdb.ParseAggregation(aggregation)
+	if err != nil {
+		return 0, false
+	}
+
+	cacheKey := fmt.Sprintf("%s%d:%s:%s:%s:%d", model.CacheKeyMetricHistory, serverID, ruleType, target, agg, window)
+	if Cache != nil {
+		if cached, has := Cache.Get(cacheKey); has {
+			v := cached.(serverMetricHistoryValue)
+			return v.value, v.ok
+		}
+	}
+
+	value, ok, err := TSDBShared.QueryServerDeviceMetricAggregate(serverID, target, window, agg, metrics...)
+	if err != nil {
+		log.Printf("NEZHA>> Failed to query metric history for server %d: %v", serverID, err)
+		return 0, false
+	}
+	if Cache != nil {
+		Cache.Set(cacheKey, serverMetricHistoryValue{value: value, ok: ok}, serverMetricHistoryCacheTTL)
+	}
+	return value, ok
+}
+
 func TSDBEnabled() bool {
 	return TSDBShared != nil && !TSDBShared.IsClosed()
 }